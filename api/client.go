@@ -0,0 +1,359 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Sentinel errors returned (wrapped) by Client methods. Use errors.Is to check them.
+var (
+	// ErrUnauthorized means the Clash API rejected the secret (401/403)
+	ErrUnauthorized = errors.New("clash API: unauthorized")
+	// ErrNotFound means the requested proxy, group or connection does not exist (404)
+	ErrNotFound = errors.New("clash API: not found")
+	// ErrUnreachable means the Clash API could not be reached (sing-box not running, wrong address)
+	ErrUnreachable = errors.New("clash API: unreachable")
+)
+
+// StatusError is returned when the Clash API responds with a non-2xx status.
+// It unwraps to ErrUnauthorized or ErrNotFound for the corresponding statuses.
+type StatusError struct {
+	Code int
+	Body string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status code: %d, body: %s", e.Code, e.Body)
+}
+
+// Unwrap maps the status code to a sentinel error
+func (e *StatusError) Unwrap() error {
+	switch e.Code {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return ErrUnauthorized
+	case http.StatusNotFound:
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Logger receives request logs of a Client. The secret is redacted before it reaches the logger.
+type Logger interface {
+	Printf(format string, args ...interface{})
+}
+
+// fileLogger writes timestamped lines to the API log file
+type fileLogger struct {
+	file *os.File
+}
+
+func (l fileLogger) Printf(format string, args ...interface{}) {
+	timestamp := time.Now().Format("2006-01-02 15:04:05")
+	fmt.Fprintf(l.file, "[%s] %s\n", timestamp, fmt.Sprintf(format, args...))
+}
+
+// ClientOption configures a Client
+type ClientOption func(*Client)
+
+// WithLogger sets the request logger
+func WithLogger(logger Logger) ClientOption {
+	return func(c *Client) { c.logger = logger }
+}
+
+// WithLogFile logs requests to the API log file (no-op for nil)
+func WithLogFile(file *os.File) ClientOption {
+	return func(c *Client) {
+		if file != nil {
+			c.logger = fileLogger{file: file}
+		}
+	}
+}
+
+// WithHTTPClient replaces the HTTP client used for regular (non-streaming) requests
+func WithHTTPClient(httpClient *http.Client) ClientOption {
+	return func(c *Client) { c.httpClient = httpClient }
+}
+
+// Client talks to the sing-box Clash API. It is cheap to create and safe for concurrent use.
+// Requests without a deadline in ctx are limited by the default request timeout.
+type Client struct {
+	baseURL      string
+	token        string
+	httpClient   *http.Client
+	streamClient *http.Client
+	logger       Logger
+}
+
+// NewClient creates a client for the Clash API at baseURL (e.g. "http://127.0.0.1:9090")
+func NewClient(baseURL, token string, opts ...ClientOption) *Client {
+	c := &Client{
+		baseURL:      strings.TrimRight(baseURL, "/"),
+		token:        token,
+		httpClient:   httpClient,
+		streamClient: streamHTTPClient,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// BaseURL returns the API address the client talks to
+func (c *Client) BaseURL() string {
+	return c.baseURL
+}
+
+// logf writes a log line with the secret redacted
+func (c *Client) logf(format string, args ...interface{}) {
+	if c.logger == nil {
+		return
+	}
+	c.logger.Printf("%s", RedactSecret(fmt.Sprintf(format, args...), c.token))
+}
+
+// RedactSecret replaces every occurrence of secret in s with "***"
+func RedactSecret(s, secret string) string {
+	if secret == "" {
+		return s
+	}
+	return strings.ReplaceAll(s, secret, "***")
+}
+
+// newRequest builds an authorized request for path (relative to the base URL)
+func (c *Client) newRequest(ctx context.Context, method, path string, body interface{}) (*http.Request, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal %s %s request: %w", method, path, err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s %s request: %w", method, path, err)
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return req, nil
+}
+
+// do performs a request and returns the body of a 2xx response
+func (c *Client) do(ctx context.Context, method, path string, body interface{}) ([]byte, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(httpRequestTimeoutSeconds)*time.Second)
+		defer cancel()
+	}
+	req, err := c.newRequest(ctx, method, path, body)
+	if err != nil {
+		return nil, err
+	}
+
+	c.logf("%s %s request started.", method, path)
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		c.logf("Error executing %s %s: %v", method, path, err)
+		return nil, transportError(err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		c.logf("Error reading %s %s response: %v", method, path, err)
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	c.logf("%s %s response status: %d", method, path, resp.StatusCode)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, &StatusError{Code: resp.StatusCode, Body: RedactSecret(strings.TrimSpace(string(data)), c.token)}
+	}
+	return data, nil
+}
+
+// getJSON performs a GET request and unmarshals the response into out
+func (c *Client) getJSON(ctx context.Context, path string, out interface{}) error {
+	data, err := c.do(ctx, "GET", path, nil)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		c.logf("Error unmarshalling %s response: %v", path, err)
+		return fmt.Errorf("failed to unmarshal %s response: %w", path, err)
+	}
+	return nil
+}
+
+// transportError converts a transport failure into a readable error wrapping ErrUnreachable where appropriate
+func transportError(err error) error {
+	if errors.Is(err, context.Canceled) {
+		return err
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return fmt.Errorf("network timeout: connection timed out")
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return fmt.Errorf("%w: cannot connect to server", ErrUnreachable)
+	}
+	return fmt.Errorf("%w: %v", ErrUnreachable, err)
+}
+
+// Version is the /version response
+type Version struct {
+	Version string `json:"version"`
+	Premium bool   `json:"premium"`
+	Meta    bool   `json:"meta"`
+}
+
+// Version returns the core version; also used to check that the API is reachable and the secret is valid
+func (c *Client) Version(ctx context.Context) (*Version, error) {
+	var version Version
+	if err := c.getJSON(ctx, "/version", &version); err != nil {
+		return nil, err
+	}
+	return &version, nil
+}
+
+// DelayHistory is one entry of a proxy's latency test history
+type DelayHistory struct {
+	Time  time.Time `json:"time"`
+	Delay int64     `json:"delay"`
+}
+
+// Proxy is an outbound as reported by /proxies. Groups (selector, urltest) also have Now and All.
+type Proxy struct {
+	Name    string         `json:"name"`
+	Type    string         `json:"type"`
+	UDP     bool           `json:"udp"`
+	Up      int64          `json:"up,omitempty"`
+	Down    int64          `json:"down,omitempty"`
+	Now     string         `json:"now,omitempty"`
+	All     []string       `json:"all,omitempty"`
+	History []DelayHistory `json:"history"`
+}
+
+// IsGroup reports whether the proxy is a group with members
+func (p Proxy) IsGroup() bool {
+	return p.All != nil
+}
+
+// LastDelay returns the delay of the latest test in ms (0 if untested or failed)
+func (p Proxy) LastDelay() int64 {
+	if len(p.History) == 0 {
+		return 0
+	}
+	return p.History[len(p.History)-1].Delay
+}
+
+// Proxies returns all outbounds by name
+func (c *Client) Proxies(ctx context.Context) (map[string]Proxy, error) {
+	var response struct {
+		Proxies map[string]Proxy `json:"proxies"`
+	}
+	if err := c.getJSON(ctx, "/proxies", &response); err != nil {
+		return nil, err
+	}
+	if response.Proxies == nil {
+		return nil, fmt.Errorf("'proxies' key not found in the response")
+	}
+	return response.Proxies, nil
+}
+
+// Group returns a single group. Returns ErrNotFound if it does not exist or is not a group.
+func (c *Client) Group(ctx context.Context, name string) (*Proxy, error) {
+	var proxy Proxy
+	if err := c.getJSON(ctx, "/proxies/"+url.PathEscape(name), &proxy); err != nil {
+		return nil, err
+	}
+	if !proxy.IsGroup() {
+		return nil, fmt.Errorf("'%s' is not a group: %w", name, ErrNotFound)
+	}
+	return &proxy, nil
+}
+
+// ProxiesInGroup returns the members of a group sorted by name, with their last delay, and the active member
+func (c *Client) ProxiesInGroup(ctx context.Context, groupName string) ([]ProxyInfo, string, error) {
+	proxies, err := c.Proxies(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	group, ok := proxies[groupName]
+	if !ok || !group.IsGroup() {
+		var availableGroups []string
+		for name, proxy := range proxies {
+			if proxy.IsGroup() {
+				availableGroups = append(availableGroups, name)
+			}
+		}
+		c.logf("ProxiesInGroup: Proxy group '%s' not found. Available groups: %v", groupName, availableGroups)
+		return nil, "", fmt.Errorf("proxy group '%s' not found: %w", groupName, ErrNotFound)
+	}
+
+	result := make([]ProxyInfo, 0, len(group.All))
+	for _, name := range group.All {
+		member := proxies[name]
+		result = append(result, ProxyInfo{Name: name, Traffic: [2]int64{member.Up, member.Down}, Delay: member.LastDelay()})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	c.logf("ProxiesInGroup: %d proxies in group '%s', active '%s'.", len(result), groupName, group.Now)
+	return result, group.Now, nil
+}
+
+// SwitchProxy selects proxy in the selector group
+func (c *Client) SwitchProxy(ctx context.Context, group, proxy string) error {
+	_, err := c.do(ctx, "PUT", "/proxies/"+url.PathEscape(group), map[string]string{"name": proxy})
+	if err != nil {
+		return err
+	}
+	c.logf("Successfully switched group '%s' to '%s'.", group, proxy)
+	return nil
+}
+
+// Configs is the /configs response (the fields sing-box reports)
+type Configs struct {
+	Port        int      `json:"port"`
+	SocksPort   int      `json:"socks-port"`
+	RedirPort   int      `json:"redir-port"`
+	TProxyPort  int      `json:"tproxy-port"`
+	MixedPort   int      `json:"mixed-port"`
+	AllowLan    bool     `json:"allow-lan"`
+	BindAddress string   `json:"bind-address"`
+	Mode        string   `json:"mode"`
+	ModeList    []string `json:"mode-list,omitempty"`
+	LogLevel    string   `json:"log-level"`
+	IPv6        bool     `json:"ipv6"`
+}
+
+// Configs returns the running configuration
+func (c *Client) Configs(ctx context.Context) (*Configs, error) {
+	var configs Configs
+	if err := c.getJSON(ctx, "/configs", &configs); err != nil {
+		return nil, err
+	}
+	return &configs, nil
+}
+
+// PatchConfigs changes the running configuration (e.g. {"mode": "global"})
+func (c *Client) PatchConfigs(ctx context.Context, patch map[string]interface{}) error {
+	_, err := c.do(ctx, "PATCH", "/configs", patch)
+	return err
+}
@@ -0,0 +1,289 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testSecret = "s3cr3t-token"
+
+// fakeClashServer emulates the parts of the sing-box Clash API used by the launcher
+type fakeClashServer struct {
+	mu     sync.Mutex
+	now    string
+	mode   string
+	closed []string
+}
+
+func newFakeClashServer(t *testing.T) (*fakeClashServer, *httptest.Server) {
+	fake := &fakeClashServer{now: "🇩🇪 Germany", mode: "rule"}
+	server := httptest.NewServer(http.HandlerFunc(fake.serve))
+	t.Cleanup(server.Close)
+	return fake, server
+}
+
+func (f *fakeClashServer) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.Header.Get("Authorization") != "Bearer "+testSecret {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"message":"Unauthorized"}`)
+		return
+	}
+
+	switch {
+	case r.Method == "GET" && r.URL.Path == "/version":
+		fmt.Fprint(w, `{"version":"sing-box 1.12.0","premium":true,"meta":true}`)
+	case r.Method == "GET" && r.URL.Path == "/proxies":
+		fmt.Fprintf(w, `{"proxies":{
+			"proxy-out":{"type":"Selector","name":"proxy-out","udp":true,"now":%q,"all":["🇩🇪 Germany","🇳🇱 Netherlands","direct-out"],"history":[]},
+			"🇩🇪 Germany":{"type":"VLESS","name":"🇩🇪 Germany","udp":true,"history":[{"time":"2024-05-01T12:00:00Z","delay":300},{"time":"2024-05-01T12:05:00Z","delay":120}]},
+			"🇳🇱 Netherlands":{"type":"Trojan","name":"🇳🇱 Netherlands","udp":false,"history":[]},
+			"direct-out":{"type":"Direct","name":"direct-out","udp":true,"history":[]}
+		}}`, f.now)
+	case r.Method == "GET" && r.URL.EscapedPath() == "/proxies/proxy-out":
+		fmt.Fprintf(w, `{"type":"Selector","name":"proxy-out","now":%q,"all":["🇩🇪 Germany","direct-out"],"history":[]}`, f.now)
+	case r.Method == "GET" && r.URL.EscapedPath() == "/proxies/direct-out":
+		fmt.Fprint(w, `{"type":"Direct","name":"direct-out","history":[]}`)
+	case r.Method == "PUT" && r.URL.EscapedPath() == "/proxies/proxy-out":
+		var body struct {
+			Name string `json:"name"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Name == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if body.Name == "missing" {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message":"Selector update error: not found"}`)
+			return
+		}
+		f.now = body.Name
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == "GET" && r.URL.Path == "/configs":
+		fmt.Fprintf(w, `{"port":0,"socks-port":0,"mixed-port":2080,"allow-lan":false,"mode":%q,"mode-list":["rule","global","direct"],"log-level":"info"}`, f.mode)
+	case r.Method == "PATCH" && r.URL.Path == "/configs":
+		var patch map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if mode, ok := patch["mode"].(string); ok {
+			f.mode = mode
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == "GET" && r.URL.Path == "/connections":
+		fmt.Fprint(w, `{"downloadTotal":2048,"uploadTotal":1024,"connections":[
+			{"id":"c1","metadata":{"network":"tcp","host":"example.com","destinationPort":"443"},"upload":10,"download":20,"start":"2024-05-01T12:00:00Z","chains":["🇩🇪 Germany","proxy-out"],"rule":"final","rulePayload":""}
+		]}`)
+	case r.Method == "DELETE" && strings.HasPrefix(r.URL.Path, "/connections"):
+		f.closed = append(f.closed, strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/connections"), "/"))
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"message":"Resource not found"}`)
+	}
+}
+
+// TestClientVersion tests typed decoding and authorization errors
+func TestClientVersion(t *testing.T) {
+	_, server := newFakeClashServer(t)
+
+	version, err := NewClient(server.URL, testSecret).Version(context.Background())
+	if err != nil {
+		t.Fatalf("Version() error: %v", err)
+	}
+	if version.Version != "sing-box 1.12.0" || !version.Meta {
+		t.Errorf("Unexpected version: %+v", version)
+	}
+
+	_, err = NewClient(server.URL, "wrong").Version(context.Background())
+	if !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Expected ErrUnauthorized, got %v", err)
+	}
+}
+
+// TestClientUnreachable tests that connection failures wrap ErrUnreachable
+func TestClientUnreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	baseURL := server.URL
+	server.Close()
+
+	_, err := NewClient(baseURL, testSecret).Version(context.Background())
+	if !errors.Is(err, ErrUnreachable) {
+		t.Errorf("Expected ErrUnreachable, got %v", err)
+	}
+}
+
+// TestClientContextCancel tests that a cancelled context aborts the request
+func TestClientContextCancel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	if _, err := NewClient(server.URL, testSecret).Version(ctx); err == nil {
+		t.Error("Expected error after context deadline")
+	}
+	if time.Since(started) > 5*time.Second {
+		t.Error("Request was not aborted by the context")
+	}
+}
+
+// TestClientProxiesInGroup tests group members, active member and last delay from history
+func TestClientProxiesInGroup(t *testing.T) {
+	_, server := newFakeClashServer(t)
+	client := NewClient(server.URL, testSecret)
+
+	proxies, now, err := client.ProxiesInGroup(context.Background(), "proxy-out")
+	if err != nil {
+		t.Fatalf("ProxiesInGroup() error: %v", err)
+	}
+	if now != "🇩🇪 Germany" {
+		t.Errorf("Expected active '🇩🇪 Germany', got %q", now)
+	}
+	if len(proxies) != 3 || proxies[0].Name != "direct-out" {
+		t.Fatalf("Expected 3 proxies sorted by name, got %+v", proxies)
+	}
+	if proxies[1].Name != "🇩🇪 Germany" || proxies[1].Delay != 120 {
+		t.Errorf("Expected latest delay 120 for Germany, got %+v", proxies[1])
+	}
+
+	if _, _, err := client.ProxiesInGroup(context.Background(), "direct-out"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for non-group, got %v", err)
+	}
+}
+
+// TestClientGroup tests fetching a single group
+func TestClientGroup(t *testing.T) {
+	_, server := newFakeClashServer(t)
+	client := NewClient(server.URL, testSecret)
+
+	group, err := client.Group(context.Background(), "proxy-out")
+	if err != nil {
+		t.Fatalf("Group() error: %v", err)
+	}
+	if group.Type != "Selector" || group.Now != "🇩🇪 Germany" || len(group.All) != 2 {
+		t.Errorf("Unexpected group: %+v", group)
+	}
+	if _, err := client.Group(context.Background(), "direct-out"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for non-group, got %v", err)
+	}
+	if _, err := client.Group(context.Background(), "nope"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for missing group, got %v", err)
+	}
+}
+
+// TestClientSwitchProxy tests PUT /proxies/{group}
+func TestClientSwitchProxy(t *testing.T) {
+	fake, server := newFakeClashServer(t)
+	client := NewClient(server.URL, testSecret)
+
+	if err := client.SwitchProxy(context.Background(), "proxy-out", "direct-out"); err != nil {
+		t.Fatalf("SwitchProxy() error: %v", err)
+	}
+	if fake.now != "direct-out" {
+		t.Errorf("Expected server selection 'direct-out', got %q", fake.now)
+	}
+	if err := client.SwitchProxy(context.Background(), "proxy-out", "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+// TestClientConfigs tests reading and patching /configs
+func TestClientConfigs(t *testing.T) {
+	_, server := newFakeClashServer(t)
+	client := NewClient(server.URL, testSecret)
+
+	if err := client.PatchConfigs(context.Background(), map[string]interface{}{"mode": "global"}); err != nil {
+		t.Fatalf("PatchConfigs() error: %v", err)
+	}
+	configs, err := client.Configs(context.Background())
+	if err != nil {
+		t.Fatalf("Configs() error: %v", err)
+	}
+	if configs.Mode != "global" || configs.MixedPort != 2080 || len(configs.ModeList) != 3 {
+		t.Errorf("Unexpected configs: %+v", configs)
+	}
+}
+
+// TestClientConnections tests listing and closing connections
+func TestClientConnections(t *testing.T) {
+	fake, server := newFakeClashServer(t)
+	client := NewClient(server.URL, testSecret)
+
+	snapshot, err := client.Connections(context.Background())
+	if err != nil {
+		t.Fatalf("Connections() error: %v", err)
+	}
+	if snapshot.DownloadTotal != 2048 || len(snapshot.Connections) != 1 {
+		t.Fatalf("Unexpected snapshot: %+v", snapshot)
+	}
+	conn := snapshot.Connections[0]
+	if conn.Destination() != "example.com:443" || conn.Start.IsZero() || len(conn.Chains) != 2 {
+		t.Errorf("Unexpected connection: %+v", conn)
+	}
+
+	if err := client.CloseConnection(context.Background(), "c1"); err != nil {
+		t.Errorf("CloseConnection() error: %v", err)
+	}
+	if err := client.CloseAllConnections(context.Background()); err != nil {
+		t.Errorf("CloseAllConnections() error: %v", err)
+	}
+	if len(fake.closed) != 2 || fake.closed[0] != "c1" || fake.closed[1] != "" {
+		t.Errorf("Unexpected close requests: %v", fake.closed)
+	}
+}
+
+// recordingLogger collects log lines
+type recordingLogger struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (l *recordingLogger) Printf(format string, args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lines = append(l.lines, fmt.Sprintf(format, args...))
+}
+
+// TestClientLoggerRedactsSecret tests that the secret never reaches the logger
+func TestClientLoggerRedactsSecret(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Echo the Authorization header back, as a misbehaving server might
+		w.WriteHeader(http.StatusBadRequest)
+		io.WriteString(w, "bad header: "+r.Header.Get("Authorization"))
+	}))
+	defer server.Close()
+
+	logger := &recordingLogger{}
+	client := NewClient(server.URL, testSecret, WithLogger(logger))
+	_, err := client.Delay(context.Background(), testSecret, DelayTestOptions{})
+	if err == nil {
+		t.Fatal("Expected error")
+	}
+	if strings.Contains(err.Error(), testSecret) {
+		t.Errorf("Secret leaked into error: %v", err)
+	}
+	if len(logger.lines) == 0 {
+		t.Fatal("Expected log lines")
+	}
+	for _, line := range logger.lines {
+		if strings.Contains(line, testSecret) {
+			t.Errorf("Secret leaked into log: %q", line)
+		}
+	}
+}
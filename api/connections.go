@@ -0,0 +1,74 @@
+package api
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"time"
+)
+
+// ConnectionMetadata describes the endpoints of an active connection.
+type ConnectionMetadata struct {
+	Network         string `json:"network"`
+	Type            string `json:"type"`
+	SourceIP        string `json:"sourceIP"`
+	SourcePort      string `json:"sourcePort"`
+	DestinationIP   string `json:"destinationIP"`
+	DestinationPort string `json:"destinationPort"`
+	Host            string `json:"host"`
+	DNSMode         string `json:"dnsMode"`
+	ProcessPath     string `json:"processPath"`
+}
+
+// Connection is a single active connection reported by /connections.
+type Connection struct {
+	ID          string             `json:"id"`
+	Metadata    ConnectionMetadata `json:"metadata"`
+	Upload      int64              `json:"upload"`
+	Download    int64              `json:"download"`
+	Start       time.Time          `json:"start"`
+	Chains      []string           `json:"chains"`
+	Rule        string             `json:"rule"`
+	RulePayload string             `json:"rulePayload"`
+}
+
+// Destination returns "host:port", falling back to the destination IP when the host is unknown
+func (c Connection) Destination() string {
+	host := c.Metadata.Host
+	if host == "" {
+		host = c.Metadata.DestinationIP
+	}
+	if c.Metadata.DestinationPort == "" {
+		return host
+	}
+	return net.JoinHostPort(host, c.Metadata.DestinationPort)
+}
+
+// ConnectionsSnapshot is the /connections response: totals and the active connections.
+type ConnectionsSnapshot struct {
+	DownloadTotal int64        `json:"downloadTotal"`
+	UploadTotal   int64        `json:"uploadTotal"`
+	Connections   []Connection `json:"connections"`
+	Memory        uint64       `json:"memory"`
+}
+
+// Connections returns the active connections and traffic totals.
+func (c *Client) Connections(ctx context.Context) (*ConnectionsSnapshot, error) {
+	var snapshot ConnectionsSnapshot
+	if err := c.getJSON(ctx, "/connections", &snapshot); err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+// CloseConnection closes a single connection by its ID.
+func (c *Client) CloseConnection(ctx context.Context, id string) error {
+	_, err := c.do(ctx, "DELETE", "/connections/"+url.PathEscape(id), nil)
+	return err
+}
+
+// CloseAllConnections closes all active connections.
+func (c *Client) CloseAllConnections(ctx context.Context) error {
+	_, err := c.do(ctx, "DELETE", "/connections", nil)
+	return err
+}
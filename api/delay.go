@@ -0,0 +1,115 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	// DefaultDelayTestURL is the URL used for latency tests when neither config nor settings specify one
+	DefaultDelayTestURL = "http://www.gstatic.com/generate_204"
+	// DefaultDelayTestTimeout is the per-proxy latency test timeout
+	DefaultDelayTestTimeout = 5 * time.Second
+	// DelayTimedOut marks ProxyInfo.Delay of a proxy that failed the last latency test
+	DelayTimedOut int64 = -1
+)
+
+// ErrDelayTimeout is returned when the proxy did not answer the latency test in time
+// (or the test URL could not be reached through it).
+var ErrDelayTimeout = errors.New("delay test timed out")
+
+// DelayTestOptions configures a latency test request.
+type DelayTestOptions struct {
+	URL            string        // Test URL; DefaultDelayTestURL if empty
+	Timeout        time.Duration // Test timeout; DefaultDelayTestTimeout if zero
+	ExpectedStatus string        // Expected HTTP status, e.g. "204" or "200-299" (ignored by cores without support)
+}
+
+// query builds the delay endpoint query string
+func (o DelayTestOptions) query() string {
+	testURL := o.URL
+	if testURL == "" {
+		testURL = DefaultDelayTestURL
+	}
+	timeout := o.Timeout
+	if timeout <= 0 {
+		timeout = DefaultDelayTestTimeout
+	}
+	values := url.Values{}
+	values.Set("url", testURL)
+	values.Set("timeout", strconv.FormatInt(timeout.Milliseconds(), 10))
+	if o.ExpectedStatus != "" {
+		values.Set("expected", o.ExpectedStatus)
+	}
+	return values.Encode()
+}
+
+// requestTimeout returns the HTTP timeout for a delay request: the test timeout plus a margin for the API itself
+func (o DelayTestOptions) requestTimeout() time.Duration {
+	timeout := o.Timeout
+	if timeout <= 0 {
+		timeout = DefaultDelayTestTimeout
+	}
+	return timeout + time.Duration(httpDialTimeoutSeconds)*time.Second
+}
+
+// Delay tests the latency of a single proxy through /proxies/{name}/delay.
+// Returns ErrDelayTimeout (wrapped) when the proxy failed the test.
+func (c *Client) Delay(ctx context.Context, proxyName string, opts DelayTestOptions) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, opts.requestTimeout())
+	defer cancel()
+
+	var data struct {
+		Delay *int64 `json:"delay"`
+	}
+	path := fmt.Sprintf("/proxies/%s/delay?%s", url.PathEscape(proxyName), opts.query())
+	if err := c.getJSON(ctx, path, &data); err != nil {
+		return 0, delayError(proxyName, err)
+	}
+	if data.Delay == nil {
+		c.logf("Unexpected response structure for delay %s, 'delay' field missing or wrong type", proxyName)
+		return 0, fmt.Errorf("unexpected response structure, 'delay' field missing or wrong type")
+	}
+	if *data.Delay <= 0 {
+		return 0, fmt.Errorf("%s: %w", proxyName, ErrDelayTimeout)
+	}
+	c.logf("Successfully got delay for %s: %d ms.", proxyName, *data.Delay)
+	return *data.Delay, nil
+}
+
+// GroupDelay tests all members of a group at once through /group/{name}/delay.
+// The result maps member names to delay in ms; members that failed the test are absent.
+func (c *Client) GroupDelay(ctx context.Context, group string, opts DelayTestOptions) (map[string]int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, opts.requestTimeout())
+	defer cancel()
+
+	var raw map[string]int64
+	if err := c.getJSON(ctx, fmt.Sprintf("/group/%s/delay?%s", url.PathEscape(group), opts.query()), &raw); err != nil {
+		return nil, err
+	}
+	result := make(map[string]int64, len(raw))
+	for name, delay := range raw {
+		if delay > 0 {
+			result[name] = delay
+		}
+	}
+	c.logf("Group delay for '%s': %d of %d members answered.", group, len(result), len(raw))
+	return result, nil
+}
+
+// delayError maps a failed delay request to ErrDelayTimeout when the core reports a failed test
+func delayError(proxyName string, err error) error {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		switch statusErr.Code {
+		case http.StatusGatewayTimeout, http.StatusRequestTimeout, http.StatusServiceUnavailable:
+			return fmt.Errorf("%s: %w", proxyName, ErrDelayTimeout)
+		}
+	}
+	return err
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestClientDelay tests query parameters, escaping and timeout mapping
+func TestClientDelay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.EscapedPath() {
+		case "/proxies/%F0%9F%87%A9%F0%9F%87%AA%20Germany/delay":
+			if r.URL.Query().Get("url") != "https://cp.cloudflare.com" || r.URL.Query().Get("timeout") != "3000" || r.URL.Query().Get("expected") != "204" {
+				t.Errorf("Unexpected query: %s", r.URL.RawQuery)
+			}
+			w.Write([]byte(`{"delay":123}`))
+		case "/proxies/dead/delay":
+			w.WriteHeader(http.StatusGatewayTimeout)
+			w.Write([]byte(`{"message":"timeout"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	opts := DelayTestOptions{URL: "https://cp.cloudflare.com", Timeout: 3 * time.Second, ExpectedStatus: "204"}
+	client := NewClient(server.URL, "secret")
+	delay, err := client.Delay(context.Background(), "🇩🇪 Germany", opts)
+	if err != nil || delay != 123 {
+		t.Errorf("Delay() = %d, %v, expected 123", delay, err)
+	}
+
+	if _, err := client.Delay(context.Background(), "dead", opts); !errors.Is(err, ErrDelayTimeout) {
+		t.Errorf("Expected ErrDelayTimeout, got %v", err)
+	}
+
+	_, err = client.Delay(context.Background(), "missing", opts)
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.Code != http.StatusNotFound || !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected StatusError 404 wrapping ErrNotFound, got %v", err)
+	}
+}
+
+// TestClientGroupDelay tests that failed members are dropped from the result
+func TestClientGroupDelay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/group/proxy-out/delay" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.URL.Query().Get("url") != DefaultDelayTestURL || r.URL.Query().Get("timeout") != "5000" {
+			t.Errorf("Unexpected query: %s", r.URL.RawQuery)
+		}
+		w.Write([]byte(`{"a":50,"b":0,"c":310}`))
+	}))
+	defer server.Close()
+
+	delays, err := NewClient(server.URL, "secret").GroupDelay(context.Background(), "proxy-out", DelayTestOptions{})
+	if err != nil {
+		t.Fatalf("GroupDelay() error: %v", err)
+	}
+	if len(delays) != 2 || delays["a"] != 50 || delays["c"] != 310 {
+		t.Errorf("Unexpected delays: %v", delays)
+	}
+}
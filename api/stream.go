@@ -0,0 +1,128 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// streamHTTPClient is used for long-lived streaming endpoints.
+// Unlike httpClient it has no overall timeout: the stream lasts until the context is cancelled.
+var streamHTTPClient = &http.Client{
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: time.Duration(httpDialTimeoutSeconds) * time.Second,
+		}).DialContext,
+		ResponseHeaderTimeout: time.Duration(httpRequestTimeoutSeconds) * time.Second,
+	},
+}
+
+// TrafficSample is a single /traffic message: current speed in bytes per second.
+type TrafficSample struct {
+	Up   int64 `json:"up"`
+	Down int64 `json:"down"`
+}
+
+// MemorySample is a single /memory message: memory used by sing-box in bytes.
+type MemorySample struct {
+	InUse   uint64 `json:"inuse"`
+	OSLimit uint64 `json:"oslimit"`
+}
+
+// LogMessage is a single /logs message.
+type LogMessage struct {
+	Type    string `json:"type"` // debug, info, warning, error
+	Payload string `json:"payload"`
+}
+
+// StreamTraffic streams /traffic and calls onSample for every message (once per second).
+// Blocks until ctx is cancelled or the stream ends; returns nil on cancellation.
+func (c *Client) StreamTraffic(ctx context.Context, onSample func(TrafficSample)) error {
+	return c.streamJSONLines(ctx, "/traffic", func(line []byte) error {
+		var sample TrafficSample
+		if err := json.Unmarshal(line, &sample); err != nil {
+			return fmt.Errorf("failed to parse /traffic message: %w", err)
+		}
+		onSample(sample)
+		return nil
+	})
+}
+
+// StreamMemory streams /memory and calls onSample for every message.
+// Blocks until ctx is cancelled or the stream ends; returns nil on cancellation.
+func (c *Client) StreamMemory(ctx context.Context, onSample func(MemorySample)) error {
+	return c.streamJSONLines(ctx, "/memory", func(line []byte) error {
+		var sample MemorySample
+		if err := json.Unmarshal(line, &sample); err != nil {
+			return fmt.Errorf("failed to parse /memory message: %w", err)
+		}
+		onSample(sample)
+		return nil
+	})
+}
+
+// StreamLogs streams /logs with the given minimum level (debug, info, warning, error; empty means info)
+// and calls onLog for every message. Blocks until ctx is cancelled or the stream ends.
+func (c *Client) StreamLogs(ctx context.Context, level string, onLog func(LogMessage)) error {
+	path := "/logs"
+	if level != "" {
+		path += "?level=" + url.QueryEscape(level)
+	}
+	return c.streamJSONLines(ctx, path, func(line []byte) error {
+		var msg LogMessage
+		if err := json.Unmarshal(line, &msg); err != nil {
+			return fmt.Errorf("failed to parse /logs message: %w", err)
+		}
+		onLog(msg)
+		return nil
+	})
+}
+
+// streamJSONLines performs a GET request to a chunked Clash API endpoint
+// and calls onLine for every newline-delimited JSON message.
+func (c *Client) streamJSONLines(ctx context.Context, path string, onLine func([]byte) error) error {
+	req, err := c.newRequest(ctx, "GET", path, nil)
+	if err != nil {
+		return err
+	}
+
+	c.logf("GET %s stream started.", path)
+	resp, err := c.streamClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return transportError(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return &StatusError{Code: resp.StatusCode, Body: string(bodyBytes)}
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		if err := onLine(line); err != nil {
+			return err
+		}
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("stream interrupted: %w", err)
+	}
+	return fmt.Errorf("stream closed by server")
+}
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestStreamTraffic tests reading newline-delimited JSON from a chunked response
+func TestStreamTraffic(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/traffic" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		flusher := w.(http.Flusher)
+		for i := 1; i <= 3; i++ {
+			fmt.Fprintf(w, "{\"up\":%d,\"down\":%d}\n", i*100, i*1000)
+			flusher.Flush()
+		}
+	}))
+	defer server.Close()
+
+	var samples []TrafficSample
+	err := NewClient(server.URL, "secret").StreamTraffic(context.Background(), func(s TrafficSample) {
+		samples = append(samples, s)
+	})
+	if err == nil {
+		t.Error("Expected error when the server closes the stream")
+	}
+	if len(samples) != 3 || samples[2].Up != 300 || samples[2].Down != 3000 {
+		t.Errorf("Unexpected samples: %+v", samples)
+	}
+
+	if err := NewClient(server.URL, "wrong").StreamTraffic(context.Background(), func(TrafficSample) {}); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Expected ErrUnauthorized, got %v", err)
+	}
+}
+
+// TestStreamLogs_Cancel tests that cancelling the context ends the stream without error
+func TestStreamLogs_Cancel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("level") != "debug" {
+			t.Errorf("Expected level=debug, got %q", r.URL.RawQuery)
+		}
+		fmt.Fprintln(w, `{"type":"info","payload":"router: started"}`)
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	received := make(chan LogMessage, 1)
+	done := make(chan error, 1)
+	go func() {
+		done <- NewClient(server.URL, "").StreamLogs(ctx, "debug", func(msg LogMessage) { received <- msg })
+	}()
+
+	select {
+	case msg := <-received:
+		if msg.Type != "info" || msg.Payload != "router: started" {
+			t.Errorf("Unexpected message: %+v", msg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for log message")
+	}
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Expected nil error after cancel, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Stream did not stop after cancel")
+	}
+}
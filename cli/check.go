@@ -0,0 +1,95 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"singbox-launcher/core"
+)
+
+// runCheck validates ParserConfig, the generated config.json and, optionally, the config with "sing-box check"
+func runCheck(e *env, args []string) int {
+	fs := e.newFlagSet("check", "")
+	withCore := fs.Bool("core", true, "Also run 'sing-box check' on the config (skipped if sing-box is not installed)")
+	compat := fs.String("compat", "", "Also check config.json and the template against the deprecations of this sing-box version (e.g. 1.13.0)")
+	fix := fs.Bool("fix", false, "With -compat: apply the mechanical fixes to config.json first (previous file kept as config.json.old)")
+	if code := e.parseFlags(fs, args); code >= 0 {
+		return code
+	}
+	ac, err := e.controller()
+	if err != nil {
+		return e.fail("%v", err)
+	}
+	if _, err := os.Stat(ac.ConfigPath); err != nil {
+		return e.fail("config not found: %s", ac.ConfigPath)
+	}
+
+	failed := false
+	report := func(section string, problems []error) {
+		if len(problems) == 0 {
+			fmt.Fprintf(e.stdout, "%s: OK\n", section)
+			return
+		}
+		failed = true
+		fmt.Fprintf(e.stdout, "%s: %d problem(s)\n", section, len(problems))
+		for _, problem := range problems {
+			fmt.Fprintf(e.stdout, "  - %v\n", problem)
+		}
+	}
+
+	if config, err := core.ExtractParserConfig(ac.ConfigPath); err != nil {
+		report("ParserConfig", []error{err})
+	} else {
+		report("ParserConfig", core.ValidateParserConfig(config))
+	}
+	report("config.json", core.ValidateGeneratedConfig(ac.ConfigPath))
+
+	if *compat != "" {
+		if *fix {
+			fixed, err := ac.FixCoreCompatibility(*compat)
+			if err != nil {
+				report("compatibility fixes", []error{err})
+			}
+			for _, issue := range fixed {
+				fmt.Fprintf(e.stdout, "fixed: %s\n", issue)
+			}
+		}
+		compatReport, err := ac.CheckCoreCompatibility(*compat)
+		if err != nil {
+			report("sing-box "+*compat+" compatibility", []error{err})
+		} else {
+			// Устаревшие конструкции только выводятся, удалённые считаются ошибкой
+			var problems []error
+			for _, issue := range compatReport.Config {
+				if issue.Level == core.CompatRemoved {
+					problems = append(problems, fmt.Errorf("config.json: %s", issue))
+				} else {
+					fmt.Fprintf(e.stdout, "warning: config.json: %s\n", issue)
+				}
+			}
+			for _, issue := range compatReport.Template {
+				if issue.Level == core.CompatRemoved {
+					problems = append(problems, fmt.Errorf("%s: %s", core.ConfigTemplateFileName(), issue))
+				} else {
+					fmt.Fprintf(e.stdout, "warning: %s: %s\n", core.ConfigTemplateFileName(), issue)
+				}
+			}
+			report("sing-box "+*compat+" compatibility", problems)
+		}
+	}
+
+	if *withCore {
+		if _, err := os.Stat(ac.SingboxPath); err != nil {
+			fmt.Fprintf(e.stdout, "sing-box check: skipped (sing-box not installed)\n")
+		} else if err := ac.CheckConfigWithCore(); err != nil {
+			report("sing-box check", []error{err})
+		} else {
+			report("sing-box check", nil)
+		}
+	}
+
+	if failed {
+		return exitFailure
+	}
+	return exitOK
+}
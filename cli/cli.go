@@ -0,0 +1,297 @@
+// Package cli implements headless subcommands (update, check, nodes, run, status) for servers and scripts.
+// They use the same core services as the GUI but never create a window, so they work without a display.
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"singbox-launcher/core"
+)
+
+// Exit codes
+const (
+	exitOK      = 0
+	exitFailure = 1
+	exitUsage   = 2
+)
+
+// statusAPITimeout limits the Clash API requests of the status command
+const statusAPITimeout = 3 * time.Second
+
+// command is a CLI subcommand
+type command struct {
+	name    string
+	summary string
+	run     func(env *env, args []string) int
+}
+
+// commands lists the subcommands in the order shown by help
+var commands = []command{
+	{"update", "Fetch subscriptions once and update config.json", runUpdate},
+	{"check", "Validate ParserConfig and the generated config.json", runCheck},
+	{"nodes", "List nodes parsed from the subscriptions", runNodes},
+	{"run", "Run sing-box in the foreground with crash restarts and auto-update", runRun},
+	{"status", "Show profile, config, core and Clash API status", runStatus},
+}
+
+// env is what a command works with
+type env struct {
+	stdout io.Writer
+	stderr io.Writer
+
+	// Common flags
+	profile string
+	verbose bool
+
+	notifier *core.ConsoleNotifier
+}
+
+// IsCommand reports whether name is a CLI subcommand (or "help")
+func IsCommand(name string) bool {
+	if name == "help" {
+		return true
+	}
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Run executes the subcommand args[0] with the remaining arguments and returns the process exit code
+func Run(args []string) int {
+	e := &env{stdout: os.Stdout, stderr: os.Stderr}
+	if len(args) == 0 || args[0] == "help" {
+		e.usage()
+		return exitOK
+	}
+	for _, cmd := range commands {
+		if cmd.name == args[0] {
+			return cmd.run(e, args[1:])
+		}
+	}
+	fmt.Fprintf(e.stderr, "Unknown command %q\n\n", args[0])
+	e.usage()
+	return exitUsage
+}
+
+// usage prints the list of commands
+func (e *env) usage() {
+	fmt.Fprintln(e.stderr, "Usage: singbox-launcher <command> [flags]")
+	fmt.Fprintln(e.stderr, "       singbox-launcher [-start] [-tray] [-profile name]   (GUI)")
+	fmt.Fprintln(e.stderr)
+	fmt.Fprintln(e.stderr, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(e.stderr, "  %-8s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(e.stderr)
+	fmt.Fprintln(e.stderr, "Run 'singbox-launcher <command> -h' for the flags of a command.")
+}
+
+// newFlagSet creates a flag set with the flags common to all commands
+func (e *env) newFlagSet(name, args string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	fs.StringVar(&e.profile, "profile", "", "Configuration profile to use (default: the active profile)")
+	fs.BoolVar(&e.verbose, "v", false, "Also write the launcher log to stderr")
+	fs.Usage = func() {
+		fmt.Fprintf(e.stderr, "Usage: singbox-launcher %s [flags]%s\n\nFlags:\n", name, args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseFlags parses args and returns the exit code to stop with, or -1 to continue
+func (e *env) parseFlags(fs *flag.FlagSet, args []string) int {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(e.stderr, "Unexpected arguments: %s\n", strings.Join(fs.Args(), " "))
+		fs.Usage()
+		return exitUsage
+	}
+	return -1
+}
+
+// controller creates a headless controller for the selected profile
+func (e *env) controller() (*core.AppController, error) {
+	// Progress is printed only by the commands that enable it
+	e.notifier = core.NewConsoleNotifier(e.stderr)
+	ac, err := core.NewHeadlessController(e.notifier)
+	if err != nil {
+		return nil, err
+	}
+	if e.verbose {
+		log.SetOutput(io.MultiWriter(ac.MainLogFile, e.stderr))
+	}
+	if e.profile != "" {
+		if err := ac.UseProfile(e.profile); err != nil {
+			return nil, fmt.Errorf("profile: %w", err)
+		}
+	}
+	return ac, nil
+}
+
+// fail prints an error and returns exitFailure
+func (e *env) fail(format string, args ...interface{}) int {
+	fmt.Fprintf(e.stderr, "Error: "+format+"\n", args...)
+	return exitFailure
+}
+
+// runUpdate fetches the subscriptions once and writes the result to config.json
+func runUpdate(e *env, args []string) int {
+	fs := e.newFlagSet("update", "")
+	quiet := fs.Bool("q", false, "Do not print progress")
+	if code := e.parseFlags(fs, args); code >= 0 {
+		return code
+	}
+	ac, err := e.controller()
+	if err != nil {
+		return e.fail("%v", err)
+	}
+
+	e.notifier.ShowProgress = !*quiet
+	if err := ac.ConfigService.UpdateConfigFromSubscriptions(); err != nil {
+		return e.fail("%v", err)
+	}
+	fmt.Fprintf(e.stdout, "Updated %s (profile %s)\n", ac.ConfigPath, ac.CurrentProfile())
+	return exitOK
+}
+
+// statusReport is the output of the status command
+type statusReport struct {
+	Profile      string        `json:"profile"`
+	ConfigPath   string        `json:"config_path"`
+	ConfigExists bool          `json:"config_exists"`
+	LastUpdated  string        `json:"last_updated,omitempty"`
+	Reload       string        `json:"reload,omitempty"`
+	Sources      int           `json:"sources"`
+	CorePath     string        `json:"core_path"`
+	CoreVersion  string        `json:"core_version,omitempty"`
+	Running      bool          `json:"running"`
+	PID          int           `json:"pid,omitempty"`
+	ClashAPI     string        `json:"clash_api,omitempty"`
+	APIReachable bool          `json:"api_reachable"`
+	Mode         string        `json:"mode,omitempty"`
+	Groups       []statusGroup `json:"groups,omitempty"`
+}
+
+// statusGroup is a selector or urltest group with its current choice
+type statusGroup struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Now  string `json:"now,omitempty"`
+}
+
+// runStatus prints the state of the profile, the config, the core and the Clash API
+func runStatus(e *env, args []string) int {
+	fs := e.newFlagSet("status", "")
+	asJSON := fs.Bool("json", false, "Print the status as JSON")
+	if code := e.parseFlags(fs, args); code >= 0 {
+		return code
+	}
+	ac, err := e.controller()
+	if err != nil {
+		return e.fail("%v", err)
+	}
+
+	report := statusReport{
+		Profile:    ac.CurrentProfile(),
+		ConfigPath: ac.ConfigPath,
+		CorePath:   ac.SingboxPath,
+	}
+	if _, err := os.Stat(ac.ConfigPath); err == nil {
+		report.ConfigExists = true
+		if config, err := core.ExtractParserConfig(ac.ConfigPath); err == nil {
+			report.LastUpdated = config.ParserConfig.Parser.LastUpdated
+			report.Reload = config.ParserConfig.Parser.Reload
+			report.Sources = len(config.ParserConfig.Proxies)
+		}
+	}
+	if version, err := ac.GetInstalledCoreVersion(); err == nil {
+		report.CoreVersion = version
+	}
+	report.PID, report.Running = ac.FindSingBoxProcess()
+
+	if ac.ClashAPIEnabled {
+		report.ClashAPI = ac.ClashAPIBaseURL
+		ctx, cancel := context.WithTimeout(context.Background(), statusAPITimeout)
+		defer cancel()
+		client := ac.ClashClient()
+		if _, err := client.Version(ctx); err == nil {
+			report.APIReachable = true
+			if configs, err := client.Configs(ctx); err == nil {
+				report.Mode = configs.Mode
+			}
+			proxies, _ := client.Proxies(ctx)
+			groups, _ := core.GetProxyGroupsFromConfig(ac.ConfigPath)
+			for _, group := range groups {
+				report.Groups = append(report.Groups, statusGroup{Name: group.Tag, Type: group.Type, Now: proxies[group.Tag].Now})
+			}
+		}
+	}
+
+	if *asJSON {
+		return e.printJSON(report)
+	}
+	w := tabwriter.NewWriter(e.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "Profile:\t%s\n", report.Profile)
+	if !report.ConfigExists {
+		fmt.Fprintf(w, "Config:\t%s (missing)\n", report.ConfigPath)
+	} else {
+		fmt.Fprintf(w, "Config:\t%s\n", report.ConfigPath)
+		lastUpdated := report.LastUpdated
+		if lastUpdated == "" {
+			lastUpdated = "never"
+		}
+		fmt.Fprintf(w, "Last updated:\t%s (reload %s, %d sources)\n", lastUpdated, report.Reload, report.Sources)
+	}
+	coreVersion := report.CoreVersion
+	if coreVersion == "" {
+		coreVersion = "not installed"
+	}
+	fmt.Fprintf(w, "Core:\t%s (%s)\n", report.CorePath, coreVersion)
+	if report.Running {
+		fmt.Fprintf(w, "sing-box:\trunning (PID %d)\n", report.PID)
+	} else {
+		fmt.Fprintf(w, "sing-box:\tstopped\n")
+	}
+	switch {
+	case report.ClashAPI == "":
+		fmt.Fprintf(w, "Clash API:\tdisabled\n")
+	case !report.APIReachable:
+		fmt.Fprintf(w, "Clash API:\t%s (unreachable)\n", report.ClashAPI)
+	default:
+		fmt.Fprintf(w, "Clash API:\t%s (mode %s)\n", report.ClashAPI, report.Mode)
+		for _, group := range report.Groups {
+			fmt.Fprintf(w, "  %s\t%s -> %s\n", group.Name, group.Type, group.Now)
+		}
+	}
+	w.Flush()
+	return exitOK
+}
+
+// printJSON writes v as indented JSON to stdout
+func (e *env) printJSON(v interface{}) int {
+	encoder := json.NewEncoder(e.stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		return e.fail("%v", err)
+	}
+	return exitOK
+}
@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"bytes"
+	"testing"
+)
+
+// TestIsCommand tests that only subcommands are taken away from the GUI flags
+func TestIsCommand(t *testing.T) {
+	for _, name := range []string{"update", "check", "nodes", "run", "status", "help"} {
+		if !IsCommand(name) {
+			t.Errorf("IsCommand(%q) = false", name)
+		}
+	}
+	for _, name := range []string{"", "-start", "-tray", "gui"} {
+		if IsCommand(name) {
+			t.Errorf("IsCommand(%q) = true", name)
+		}
+	}
+}
+
+// TestParseFlags tests exit codes for help, bad flags and extra arguments
+func TestParseFlags(t *testing.T) {
+	tests := []struct {
+		args []string
+		code int
+	}{
+		{[]string{"-profile", "work", "-v"}, -1},
+		{[]string{"-h"}, exitOK},
+		{[]string{"-unknown"}, exitUsage},
+		{[]string{"extra"}, exitUsage},
+	}
+	for _, tt := range tests {
+		var stderr bytes.Buffer
+		e := &env{stdout: &bytes.Buffer{}, stderr: &stderr}
+		fs := e.newFlagSet("status", "")
+		if code := e.parseFlags(fs, tt.args); code != tt.code {
+			t.Errorf("parseFlags(%v) = %d, want %d (output: %s)", tt.args, code, tt.code, stderr.String())
+		}
+		if tt.code == -1 && (e.profile != "work" || !e.verbose) {
+			t.Errorf("Common flags not parsed: profile=%q verbose=%v", e.profile, e.verbose)
+		}
+	}
+}
+
+// TestNodeFilter tests parsing of repeated -filter flags
+func TestNodeFilter(t *testing.T) {
+	filter := nodeFilter{}
+	if err := filter.Set("tag=/NL/i"); err != nil {
+		t.Fatalf("Set error: %v", err)
+	}
+	if err := filter.Set("scheme=vless"); err != nil {
+		t.Fatalf("Set error: %v", err)
+	}
+	if filter["tag"] != "/NL/i" || filter["scheme"] != "vless" {
+		t.Errorf("Unexpected filter: %v", filter)
+	}
+	for _, bad := range []string{"tag", "=x", "port=443"} {
+		if err := filter.Set(bad); err == nil {
+			t.Errorf("Set(%q) expected error", bad)
+		}
+	}
+}
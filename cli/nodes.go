@@ -0,0 +1,105 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"singbox-launcher/core"
+)
+
+// nodeFilter collects repeated -filter key=pattern flags
+type nodeFilter map[string]string
+
+func (f nodeFilter) String() string {
+	parts := make([]string, 0, len(f))
+	for key, pattern := range f {
+		parts = append(parts, key+"="+pattern)
+	}
+	return strings.Join(parts, ",")
+}
+
+func (f nodeFilter) Set(value string) error {
+	key, pattern, ok := strings.Cut(value, "=")
+	key = strings.TrimSpace(key)
+	if !ok || key == "" {
+		return fmt.Errorf("expected key=pattern, got %q", value)
+	}
+	switch key {
+	case "tag", "host", "label", "scheme", "comment":
+	default:
+		return fmt.Errorf("unknown filter key %q (tag, host, label, scheme, comment)", key)
+	}
+	f[key] = pattern
+	return nil
+}
+
+// nodeInfo is one node in the JSON output of the nodes command
+type nodeInfo struct {
+	Source  int    `json:"source"`
+	Tag     string `json:"tag"`
+	Scheme  string `json:"scheme"`
+	Server  string `json:"server"`
+	Port    int    `json:"port"`
+	Label   string `json:"label,omitempty"`
+	Comment string `json:"comment,omitempty"`
+}
+
+// runNodes fetches the subscriptions and lists the parsed nodes without changing config.json
+func runNodes(e *env, args []string) int {
+	fs := e.newFlagSet("nodes", "")
+	asJSON := fs.Bool("json", false, "Print the nodes as JSON")
+	sourceIndex := fs.Int("source", 0, "Only list nodes of this source (1-based index in ParserConfig.proxies)")
+	filter := nodeFilter{}
+	fs.Var(filter, "filter", "Filter as key=pattern (keys: tag, host, label, scheme, comment; patterns: literal, !literal, /regex/i); repeatable, all must match")
+	if code := e.parseFlags(fs, args); code >= 0 {
+		return code
+	}
+	ac, err := e.controller()
+	if err != nil {
+		return e.fail("%v", err)
+	}
+	config, err := core.ExtractParserConfig(ac.ConfigPath)
+	if err != nil {
+		return e.fail("%v", err)
+	}
+	if *sourceIndex < 0 || *sourceIndex > len(config.ParserConfig.Proxies) {
+		return e.fail("source %d out of range (1..%d)", *sourceIndex, len(config.ParserConfig.Proxies))
+	}
+
+	var nodes []nodeInfo
+	for i, sourceNodes := range ac.ConfigService.ParseNodes(config) {
+		if *sourceIndex > 0 && i != *sourceIndex-1 {
+			continue
+		}
+		for _, node := range sourceNodes {
+			if len(filter) > 0 && !core.MatchesNodeFilter(node, filter) {
+				continue
+			}
+			nodes = append(nodes, nodeInfo{
+				Source:  i + 1,
+				Tag:     node.Tag,
+				Scheme:  node.Scheme,
+				Server:  node.Server,
+				Port:    node.Port,
+				Label:   node.Label,
+				Comment: node.Comment,
+			})
+		}
+	}
+
+	if *asJSON {
+		if nodes == nil {
+			nodes = []nodeInfo{}
+		}
+		return e.printJSON(nodes)
+	}
+	w := tabwriter.NewWriter(e.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "#\tTAG\tSCHEME\tSERVER\tPORT\tSOURCE")
+	for i, node := range nodes {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%d\t%d\n", i+1, node.Tag, node.Scheme, node.Server, node.Port, node.Source)
+	}
+	w.Flush()
+	fmt.Fprintf(e.stderr, "%d node(s)\n", len(nodes))
+	return exitOK
+}
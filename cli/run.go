@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"singbox-launcher/control"
+	"singbox-launcher/core"
+)
+
+// runPollInterval is how often the run command checks whether sing-box is still supervised
+const runPollInterval = time.Second
+
+// runRun starts sing-box and supervises it in the foreground until SIGINT/SIGTERM.
+// Crashes are restarted by the same policy as in the GUI; subscriptions are refreshed by the auto-update loop.
+func runRun(e *env, args []string) int {
+	fs := e.newFlagSet("run", "")
+	noUpdate := fs.Bool("no-update", false, "Do not refresh subscriptions automatically")
+	if code := e.parseFlags(fs, args); code >= 0 {
+		return code
+	}
+	ac, err := e.controller()
+	if err != nil {
+		return e.fail("%v", err)
+	}
+	if _, err := os.Stat(ac.ConfigPath); err != nil {
+		return e.fail("config not found: %s (run 'update' first)", ac.ConfigPath)
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	ac.BindSystemProxy()
+	core.StartSingBoxProcess(ac)
+	if !ac.RunningState.IsRunning() {
+		ac.GracefulExit()
+		return e.fail("sing-box did not start, see logs/sing-box.log")
+	}
+	fmt.Fprintf(e.stderr, "sing-box started (profile %s), press Ctrl+C to stop\n", ac.CurrentProfile())
+	if server, err := control.Start(ac); err != nil {
+		fmt.Fprintf(e.stderr, "Control server not started: %v\n", err)
+	} else if server != nil {
+		defer server.Close()
+		fmt.Fprintf(e.stderr, "Control API listening on http://%s\n", server.Addr())
+	}
+	if !*noUpdate {
+		ac.StartAutoUpdate()
+	}
+
+	ticker := time.NewTicker(runPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case sig := <-signals:
+			log.Printf("runRun: Received %v, shutting down", sig)
+			fmt.Fprintln(e.stderr, "Stopping sing-box...")
+			ac.GracefulExit()
+			return exitOK
+		case <-ticker.C:
+			if ac.RunningState.IsRunning() || ac.RestartPending() {
+				continue
+			}
+			// Restore the system proxy synchronously: the CoreStopped handler may not finish before exit
+			reason := ac.CrashInfo().Reason
+			ac.GracefulExit()
+			if reason != core.CrashReasonNone {
+				return e.fail("sing-box stopped: %s", reason.Description())
+			}
+			fmt.Fprintln(e.stderr, "sing-box exited")
+			return exitOK
+		}
+	}
+}
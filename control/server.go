@@ -0,0 +1,344 @@
+// Package control implements the local control API of the launcher: an HTTP server bound to localhost
+// that lets scripts, hotkeys and browser extensions start/stop sing-box, update subscriptions,
+// switch profiles, read the status and follow state changes as server-sent events.
+// Every request must carry the token from the launcher settings; browsers may call it only from
+// the extension origins listed there.
+package control
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"singbox-launcher/core"
+)
+
+const (
+	// eventBufferSize is how many events a slow SSE client may lag behind before events are dropped
+	eventBufferSize = 64
+	// keepAliveInterval is how often an idle SSE stream gets a comment line so proxies keep it open
+	keepAliveInterval = 30 * time.Second
+	// readHeaderTimeout limits slow clients (the event stream itself has no write timeout)
+	readHeaderTimeout = 10 * time.Second
+	// eventsPath is the event stream, the only endpoint that accepts the token as ?token=
+	eventsPath = "/v1/events"
+)
+
+// Server is the control API server
+type Server struct {
+	ac    *core.AppController
+	token string
+
+	// AllowedOrigins are the browser extension origins that may call the API (see core.IsExtensionOrigin).
+	// Requests with any other Origin header are refused; clients outside a browser send none.
+	AllowedOrigins []string
+
+	httpServer *http.Server
+	listener   net.Listener
+}
+
+// Status is the response of GET /v1/status and the first message of the event stream
+type Status struct {
+	Running        bool     `json:"running"`
+	RestartPending bool     `json:"restart_pending"`
+	CrashReason    string   `json:"crash_reason,omitempty"`
+	Profile        string   `json:"profile"`
+	Profiles       []string `json:"profiles"`
+	ConfigPath     string   `json:"config_path"`
+	ParserRunning  bool     `json:"parser_running"`
+	ClashMode      string   `json:"clash_mode,omitempty"`
+	Group          string   `json:"group,omitempty"`
+	ActiveProxy    string   `json:"active_proxy,omitempty"`
+}
+
+// New creates a server for ac accepting requests with token
+func New(ac *core.AppController, token string) *Server {
+	return &Server{ac: ac, token: token}
+}
+
+// Start starts the control server if it is enabled in the launcher settings.
+// Returns nil without an error when it is disabled. A missing token is generated and saved.
+func Start(ac *core.AppController) (*Server, error) {
+	settings := ac.Settings.ControlServer()
+	if !settings.Enabled {
+		return nil, nil
+	}
+	token, err := ac.Settings.EnsureControlToken()
+	if err != nil {
+		return nil, fmt.Errorf("control server: %w", err)
+	}
+	server := New(ac, token)
+	for _, origin := range settings.AllowedOrigins {
+		if !core.IsExtensionOrigin(origin) {
+			log.Printf("ControlServer: Ignoring allowed origin %q: not a browser extension origin", origin)
+			continue
+		}
+		server.AllowedOrigins = append(server.AllowedOrigins, origin)
+	}
+	if err := server.Listen(settings.ListenPort()); err != nil {
+		return nil, err
+	}
+	return server, nil
+}
+
+// Listen binds to 127.0.0.1:port and serves requests in the background
+func (s *Server) Listen(port int) error {
+	listener, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+	if err != nil {
+		return fmt.Errorf("control server: %w", err)
+	}
+	s.listener = listener
+	s.httpServer = &http.Server{Handler: s.Handler(), ReadHeaderTimeout: readHeaderTimeout}
+	log.Printf("ControlServer: Listening on http://%s", listener.Addr())
+	go func() {
+		if err := s.httpServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("ControlServer: %v", err)
+		}
+	}()
+	return nil
+}
+
+// Addr returns the address the server listens on (empty before Listen)
+func (s *Server) Addr() string {
+	if s.listener == nil {
+		return ""
+	}
+	return s.listener.Addr().String()
+}
+
+// Close stops the server and ends open event streams
+func (s *Server) Close() error {
+	if s.httpServer == nil {
+		return nil
+	}
+	return s.httpServer.Close()
+}
+
+// Handler returns the HTTP handler with all endpoints behind the token check
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/status", s.handleStatus)
+	mux.HandleFunc("GET "+eventsPath, s.handleEvents)
+	mux.HandleFunc("POST /v1/start", s.handleStart)
+	mux.HandleFunc("POST /v1/stop", s.handleStop)
+	mux.HandleFunc("POST /v1/update", s.handleUpdate)
+	mux.HandleFunc("POST /v1/profile", s.handleProfile)
+	return s.authorize(mux)
+}
+
+// authorize checks the origin of browser requests, answers CORS preflights and rejects requests without the token.
+// The token is taken from "Authorization: Bearer". EventSource clients cannot set headers,
+// so the event stream also accepts it as ?token=; other endpoints do not, to keep it out of URLs.
+func (s *Server) authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if origin := r.Header.Get("Origin"); origin != "" {
+			if !slices.Contains(s.AllowedOrigins, origin) || !core.IsExtensionOrigin(origin) {
+				writeError(w, http.StatusForbidden, "origin is not allowed")
+				return
+			}
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Add("Vary", "Origin")
+		}
+		if r.Method == http.MethodOptions {
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST")
+			w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if token == "" && r.URL.Path == eventsPath {
+			token = r.URL.Query().Get("token")
+		}
+		if s.token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			writeError(w, http.StatusUnauthorized, "invalid or missing token")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// status collects the current state of the launcher
+func (s *Server) status() Status {
+	ac := s.ac
+	ac.ParserMutex.Lock()
+	parserRunning := ac.ParserRunning
+	ac.ParserMutex.Unlock()
+
+	status := Status{
+		Running:        ac.RunningState.IsRunning(),
+		RestartPending: ac.RestartPending(),
+		CrashReason:    string(ac.CrashInfo().Reason),
+		Profile:        ac.CurrentProfile(),
+		Profiles:       ac.Profiles.Names(),
+		ConfigPath:     ac.CurrentConfigPath(),
+		ParserRunning:  parserRunning,
+		ClashMode:      ac.ClashModeState().Mode,
+	}
+	if status.Running {
+		status.Group = ac.GetSelectedClashGroup()
+		status.ActiveProxy = ac.GetActiveProxyName()
+	}
+	return status
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.status())
+}
+
+func (s *Server) handleStart(w http.ResponseWriter, r *http.Request) {
+	if s.ac.RunningState.IsRunning() {
+		writeError(w, http.StatusConflict, "sing-box is already running")
+		return
+	}
+	log.Println("ControlServer: Start requested")
+	go core.StartSingBoxProcess(s.ac)
+	writeAccepted(w)
+}
+
+func (s *Server) handleStop(w http.ResponseWriter, r *http.Request) {
+	if !s.ac.RunningState.IsRunning() && !s.ac.RestartPending() {
+		writeError(w, http.StatusConflict, "sing-box is not running")
+		return
+	}
+	log.Println("ControlServer: Stop requested")
+	go core.StopSingBoxProcess(s.ac)
+	writeAccepted(w)
+}
+
+func (s *Server) handleUpdate(w http.ResponseWriter, r *http.Request) {
+	s.ac.ParserMutex.Lock()
+	parserRunning := s.ac.ParserRunning
+	s.ac.ParserMutex.Unlock()
+	if parserRunning {
+		writeError(w, http.StatusConflict, "configuration update is already in progress")
+		return
+	}
+	log.Println("ControlServer: Subscription update requested")
+	go s.ac.ConfigService.RunParserProcess()
+	writeAccepted(w)
+}
+
+// handleProfile switches the profile given as {"name": "..."}; it answers after sing-box was restarted
+func (s *Server) handleProfile(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.Name == "" {
+		writeError(w, http.StatusBadRequest, `expected {"name": "<profile>"}`)
+		return
+	}
+	log.Printf("ControlServer: Switch to profile '%s' requested", request.Name)
+	if err := s.ac.SwitchProfile(request.Name); err != nil {
+		code := http.StatusConflict
+		if errors.Is(err, core.ErrProfileNotFound) {
+			code = http.StatusNotFound
+		}
+		writeError(w, code, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, s.status())
+}
+
+// handleEvents streams bus events as server-sent events: "event: <name>" with the event as JSON data.
+// The stream starts with a "status" event; ?events=core.started,core.stopped limits the event types.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming is not supported")
+		return
+	}
+	events := core.AllEvents
+	if filter := r.URL.Query().Get("events"); filter != "" {
+		events = selectEvents(strings.Split(filter, ","))
+		if len(events) == 0 {
+			writeError(w, http.StatusBadRequest, "no known events in filter")
+			return
+		}
+	}
+
+	queue := make(chan core.Event, eventBufferSize)
+	unsubscribe := s.ac.Events.SubscribeEvents(events, func(e core.Event) {
+		select {
+		case queue <- e:
+		default:
+			log.Printf("ControlServer: Event stream client is too slow, dropping %s", e.EventName())
+		}
+	})
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	if err := writeEvent(w, "status", s.status()); err != nil {
+		return
+	}
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e := <-queue:
+			if err := writeEvent(w, e.EventName(), e); err != nil {
+				return
+			}
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// selectEvents returns the event types with the given names
+func selectEvents(names []string) []core.Event {
+	var events []core.Event
+	for _, e := range core.AllEvents {
+		for _, name := range names {
+			if strings.TrimSpace(name) == e.EventName() {
+				events = append(events, e)
+				break
+			}
+		}
+	}
+	return events
+}
+
+// writeEvent writes one server-sent event
+func writeEvent(w http.ResponseWriter, name string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, payload)
+	return err
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("ControlServer: failed to write response: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, code int, message string) {
+	writeJSON(w, code, map[string]string{"error": message})
+}
+
+// writeAccepted answers an action that continues in the background; follow /v1/events for the result
+func writeAccepted(w http.ResponseWriter) {
+	writeJSON(w, http.StatusAccepted, map[string]bool{"ok": true})
+}
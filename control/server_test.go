@@ -0,0 +1,184 @@
+package control
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"singbox-launcher/core"
+)
+
+const testToken = "secret-token"
+
+// newTestServer starts the handler for a controller that has no sing-box and no profiles
+func newTestServer(t *testing.T) (*core.AppController, *httptest.Server) {
+	t.Helper()
+	ac := &core.AppController{
+		Events:        core.NewEventBus(),
+		RunningState:  &core.RunningState{},
+		Profiles:      core.NewProfileManager(t.TempDir(), nil),
+		ActiveProfile: core.DefaultProfileName,
+	}
+	server := httptest.NewServer(New(ac, testToken).Handler())
+	t.Cleanup(server.Close)
+	return ac, server
+}
+
+func request(t *testing.T, method, url, token, body string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+// TestServer_Authorization tests that every endpoint requires the token and preflights do not
+func TestServer_Authorization(t *testing.T) {
+	_, server := newTestServer(t)
+
+	tests := []struct {
+		method, path, token string
+		want                int
+	}{
+		{"GET", "/v1/status", "", http.StatusUnauthorized},
+		{"GET", "/v1/status", "wrong", http.StatusUnauthorized},
+		{"POST", "/v1/start", "", http.StatusUnauthorized},
+		{"GET", "/v1/status?token=" + testToken, "", http.StatusUnauthorized},
+		{"GET", "/v1/events?events=core.started&token=wrong", "", http.StatusUnauthorized},
+		{"OPTIONS", "/v1/start", "", http.StatusNoContent},
+		{"GET", "/v1/start", testToken, http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		resp := request(t, tt.method, server.URL+tt.path, tt.token, "")
+		if resp.StatusCode != tt.want {
+			t.Errorf("%s %s (token %q): expected %d, got %d", tt.method, tt.path, tt.token, tt.want, resp.StatusCode)
+		}
+	}
+}
+
+// TestServer_Origins tests that browsers may call the API only from the allowed extension origins
+func TestServer_Origins(t *testing.T) {
+	ac, _ := newTestServer(t)
+	server := New(ac, testToken)
+	server.AllowedOrigins = []string{"chrome-extension://abcdefgh", "https://example.com"}
+	httpServer := httptest.NewServer(server.Handler())
+	t.Cleanup(httpServer.Close)
+
+	tests := []struct {
+		method, origin string
+		want           int
+	}{
+		{"GET", "", http.StatusOK},
+		{"GET", "chrome-extension://abcdefgh", http.StatusOK},
+		{"OPTIONS", "chrome-extension://abcdefgh", http.StatusNoContent},
+		{"GET", "chrome-extension://other", http.StatusForbidden},
+		{"OPTIONS", "https://evil.example", http.StatusForbidden},
+		// Only extension origins count, even if the list has something else
+		{"GET", "https://example.com", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		req, err := http.NewRequest(tt.method, httpServer.URL+"/v1/status", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		if tt.origin != "" {
+			req.Header.Set("Origin", tt.origin)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.want {
+			t.Errorf("%s from %q: expected %d, got %d", tt.method, tt.origin, tt.want, resp.StatusCode)
+		}
+		allowed := resp.Header.Get("Access-Control-Allow-Origin")
+		if tt.want == http.StatusForbidden || tt.origin == "" {
+			if allowed != "" {
+				t.Errorf("%s from %q: unexpected Access-Control-Allow-Origin %q", tt.method, tt.origin, allowed)
+			}
+		} else if allowed != tt.origin {
+			t.Errorf("%s from %q: expected Access-Control-Allow-Origin %q, got %q", tt.method, tt.origin, tt.origin, allowed)
+		}
+	}
+}
+
+// TestServer_Actions tests status and the errors of actions that cannot be performed
+func TestServer_Actions(t *testing.T) {
+	_, server := newTestServer(t)
+
+	resp := request(t, "GET", server.URL+"/v1/status", testToken, "")
+	var status Status
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		t.Fatal(err)
+	}
+	if status.Running || status.Profile != core.DefaultProfileName {
+		t.Errorf("Unexpected status: %+v", status)
+	}
+
+	if resp := request(t, "POST", server.URL+"/v1/stop", testToken, ""); resp.StatusCode != http.StatusConflict {
+		t.Errorf("Expected 409 when stopping a stopped core, got %d", resp.StatusCode)
+	}
+	if resp := request(t, "POST", server.URL+"/v1/profile", testToken, `{"name":"missing"}`); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 for a missing profile, got %d", resp.StatusCode)
+	}
+	if resp := request(t, "POST", server.URL+"/v1/profile", testToken, `{}`); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 without a profile name, got %d", resp.StatusCode)
+	}
+}
+
+// TestServer_Events tests the event stream: initial status, filtered bus events as JSON
+func TestServer_Events(t *testing.T) {
+	ac, server := newTestServer(t)
+
+	resp := request(t, "GET", server.URL+"/v1/events?events=core.crashed,proxies.switched&token="+testToken, "", "")
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("Unexpected response: %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+	next := func() string {
+		select {
+		case line := <-lines:
+			return line
+		case <-time.After(2 * time.Second):
+			t.Fatal("Timed out waiting for the event stream")
+			return ""
+		}
+	}
+
+	if line := next(); line != "event: status" {
+		t.Fatalf("Expected status event first, got %q", line)
+	}
+	next() // data
+	next() // blank line
+
+	ac.Events.Publish(core.CoreStarted{}) // filtered out
+	ac.Events.Publish(core.ProxySwitched{Group: "proxy-out", Proxy: "node-1"})
+	if line := next(); line != "event: proxies.switched" {
+		t.Fatalf("Expected proxies.switched, got %q", line)
+	}
+	if line := next(); line != `data: {"group":"proxy-out","proxy":"node-1"}` {
+		t.Errorf("Unexpected data: %q", line)
+	}
+}
@@ -0,0 +1,469 @@
+package core
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Автозапуск при входе в систему (Linux). Лаунчер пишет либо XDG autostart-файл
+// ~/.config/autostart/singbox-launcher.desktop, либо пользовательский unit systemd
+// ~/.config/systemd/user/singbox-launcher.service. Состояние всегда читается с диска: файл могли
+// изменить или удалить вручную, и тогда вкладка Settings показывает это вместо сохранённых настроек.
+
+// Autostart modes
+const (
+	AutostartDesktop = "desktop" // XDG autostart entry, started by the desktop session
+	AutostartSystemd = "systemd" // systemd user service bound to graphical-session.target
+)
+
+// Autostart file names
+const (
+	autostartDesktopFileName = "singbox-launcher.desktop"
+	autostartServiceName     = "singbox-launcher.service"
+)
+
+// AutostartOptions are the flags the launcher is started with on login
+type AutostartOptions struct {
+	Mode       string // AutostartDesktop or AutostartSystemd
+	StartVPN   bool   // -start
+	Tray       bool   // -tray
+	Profile    string // -profile, empty for the last used profile
+	Executable string // Launcher to start; empty for the running one
+}
+
+// AutostartStatus is the autostart entry found on disk
+type AutostartStatus struct {
+	Enabled  bool
+	Options  AutostartOptions // Parsed from the Exec/ExecStart line
+	Path     string           // File of the entry (empty if none)
+	Modified bool             // The file differs from what the launcher writes for Options (edited outside the launcher)
+	Detail   string           // Why the entry is modified or disabled
+}
+
+// AutostartSupported reports whether autostart can be managed on this platform
+func AutostartSupported() bool {
+	return runtime.GOOS == "linux"
+}
+
+// autostartDesktopPath returns ~/.config/autostart/singbox-launcher.desktop
+func autostartDesktopPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot find the config directory: %w", err)
+	}
+	return filepath.Join(configDir, "autostart", autostartDesktopFileName), nil
+}
+
+// autostartServicePath returns ~/.config/systemd/user/singbox-launcher.service
+func autostartServicePath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot find the config directory: %w", err)
+	}
+	return filepath.Join(configDir, "systemd", "user", autostartServiceName), nil
+}
+
+// launcherExecutable returns the path to start on login: the AppImage itself when running from one
+func launcherExecutable() (string, error) {
+	if appImage := os.Getenv("APPIMAGE"); appImage != "" {
+		return appImage, nil
+	}
+	execPath, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("cannot detect executable path: %w", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(execPath); err == nil {
+		execPath = resolved
+	}
+	return execPath, nil
+}
+
+// autostartArgs returns the command line of options
+func autostartArgs(options AutostartOptions) []string {
+	args := []string{options.Executable}
+	if options.StartVPN {
+		args = append(args, "-start")
+	}
+	if options.Tray {
+		args = append(args, "-tray")
+	}
+	if options.Profile != "" {
+		args = append(args, "-profile", options.Profile)
+	}
+	return args
+}
+
+// quoteExecArg quotes an argument for Exec= of a desktop entry or ExecStart= of a unit; '%' is doubled for both.
+//
+// systemd: "$" is written as "$$" (it expands $VAR otherwise) and double quotes take C escapes (\\, \", \n).
+// Desktop Entry: an argument with reserved characters is quoted and '"', '`', '$', '\' are escaped
+// with a backslash inside the quotes. Exec is a string value, so the string escaping (\\, \n, \t)
+// is applied on top and every backslash is doubled once more.
+func quoteExecArg(arg string, systemd bool) string {
+	arg = strings.ReplaceAll(arg, "%", "%%")
+	if systemd {
+		arg = strings.ReplaceAll(arg, "$", "$$")
+		if arg != "" && !strings.ContainsAny(arg, " \t\n\"'\\;") {
+			return arg
+		}
+		return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`).Replace(arg) + `"`
+	}
+	if arg == "" || strings.ContainsAny(arg, " \t\n\"'\\$`<>~|&;*?#()=") {
+		arg = `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "`", "\\`", "$", `\$`).Replace(arg) + `"`
+	}
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, "\t", `\t`, "\r", `\r`).Replace(arg)
+}
+
+// unescapeDesktopString undoes the escaping of a Desktop Entry string value: \s, \n, \t, \r and \\.
+// Other backslashes are kept for the Exec quoting rules.
+func unescapeDesktopString(value string) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' || i+1 == len(value) {
+			b.WriteByte(value[i])
+			continue
+		}
+		i++
+		switch value[i] {
+		case 's':
+			b.WriteByte(' ')
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case 'r':
+			b.WriteByte('\r')
+		case '\\':
+			b.WriteByte('\\')
+		default:
+			b.WriteByte('\\')
+			b.WriteByte(value[i])
+		}
+	}
+	return b.String()
+}
+
+// splitExecLine splits an Exec=/ExecStart= value into arguments (reverse of quoteExecArg)
+func splitExecLine(line string, systemd bool) ([]string, error) {
+	if !systemd {
+		line = unescapeDesktopString(line)
+	}
+	var args []string
+	var current strings.Builder
+	inQuotes, hasArg := false, false
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == '\\' && i+1 < len(line):
+			i++
+			c = line[i]
+			if systemd {
+				// C escapes of systemd
+				switch c {
+				case 'n':
+					c = '\n'
+				case 't':
+					c = '\t'
+				}
+			}
+			current.WriteByte(c)
+			hasArg = true
+		case c == '"':
+			inQuotes = !inQuotes
+			hasArg = true
+		case (c == ' ' || c == '\t') && !inQuotes:
+			if hasArg {
+				args = append(args, current.String())
+				current.Reset()
+				hasArg = false
+			}
+		default:
+			current.WriteByte(c)
+			hasArg = true
+		}
+	}
+	if inQuotes {
+		return nil, fmt.Errorf("unterminated quote in %q", line)
+	}
+	if hasArg {
+		args = append(args, current.String())
+	}
+	for i := range args {
+		args[i] = strings.ReplaceAll(args[i], "%%", "%")
+		if systemd {
+			args[i] = strings.ReplaceAll(args[i], "$$", "$")
+		}
+	}
+	return args, nil
+}
+
+// parseAutostartArgs reads the options back from a command line. Unknown arguments are ignored;
+// the regenerated file then differs and the entry is reported as modified.
+func parseAutostartArgs(args []string, mode string) AutostartOptions {
+	options := AutostartOptions{Mode: mode}
+	if len(args) == 0 {
+		return options
+	}
+	options.Executable = args[0]
+	for i := 1; i < len(args); i++ {
+		name, value, hasValue := strings.Cut(strings.TrimLeft(args[i], "-"), "=")
+		switch name {
+		case "start":
+			options.StartVPN = !hasValue || value == "true"
+		case "tray":
+			options.Tray = !hasValue || value == "true"
+		case "profile":
+			if !hasValue && i+1 < len(args) {
+				i++
+				value = args[i]
+			}
+			options.Profile = value
+		}
+	}
+	return options
+}
+
+// autostartDesktopEntry returns the content of the XDG autostart file
+func autostartDesktopEntry(options AutostartOptions) string {
+	args := autostartArgs(options)
+	for i := range args {
+		args[i] = quoteExecArg(args[i], false)
+	}
+	return "[Desktop Entry]\n" +
+		"Type=Application\n" +
+		"Name=Sing-Box Launcher\n" +
+		"Comment=Start Sing-Box Launcher on login\n" +
+		"Exec=" + strings.Join(args, " ") + "\n" +
+		"Terminal=false\n" +
+		"X-GNOME-Autostart-enabled=true\n"
+}
+
+// autostartServiceUnit returns the content of the systemd user unit
+func autostartServiceUnit(options AutostartOptions) string {
+	args := autostartArgs(options)
+	for i := range args {
+		args[i] = quoteExecArg(args[i], true)
+	}
+	return "[Unit]\n" +
+		"Description=Sing-Box Launcher\n" +
+		"PartOf=graphical-session.target\n" +
+		"After=graphical-session.target\n" +
+		"\n" +
+		"[Service]\n" +
+		"Type=simple\n" +
+		"ExecStart=" + strings.Join(args, " ") + "\n" +
+		"Restart=on-failure\n" +
+		"RestartSec=5\n" +
+		"\n" +
+		"[Install]\n" +
+		"WantedBy=graphical-session.target\n"
+}
+
+// iniValue returns the value of the first key= line of an INI-like file
+func iniValue(content, key string) (string, bool) {
+	for _, line := range strings.Split(content, "\n") {
+		if value, ok := strings.CutPrefix(strings.TrimSpace(line), key+"="); ok {
+			return strings.TrimSpace(value), true
+		}
+	}
+	return "", false
+}
+
+// systemctlUser runs systemctl --user
+func systemctlUser(args ...string) (string, error) {
+	systemctl, err := exec.LookPath("systemctl")
+	if err != nil {
+		return "", fmt.Errorf("systemctl not found: the systemd user service needs systemd, use the desktop autostart entry instead")
+	}
+	output, err := exec.Command(systemctl, append([]string{"--user"}, args...)...).CombinedOutput()
+	return strings.TrimSpace(string(output)), err
+}
+
+// ReadAutostart returns the autostart entry found on disk. The desktop entry is checked first.
+func ReadAutostart() (AutostartStatus, error) {
+	if !AutostartSupported() {
+		return AutostartStatus{}, nil
+	}
+	desktopPath, err := autostartDesktopPath()
+	if err != nil {
+		return AutostartStatus{}, err
+	}
+	if data, err := os.ReadFile(desktopPath); err == nil {
+		return readDesktopAutostart(desktopPath, string(data))
+	} else if !os.IsNotExist(err) {
+		return AutostartStatus{}, fmt.Errorf("failed to read %s: %w", desktopPath, err)
+	}
+
+	servicePath, err := autostartServicePath()
+	if err != nil {
+		return AutostartStatus{}, err
+	}
+	data, err := os.ReadFile(servicePath)
+	if os.IsNotExist(err) {
+		return AutostartStatus{}, nil
+	}
+	if err != nil {
+		return AutostartStatus{}, fmt.Errorf("failed to read %s: %w", servicePath, err)
+	}
+	return readServiceAutostart(servicePath, string(data))
+}
+
+// readDesktopAutostart parses an XDG autostart entry
+func readDesktopAutostart(path, content string) (AutostartStatus, error) {
+	status := AutostartStatus{Enabled: true, Path: path}
+	execLine, _ := iniValue(content, "Exec")
+	args, err := splitExecLine(execLine, false)
+	if err != nil {
+		return status, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	status.Options = parseAutostartArgs(args, AutostartDesktop)
+	if hidden, _ := iniValue(content, "Hidden"); hidden == "true" {
+		status.Enabled, status.Detail = false, "Hidden=true"
+	}
+	if enabled, ok := iniValue(content, "X-GNOME-Autostart-enabled"); ok && enabled == "false" {
+		status.Enabled, status.Detail = false, "X-GNOME-Autostart-enabled=false"
+	}
+	if content != autostartDesktopEntry(status.Options) {
+		status.Modified = true
+		if status.Detail == "" {
+			status.Detail = "edited outside the launcher"
+		}
+	}
+	return status, nil
+}
+
+// readServiceAutostart parses the systemd user unit and asks systemd whether it is enabled
+func readServiceAutostart(path, content string) (AutostartStatus, error) {
+	status := AutostartStatus{Path: path}
+	execLine, _ := iniValue(content, "ExecStart")
+	args, err := splitExecLine(execLine, true)
+	if err != nil {
+		return status, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	status.Options = parseAutostartArgs(args, AutostartSystemd)
+	state, err := systemctlUser("is-enabled", autostartServiceName)
+	status.Enabled = err == nil && state == "enabled"
+	if !status.Enabled {
+		status.Detail = "service is not enabled in systemd"
+		if state != "" {
+			status.Detail = "systemctl --user is-enabled: " + state
+		}
+	}
+	if content != autostartServiceUnit(status.Options) {
+		status.Modified = true
+		if status.Detail == "" {
+			status.Detail = "edited outside the launcher"
+		}
+	}
+	return status, nil
+}
+
+// EnableAutostart writes the autostart entry of options.Mode and removes the entry of the other mode
+func EnableAutostart(options AutostartOptions) error {
+	if !AutostartSupported() {
+		return fmt.Errorf("autostart is only managed on Linux")
+	}
+	if options.Executable == "" {
+		executable, err := launcherExecutable()
+		if err != nil {
+			return err
+		}
+		options.Executable = executable
+	}
+
+	switch options.Mode {
+	case AutostartDesktop:
+		if err := disableServiceAutostart(); err != nil {
+			return err
+		}
+		path, err := autostartDesktopPath()
+		if err != nil {
+			return err
+		}
+		if err := writeAutostartFile(path, autostartDesktopEntry(options)); err != nil {
+			return err
+		}
+		log.Printf("EnableAutostart: wrote %s", path)
+	case AutostartSystemd:
+		path, err := autostartServicePath()
+		if err != nil {
+			return err
+		}
+		if err := writeAutostartFile(path, autostartServiceUnit(options)); err != nil {
+			return err
+		}
+		if output, err := systemctlUser("daemon-reload"); err != nil {
+			return fmt.Errorf("systemctl --user daemon-reload failed: %w (%s)", err, output)
+		}
+		if output, err := systemctlUser("enable", autostartServiceName); err != nil {
+			return fmt.Errorf("systemctl --user enable failed: %w (%s)", err, output)
+		}
+		if err := removeDesktopAutostart(); err != nil {
+			return err
+		}
+		log.Printf("EnableAutostart: enabled %s", path)
+	default:
+		return fmt.Errorf("unknown autostart mode %q", options.Mode)
+	}
+	return nil
+}
+
+// DisableAutostart removes the autostart entries of both modes
+func DisableAutostart() error {
+	if !AutostartSupported() {
+		return nil
+	}
+	if err := removeDesktopAutostart(); err != nil {
+		return err
+	}
+	return disableServiceAutostart()
+}
+
+// writeAutostartFile writes content, creating the directory
+func writeAutostartFile(path, content string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// removeDesktopAutostart removes the XDG autostart entry
+func removeDesktopAutostart() error {
+	path, err := autostartDesktopPath()
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove %s: %w", path, err)
+	}
+	return nil
+}
+
+// disableServiceAutostart disables and removes the systemd user unit if it exists
+func disableServiceAutostart() error {
+	path, err := autostartServicePath()
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+	if output, err := systemctlUser("disable", autostartServiceName); err != nil {
+		log.Printf("disableServiceAutostart: systemctl --user disable: %v (%s)", err, output)
+	}
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", path, err)
+	}
+	if output, err := systemctlUser("daemon-reload"); err != nil {
+		log.Printf("disableServiceAutostart: systemctl --user daemon-reload: %v (%s)", err, output)
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// TestQuoteExecArg tests the exact quoting of Exec= and ExecStart= and that it reads back unchanged
+func TestQuoteExecArg(t *testing.T) {
+	tests := []struct {
+		arg, desktop, systemd string
+	}{
+		{"/usr/bin/singbox-launcher", `/usr/bin/singbox-launcher`, `/usr/bin/singbox-launcher`},
+		{"/opt/sing box/singbox-launcher", `"/opt/sing box/singbox-launcher"`, `"/opt/sing box/singbox-launcher"`},
+		{"$HOME", `"\\$HOME"`, `$$HOME`},
+		{"cost $5", `"cost \\$5"`, `"cost $$5"`},
+		{`C:\profiles\home`, `"C:\\\\profiles\\\\home"`, `"C:\\profiles\\home"`},
+		{`Home "50%" $HOME`, `"Home \\"50%%\\" \\$HOME"`, `"Home \"50%%\" $$HOME"`},
+		{"", `""`, `""`},
+	}
+	for _, tt := range tests {
+		if got := quoteExecArg(tt.arg, false); got != tt.desktop {
+			t.Errorf("desktop quoteExecArg(%q) = %s, expected %s", tt.arg, got, tt.desktop)
+		}
+		if got := quoteExecArg(tt.arg, true); got != tt.systemd {
+			t.Errorf("systemd quoteExecArg(%q) = %s, expected %s", tt.arg, got, tt.systemd)
+		}
+		for _, systemd := range []bool{false, true} {
+			line := "/usr/bin/singbox-launcher -profile " + quoteExecArg(tt.arg, systemd)
+			args, err := splitExecLine(line, systemd)
+			if err != nil || len(args) != 3 || args[2] != tt.arg {
+				t.Errorf("splitExecLine(%s, systemd=%v) = %q, %v; expected %q", line, systemd, args, err, tt.arg)
+			}
+		}
+	}
+}
+
+// TestAutostart_Desktop tests writing, reading back and external edits of the XDG autostart entry
+func TestAutostart_Desktop(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("autostart is managed on Linux only")
+	}
+	configDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configDir)
+	t.Setenv("PATH", "")
+
+	options := AutostartOptions{
+		Mode:       AutostartDesktop,
+		StartVPN:   true,
+		Tray:       true,
+		Profile:    `Home "50%" $HOME`,
+		Executable: "/opt/sing box/singbox-launcher",
+	}
+	if err := EnableAutostart(options); err != nil {
+		t.Fatalf("EnableAutostart: %v", err)
+	}
+	status, err := ReadAutostart()
+	if err != nil {
+		t.Fatalf("ReadAutostart: %v", err)
+	}
+	if !status.Enabled || status.Modified || !reflect.DeepEqual(status.Options, options) {
+		t.Fatalf("unexpected status %+v", status)
+	}
+	if status.Path != filepath.Join(configDir, "autostart", "singbox-launcher.desktop") {
+		t.Errorf("unexpected path %s", status.Path)
+	}
+
+	// Правка вручную: флаги читаются из Exec, запись помечается как изменённая
+	data, _ := os.ReadFile(status.Path)
+	edited := strings.Replace(string(data), " -tray", "", 1) + "X-GNOME-Autostart-Delay=10\n"
+	if err := os.WriteFile(status.Path, []byte(edited), 0644); err != nil {
+		t.Fatal(err)
+	}
+	status, _ = ReadAutostart()
+	if !status.Enabled || !status.Modified || status.Options.Tray || !status.Options.StartVPN {
+		t.Errorf("external edit not detected: %+v", status)
+	}
+
+	edited = strings.Replace(edited, "X-GNOME-Autostart-enabled=true", "X-GNOME-Autostart-enabled=false", 1)
+	os.WriteFile(status.Path, []byte(edited), 0644)
+	if status, _ = ReadAutostart(); status.Enabled {
+		t.Errorf("entry disabled in the desktop environment reported as enabled")
+	}
+
+	if err := DisableAutostart(); err != nil {
+		t.Fatalf("DisableAutostart: %v", err)
+	}
+	if status, _ = ReadAutostart(); status.Enabled || status.Path != "" {
+		t.Errorf("entry not removed: %+v", status)
+	}
+}
+
+// TestAutostart_Systemd tests the user unit with a fake systemctl
+func TestAutostart_Systemd(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("autostart is managed on Linux only")
+	}
+	configDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configDir)
+	binDir := t.TempDir()
+	t.Setenv("PATH", binDir)
+	logPath := filepath.Join(binDir, "systemctl.log")
+	systemctl := "#!/bin/sh\necho \"$@\" >> " + logPath + "\n" +
+		"case \"$*\" in *is-enabled*) echo enabled ;; esac\n"
+	if err := os.WriteFile(filepath.Join(binDir, "systemctl"), []byte(systemctl), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// Переключение режима убирает .desktop
+	if err := EnableAutostart(AutostartOptions{Mode: AutostartDesktop, Executable: "/usr/bin/singbox-launcher"}); err != nil {
+		t.Fatalf("EnableAutostart desktop: %v", err)
+	}
+	options := AutostartOptions{Mode: AutostartSystemd, Tray: true, Executable: "/usr/bin/singbox-launcher"}
+	if err := EnableAutostart(options); err != nil {
+		t.Fatalf("EnableAutostart systemd: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(configDir, "autostart", "singbox-launcher.desktop")); !os.IsNotExist(err) {
+		t.Errorf("desktop entry not removed when switching to systemd")
+	}
+	status, err := ReadAutostart()
+	if err != nil {
+		t.Fatalf("ReadAutostart: %v", err)
+	}
+	if !status.Enabled || status.Modified || !reflect.DeepEqual(status.Options, options) {
+		t.Fatalf("unexpected status %+v", status)
+	}
+
+	if err := DisableAutostart(); err != nil {
+		t.Fatalf("DisableAutostart: %v", err)
+	}
+	if _, err := os.Stat(status.Path); !os.IsNotExist(err) {
+		t.Errorf("unit not removed")
+	}
+	calls, _ := os.ReadFile(logPath)
+	for _, want := range []string{"--user enable singbox-launcher.service", "--user disable singbox-launcher.service"} {
+		if !strings.Contains(string(calls), want) {
+			t.Errorf("systemctl not called with %q:\n%s", want, calls)
+		}
+	}
+}
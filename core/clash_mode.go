@@ -0,0 +1,133 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// ClashModeState is the current Clash mode and the modes offered by the running config.
+// Modes come from the config's clash_api.default_mode and clash_mode route rules.
+type ClashModeState struct {
+	Mode  string
+	Modes []string
+}
+
+// Available reports whether there is anything to switch between
+func (s ClashModeState) Available() bool {
+	return len(s.Modes) > 1
+}
+
+// ClashModeState returns the last known mode state (empty when sing-box is not running)
+func (ac *AppController) ClashModeState() ClashModeState {
+	ac.APIStateMutex.RLock()
+	defer ac.APIStateMutex.RUnlock()
+	return ClashModeState{Mode: ac.ClashMode, Modes: append([]string(nil), ac.ClashModes...)}
+}
+
+// setClashModeState stores the mode state and notifies the UI and tray
+func (ac *AppController) setClashModeState(state ClashModeState) {
+	ac.APIStateMutex.Lock()
+	changed := ac.ClashMode != state.Mode || strings.Join(ac.ClashModes, "\n") != strings.Join(state.Modes, "\n")
+	ac.ClashMode = state.Mode
+	ac.ClashModes = state.Modes
+	ac.APIStateMutex.Unlock()
+
+	if !changed {
+		return
+	}
+	ac.Events.Publish(ClashModeChanged{Mode: state.Mode})
+}
+
+// ResetClashModeState forgets the mode state (sing-box stopped)
+func (ac *AppController) ResetClashModeState() {
+	ac.setClashModeState(ClashModeState{})
+}
+
+// RefreshClashMode reads the current mode and the available modes from GET /configs
+func (ac *AppController) RefreshClashMode(ctx context.Context) (ClashModeState, error) {
+	configs, err := ac.ClashClient().Configs(ctx)
+	if err != nil {
+		return ClashModeState{}, fmt.Errorf("failed to read Clash mode: %w", err)
+	}
+	state := ClashModeState{Mode: configs.Mode, Modes: configs.ModeList}
+	if len(state.Modes) == 0 && state.Mode != "" {
+		state.Modes = []string{state.Mode}
+	}
+	ac.setClashModeState(state)
+	return state, nil
+}
+
+// SetClashMode switches the Clash mode via PATCH /configs and remembers the choice
+func (ac *AppController) SetClashMode(ctx context.Context, mode string) error {
+	state := ac.ClashModeState()
+	if len(state.Modes) > 0 && !containsModeFold(state.Modes, mode) {
+		return fmt.Errorf("mode '%s' is not available (available: %s)", mode, strings.Join(state.Modes, ", "))
+	}
+	if err := ac.ClashClient().PatchConfigs(ctx, map[string]interface{}{"mode": mode}); err != nil {
+		return fmt.Errorf("failed to switch Clash mode: %w", err)
+	}
+	log.Printf("SetClashMode: switched to '%s'", mode)
+
+	if store := ac.CurrentStateStore(); store != nil {
+		if err := store.SetClashMode(mode); err != nil {
+			log.Printf("SetClashMode: failed to remember mode: %v", err)
+		}
+	}
+	if _, err := ac.RefreshClashMode(ctx); err != nil {
+		log.Printf("SetClashMode: %v", err)
+		state.Mode = mode
+		ac.setClashModeState(state)
+	}
+	return nil
+}
+
+// RestoreClashMode re-applies the remembered Clash mode after sing-box starts.
+// sing-box starts in default_mode, so without this the user's choice would be lost on every restart.
+func (ac *AppController) RestoreClashMode() {
+	ac.APIStateMutex.RLock()
+	enabled := ac.ClashAPIEnabled
+	ac.APIStateMutex.RUnlock()
+	if !enabled {
+		return
+	}
+	if !ac.waitForClashAPI(ac.ClashClient()) {
+		log.Println("RestoreClashMode: Clash API is not reachable, skipping")
+		return
+	}
+
+	state, err := ac.RefreshClashMode(ac.ctx)
+	if err != nil {
+		log.Printf("RestoreClashMode: %v", err)
+		return
+	}
+	store := ac.CurrentStateStore()
+	if store == nil {
+		return
+	}
+	wanted := store.ClashMode()
+	if wanted == "" || strings.EqualFold(wanted, state.Mode) {
+		return
+	}
+	if !containsModeFold(state.Modes, wanted) {
+		log.Printf("RestoreClashMode: remembered mode '%s' is not offered by the config (available: %s), keeping '%s'",
+			wanted, strings.Join(state.Modes, ", "), state.Mode)
+		return
+	}
+	if err := ac.SetClashMode(ac.ctx, wanted); err != nil {
+		log.Printf("RestoreClashMode: %v", err)
+		return
+	}
+	log.Printf("RestoreClashMode: restored mode '%s'", wanted)
+}
+
+// containsModeFold reports whether modes contains mode (sing-box compares modes case-insensitively)
+func containsModeFold(modes []string, mode string) bool {
+	for _, m := range modes {
+		if strings.EqualFold(m, mode) {
+			return true
+		}
+	}
+	return false
+}
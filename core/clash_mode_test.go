@@ -0,0 +1,90 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// newModeTestController returns a controller talking to a fake /configs endpoint
+func newModeTestController(t *testing.T, modes []string, mode string) (*AppController, *string) {
+	var mu sync.Mutex
+	current := mode
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.URL.Path == "/version":
+			fmt.Fprint(w, `{"version":"sing-box 1.12.0"}`)
+		case r.URL.Path == "/configs" && r.Method == "GET":
+			json.NewEncoder(w).Encode(map[string]interface{}{"mode": current, "mode-list": modes})
+		case r.URL.Path == "/configs" && r.Method == "PATCH":
+			var patch map[string]string
+			json.NewDecoder(r.Body).Decode(&patch)
+			current = patch["mode"]
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	ac := &AppController{
+		ClashAPIBaseURL: server.URL,
+		ClashAPIEnabled: true,
+		StateStore:      NewStateStore(filepath.Join(t.TempDir(), "launcher_state.json")),
+		ctx:             context.Background(),
+	}
+	ac.RunningState = &RunningState{running: true, controller: ac}
+	return ac, &current
+}
+
+// TestSetClashMode tests switching, remembering and rejecting unknown modes
+func TestSetClashMode(t *testing.T) {
+	ac, current := newModeTestController(t, []string{"Rule", "Global", "Direct"}, "Rule")
+
+	state, err := ac.RefreshClashMode(context.Background())
+	if err != nil || state.Mode != "Rule" || !state.Available() {
+		t.Fatalf("RefreshClashMode() = %+v, %v", state, err)
+	}
+
+	if err := ac.SetClashMode(context.Background(), "Global"); err != nil {
+		t.Fatalf("SetClashMode() error: %v", err)
+	}
+	if *current != "Global" || ac.ClashModeState().Mode != "Global" {
+		t.Errorf("Expected mode Global, server has %q, controller has %q", *current, ac.ClashModeState().Mode)
+	}
+	if ac.StateStore.ClashMode() != "Global" {
+		t.Errorf("Expected remembered mode Global, got %q", ac.StateStore.ClashMode())
+	}
+
+	if err := ac.SetClashMode(context.Background(), "Unknown"); err == nil {
+		t.Error("Expected error for a mode the config does not offer")
+	}
+}
+
+// TestRestoreClashMode tests re-applying the remembered mode after a restart
+func TestRestoreClashMode(t *testing.T) {
+	ac, current := newModeTestController(t, []string{"Rule", "Global", "Direct"}, "Rule")
+	if err := ac.StateStore.SetClashMode("direct"); err != nil {
+		t.Fatal(err)
+	}
+
+	ac.RestoreClashMode()
+	if *current != "direct" {
+		t.Errorf("Expected remembered mode to be re-applied, server has %q", *current)
+	}
+
+	// A remembered mode that the new config does not offer is left alone
+	ac, current = newModeTestController(t, []string{"Rule"}, "Rule")
+	ac.StateStore.SetClashMode("Global")
+	ac.RestoreClashMode()
+	if *current != "Rule" {
+		t.Errorf("Expected mode to stay Rule, server has %q", *current)
+	}
+}
@@ -0,0 +1,211 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"singbox-launcher/core/parsers"
+	"singbox-launcher/internal/platform"
+)
+
+// ValidateParserConfig checks ParserConfig for mistakes that the parser would otherwise skip with
+// only a log line (bad sources, broken filters, duplicate selector tags). Returns every problem found.
+func ValidateParserConfig(config *ParserConfig) []error {
+	var problems []error
+	if config == nil {
+		return []error{fmt.Errorf("ParserConfig is empty")}
+	}
+	pc := config.ParserConfig
+
+	if len(pc.Proxies) == 0 {
+		problems = append(problems, fmt.Errorf("no proxy sources in ParserConfig.proxies"))
+	}
+	if pc.Parser.Reload != "" {
+		if _, err := time.ParseDuration(pc.Parser.Reload); err != nil {
+			problems = append(problems, fmt.Errorf("parser.reload %q is not a valid duration (e.g. \"4h\")", pc.Parser.Reload))
+		}
+	}
+
+	tags := make(map[string]string) // selector tag -> where it was defined
+	checkOutbound := func(outbound OutboundConfig, where string) {
+		if outbound.Tag == "" {
+			problems = append(problems, fmt.Errorf("%s: outbound without tag", where))
+			return
+		}
+		if previous, ok := tags[outbound.Tag]; ok {
+			problems = append(problems, fmt.Errorf("%s: duplicate outbound tag '%s' (also in %s)", where, outbound.Tag, previous))
+		}
+		tags[outbound.Tag] = where
+		if outbound.Type != "selector" && outbound.Type != "urltest" {
+			problems = append(problems, fmt.Errorf("%s: outbound '%s' has unsupported type %q (selector or urltest)", where, outbound.Tag, outbound.Type))
+		}
+		for _, filter := range filterObjects(outbound.Filters) {
+			for key, value := range filter {
+				pattern, ok := value.(string)
+				if !ok {
+					problems = append(problems, fmt.Errorf("%s: outbound '%s' filter '%s' must be a string", where, outbound.Tag, key))
+					continue
+				}
+				if err := validateFilterPattern(pattern); err != nil {
+					problems = append(problems, fmt.Errorf("%s: outbound '%s' filter '%s': %w", where, outbound.Tag, key, err))
+				}
+			}
+		}
+	}
+
+	for i, source := range pc.Proxies {
+		where := fmt.Sprintf("proxies[%d]", i)
+		if source.Source == "" && len(source.Connections) == 0 {
+			problems = append(problems, fmt.Errorf("%s: neither source nor connections are set", where))
+		}
+		if source.Source != "" && !IsSubscriptionURL(source.Source) && !parsers.IsDirectLink(source.Source) {
+			problems = append(problems, fmt.Errorf("%s: source %q is neither a subscription URL nor a direct link", where, source.Source))
+		}
+		for j, connection := range source.Connections {
+			if strings.TrimSpace(connection) != "" && !parsers.IsDirectLink(connection) {
+				problems = append(problems, fmt.Errorf("%s.connections[%d]: unsupported link format", where, j))
+			}
+		}
+		for _, outbound := range source.Outbounds {
+			checkOutbound(outbound, where)
+		}
+	}
+	for i, outbound := range pc.Outbounds {
+		checkOutbound(outbound, fmt.Sprintf("outbounds[%d]", i))
+	}
+	return problems
+}
+
+// filterObjects returns the filter objects of a selector (a single object or an array of objects)
+func filterObjects(filters interface{}) []map[string]interface{} {
+	switch f := filters.(type) {
+	case map[string]interface{}:
+		return []map[string]interface{}{f}
+	case []interface{}:
+		var result []map[string]interface{}
+		for _, item := range f {
+			if m, ok := item.(map[string]interface{}); ok {
+				result = append(result, m)
+			}
+		}
+		return result
+	}
+	return nil
+}
+
+// validateFilterPattern checks that a /regex/i pattern compiles (literals are always valid)
+func validateFilterPattern(pattern string) error {
+	regexStr := strings.TrimPrefix(pattern, "!")
+	if !strings.HasPrefix(regexStr, "/") || !strings.HasSuffix(regexStr, "/i") || len(regexStr) < 3 {
+		return nil
+	}
+	regexStr = strings.TrimSuffix(strings.TrimPrefix(regexStr, "/"), "/i")
+	if _, err := regexp.Compile("(?i)" + regexStr); err != nil {
+		return fmt.Errorf("invalid regex %q: %w", pattern, err)
+	}
+	return nil
+}
+
+// ValidateGeneratedConfig checks config.json written by the parser: syntax, the @ParserSTART/@ParserEND
+// markers, unique outbound tags and that groups and route.final only reference existing outbounds.
+func ValidateGeneratedConfig(configPath string) []error {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return []error{fmt.Errorf("failed to read config: %w", err)}
+	}
+	var problems []error
+	if !strings.Contains(string(data), "/** @ParserSTART */") || !strings.Contains(string(data), "/** @ParserEND */") {
+		problems = append(problems, fmt.Errorf("markers @ParserSTART or @ParserEND not found"))
+	}
+
+	config, err := readConfigJSON(configPath)
+	if err != nil {
+		return append(problems, err)
+	}
+	outbounds, _ := config["outbounds"].([]interface{})
+	if len(outbounds) == 0 {
+		return append(problems, fmt.Errorf("config has no outbounds"))
+	}
+
+	tags := make(map[string]bool)
+	for i, item := range outbounds {
+		outbound, _ := item.(map[string]interface{})
+		tag, _ := outbound["tag"].(string)
+		if tag == "" {
+			problems = append(problems, fmt.Errorf("outbounds[%d] has no tag", i))
+			continue
+		}
+		if tags[tag] {
+			problems = append(problems, fmt.Errorf("duplicate outbound tag '%s'", tag))
+		}
+		tags[tag] = true
+	}
+	for _, item := range outbounds {
+		outbound, _ := item.(map[string]interface{})
+		tag, _ := outbound["tag"].(string)
+		members, _ := outbound["outbounds"].([]interface{})
+		for _, member := range members {
+			if name, _ := member.(string); name != "" && !tags[name] {
+				problems = append(problems, fmt.Errorf("group '%s' references unknown outbound '%s'", tag, name))
+			}
+		}
+		if outbound["type"] == "selector" && len(members) == 0 {
+			problems = append(problems, fmt.Errorf("selector '%s' is empty (no nodes matched its filters)", tag))
+		}
+		if def, _ := outbound["default"].(string); def != "" && !tags[def] {
+			problems = append(problems, fmt.Errorf("group '%s' default '%s' does not exist", tag, def))
+		}
+	}
+	if route, ok := config["route"].(map[string]interface{}); ok {
+		if final, _ := route["final"].(string); final != "" && !tags[final] {
+			problems = append(problems, fmt.Errorf("route.final references unknown outbound '%s'", final))
+		}
+	}
+	return problems
+}
+
+// CheckConfigWithCore runs "sing-box check" on the active config. Returns the core's output on failure.
+func (ac *AppController) CheckConfigWithCore() error {
+	if _, err := os.Stat(ac.SingboxPath); err != nil {
+		return fmt.Errorf("sing-box not found at %s", ac.SingboxPath)
+	}
+	binDir := platform.GetBinDir(ac.ExecDir)
+	configArg, err := filepath.Rel(binDir, ac.ConfigPath)
+	if err != nil {
+		configArg = ac.ConfigPath
+	}
+	cmd := exec.Command(ac.SingboxPath, "check", "-c", configArg)
+	platform.PrepareCommand(cmd)
+	cmd.Dir = binDir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("sing-box check failed: %w\n%s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// ParseNodes fetches and parses all proxy sources of config without writing anything.
+// The result is indexed like config.ParserConfig.Proxies; tags are deduplicated the same way as on update.
+func (svc *ConfigService) ParseNodes(config *ParserConfig) [][]*parsers.ParsedNode {
+	tagCounts := make(map[string]int)
+	total := len(config.ParserConfig.Proxies)
+	result := make([][]*parsers.ParsedNode, total)
+	for i, source := range config.ParserConfig.Proxies {
+		nodes, err := svc.ProcessProxySource(source, tagCounts, nil, i, total)
+		if err != nil {
+			continue
+		}
+		result[i] = nodes
+	}
+	return result
+}
+
+// MatchesNodeFilter reports whether node matches the filter (AND between keys), using the
+// same keys (tag, host, label, scheme, comment) and patterns (literal, !literal, /regex/i) as ParserConfig filters.
+func MatchesNodeFilter(node *parsers.ParsedNode, filter map[string]string) bool {
+	return matchesFilter(node, filter)
+}
@@ -0,0 +1,89 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestValidateParserConfig tests detection of broken sources, filters and duplicate tags
+func TestValidateParserConfig(t *testing.T) {
+	valid := &ParserConfig{}
+	valid.ParserConfig.Parser.Reload = "4h"
+	valid.ParserConfig.Proxies = []ProxySource{{Source: "https://example.com/sub"}}
+	valid.ParserConfig.Outbounds = []OutboundConfig{
+		{Tag: "proxy-out", Type: "selector", Filters: map[string]interface{}{"tag": "/🇳🇱|NL/i"}},
+		{Tag: "auto", Type: "urltest"},
+	}
+	if problems := ValidateParserConfig(valid); len(problems) != 0 {
+		t.Errorf("Expected no problems, got %v", problems)
+	}
+
+	broken := &ParserConfig{}
+	broken.ParserConfig.Parser.Reload = "often"
+	broken.ParserConfig.Proxies = []ProxySource{
+		{},
+		{Source: "not a link", Connections: []string{"http://example.com"}},
+	}
+	broken.ParserConfig.Outbounds = []OutboundConfig{
+		{Tag: "proxy-out", Type: "selector", Filters: map[string]interface{}{"tag": "/([/i"}},
+		{Tag: "proxy-out", Type: "direct"},
+	}
+	problems := ValidateParserConfig(broken)
+	joined := ""
+	for _, problem := range problems {
+		joined += problem.Error() + "\n"
+	}
+	for _, expected := range []string{"parser.reload", "neither source nor connections", "neither a subscription URL", "unsupported link format", "invalid regex", "duplicate outbound tag", "unsupported type"} {
+		if !strings.Contains(joined, expected) {
+			t.Errorf("Expected a problem containing %q, got:\n%s", expected, joined)
+		}
+	}
+}
+
+// TestValidateGeneratedConfig tests checks of the config written by the parser
+func TestValidateGeneratedConfig(t *testing.T) {
+	dir := t.TempDir()
+	write := func(content string) string {
+		path := filepath.Join(dir, "config.json")
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	good := write(`{
+  "outbounds": [
+    /** @ParserSTART */
+    {"type": "vless", "tag": "nl-1"},
+    {"type": "selector", "tag": "proxy-out", "outbounds": ["nl-1", "direct-out"], "default": "nl-1"},
+    /** @ParserEND */
+    {"type": "direct", "tag": "direct-out"}
+  ],
+  "route": {"final": "proxy-out"}
+}`)
+	if problems := ValidateGeneratedConfig(good); len(problems) != 0 {
+		t.Errorf("Expected no problems, got %v", problems)
+	}
+
+	bad := write(`{
+  "outbounds": [
+    {"type": "vless", "tag": "nl-1"},
+    {"type": "vless", "tag": "nl-1"},
+    {"type": "selector", "tag": "proxy-out", "outbounds": ["de-1"], "default": "de-1"},
+    {"type": "selector", "tag": "empty"}
+  ],
+  "route": {"final": "missing"}
+}`)
+	problems := ValidateGeneratedConfig(bad)
+	joined := ""
+	for _, problem := range problems {
+		joined += problem.Error() + "\n"
+	}
+	for _, expected := range []string{"markers", "duplicate outbound tag 'nl-1'", "unknown outbound 'de-1'", "default 'de-1'", "selector 'empty' is empty", "route.final"} {
+		if !strings.Contains(joined, expected) {
+			t.Errorf("Expected a problem containing %q, got:\n%s", expected, joined)
+		}
+	}
+}
@@ -0,0 +1,623 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/muhammadmuzzammil1998/jsonc"
+
+	"singbox-launcher/internal/platform"
+)
+
+// CompatLevel is how a config construct is affected by a sing-box version
+type CompatLevel int
+
+const (
+	// CompatDeprecated means the version still accepts the construct but warns about it
+	CompatDeprecated CompatLevel = iota
+	// CompatRemoved means the version rejects the config
+	CompatRemoved
+)
+
+// CompatIssue is a config construct that is deprecated or removed in a sing-box version
+type CompatIssue struct {
+	Rule       string // Rule ID, e.g. "special-outbounds"
+	Path       string // Where in the config, e.g. `outbounds[3] "block"`
+	Message    string
+	Deprecated string // sing-box version that deprecated the construct
+	Removed    string // sing-box version that removed it
+	Level      CompatLevel
+	Fixable    bool // FixConfigCompatibility can rewrite it mechanically
+}
+
+// String formats the issue for logs and dialogs
+func (issue CompatIssue) String() string {
+	state := "deprecated in " + issue.Deprecated
+	if issue.Level == CompatRemoved {
+		state = "removed in " + issue.Removed
+	}
+	return fmt.Sprintf("%s: %s (%s)", issue.Path, issue.Message, state)
+}
+
+// compatMatch is one occurrence of a compatibility rule in a config
+type compatMatch struct {
+	Path    string
+	Message string
+	Fixable bool
+}
+
+// compatRule describes a construct deprecated in Deprecated and removed in Removed.
+// fix rewrites the fixable matches in place (elements of top-level arrays are set to nil to remove them).
+type compatRule struct {
+	ID         string
+	Deprecated string
+	Removed    string
+	check      func(config map[string]interface{}) []compatMatch
+	fix        func(config map[string]interface{})
+}
+
+// compatRules lists the known breaking changes of sing-box configs, oldest first
+var compatRules = []compatRule{
+	{ID: "geoip-geosite", Deprecated: "1.8.0", Removed: "1.12.0", check: checkGeoRules},
+	{ID: "tun-address-fields", Deprecated: "1.10.0", Removed: "1.12.0", check: checkTunAddressFields, fix: fixTunAddressFields},
+	{ID: "inbound-legacy-fields", Deprecated: "1.11.0", Removed: "1.13.0", check: checkInboundLegacyFields, fix: fixInboundLegacyFields},
+	{ID: "special-outbounds", Deprecated: "1.11.0", Removed: "1.13.0", check: checkSpecialOutbounds, fix: fixSpecialOutbounds},
+	{ID: "wireguard-outbound", Deprecated: "1.11.0", Removed: "1.13.0", check: checkWireGuardOutbounds},
+	{ID: "legacy-dns-servers", Deprecated: "1.12.0", Removed: "1.14.0", check: checkLegacyDNSServers, fix: fixLegacyDNSServers},
+	{ID: "outbound-domain-strategy", Deprecated: "1.12.0", Removed: "1.14.0", check: checkOutboundDomainStrategy},
+}
+
+// compatVersionAtLeast compares the release part of version (1.13.0-beta.1 already has the 1.13.0 changes)
+func compatVersionAtLeast(version, min string) bool {
+	release, _, _ := strings.Cut(strings.TrimPrefix(version, "v"), "-")
+	return CompareVersions(release, min) >= 0
+}
+
+// parseCompatConfig parses a JSONC config keeping numbers as written
+func parseCompatConfig(data []byte) (map[string]interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(jsonc.ToJSON(data)))
+	decoder.UseNumber()
+	var config map[string]interface{}
+	if err := decoder.Decode(&config); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	return config, nil
+}
+
+// CheckConfigCompatibility lists the constructs of a JSONC config that sing-box version deprecates or removed
+func CheckConfigCompatibility(data []byte, version string) ([]CompatIssue, error) {
+	config, err := parseCompatConfig(data)
+	if err != nil {
+		return nil, err
+	}
+	var issues []CompatIssue
+	for _, rule := range compatRules {
+		if !compatVersionAtLeast(version, rule.Deprecated) {
+			continue
+		}
+		level := CompatDeprecated
+		if compatVersionAtLeast(version, rule.Removed) {
+			level = CompatRemoved
+		}
+		for _, match := range rule.check(config) {
+			issues = append(issues, CompatIssue{
+				Rule:       rule.ID,
+				Path:       match.Path,
+				Message:    match.Message,
+				Deprecated: rule.Deprecated,
+				Removed:    rule.Removed,
+				Level:      level,
+				Fixable:    match.Fixable && rule.fix != nil,
+			})
+		}
+	}
+	return issues, nil
+}
+
+// FixConfigCompatibility rewrites the fixable constructs reported for version.
+// Only the changed parts of the JSONC text are replaced; comments elsewhere are kept.
+// Returns the new content and the issues that were fixed.
+func FixConfigCompatibility(data []byte, version string) ([]byte, []CompatIssue, error) {
+	issues, err := CheckConfigCompatibility(data, version)
+	if err != nil {
+		return nil, nil, err
+	}
+	config, err := parseCompatConfig(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	original, _ := parseCompatConfig(data)
+
+	var fixed []CompatIssue
+	fixedRules := make(map[string]bool)
+	for _, issue := range issues {
+		if issue.Fixable {
+			fixed = append(fixed, issue)
+			fixedRules[issue.Rule] = true
+		}
+	}
+	if len(fixed) == 0 {
+		return data, nil, nil
+	}
+	for _, rule := range compatRules {
+		if fixedRules[rule.ID] {
+			rule.fix(config)
+		}
+	}
+	content, err := applyJSONCChanges(string(data), original, config)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to update config: %w", err)
+	}
+	if _, err := parseCompatConfig([]byte(content)); err != nil {
+		return nil, nil, fmt.Errorf("fixed config is invalid: %w", err)
+	}
+	return []byte(content), fixed, nil
+}
+
+// CompatReport is the result of checking config.json and the config template against a sing-box version
+type CompatReport struct {
+	Version  string
+	Config   []CompatIssue // bin/config.json
+	Template []CompatIssue // bin/config_template.json
+}
+
+// Breaking returns the number of constructs the version no longer accepts
+func (r CompatReport) Breaking() int {
+	count := 0
+	for _, issue := range append(append([]CompatIssue{}, r.Config...), r.Template...) {
+		if issue.Level == CompatRemoved {
+			count++
+		}
+	}
+	return count
+}
+
+// Fixable returns the number of config.json issues FixCoreCompatibility can rewrite
+func (r CompatReport) Fixable() int {
+	count := 0
+	for _, issue := range r.Config {
+		if issue.Fixable {
+			count++
+		}
+	}
+	return count
+}
+
+// ConfigTemplateFileName returns the config template of the current platform
+func ConfigTemplateFileName() string {
+	if runtime.GOOS == "darwin" {
+		return "config_template_macos.json"
+	}
+	return "config_template.json"
+}
+
+// ConfigTemplatePath returns the path of the config template in bin/
+func (ac *AppController) ConfigTemplatePath() string {
+	return filepath.Join(platform.GetBinDir(ac.ExecDir), ConfigTemplateFileName())
+}
+
+// CheckCoreCompatibility checks config.json and the config template against sing-box version.
+// Missing files are skipped.
+func (ac *AppController) CheckCoreCompatibility(version string) (CompatReport, error) {
+	report := CompatReport{Version: version}
+	for _, file := range []struct {
+		path   string
+		issues *[]CompatIssue
+	}{
+		{ac.ConfigPath, &report.Config},
+		{ac.ConfigTemplatePath(), &report.Template},
+	} {
+		data, err := os.ReadFile(file.path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return report, fmt.Errorf("failed to read %s: %w", filepath.Base(file.path), err)
+		}
+		issues, err := CheckConfigCompatibility(data, version)
+		if err != nil {
+			return report, fmt.Errorf("%s: %w", filepath.Base(file.path), err)
+		}
+		*file.issues = issues
+	}
+	return report, nil
+}
+
+// FixCoreCompatibility applies the mechanical fixes for version to config.json.
+// The previous file is kept as config.json.old.
+func (ac *AppController) FixCoreCompatibility(version string) ([]CompatIssue, error) {
+	data, err := os.ReadFile(ac.ConfigPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	content, fixed, err := FixConfigCompatibility(data, version)
+	if err != nil || len(fixed) == 0 {
+		return nil, err
+	}
+	if err := os.WriteFile(ac.ConfigPath+".old", data, 0644); err != nil {
+		return nil, fmt.Errorf("failed to back up config: %w", err)
+	}
+	if err := os.WriteFile(ac.ConfigPath, content, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write config: %w", err)
+	}
+	for _, issue := range fixed {
+		log.Printf("FixCoreCompatibility: fixed %s", issue)
+	}
+	return fixed, nil
+}
+
+// logCoreCompatibility logs what the active sing-box version deprecates in the current config
+func (ac *AppController) logCoreCompatibility(version string) {
+	report, err := ac.CheckCoreCompatibility(version)
+	if err != nil {
+		log.Printf("logCoreCompatibility: %v", err)
+		return
+	}
+	for _, issue := range report.Config {
+		log.Printf("logCoreCompatibility: config.json: %s", issue)
+	}
+	for _, issue := range report.Template {
+		log.Printf("logCoreCompatibility: %s: %s", ConfigTemplateFileName(), issue)
+	}
+}
+
+// --- helpers -----------------------------------------------------------------
+
+func compatArray(value interface{}) []interface{} {
+	array, _ := value.([]interface{})
+	return array
+}
+
+func compatObject(value interface{}) map[string]interface{} {
+	object, _ := value.(map[string]interface{})
+	return object
+}
+
+func compatString(object map[string]interface{}, key string) string {
+	value, _ := object[key].(string)
+	return value
+}
+
+// compatPath formats an array element with its tag: inbounds[0] "tun-in"
+func compatPath(section string, index int, object map[string]interface{}) string {
+	if tag := compatString(object, "tag"); tag != "" {
+		return fmt.Sprintf("%s[%d] %q", section, index, tag)
+	}
+	return fmt.Sprintf("%s[%d]", section, index)
+}
+
+// compatRuleLists returns route.rules and dns.rules with their names
+func compatRuleLists(config map[string]interface{}) map[string][]interface{} {
+	return map[string][]interface{}{
+		"route.rules": compatArray(compatObject(config["route"])["rules"]),
+		"dns.rules":   compatArray(compatObject(config["dns"])["rules"]),
+	}
+}
+
+// --- geoip / geosite -----------------------------------------------------------
+
+func checkGeoRules(config map[string]interface{}) []compatMatch {
+	var matches []compatMatch
+	route := compatObject(config["route"])
+	for _, key := range []string{"geoip", "geosite"} {
+		if _, ok := route[key]; ok {
+			matches = append(matches, compatMatch{Path: "route." + key, Message: key + " database: use rule_set"})
+		}
+	}
+	for _, name := range []string{"route.rules", "dns.rules"} {
+		for i, item := range compatRuleLists(config)[name] {
+			rule := compatObject(item)
+			for _, key := range []string{"geoip", "source_geoip", "geosite"} {
+				if _, ok := rule[key]; ok {
+					matches = append(matches, compatMatch{Path: fmt.Sprintf("%s[%d]", name, i), Message: key + " rule item: use rule_set"})
+				}
+			}
+		}
+	}
+	return matches
+}
+
+// --- TUN inet4/inet6 fields ----------------------------------------------------------
+
+// tunAddressFields maps legacy TUN fields to the merged ones
+var tunAddressFields = []struct{ legacy, merged string }{
+	{"inet4_address", "address"},
+	{"inet6_address", "address"},
+	{"inet4_route_address", "route_address"},
+	{"inet6_route_address", "route_address"},
+	{"inet4_route_exclude_address", "route_exclude_address"},
+	{"inet6_route_exclude_address", "route_exclude_address"},
+}
+
+func checkTunAddressFields(config map[string]interface{}) []compatMatch {
+	var matches []compatMatch
+	for i, item := range compatArray(config["inbounds"]) {
+		inbound := compatObject(item)
+		if compatString(inbound, "type") != "tun" {
+			continue
+		}
+		for _, field := range tunAddressFields {
+			if _, ok := inbound[field.legacy]; ok {
+				matches = append(matches, compatMatch{
+					Path:    compatPath("inbounds", i, inbound),
+					Message: fmt.Sprintf("%s: merged into %s", field.legacy, field.merged),
+					Fixable: true,
+				})
+			}
+		}
+	}
+	return matches
+}
+
+func fixTunAddressFields(config map[string]interface{}) {
+	for _, item := range compatArray(config["inbounds"]) {
+		inbound := compatObject(item)
+		if compatString(inbound, "type") != "tun" {
+			continue
+		}
+		for _, field := range tunAddressFields {
+			value, ok := inbound[field.legacy]
+			if !ok {
+				continue
+			}
+			merged := compatArray(inbound[field.merged])
+			if s, isString := inbound[field.merged].(string); isString {
+				merged = []interface{}{s}
+			}
+			if s, isString := value.(string); isString {
+				merged = append(merged, s)
+			} else {
+				merged = append(merged, compatArray(value)...)
+			}
+			inbound[field.merged] = merged
+			delete(inbound, field.legacy)
+		}
+	}
+}
+
+// --- legacy inbound fields (sniff, domain_strategy) --------------------------------------
+
+// inboundLegacyFields are replaced by the sniff and resolve rule actions
+var inboundLegacyFields = []string{"sniff", "sniff_override_destination", "sniff_timeout", "domain_strategy", "udp_disable_domain_unmapping"}
+
+// inboundLegacyFixable reports whether the fields of inbound map to rule actions without changing behaviour
+func inboundLegacyFixable(config map[string]interface{}, inbound map[string]interface{}) bool {
+	if compatString(inbound, "tag") == "" || compatObject(config["route"]) == nil {
+		return false
+	}
+	for _, key := range []string{"sniff_override_destination", "udp_disable_domain_unmapping"} {
+		if enabled, _ := inbound[key].(bool); enabled {
+			return false
+		}
+	}
+	return true
+}
+
+func checkInboundLegacyFields(config map[string]interface{}) []compatMatch {
+	var matches []compatMatch
+	for i, item := range compatArray(config["inbounds"]) {
+		inbound := compatObject(item)
+		var found []string
+		for _, key := range inboundLegacyFields {
+			if _, ok := inbound[key]; ok {
+				found = append(found, key)
+			}
+		}
+		if len(found) > 0 {
+			matches = append(matches, compatMatch{
+				Path:    compatPath("inbounds", i, inbound),
+				Message: strings.Join(found, ", ") + ": use the sniff and resolve route rule actions",
+				Fixable: inboundLegacyFixable(config, inbound),
+			})
+		}
+	}
+	return matches
+}
+
+func fixInboundLegacyFields(config map[string]interface{}) {
+	var actions []interface{}
+	for _, item := range compatArray(config["inbounds"]) {
+		inbound := compatObject(item)
+		if !inboundLegacyFixable(config, inbound) {
+			continue
+		}
+		tag := compatString(inbound, "tag")
+		if strategy := compatString(inbound, "domain_strategy"); strategy != "" {
+			actions = append(actions, map[string]interface{}{"inbound": tag, "action": "resolve", "strategy": strategy})
+		}
+		if sniff, _ := inbound["sniff"].(bool); sniff {
+			action := map[string]interface{}{"inbound": tag, "action": "sniff"}
+			if timeout := compatString(inbound, "sniff_timeout"); timeout != "" {
+				action["timeout"] = timeout
+			}
+			actions = append(actions, action)
+		}
+		for _, key := range inboundLegacyFields {
+			delete(inbound, key)
+		}
+	}
+	if len(actions) > 0 {
+		route := compatObject(config["route"])
+		route["rules"] = append(actions, compatArray(route["rules"])...)
+	}
+}
+
+// --- block and dns outbounds ----------------------------------------------------------
+
+// specialOutboundActions maps the legacy outbound types to the rule actions that replace them
+var specialOutboundActions = map[string]string{"block": "reject", "dns": "hijack-dns"}
+
+// specialOutboundFixable reports whether tag is only used as the outbound of route rules
+func specialOutboundFixable(config map[string]interface{}, tag string) bool {
+	if tag == "" || compatString(compatObject(config["route"]), "final") == tag {
+		return false
+	}
+	for _, item := range compatArray(config["outbounds"]) {
+		outbound := compatObject(item)
+		if compatString(outbound, "detour") == tag {
+			return false
+		}
+		for _, member := range compatArray(outbound["outbounds"]) {
+			if member == tag {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func checkSpecialOutbounds(config map[string]interface{}) []compatMatch {
+	var matches []compatMatch
+	for i, item := range compatArray(config["outbounds"]) {
+		outbound := compatObject(item)
+		outboundType := compatString(outbound, "type")
+		action, ok := specialOutboundActions[outboundType]
+		if !ok {
+			continue
+		}
+		matches = append(matches, compatMatch{
+			Path:    compatPath("outbounds", i, outbound),
+			Message: fmt.Sprintf("%s outbound: use the %q rule action", outboundType, action),
+			Fixable: specialOutboundFixable(config, compatString(outbound, "tag")),
+		})
+	}
+	return matches
+}
+
+func fixSpecialOutbounds(config map[string]interface{}) {
+	outbounds := compatArray(config["outbounds"])
+	rules := compatArray(compatObject(config["route"])["rules"])
+	for i, item := range outbounds {
+		outbound := compatObject(item)
+		action, ok := specialOutboundActions[compatString(outbound, "type")]
+		tag := compatString(outbound, "tag")
+		if !ok || !specialOutboundFixable(config, tag) {
+			continue
+		}
+		for _, ruleItem := range rules {
+			rule := compatObject(ruleItem)
+			if compatString(rule, "outbound") == tag {
+				delete(rule, "outbound")
+				rule["action"] = action
+			}
+		}
+		outbounds[i] = nil
+	}
+}
+
+// --- WireGuard outbound --------------------------------------------------------------
+
+func checkWireGuardOutbounds(config map[string]interface{}) []compatMatch {
+	var matches []compatMatch
+	for i, item := range compatArray(config["outbounds"]) {
+		outbound := compatObject(item)
+		if compatString(outbound, "type") == "wireguard" {
+			matches = append(matches, compatMatch{Path: compatPath("outbounds", i, outbound), Message: "wireguard outbound: move it to endpoints"})
+		}
+	}
+	return matches
+}
+
+// --- legacy DNS servers ------------------------------------------------------------------
+
+// legacyDNSServerKeys are the fields a legacy server may have to be converted mechanically
+var legacyDNSServerKeys = map[string]bool{"tag": true, "address": true, "address_resolver": true, "detour": true, "client_subnet": true}
+
+// convertLegacyDNSServer returns the new-format server or nil if it cannot be converted mechanically
+func convertLegacyDNSServer(server map[string]interface{}) map[string]interface{} {
+	for key := range server {
+		if !legacyDNSServerKeys[key] {
+			return nil
+		}
+	}
+	result := make(map[string]interface{})
+	for key, value := range server {
+		switch key {
+		case "address":
+		case "address_resolver":
+			result["domain_resolver"] = value
+		default:
+			result[key] = value
+		}
+	}
+
+	address := compatString(server, "address")
+	if address == "local" {
+		result["type"] = "local"
+		return result
+	}
+	if !strings.Contains(address, "://") {
+		address = "udp://" + address
+	}
+	parsed, err := url.Parse(address)
+	if err != nil || parsed.Hostname() == "" {
+		return nil
+	}
+	switch parsed.Scheme {
+	case "udp", "tcp", "tls", "quic", "https", "h3":
+	default:
+		return nil // dhcp, fakeip, rcode: need manual changes
+	}
+	result["type"] = parsed.Scheme
+	result["server"] = parsed.Hostname()
+	if port := parsed.Port(); port != "" {
+		result["server_port"] = json.Number(port)
+	}
+	if (parsed.Scheme == "https" || parsed.Scheme == "h3") && parsed.Path != "" && parsed.Path != "/dns-query" {
+		result["path"] = parsed.Path
+	}
+	if ip := net.ParseIP(parsed.Hostname()); ip == nil && result["domain_resolver"] == nil {
+		return nil // A domain name needs a domain_resolver
+	}
+	return result
+}
+
+func checkLegacyDNSServers(config map[string]interface{}) []compatMatch {
+	var matches []compatMatch
+	for i, item := range compatArray(compatObject(config["dns"])["servers"]) {
+		server := compatObject(item)
+		if _, ok := server["address"]; !ok || server["type"] != nil {
+			continue
+		}
+		matches = append(matches, compatMatch{
+			Path:    compatPath("dns.servers", i, server),
+			Message: fmt.Sprintf("legacy server address %q: use type and server", compatString(server, "address")),
+			Fixable: convertLegacyDNSServer(server) != nil,
+		})
+	}
+	return matches
+}
+
+func fixLegacyDNSServers(config map[string]interface{}) {
+	servers := compatArray(compatObject(config["dns"])["servers"])
+	for i, item := range servers {
+		server := compatObject(item)
+		if _, ok := server["address"]; !ok || server["type"] != nil {
+			continue
+		}
+		if converted := convertLegacyDNSServer(server); converted != nil {
+			servers[i] = converted
+		}
+	}
+}
+
+// --- outbound domain_strategy ------------------------------------------------------------
+
+func checkOutboundDomainStrategy(config map[string]interface{}) []compatMatch {
+	var matches []compatMatch
+	for i, item := range compatArray(config["outbounds"]) {
+		outbound := compatObject(item)
+		if _, ok := outbound["domain_strategy"]; ok {
+			matches = append(matches, compatMatch{Path: compatPath("outbounds", i, outbound), Message: "domain_strategy dial field: use domain_resolver"})
+		}
+	}
+	return matches
+}
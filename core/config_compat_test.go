@@ -0,0 +1,143 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+// compatTestConfig uses the constructs removed in sing-box 1.12-1.14 around the parser markers
+const compatTestConfig = `/** @ParserConfig
+{"ParserConfig": {"proxies": [{"source": "https://example.com/sub"}]}}
+*/
+{
+  "dns": {
+    "servers": [
+      {"tag": "google", "address": "tls://8.8.8.8", "detour": "proxy-out"},
+      {"tag": "local", "address": "local"},
+      {"tag": "fake", "address": "fakeip"}
+    ]
+  },
+  "inbounds": [
+    // TUN
+    {"type": "tun", "tag": "tun-in", "inet4_address": "172.19.0.1/30", "sniff": true, "sniff_timeout": "1s", "domain_strategy": "prefer_ipv4"},
+    {"type": "mixed", "listen_port": 7890, "sniff": true}
+  ],
+  "outbounds": [
+    {"type": "selector", "tag": "proxy-out", "outbounds": ["node-1"]},
+    /** @ParserSTART */
+    {"type": "vless", "tag": "node-1", "server": "example.com", "server_port": 443},
+    /** @ParserEND */
+    {"type": "direct", "tag": "direct-out"},
+    {"type": "dns", "tag": "dns-out"},
+    {"type": "block", "tag": "block-out"}
+  ],
+  "route": {
+    "rules": [
+      {"protocol": "dns", "outbound": "dns-out"},
+      {"geosite": "category-ads-all", "outbound": "block-out"}
+    ],
+    "final": "proxy-out"
+  }
+}
+`
+
+// compatRulesOf returns the rule IDs of issues
+func compatRulesOf(issues []CompatIssue) map[string]int {
+	rules := make(map[string]int)
+	for _, issue := range issues {
+		rules[issue.Rule]++
+	}
+	return rules
+}
+
+// TestCheckConfigCompatibility tests that issues depend on the target version
+func TestCheckConfigCompatibility(t *testing.T) {
+	issues, err := CheckConfigCompatibility([]byte(compatTestConfig), "1.10.7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, issue := range issues {
+		if issue.Level != CompatDeprecated {
+			t.Errorf("1.10.7 still accepts %s", issue)
+		}
+	}
+	if rules := compatRulesOf(issues); rules["special-outbounds"] != 0 || rules["tun-address-fields"] != 1 {
+		t.Errorf("Unexpected issues for 1.10.7: %v", rules)
+	}
+
+	issues, err = CheckConfigCompatibility([]byte(compatTestConfig), "1.13.0-beta.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules := compatRulesOf(issues)
+	want := map[string]int{"geoip-geosite": 1, "tun-address-fields": 1, "inbound-legacy-fields": 2, "special-outbounds": 2, "legacy-dns-servers": 3}
+	for rule, count := range want {
+		if rules[rule] != count {
+			t.Errorf("Expected %d %s issues, got %d (%v)", count, rule, rules[rule], issues)
+		}
+	}
+	for _, issue := range issues {
+		if issue.Rule == "special-outbounds" && issue.Level != CompatRemoved {
+			t.Errorf("Expected block/dns outbounds to be removed in 1.13.0, got %s", issue)
+		}
+		// An inbound without a tag cannot be referenced by a rule action; fakeip needs manual settings
+		if (strings.Contains(issue.Path, "inbounds[1]") || strings.Contains(issue.Path, `"fake"`)) && issue.Fixable {
+			t.Errorf("Expected %s to need a manual fix", issue)
+		}
+	}
+}
+
+// TestFixConfigCompatibility tests the mechanical fixes and that comments and parser markers survive
+func TestFixConfigCompatibility(t *testing.T) {
+	content, fixed, err := FixConfigCompatibility([]byte(compatTestConfig), "1.14.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fixed) != 6 {
+		t.Errorf("Expected 6 fixed issues, got %d: %v", len(fixed), fixed)
+	}
+	text := string(content)
+	for _, kept := range []string{"/** @ParserConfig", "/** @ParserSTART */", "/** @ParserEND */", "// TUN", `"fakeip"`} {
+		if !strings.Contains(text, kept) {
+			t.Errorf("Expected %q to be kept:\n%s", kept, text)
+		}
+	}
+
+	config, err := parseCompatConfig(content)
+	if err != nil {
+		t.Fatalf("Fixed config is invalid: %v\n%s", err, text)
+	}
+	if outbounds := compatArray(config["outbounds"]); len(outbounds) != 3 {
+		t.Errorf("Expected the dns and block outbounds to be removed, got %v", outbounds)
+	}
+	rules := compatArray(compatObject(config["route"])["rules"])
+	actions := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		actions = append(actions, compatString(compatObject(rule), "action"))
+	}
+	if got := strings.Join(actions, ","); got != "resolve,sniff,hijack-dns,reject" {
+		t.Errorf("Unexpected route rule actions: %s", got)
+	}
+	tun := compatObject(compatArray(config["inbounds"])[0])
+	if address := compatArray(tun["address"]); len(address) != 1 || tun["inet4_address"] != nil || tun["sniff"] != nil {
+		t.Errorf("Unexpected TUN inbound after fix: %v", tun)
+	}
+	google := compatObject(compatArray(compatObject(config["dns"])["servers"])[0])
+	if google["type"] != "tls" || google["server"] != "8.8.8.8" || google["detour"] != "proxy-out" {
+		t.Errorf("Unexpected DNS server after fix: %v", google)
+	}
+
+	// Only the issues that need a manual fix remain
+	issues, err := CheckConfigCompatibility(content, "1.14.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, issue := range issues {
+		if issue.Fixable {
+			t.Errorf("Expected %s to be fixed", issue)
+		}
+	}
+	if rules := compatRulesOf(issues); rules["geoip-geosite"] != 1 || rules["inbound-legacy-fields"] != 1 || rules["legacy-dns-servers"] != 1 {
+		t.Errorf("Unexpected remaining issues: %v", issues)
+	}
+}
@@ -11,30 +11,9 @@ import (
 
 // GetSelectorGroupsFromConfig extracts selector group names from config.json
 func GetSelectorGroupsFromConfig(configPath string) ([]string, string, error) {
-	// Internal function to strip comments
-	stripComments := func(data []byte) []byte {
-		commentRegex := regexp.MustCompile(`(?m)\s+//.*$|/\*[\s\S]*?\*/`)
-		var clean = commentRegex.ReplaceAll(data, nil)
-		emptyLineRegex := regexp.MustCompile(`(?m)^\s*\n`)
-		return emptyLineRegex.ReplaceAll(clean, nil)
-	}
-	removeTrailingCommas := func(data []byte) []byte {
-		re := regexp.MustCompile(`,(\s*[\]\}])`)
-		return re.ReplaceAll(data, []byte("$1"))
-	}
-
-	data, err := os.ReadFile(configPath)
+	jsonData, err := readConfigJSON(configPath)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to read config.json: %w", err)
-	}
-
-	// Convert JSONC (with comments/trailing commas) into clean JSON
-	cleanData := jsonc.ToJSON(data)
-	cleanData = removeTrailingCommas(stripComments(cleanData))
-
-	var jsonData map[string]interface{}
-	if err := json.Unmarshal(cleanData, &jsonData); err != nil {
-		return nil, "", fmt.Errorf("failed to parse JSON: %w", err)
+		return nil, "", err
 	}
 
 	// Extract selector groups from outbounds
@@ -101,3 +80,86 @@ func GetSelectorGroupsFromConfig(configPath string) ([]string, string, error) {
 
 	return selectorGroups, defaultSelector, nil
 }
+
+// ProxyGroup is a selector or urltest outbound declared in config.json
+type ProxyGroup struct {
+	Tag  string
+	Type string // "selector" or "urltest"
+}
+
+// GetProxyGroupsFromConfig returns selector and urltest outbounds in config order
+func GetProxyGroupsFromConfig(configPath string) ([]ProxyGroup, error) {
+	jsonData, err := readConfigJSON(configPath)
+	if err != nil {
+		return nil, err
+	}
+	outbounds, _ := jsonData["outbounds"].([]interface{})
+	var groups []ProxyGroup
+	seen := make(map[string]bool)
+	for _, outbound := range outbounds {
+		outboundMap, ok := outbound.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		outboundType, _ := outboundMap["type"].(string)
+		tag, _ := outboundMap["tag"].(string)
+		if (outboundType != "selector" && outboundType != "urltest") || tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		groups = append(groups, ProxyGroup{Tag: tag, Type: outboundType})
+	}
+	return groups, nil
+}
+
+// SelectorInterruptsConnections reports whether the selector group has
+// interrupt_exist_connections enabled (sing-box then closes connections itself on switch).
+func SelectorInterruptsConnections(configPath, group string) (bool, error) {
+	jsonData, err := readConfigJSON(configPath)
+	if err != nil {
+		return false, err
+	}
+	outbounds, _ := jsonData["outbounds"].([]interface{})
+	for _, outbound := range outbounds {
+		outboundMap, ok := outbound.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if tag, _ := outboundMap["tag"].(string); tag != group {
+			continue
+		}
+		interrupt, _ := outboundMap["interrupt_exist_connections"].(bool)
+		return interrupt, nil
+	}
+	return false, fmt.Errorf("outbound '%s' not found in config", group)
+}
+
+// readConfigJSON reads config.json (JSONC with comments and trailing commas) into a generic map
+func readConfigJSON(configPath string) (map[string]interface{}, error) {
+	// Internal function to strip comments
+	stripComments := func(data []byte) []byte {
+		commentRegex := regexp.MustCompile(`(?m)\s+//.*$|/\*[\s\S]*?\*/`)
+		var clean = commentRegex.ReplaceAll(data, nil)
+		emptyLineRegex := regexp.MustCompile(`(?m)^\s*\n`)
+		return emptyLineRegex.ReplaceAll(clean, nil)
+	}
+	removeTrailingCommas := func(data []byte) []byte {
+		re := regexp.MustCompile(`,(\s*[\]\}])`)
+		return re.ReplaceAll(data, []byte("$1"))
+	}
+
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config.json: %w", err)
+	}
+
+	// Convert JSONC (with comments/trailing commas) into clean JSON
+	cleanData := jsonc.ToJSON(data)
+	cleanData = removeTrailingCommas(stripComments(cleanData))
+
+	var jsonData map[string]interface{}
+	if err := json.Unmarshal(cleanData, &jsonData); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON: %w", err)
+	}
+	return jsonData, nil
+}
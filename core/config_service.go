@@ -5,8 +5,6 @@ package core
 import (
 	"fmt"
 	"log"
-
-	"singbox-launcher/internal/dialogs"
 )
 
 // ConfigService encapsulates configuration parsing and update routines.
@@ -31,7 +29,7 @@ func (svc *ConfigService) RunParserProcess() {
 	ac.ParserMutex.Lock()
 	if ac.ParserRunning {
 		ac.ParserMutex.Unlock()
-		dialogs.ShowAutoHideInfo(ac.Application, ac.MainWindow, "Parser Info", "Configuration update is already in progress.")
+		ac.Notifier.ShowAutoHideInfo("Parser Info", "Configuration update is already in progress.")
 		return
 	}
 	ac.ParserRunning = true
@@ -56,6 +54,18 @@ func (svc *ConfigService) RunParserProcess() {
 	} else {
 		log.Println("RunParser: Config updated successfully.")
 		// Progress already updated in UpdateConfigFromSubscriptions with success status
-		dialogs.ShowAutoHideInfo(ac.Application, ac.MainWindow, "Parser", "Config updated successfully!")
+		settings := ac.Settings.Get()
+		if settings.Notifications.ConfigUpdated {
+			ac.Notifier.ShowAutoHideInfo("Parser", "Config updated successfully!")
+		}
+		// sing-box keeps the old config until restarted
+		if settings.AutoApplyOnUpdate && ac.RunningState.IsRunning() {
+			log.Println("RunParser: Restarting sing-box to apply the updated config.")
+			go func() {
+				if err := ac.RestartSingBox(); err != nil {
+					ac.Notifier.ShowError(err)
+				}
+			}()
+		}
 	}
 }
@@ -115,11 +115,9 @@ func IsSubscriptionURL(input string) bool {
 		strings.HasPrefix(trimmed, "https://")
 }
 
-// updateParserProgress safely calls UpdateParserProgressFunc if it's not nil
+// updateParserProgress publishes parser progress
 func updateParserProgress(ac *AppController, progress float64, status string) {
-	if ac.UpdateParserProgressFunc != nil {
-		ac.UpdateParserProgressFunc(progress, status)
-	}
+	ac.Events.Publish(ParserProgress{Progress: progress, Status: status})
 }
 
 // LogDuplicateTagStatistics logs statistics about duplicate tags found in tagCounts.
@@ -135,7 +133,7 @@ func LogDuplicateTagStatistics(tagCounts map[string]int, logPrefix string) {
 	if duplicateCount > 0 {
 		log.Printf("%s: Found %d tags with duplicates, all have been renamed", logPrefix, duplicateCount)
 	} else {
-		log.Printf("%s: No duplicate tags found, all tags are unique", logPrefix, duplicateCount)
+		log.Printf("%s: No duplicate tags found, all tags are unique", logPrefix)
 	}
 }
 
@@ -900,10 +898,16 @@ func (svc *ConfigService) UpdateConfigFromSubscriptions() error {
 	log.Printf("Parser: Successfully updated last_updated timestamp")
 
 	updateParserProgress(ac, 100, "Configuration updated successfully!")
+	ac.Events.Publish(ConfigUpdated{ConfigPath: ac.ConfigPath})
 
 	// Resume auto-update after successful update
 	ac.resumeAutoUpdate()
 
+	// Node tags may have changed - re-apply remembered selector choices
+	if ac.RunningState.IsRunning() {
+		go ac.RestoreSelectorChoices()
+	}
+
 	return nil
 }
 
@@ -1,13 +1,9 @@
 package core
 
 import (
-	"encoding/base64"
-	"os"
 	"path/filepath"
 	"strings"
 	"testing"
-
-	"singbox-launcher/core/parsers"
 )
 
 // TestProcessProxySource_Subscription tests processing subscription URLs
@@ -22,10 +18,6 @@ func TestProcessProxySource_Subscription(t *testing.T) {
 	}
 	svc := NewConfigService(ac)
 
-	// Create a test subscription content (base64 encoded)
-	subscriptionContent := "vless://test-uuid@example.com:443#Test Server\nvmess://test-base64"
-	encodedContent := base64.URLEncoding.EncodeToString([]byte(subscriptionContent))
-
 	// Note: This test would require mocking HTTP requests or using a test HTTP server
 	// For now, we'll test the logic that doesn't require network access
 
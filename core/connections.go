@@ -0,0 +1,112 @@
+package core
+
+import (
+	"log"
+	"sort"
+	"strings"
+
+	"singbox-launcher/api"
+)
+
+// CloseConnectionsAfterSwitch closes existing connections that go through group after a manual switch.
+// It does nothing unless auto-close is enabled, and skips groups with interrupt_exist_connections,
+// because sing-box already closes their connections itself.
+func (ac *AppController) CloseConnectionsAfterSwitch(group string) {
+	if store := ac.CurrentStateStore(); store == nil || !store.AutoCloseConnections() {
+		return
+	}
+	if interrupt, err := SelectorInterruptsConnections(ac.ConfigPath, group); err == nil && interrupt {
+		return
+	}
+
+	client := ac.ClashClient()
+	snapshot, err := client.Connections(ac.ctx)
+	if err != nil {
+		log.Printf("CloseConnectionsAfterSwitch: failed to get connections: %v", err)
+		return
+	}
+	closed := 0
+	for _, conn := range FilterConnectionsByChain(snapshot.Connections, group) {
+		if err := client.CloseConnection(ac.ctx, conn.ID); err != nil {
+			log.Printf("CloseConnectionsAfterSwitch: failed to close connection %s: %v", conn.ID, err)
+			continue
+		}
+		closed++
+	}
+	log.Printf("CloseConnectionsAfterSwitch: closed %d connection(s) of group '%s'", closed, group)
+}
+
+// FilterConnectionsByChain returns connections whose outbound chain contains tag
+func FilterConnectionsByChain(connections []api.Connection, tag string) []api.Connection {
+	var result []api.Connection
+	for _, conn := range connections {
+		if containsString(conn.Chains, tag) {
+			result = append(result, conn)
+		}
+	}
+	return result
+}
+
+// Connection sort keys for SortConnections
+const (
+	ConnectionSortStart    = "Start"
+	ConnectionSortHost     = "Host"
+	ConnectionSortUpload   = "Upload"
+	ConnectionSortDownload = "Download"
+	ConnectionSortChain    = "Chain"
+)
+
+// ConnectionSortKeys lists sort keys in the order shown in the UI
+var ConnectionSortKeys = []string{ConnectionSortStart, ConnectionSortHost, ConnectionSortDownload, ConnectionSortUpload, ConnectionSortChain}
+
+// SortConnections sorts connections in place by key; ties keep newest first
+func SortConnections(connections []api.Connection, key string, descending bool) {
+	less := func(a, b api.Connection) bool {
+		switch key {
+		case ConnectionSortHost:
+			return strings.ToLower(a.Destination()) < strings.ToLower(b.Destination())
+		case ConnectionSortUpload:
+			return a.Upload < b.Upload
+		case ConnectionSortDownload:
+			return a.Download < b.Download
+		case ConnectionSortChain:
+			return strings.Join(a.Chains, "/") < strings.Join(b.Chains, "/")
+		default:
+			return a.Start.Before(b.Start)
+		}
+	}
+	sort.SliceStable(connections, func(i, j int) bool {
+		if descending {
+			return less(connections[j], connections[i])
+		}
+		return less(connections[i], connections[j])
+	})
+}
+
+// FilterConnections returns connections whose host, destination, process, rule or chain contains query (case-insensitive)
+func FilterConnections(connections []api.Connection, query string) []api.Connection {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return connections
+	}
+	var result []api.Connection
+	for _, conn := range connections {
+		fields := []string{
+			conn.Destination(),
+			conn.Metadata.DestinationIP,
+			conn.Metadata.SourceIP,
+			conn.Metadata.ProcessPath,
+			conn.Metadata.Network,
+			conn.Rule,
+			conn.RulePayload,
+			strings.Join(conn.Chains, " "),
+		}
+		for _, field := range fields {
+			if strings.Contains(strings.ToLower(field), query) {
+				result = append(result, conn)
+				break
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,97 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"singbox-launcher/api"
+)
+
+func testConnections() []api.Connection {
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	return []api.Connection{
+		{ID: "1", Metadata: api.ConnectionMetadata{Host: "example.com", DestinationPort: "443", Network: "tcp"}, Upload: 10, Download: 500, Start: base, Chains: []string{"🇩🇪 Germany", "proxy-out"}, Rule: "final"},
+		{ID: "2", Metadata: api.ConnectionMetadata{DestinationIP: "1.1.1.1", DestinationPort: "53", Network: "udp", ProcessPath: "/usr/bin/firefox"}, Upload: 300, Download: 20, Start: base.Add(time.Minute), Chains: []string{"direct-out"}, Rule: "rule_set", RulePayload: "geoip-ru"},
+		{ID: "3", Metadata: api.ConnectionMetadata{Host: "api.github.com", DestinationPort: "443", Network: "tcp"}, Upload: 50, Download: 50, Start: base.Add(2 * time.Minute), Chains: []string{"🇳🇱 Netherlands", "streaming", "proxy-out"}},
+	}
+}
+
+// TestSortConnections tests sorting by the supported keys
+func TestSortConnections(t *testing.T) {
+	ids := func(conns []api.Connection) string {
+		s := ""
+		for _, c := range conns {
+			s += c.ID
+		}
+		return s
+	}
+	tests := []struct {
+		key        string
+		descending bool
+		expected   string
+	}{
+		{ConnectionSortStart, true, "321"},
+		{ConnectionSortStart, false, "123"},
+		{ConnectionSortDownload, true, "132"},
+		{ConnectionSortUpload, true, "231"},
+		{ConnectionSortHost, false, "231"},
+	}
+	for _, tt := range tests {
+		conns := testConnections()
+		SortConnections(conns, tt.key, tt.descending)
+		if got := ids(conns); got != tt.expected {
+			t.Errorf("SortConnections(%s, desc=%v) = %s, expected %s", tt.key, tt.descending, got, tt.expected)
+		}
+	}
+}
+
+// TestFilterConnections tests search across host, process, rule and chain
+func TestFilterConnections(t *testing.T) {
+	conns := testConnections()
+	tests := map[string]int{
+		"":          3,
+		"GITHUB":    1,
+		"firefox":   1,
+		"geoip":     1,
+		"proxy-out": 2,
+		"1.1.1.1":   1,
+		"nothing":   0,
+	}
+	for query, expected := range tests {
+		if got := len(FilterConnections(conns, query)); got != expected {
+			t.Errorf("FilterConnections(%q) returned %d, expected %d", query, got, expected)
+		}
+	}
+	if got := len(FilterConnectionsByChain(conns, "streaming")); got != 1 {
+		t.Errorf("FilterConnectionsByChain(streaming) returned %d, expected 1", got)
+	}
+	if got := conns[1].Destination(); got != "1.1.1.1:53" {
+		t.Errorf("Destination() = %q", got)
+	}
+}
+
+// TestSelectorInterruptsConnections tests reading interrupt_exist_connections from config
+func TestSelectorInterruptsConnections(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	config := `{
+  // comment
+  "outbounds": [
+    {"type": "selector", "tag": "proxy-out", "outbounds": ["a"], "interrupt_exist_connections": true},
+    {"type": "selector", "tag": "streaming", "outbounds": ["a"],},
+  ]
+}`
+	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if interrupt, err := SelectorInterruptsConnections(configPath, "proxy-out"); err != nil || !interrupt {
+		t.Errorf("Expected proxy-out to interrupt connections, got %v, %v", interrupt, err)
+	}
+	if interrupt, err := SelectorInterruptsConnections(configPath, "streaming"); err != nil || interrupt {
+		t.Errorf("Expected streaming not to interrupt connections, got %v, %v", interrupt, err)
+	}
+	if _, err := SelectorInterruptsConnections(configPath, "missing"); err == nil {
+		t.Error("Expected error for missing group")
+	}
+}
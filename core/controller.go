@@ -14,16 +14,8 @@ import (
 	"sync"
 	"time"
 
-	"fyne.io/fyne/v2"
-	"fyne.io/fyne/v2/app"
-	"fyne.io/fyne/v2/container"
-	"fyne.io/fyne/v2/dialog"
-	"fyne.io/fyne/v2/driver/desktop"
-	"fyne.io/fyne/v2/widget"
-
 	"singbox-launcher/api"
 	"singbox-launcher/internal/constants"
-	"singbox-launcher/internal/dialogs"
 	"singbox-launcher/internal/platform"
 
 	ps "github.com/mitchellh/go-ps"
@@ -55,24 +47,10 @@ const (
 // - ConfigService: configuration parsing and updates
 // The controller maintains application-wide state and provides callbacks for UI updates.
 type AppController struct {
-	// --- Fyne Components ---
-	Application    fyne.App
-	MainWindow     fyne.Window
-	TrayIcon       fyne.Resource
-	ApiStatusLabel *widget.Label
-
-	// --- UI State Fields ---
-	ProxiesListWidget *widget.List
-	ActiveProxyName   string
-	SelectedIndex     int
-	ProxiesList       []api.ProxyInfo
-	ListStatusLabel   *widget.Label
-
-	// --- Icon Resources ---
-	AppIconData   fyne.Resource
-	GreenIconData fyne.Resource
-	GreyIconData  fyne.Resource
-	RedIconData   fyne.Resource // Icon for error state
+	// --- Clash API selection state (widgets live in the ui package) ---
+	ActiveProxyName string
+	SelectedIndex   int
+	ProxiesList     []api.ProxyInfo
 
 	// --- Process State ---
 	SingboxCmd               *exec.Cmd
@@ -81,14 +59,27 @@ type AppController struct {
 	ParserRunning            bool
 	StoppedByUser            bool
 	ConsecutiveCrashAttempts int
+	CrashRestartLimit        int          // Restart limit of the policy for LastCrashReason
+	LastCrashReason          CrashReason  // Classified reason of the last unexpected exit (empty if none)
+	LastCrashDetail          string       // Log line that led to LastCrashReason
 	APIStateMutex            sync.RWMutex // Mutex for API-related fields (ProxiesList, ActiveProxyName, SelectedIndex)
 
 	// --- File Paths ---
 	ExecDir     string
-	ConfigPath  string
+	ConfigPath  string // config.json of the active profile
 	SingboxPath string
 	WintunPath  string
 
+	// Notifier receives messages, progress and state changes (ui.Notifier in the GUI, console output in headless mode)
+	Notifier Notifier
+	// Events carries state changes to the dashboard, the Servers tab and the tray (see events.go)
+	Events *EventBus
+
+	// --- Configuration profiles ---
+	Profiles      *ProfileManager
+	ActiveProfile string     // Name of the profile ConfigPath belongs to
+	ProfileMutex  sync.Mutex // Protects ActiveProfile and ConfigPath during a switch
+
 	// --- VPN Operation State ---
 	RunningState *RunningState
 
@@ -97,6 +88,13 @@ type AppController struct {
 	ProcessService *ProcessService
 	// ConfigService handles configuration parsing, subscription fetching, and JSON generation
 	ConfigService *ConfigService
+	// StateStore persists launcher state (remembered selector choices) across restarts.
+	// Replaced when the profile changes: guarded by APIStateMutex, use CurrentStateStore
+	StateStore *StateStore
+	// Settings holds launcher-wide preferences shared by all profiles (bin/settings.json)
+	Settings *SettingsStore
+	// TrafficMonitor streams speed and memory usage from the Clash API while sing-box is running
+	TrafficMonitor *TrafficMonitor
 
 	// --- Logging ---
 	MainLogFile  *os.File
@@ -107,14 +105,19 @@ type AppController struct {
 	ClashAPIBaseURL    string
 	ClashAPIToken      string
 	ClashAPIEnabled    bool
-	SelectedClashGroup string
+	SelectedClashGroup string     // Guarded by APIStateMutex, use Get/SetSelectedClashGroup
+	ClashMode          string     // Current Clash mode from GET /configs (empty when unknown)
+	ClashModes         []string   // Modes offered by the running config
 	AutoLoadInProgress bool       // Flag to prevent multiple auto-load attempts
 	AutoLoadMutex      sync.Mutex // Mutex for AutoLoadInProgress
 
-	// --- Tray menu update protection ---
-	TrayMenuUpdateInProgress bool        // Flag to prevent multiple simultaneous menu updates
-	TrayMenuUpdateMutex      sync.Mutex  // Mutex for TrayMenuUpdateInProgress
-	TrayMenuUpdateTimer      *time.Timer // Timer for debouncing menu updates
+	// Groups shown in the tray menu (guarded by APIStateMutex, see tray_groups.go)
+	trayGroups           []TrayGroup
+	trayGroupsUpdated    time.Time
+	trayGroupsRefreshing bool
+
+	LatencyTestInProgress bool       // Flag to prevent concurrent "Test all" runs
+	LatencyTestMutex      sync.Mutex // Mutex for LatencyTestInProgress
 
 	// --- Version check caching ---
 	VersionCheckCache      string       // Cached latest version
@@ -128,22 +131,16 @@ type AppController struct {
 	LauncherVersionCheckMutex      sync.RWMutex // Mutex for launcher version check cache
 	LauncherVersionCheckInProgress bool         // Flag to prevent multiple launcher version checks
 
+	// --- GitHub API ETag cache and rate limits (see githubGet) ---
+	gitHub githubAPIState
+
+	// --- System proxy (see BindSystemProxy) ---
+	systemProxyMutex sync.Mutex // Serializes setting and restoring the system proxy
+
 	// --- Context for goroutine cancellation ---
 	ctx        context.Context    // Context for cancellation
 	cancelFunc context.CancelFunc // Cancel function for stopping goroutines
 
-	// --- Callbacks for UI logic ---
-	RefreshAPIFunc         func()
-	ResetAPIStateFunc      func()
-	UpdateCoreStatusFunc   func() // Callback to update status in Core Dashboard
-	UpdateConfigStatusFunc func() // Callback to update config status in Core Dashboard
-	UpdateTrayMenuFunc     func() // Callback to update tray menu
-
-	// --- Parser progress UI ---
-	ParserProgressBar        *widget.ProgressBar
-	ParserStatusLabel        *widget.Label
-	UpdateParserProgressFunc func(progress float64, status string) // Callback to update parser progress
-
 	// --- Auto-update configuration ---
 	AutoUpdateEnabled        bool       // Flag to enable/disable auto-updates (false after 10 failed attempts)
 	AutoUpdateFailedAttempts int        // Counter for consecutive failed attempts (reset on success)
@@ -157,22 +154,62 @@ type RunningState struct {
 	controller *AppController
 }
 
-// NewAppController creates and initializes a new AppController instance.
-func NewAppController(appIconData, greyIconData, greenIconData, redIconData []byte) (*AppController, error) {
-	ac := &AppController{}
+// NewAppController creates the controller of the GUI: the traffic monitor, the system proxy and the auto-update loop.
+// The Fyne application, window and tray belong to the ui package (see ui.NewDesktop).
+func NewAppController() (*AppController, error) {
+	ac, err := newController("NewAppController")
+	if err != nil {
+		return nil, err
+	}
+	// The ui package installs its Notifier once the main window exists (see ui.NewNotifier)
+
+	log.Println("Application initializing...")
+	ac.TrafficMonitor = NewTrafficMonitor(ac)
+	ac.BindSystemProxy()
+
+	ac.StartAutoUpdate()
+	return ac, nil
+}
+
+// NewHeadlessController creates a controller for CLI commands: no traffic monitor, system proxy or tray.
+// Messages go to notifier; the auto-update loop is not started (see StartAutoUpdate).
+func NewHeadlessController(notifier Notifier) (*AppController, error) {
+	ac, err := newController("NewHeadlessController")
+	if err != nil {
+		return nil, err
+	}
+	ac.Notifier = notifier
+	return ac, nil
+}
+
+// newController sets up everything shared by the GUI and headless modes:
+// paths, the active profile, log files, services and the Clash API config.
+func newController(caller string) (*AppController, error) {
+	ac := &AppController{Notifier: NopNotifier{}, Events: NewEventBus()}
+	ac.bridgeNotifier()
 
 	ex, err := os.Executable()
 	if err != nil {
-		return nil, fmt.Errorf("NewAppController: cannot determine executable path: %w", err)
+		return nil, fmt.Errorf("%s: cannot determine executable path: %w", caller, err)
 	}
 	ac.ExecDir = filepath.Dir(ex)
 
 	// Use platform-specific functions
 	if err := platform.EnsureDirectories(ac.ExecDir); err != nil {
-		return nil, fmt.Errorf("NewAppController: cannot create directories: %w", err)
+		return nil, fmt.Errorf("%s: cannot create directories: %w", caller, err)
 	}
 
-	ac.ConfigPath = platform.GetConfigPath(ac.ExecDir)
+	ac.Settings = NewSettingsStore(GetSettingsPath(platform.GetBinDir(ac.ExecDir)))
+
+	// Use the config of the profile that was active on the last launch
+	ac.Profiles = NewProfileManager(platform.GetBinDir(ac.ExecDir), ac.Settings)
+	ac.ActiveProfile = ac.Profiles.Active()
+	if profile, err := ac.Profiles.Profile(ac.ActiveProfile); err == nil {
+		ac.ConfigPath = profile.ConfigPath
+	} else {
+		ac.ActiveProfile = DefaultProfileName
+		ac.ConfigPath = platform.GetConfigPath(ac.ExecDir)
+	}
 	singboxName := platform.GetExecutableNames()
 	ac.SingboxPath = filepath.Join(ac.ExecDir, "bin", singboxName)
 	ac.WintunPath = platform.GetWintunPath(ac.ExecDir)
@@ -180,14 +217,14 @@ func NewAppController(appIconData, greyIconData, greenIconData, redIconData []by
 	// Open log files with rotation support
 	logFile, err := openLogFileWithRotation(filepath.Join(ac.ExecDir, logFileName))
 	if err != nil {
-		return nil, fmt.Errorf("NewAppController: cannot open main log file: %w", err)
+		return nil, fmt.Errorf("%s: cannot open main log file: %w", caller, err)
 	}
 	log.SetOutput(logFile)
 	ac.MainLogFile = logFile
 
 	childLogFile, err := openLogFileWithRotation(filepath.Join(ac.ExecDir, childLogFileName))
 	if err != nil {
-		log.Printf("NewAppController: failed to open sing-box child log file: %v", err)
+		log.Printf("%s: failed to open sing-box child log file: %v", caller, err)
 		ac.ChildLogFile = nil
 	} else {
 		ac.ChildLogFile = childLogFile
@@ -195,63 +232,27 @@ func NewAppController(appIconData, greyIconData, greenIconData, redIconData []by
 
 	apiLogFile, err := openLogFileWithRotation(filepath.Join(ac.ExecDir, apiLogFileName))
 	if err != nil {
-		log.Printf("NewAppController: failed to open API log file: %v", err)
+		log.Printf("%s: failed to open API log file: %v", caller, err)
 		ac.ApiLogFile = nil
 	} else {
 		ac.ApiLogFile = apiLogFile
 	}
 
-	ac.AppIconData = fyne.NewStaticResource("appIcon", appIconData)
-	ac.GreyIconData = fyne.NewStaticResource("trayIcon", greyIconData)
-	ac.GreenIconData = fyne.NewStaticResource("runningIcon", greenIconData)
-	ac.RedIconData = fyne.NewStaticResource("errorIcon", redIconData)
-
-	log.Println("Application initializing...")
-	ac.Application = app.NewWithID("com.singbox.launcher")
-	ac.Application.SetIcon(ac.AppIconData)
 	ac.RunningState = &RunningState{controller: ac}
 	ac.RunningState.Set(false) // Use Set() method instead of direct assignment
 	ac.ConsecutiveCrashAttempts = 0
 	ac.ProcessService = NewProcessService(ac)
 	ac.ConfigService = NewConfigService(ac)
+	ac.StateStore = NewStateStore(GetStatePath(ac.ConfigPath))
 
-	if base, tok, err := api.LoadClashAPIConfig(ac.ConfigPath); err != nil {
-		log.Printf("NewAppController: Clash API config error: %v", err)
-		ac.ClashAPIBaseURL = ""
-		ac.ClashAPIToken = ""
-		ac.ClashAPIEnabled = false
-	} else {
-		ac.ClashAPIBaseURL = base
-		ac.ClashAPIToken = tok
-		ac.ClashAPIEnabled = true
-	}
-
-	// Initialize SelectedClashGroup from config (needed for auto-loading proxies)
-	if ac.ClashAPIEnabled {
-		_, defaultSelector, err := GetSelectorGroupsFromConfig(ac.ConfigPath)
-		if err != nil {
-			log.Printf("NewAppController: Failed to get selector groups: %v", err)
-			ac.SelectedClashGroup = "proxy-out" // Default fallback
-		} else {
-			ac.SelectedClashGroup = defaultSelector
-			log.Printf("NewAppController: Initialized SelectedClashGroup: %s", defaultSelector)
-		}
-	}
+	// Initialize API config and SelectedClashGroup from config (needed for auto-loading proxies)
+	ac.loadClashAPIConfig(caller)
 
 	// Initialize API state fields (safe during initialization, but using methods for consistency)
 	ac.SetProxiesList([]api.ProxyInfo{})
 	ac.SetSelectedIndex(-1)
 	ac.SetActiveProxyName("")
 
-	ac.RefreshAPIFunc = func() { log.Println("RefreshAPIFunc handler is not set yet.") }
-	ac.ResetAPIStateFunc = func() { log.Println("ResetAPIStateFunc handler is not set yet.") }
-	ac.UpdateCoreStatusFunc = func() { log.Println("UpdateCoreStatusFunc handler is not set yet.") }
-	ac.UpdateConfigStatusFunc = func() { log.Println("UpdateConfigStatusFunc handler is not set yet.") }
-	ac.UpdateTrayMenuFunc = func() { log.Println("UpdateTrayMenuFunc handler is not set yet.") }
-	ac.UpdateParserProgressFunc = func(progress float64, status string) {
-		log.Printf("UpdateParserProgressFunc handler is not set yet. Progress: %.0f%%, Status: %s", progress, status)
-	}
-
 	// Initialize context for goroutine cancellation
 	ac.ctx, ac.cancelFunc = context.WithCancel(context.Background())
 
@@ -264,59 +265,88 @@ func NewAppController(appIconData, greyIconData, greenIconData, redIconData []by
 		log.Printf("Auto-update: Config file does not exist (%s), auto-update disabled", ac.ConfigPath)
 		ac.AutoUpdateEnabled = false
 	}
-	go ac.startAutoUpdateLoop()
 	return ac, nil
 }
 
-// UpdateUI updates all UI elements based on the current application state.
-func (ac *AppController) UpdateUI() {
-	fyne.Do(func() {
-		// Update tray icon (this is a system function, not a UI widget)
-		if desk, ok := ac.Application.(desktop.App); ok {
-			// Check that icons are initialized
-			if ac.GreenIconData == nil || ac.GreyIconData == nil || ac.RedIconData == nil {
-				log.Printf("UpdateUI: Icons not initialized, skipping icon update")
-				return
-			}
+// StartAutoUpdate starts the background loop that updates the config from subscriptions on schedule
+func (ac *AppController) StartAutoUpdate() {
+	go ac.startAutoUpdateLoop()
+}
 
-			var iconToSet fyne.Resource
+// loadClashAPIConfig reads the Clash API address and secret and the default selector group from ConfigPath.
+// The API is disabled when the config has no usable clash_api section.
+func (ac *AppController) loadClashAPIConfig(caller string) {
+	configPath := ac.CurrentConfigPath()
+	base, tok, err := api.LoadClashAPIConfig(configPath)
+	if err != nil {
+		log.Printf("%s: Clash API config error: %v", caller, err)
+		base, tok = "", ""
+	}
+	ac.APIStateMutex.Lock()
+	ac.ClashAPIBaseURL = base
+	ac.ClashAPIToken = tok
+	ac.ClashAPIEnabled = err == nil
+	ac.APIStateMutex.Unlock()
 
-			if ac.RunningState.IsRunning() {
-				// Green icon - if running
-				iconToSet = ac.GreenIconData
-			} else {
-				// Check for binary to determine error state (simple file check)
-				if _, err := os.Stat(ac.SingboxPath); os.IsNotExist(err) {
-					// Red icon - on error (binary not found)
-					iconToSet = ac.RedIconData
-				} else {
-					// Grey icon - on normal stop
-					iconToSet = ac.GreyIconData
-				}
-			}
+	if err != nil {
+		ac.SetSelectedClashGroup("")
+		return
+	}
+	selectors, defaultSelector, err := GetSelectorGroupsFromConfig(configPath)
+	if err != nil {
+		log.Printf("%s: Failed to get selector groups: %v", caller, err)
+		ac.SetSelectedClashGroup("proxy-out") // Default fallback
+		return
+	}
+	// The group chosen on the Servers tab last time, if the config still has it
+	if remembered := ac.Settings.SelectedGroup(ac.CurrentProfile()); containsString(selectors, remembered) {
+		defaultSelector = remembered
+	}
+	ac.SetSelectedClashGroup(defaultSelector)
+	log.Printf("%s: SelectedClashGroup: %s", caller, defaultSelector)
+}
 
-			desk.SetSystemTrayIcon(iconToSet)
-		}
+// SelectClashGroup makes group the selector group of the Servers tab and remembers it for the current profile
+func (ac *AppController) SelectClashGroup(group string) {
+	ac.SetSelectedClashGroup(group)
+	if err := ac.Settings.SetSelectedGroup(ac.CurrentProfile(), group); err != nil {
+		log.Printf("SelectClashGroup: failed to save settings: %v", err)
+	}
+}
 
-		// Если состояние Down, сбрасываем API состояние
-		if !ac.RunningState.IsRunning() && ac.ResetAPIStateFunc != nil {
-			log.Println("UpdateUI: Triggering API state reset because state is 'Down'.")
-			ac.ResetAPIStateFunc()
-		}
+// TrayIconState is the state shown by the tray icon
+type TrayIconState int
 
-		// Update tray menu when state changes (same as Core Dashboard)
-		if ac.UpdateTrayMenuFunc != nil {
-			ac.UpdateTrayMenuFunc()
-		}
+const (
+	TrayIconStopped TrayIconState = iota // sing-box is stopped
+	TrayIconRunning                      // sing-box is running
+	TrayIconError                        // The sing-box binary is missing
+)
 
-		// Update Core Dashboard status when state changes (synchronize with tray)
-		if ac.UpdateCoreStatusFunc != nil {
-			ac.UpdateCoreStatusFunc()
-		}
-	})
+// TrayIconState returns the state for the tray icon: running, missing sing-box binary or stopped.
+func (ac *AppController) TrayIconState() TrayIconState {
+	if ac.RunningState.IsRunning() {
+		return TrayIconRunning
+	}
+	// Check for binary to determine error state (simple file check)
+	if _, err := os.Stat(ac.SingboxPath); os.IsNotExist(err) {
+		return TrayIconError
+	}
+	return TrayIconStopped
 }
 
-// GracefulExit performs a graceful shutdown of the application.
+// resetAPIState forgets everything loaded from the Clash API of the previous sing-box run
+func (ac *AppController) resetAPIState() {
+	log.Println("resetAPIState: Resetting API state cache.")
+	ac.SetProxiesList([]api.ProxyInfo{})
+	ac.SetActiveProxyName("")
+	ac.SetSelectedIndex(-1)
+	ac.ResetClashModeState()
+	ac.ResetTrayGroups()
+}
+
+// GracefulExit stops sing-box, restores the system proxy and closes the log files.
+// The GUI quits the Fyne application afterwards (see ui.Desktop.Quit).
 func (ac *AppController) GracefulExit() {
 	// Cancel context to signal all goroutines to stop
 	if ac.cancelFunc != nil {
@@ -324,14 +354,6 @@ func (ac *AppController) GracefulExit() {
 		log.Println("GracefulExit: Context cancelled, signalling goroutines to stop")
 	}
 
-	// Stop any pending menu update timer
-	ac.TrayMenuUpdateMutex.Lock()
-	if ac.TrayMenuUpdateTimer != nil {
-		ac.TrayMenuUpdateTimer.Stop()
-		ac.TrayMenuUpdateTimer = nil
-	}
-	ac.TrayMenuUpdateMutex.Unlock()
-
 	StopSingBoxProcess(ac)
 
 	log.Println("GracefulExit: Waiting for sing-box to stop...")
@@ -357,6 +379,11 @@ func (ac *AppController) GracefulExit() {
 	}
 end_loop:
 
+	// Восстанавливаем системный прокси синхронно: обработчик CoreStopped может не успеть до выхода
+	if err := ac.RestoreSystemProxy(); err != nil {
+		log.Printf("GracefulExit: %v", err)
+	}
+
 	if ac.MainLogFile != nil {
 		ac.MainLogFile.Close()
 	}
@@ -366,8 +393,6 @@ end_loop:
 	if ac.ApiLogFile != nil {
 		ac.ApiLogFile.Close()
 	}
-
-	ac.Application.Quit()
 }
 
 // RunHidden launches an external command in a hidden window.
@@ -381,7 +406,7 @@ func (ac *AppController) RunHidden(name string, args []string, logPath string, d
 	if logPath != "" {
 		if logPath == filepath.Join(ac.ExecDir, childLogFileName) && ac.ChildLogFile != nil {
 			// For sing-box logs, check and rotate if needed before writing
-			checkAndRotateLogFile(logPath)
+			ac.rotateChildLogFile()
 			logFile := ac.ChildLogFile
 			// Don't truncate - append to preserve logs, rotation handles size limits
 			cmd.Stdout = logFile
@@ -403,10 +428,15 @@ func (ac *AppController) RunHidden(name string, args []string, logPath string, d
 
 // CheckLinuxCapabilities checks Linux capabilities and shows a suggestion if needed
 func CheckLinuxCapabilities(ac *AppController) {
+	if err := ac.EnsureCoreCapabilities(); err != nil {
+		log.Printf("CheckLinuxCapabilities: %v", err)
+		ac.Notifier.ShowError(err)
+		return
+	}
 	if suggestion := platform.CheckAndSuggestCapabilities(ac.SingboxPath); suggestion != "" {
 		log.Printf("CheckLinuxCapabilities: %s", suggestion)
 		// Show info dialog (not error) - capabilities can be set later
-		dialogs.ShowInfo(ac.MainWindow, "Linux Capabilities", suggestion)
+		ac.Notifier.ShowInfo("Linux Capabilities", suggestion)
 	}
 }
 
@@ -420,13 +450,22 @@ func (r *RunningState) Set(value bool) {
 	r.running = value
 	r.Unlock()
 
-	r.controller.UpdateUI()
-
-	// Call callback to update status in Core Dashboard
-	if r.controller.UpdateCoreStatusFunc != nil {
-		r.controller.UpdateCoreStatusFunc()
+	// Live traffic streams follow the running state
+	if r.controller.TrafficMonitor != nil {
+		if value {
+			r.controller.TrafficMonitor.Start()
+		} else {
+			r.controller.TrafficMonitor.Stop()
+		}
 	}
 
+	if value {
+		r.controller.Events.Publish(CoreStarted{})
+	} else {
+		// Proxies, modes and tray groups of the stopped instance are stale
+		r.controller.resetAPIState()
+		r.controller.Events.Publish(CoreStopped{})
+	}
 }
 
 // IsRunning checks if the VPN is running.
@@ -453,6 +492,23 @@ func (ac *AppController) GetProxiesList() []api.ProxyInfo {
 	return result
 }
 
+// ClashClient returns a Clash API client for the current API address and secret.
+// The client is cheap to create, so callers get a fresh one after the config is reloaded.
+func (ac *AppController) ClashClient() *api.Client {
+	ac.APIStateMutex.RLock()
+	baseURL := ac.ClashAPIBaseURL
+	token := ac.ClashAPIToken
+	ac.APIStateMutex.RUnlock()
+	return api.NewClient(baseURL, token, api.WithLogFile(ac.ApiLogFile))
+}
+
+// CurrentStateStore returns the state store of the active profile (nil if it is not available)
+func (ac *AppController) CurrentStateStore() *StateStore {
+	ac.APIStateMutex.RLock()
+	defer ac.APIStateMutex.RUnlock()
+	return ac.StateStore
+}
+
 // SetActiveProxyName safely sets the active proxy name with mutex protection.
 func (ac *AppController) SetActiveProxyName(name string) {
 	ac.APIStateMutex.Lock()
@@ -467,6 +523,21 @@ func (ac *AppController) GetActiveProxyName() string {
 	return ac.ActiveProxyName
 }
 
+// SetSelectedClashGroup safely sets the selector group of the Servers tab with mutex protection.
+// Unlike SelectClashGroup it does not remember the group in the settings.
+func (ac *AppController) SetSelectedClashGroup(group string) {
+	ac.APIStateMutex.Lock()
+	defer ac.APIStateMutex.Unlock()
+	ac.SelectedClashGroup = group
+}
+
+// GetSelectedClashGroup safely gets the selector group of the Servers tab with mutex protection.
+func (ac *AppController) GetSelectedClashGroup() string {
+	ac.APIStateMutex.RLock()
+	defer ac.APIStateMutex.RUnlock()
+	return ac.SelectedClashGroup
+}
+
 // SetSelectedIndex safely sets the selected index with mutex protection.
 func (ac *AppController) SetSelectedIndex(index int) {
 	ac.APIStateMutex.Lock()
@@ -602,6 +673,26 @@ func isSingBoxProcessRunningWithPS(ac *AppController, ourPID int) (bool, int) {
 	return false, -1
 }
 
+// FindSingBoxProcess looks for a running sing-box process (started by the launcher or not)
+func (ac *AppController) FindSingBoxProcess() (pid int, found bool) {
+	found, pid = isSingBoxProcessRunning(ac)
+	return pid, found
+}
+
+// RestartPending reports whether sing-box crashed and is waiting for an automatic restart
+func (ac *AppController) RestartPending() bool {
+	ac.CmdMutex.Lock()
+	defer ac.CmdMutex.Unlock()
+	return !ac.RunningState.IsRunning() && ac.ConsecutiveCrashAttempts > 0
+}
+
+// CrashInfo returns the classified reason of the last unexpected exit of sing-box (empty if none)
+func (ac *AppController) CrashInfo() CrashInfo {
+	ac.CmdMutex.Lock()
+	defer ac.CmdMutex.Unlock()
+	return CrashInfo{Reason: ac.LastCrashReason, Line: ac.LastCrashDetail}
+}
+
 // checkAndShowSingBoxRunningWarning checks if sing-box is running and shows warning dialog if found.
 // Returns true if process was found and warning was shown, false otherwise.
 func checkAndShowSingBoxRunningWarning(ac *AppController, context string) bool {
@@ -746,6 +837,17 @@ func StopSingBoxProcess(ac *AppController) {
 	ac.ProcessService.Stop()
 }
 
+// RestartSingBox stops sing-box and starts it again with the current config.json.
+// Blocks until sing-box has stopped, so call it from a background goroutine.
+func (ac *AppController) RestartSingBox() error {
+	StopSingBoxProcess(ac)
+	if !ac.waitForStop(profileStopTimeout) {
+		return fmt.Errorf("RestartSingBox: sing-box did not stop within %v", profileStopTimeout)
+	}
+	StartSingBoxProcess(ac)
+	return nil
+}
+
 // RunParserProcess starts the internal configuration update process.
 // Note: ConfigService must be initialized in NewAppController. This is a wrapper for backward compatibility.
 func RunParserProcess(ac *AppController) {
@@ -781,7 +883,7 @@ func CheckConfigFileExists(ac *AppController) {
 			constants.ConfigFileName,
 		)
 
-		dialogs.ShowInfo(ac.MainWindow, "Configuration Not Found", message)
+		ac.Notifier.ShowInfo("Configuration Not Found", message)
 	}
 }
 
@@ -805,7 +907,7 @@ func CheckIfLauncherAlreadyRunningUtil(ac *AppController) {
 			continue
 		}
 		if strings.EqualFold(p.Executable(), execName) {
-			dialogs.ShowInfo(ac.MainWindow, "Information", "The application is already running. Use the existing instance or close it before starting a new one.")
+			ac.Notifier.ShowInfo("Information", "The application is already running. Use the existing instance or close it before starting a new one.")
 			return
 		}
 	}
@@ -830,7 +932,7 @@ func CheckFilesUtil(ac *AppController) {
 	} else {
 		msg += "\nSome files missing. ❌"
 	}
-	dialogs.ShowInfo(ac.MainWindow, "File Check", msg)
+	ac.Notifier.ShowInfo("File Check", msg)
 }
 
 func FormatBytesUtil(b int64) string {
@@ -846,23 +948,13 @@ func FormatBytesUtil(b int64) string {
 	return fmt.Sprintf("%.1f %cB", float64(b)/float64(div), "KMGTPE"[exp])
 }
 
+// ShowSingBoxAlreadyRunningWarningUtil tells the user about a foreign sing-box process and offers to kill it
 func ShowSingBoxAlreadyRunningWarningUtil(ac *AppController) {
-	label := widget.NewLabel("Sing-Box appears to be already running.\nWould you like to kill the existing process?")
-	killButton := widget.NewButton("Kill Process", nil)
-	closeButton := widget.NewButton("Close This Warning", nil)
-	content := container.NewVBox(label, killButton, closeButton)
-	var d dialog.Dialog
-	d = dialog.NewCustomWithoutButtons("Warning", content, ac.MainWindow)
-	killButton.OnTapped = func() {
-		go func() {
-			processName := platform.GetProcessNameForCheck()
-			_ = platform.KillProcess(processName)
-			ac.RunningState.Set(false)
-		}()
-		fyne.Do(func() { d.Hide() })
-	}
-	closeButton.OnTapped = func() { fyne.Do(func() { d.Hide() }) }
-	fyne.Do(func() { d.Show() })
+	ac.Notifier.ShowCoreAlreadyRunning(func() {
+		processName := platform.GetProcessNameForCheck()
+		_ = platform.KillProcess(processName)
+		ac.RunningState.Set(false)
+	})
 }
 
 // AutoLoadProxies attempts to load proxies with retry intervals (1, 3, 7, 13, 17 seconds)
@@ -938,8 +1030,6 @@ func (ac *AppController) AutoLoadProxies() {
 			// Get current group (it might have changed)
 			ac.APIStateMutex.RLock()
 			currentGroup := ac.SelectedClashGroup
-			baseURL := ac.ClashAPIBaseURL
-			token := ac.ClashAPIToken
 			ac.APIStateMutex.RUnlock()
 
 			if currentGroup == "" {
@@ -947,36 +1037,14 @@ func (ac *AppController) AutoLoadProxies() {
 				return
 			}
 
-			// Try to load proxies
-			proxies, now, err := api.GetProxiesInGroup(baseURL, token, currentGroup, ac.ApiLogFile)
+			// Try to load proxies (subscribers of ProxiesLoaded update the Servers tab and the tray)
+			proxies, _, err := ac.LoadGroupProxies(ac.ctx, currentGroup)
 			if err != nil {
 				log.Printf("AutoLoadProxies: Attempt %d failed: %v", attempt+1, err)
 				// Continue to next attempt
 				continue
 			}
 
-			// Success - update proxies list
-			fyne.Do(func() {
-				ac.SetProxiesList(proxies)
-				ac.SetActiveProxyName(now)
-
-				// Update UI if callbacks are set
-				if ac.ProxiesListWidget != nil {
-					ac.ProxiesListWidget.Refresh()
-				}
-				if ac.ListStatusLabel != nil {
-					ac.ListStatusLabel.SetText(fmt.Sprintf("Proxies loaded for '%s'. Active: %s", currentGroup, now))
-				}
-				if ac.RefreshAPIFunc != nil {
-					ac.RefreshAPIFunc()
-				}
-
-				// Update tray menu AFTER UI updates (important: this must be last)
-				if ac.UpdateTrayMenuFunc != nil {
-					ac.UpdateTrayMenuFunc()
-				}
-			})
-
 			log.Printf("AutoLoadProxies: Successfully loaded %d proxies for group '%s' on attempt %d", len(proxies), currentGroup, attempt+1)
 
 			ac.AutoLoadMutex.Lock()
@@ -1059,125 +1127,6 @@ func (ac *AppController) GetVPNButtonState() VPNButtonState {
 	return state
 }
 
-// CreateTrayMenu creates the system tray menu with proxy selection submenu
-func (ac *AppController) CreateTrayMenu() *fyne.Menu {
-	// Get proxies from current group
-	ac.APIStateMutex.RLock()
-	proxies := ac.ProxiesList
-	activeProxy := ac.ActiveProxyName
-	selectedGroup := ac.SelectedClashGroup
-	clashAPIEnabled := ac.ClashAPIEnabled
-	ac.APIStateMutex.RUnlock()
-
-	// Auto-load proxies if list is empty and API is enabled
-	// Note: AutoLoadProxies has internal guard to prevent multiple simultaneous loads
-	if clashAPIEnabled && selectedGroup != "" && len(proxies) == 0 {
-		// Only auto-load if sing-box is running
-		if ac.RunningState.IsRunning() {
-			// Check if auto-load is already in progress to avoid duplicate calls
-			ac.AutoLoadMutex.Lock()
-			alreadyInProgress := ac.AutoLoadInProgress
-			ac.AutoLoadMutex.Unlock()
-
-			if !alreadyInProgress {
-				// Start auto-loading in background (non-blocking)
-				go ac.AutoLoadProxies()
-			}
-		}
-	}
-
-	// Create proxy submenu items
-	var proxyMenuItems []*fyne.MenuItem
-	if clashAPIEnabled && selectedGroup != "" && len(proxies) > 0 {
-		for i := range proxies {
-			proxy := proxies[i]
-			proxyName := proxy.Name
-			isActive := proxyName == activeProxy
-
-			// Create local copy for closure
-			pName := proxyName
-			menuItem := fyne.NewMenuItem(proxyName, func() {
-				// Switch to selected proxy
-				go func() {
-					err := api.SwitchProxy(ac.ClashAPIBaseURL, ac.ClashAPIToken, selectedGroup, pName, ac.ApiLogFile)
-					fyne.Do(func() {
-						if err != nil {
-							log.Printf("CreateTrayMenu: Failed to switch proxy: %v", err)
-							dialogs.ShowError(ac.MainWindow, fmt.Errorf("failed to switch proxy: %w", err))
-						} else {
-							ac.SetActiveProxyName(pName)
-							// Update tray menu after switch
-							if ac.UpdateTrayMenuFunc != nil {
-								ac.UpdateTrayMenuFunc()
-							}
-							// Refresh UI if callback is set
-							if ac.RefreshAPIFunc != nil {
-								ac.RefreshAPIFunc()
-							}
-						}
-					})
-				}()
-			})
-
-			// Mark active proxy with checkmark
-			if isActive {
-				menuItem.Label = "✓ " + proxyName
-			}
-
-			proxyMenuItems = append(proxyMenuItems, menuItem)
-		}
-	} else {
-		// Show disabled item if no proxies available
-		disabledItem := fyne.NewMenuItem("No proxies available", nil)
-		disabledItem.Disabled = true
-		proxyMenuItems = append(proxyMenuItems, disabledItem)
-	}
-
-	// Create proxy submenu
-	proxySubmenu := fyne.NewMenu("Select Proxy", proxyMenuItems...)
-
-	// Get button state from centralized function
-	buttonState := ac.GetVPNButtonState()
-
-	// Create main menu items
-	menuItems := []*fyne.MenuItem{
-		fyne.NewMenuItem("Open", func() { ac.MainWindow.Show() }),
-		fyne.NewMenuItemSeparator(),
-	}
-
-	// Add Start/Stop VPN buttons based on centralized state
-	if buttonState.StartEnabled {
-		menuItems = append(menuItems, fyne.NewMenuItem("Start VPN", func() { StartSingBoxProcess(ac) }))
-	} else {
-		startItem := fyne.NewMenuItem("Start VPN", nil)
-		startItem.Disabled = true
-		menuItems = append(menuItems, startItem)
-	}
-
-	if buttonState.StopEnabled {
-		menuItems = append(menuItems, fyne.NewMenuItem("Stop VPN", func() { StopSingBoxProcess(ac) }))
-	} else {
-		stopItem := fyne.NewMenuItem("Stop VPN", nil)
-		stopItem.Disabled = true
-		menuItems = append(menuItems, stopItem)
-	}
-
-	menuItems = append(menuItems, fyne.NewMenuItemSeparator())
-
-	// Add proxy submenu if Clash API is enabled
-	if clashAPIEnabled && selectedGroup != "" {
-		selectProxyItem := fyne.NewMenuItem("Select Proxy", nil)
-		selectProxyItem.ChildMenu = proxySubmenu
-		menuItems = append(menuItems, selectProxyItem)
-		menuItems = append(menuItems, fyne.NewMenuItemSeparator())
-	}
-
-	// Add Quit item
-	menuItems = append(menuItems, fyne.NewMenuItem("Quit", ac.GracefulExit))
-
-	return fyne.NewMenu("Singbox Launcher", menuItems...)
-}
-
 // startAutoUpdateLoop runs a background goroutine that periodically checks and updates configuration
 // Uses dynamic interval: max(10 minutes, parser.reload from config)
 // Handles errors with retries (10 attempts, 10 seconds between retries)
@@ -1250,9 +1199,7 @@ func (ac *AppController) startAutoUpdateLoop() {
 						ac.AutoUpdateEnabled = false
 						ac.AutoUpdateMutex.Unlock()
 						log.Printf("Auto-update: Stopped after %d consecutive failed attempts", ac.AutoUpdateFailedAttempts)
-						fyne.Do(func() {
-							dialogs.ShowAutoHideInfo(ac.Application, ac.MainWindow, "Auto-update", "Automatic configuration update stopped after 10 failed attempts. Use manual update.")
-						})
+						ac.Notifier.ShowAutoHideInfo("Auto-update", "Automatic configuration update stopped after 10 failed attempts. Use manual update.")
 					} else {
 						ac.AutoUpdateMutex.Unlock()
 					}
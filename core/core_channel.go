@@ -0,0 +1,187 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Release channels of the sing-box core (CoreSettings.Channel)
+const (
+	CoreChannelStable     = "stable"     // Latest stable release (default)
+	CoreChannelPrerelease = "prerelease" // Latest release including alpha/beta/rc
+	CoreChannelPinned     = "pinned"     // CoreSettings.Pin: an exact version or a range like 1.12.x
+)
+
+// coreReleasesURL lists sing-box releases, newest first (the first page is enough to find the newest matching one)
+const coreReleasesURL = "https://api.github.com/repos/SagerNet/sing-box/releases?per_page=50"
+
+// versionSpecRegex accepts X.Y.Z, X.Y.x, X.Y and X.x
+var versionSpecRegex = regexp.MustCompile(`^\d+(\.\d+(\.(\d+|x))?|\.x)?$`)
+
+// ValidateVersionSpec checks a pinned version: exact "1.12.12" or a range "1.12.x" / "1.x"
+func ValidateVersionSpec(spec string) error {
+	if !versionSpecRegex.MatchString(strings.TrimPrefix(spec, "v")) {
+		return fmt.Errorf("invalid version %q: expected X.Y.Z or a range like X.Y.x", spec)
+	}
+	return nil
+}
+
+// isExactVersionSpec reports whether spec pins a single version (X.Y.Z)
+func isExactVersionSpec(spec string) bool {
+	return ValidateVersionSpec(spec) == nil && strings.Count(spec, ".") == 2 && !strings.HasSuffix(spec, ".x")
+}
+
+// IsPrereleaseVersion reports whether version has a pre-release suffix (1.13.0-beta.1)
+func IsPrereleaseVersion(version string) bool {
+	return strings.Contains(version, "-")
+}
+
+// MatchesVersionSpec reports whether version satisfies spec. Ranges ("1.12.x", "1.12", "1.x") match
+// stable releases only; an exact spec matches that version.
+func MatchesVersionSpec(version, spec string) bool {
+	version = strings.TrimPrefix(version, "v")
+	spec = strings.TrimPrefix(spec, "v")
+	if isExactVersionSpec(spec) {
+		return version == spec
+	}
+	if ValidateVersionSpec(spec) != nil || IsPrereleaseVersion(version) {
+		return false
+	}
+	versionParts := strings.Split(version, ".")
+	for i, part := range strings.Split(spec, ".") {
+		if part == "x" {
+			return true
+		}
+		if i >= len(versionParts) || versionParts[i] != part {
+			return false
+		}
+	}
+	return true
+}
+
+// CoreChannel returns the release channel and pin from the settings
+func (ac *AppController) CoreChannel() (string, string) {
+	core := ac.Settings.Get().Core
+	switch core.Channel {
+	case CoreChannelPrerelease:
+		return CoreChannelPrerelease, ""
+	case CoreChannelPinned:
+		if ValidateVersionSpec(core.Pin) == nil {
+			return CoreChannelPinned, strings.TrimPrefix(core.Pin, "v")
+		}
+		log.Printf("CoreChannel: invalid pinned version %q, using the stable channel", core.Pin)
+	}
+	return CoreChannelStable, ""
+}
+
+// selectRelease returns the newest release allowed by the channel (also used for launcher releases)
+func selectRelease(releases []ReleaseInfo, channel, pin string) (*ReleaseInfo, error) {
+	var best *ReleaseInfo
+	for i := range releases {
+		release := &releases[i]
+		version := strings.TrimPrefix(release.TagName, "v")
+		if release.Draft {
+			continue
+		}
+		switch channel {
+		case CoreChannelStable:
+			if release.Prerelease || IsPrereleaseVersion(version) {
+				continue
+			}
+		case CoreChannelPinned:
+			if !MatchesVersionSpec(version, pin) {
+				continue
+			}
+		}
+		if best == nil || CompareVersions(version, strings.TrimPrefix(best.TagName, "v")) > 0 {
+			best = release
+		}
+	}
+	if best == nil {
+		if channel == CoreChannelPinned {
+			return nil, fmt.Errorf("no release matches %s", pin)
+		}
+		return nil, fmt.Errorf("no release found for channel %s", channel)
+	}
+	return best, nil
+}
+
+// latestCoreVersionForChannel gets the newest version of a prerelease or range channel from the release list
+func (ac *AppController) latestCoreVersionForChannel(channel, pin string) (string, error) {
+	sources := []struct {
+		name string
+		url  string
+	}{
+		{"GitHub API", coreReleasesURL},
+		{"GitHub Mirror (ghproxy)", "https://ghproxy.com/" + coreReleasesURL},
+	}
+	var lastErr error
+	for _, source := range sources {
+		releases, err := ac.fetchReleases(source.url)
+		if err != nil {
+			log.Printf("latestCoreVersionForChannel: %s failed: %v", source.name, err)
+			if !IsRateLimitError(lastErr) { // Сообщение о лимите GitHub полезнее ошибки зеркала
+				lastErr = err
+			}
+			continue
+		}
+		release, err := selectRelease(releases, channel, pin)
+		if err != nil {
+			return "", err
+		}
+		version := strings.TrimPrefix(release.TagName, "v")
+		log.Printf("latestCoreVersionForChannel: %s (%s %s) from %s", version, channel, pin, source.name)
+		return version, nil
+	}
+	return "", lastErr
+}
+
+// fetchReleases downloads a GitHub release list
+func (ac *AppController) fetchReleases(url string) ([]ReleaseInfo, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), NetworkRequestTimeout)
+	defer cancel()
+
+	client, err := ac.downloadHTTPClient(NetworkRequestTimeout)
+	if err != nil {
+		return nil, err
+	}
+	body, err := ac.githubGet(ctx, client, url)
+	if err != nil {
+		return nil, err
+	}
+	var releases []ReleaseInfo
+	if err := json.Unmarshal(body, &releases); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	return releases, nil
+}
+
+// CoreUpdateAvailable reports whether latest (from GetLatestCoreVersion) should replace installed.
+// A pinned channel also offers a downgrade when installed is outside the pin.
+func (ac *AppController) CoreUpdateAvailable(installed, latest string) bool {
+	if installed == "" || latest == "" || installed == latest {
+		return false
+	}
+	if channel, pin := ac.CoreChannel(); channel == CoreChannelPinned && !MatchesVersionSpec(installed, pin) {
+		return true
+	}
+	return CompareVersions(installed, latest) < 0
+}
+
+// GetCoreRelease returns the GitHub release of version with its release notes
+func (ac *AppController) GetCoreRelease(ctx context.Context, version string) (*ReleaseInfo, error) {
+	return ac.getReleaseInfoFromGitHub(ctx, version)
+}
+
+// ResetCoreVersionCache forgets the cached latest version, e.g. after the release channel changed
+func (ac *AppController) ResetCoreVersionCache() {
+	ac.VersionCheckMutex.Lock()
+	ac.VersionCheckCache = ""
+	ac.VersionCheckCacheTime = time.Time{}
+	ac.VersionCheckMutex.Unlock()
+}
@@ -0,0 +1,110 @@
+package core
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// TestMatchesVersionSpec tests exact versions and ranges of the pinned channel
+func TestMatchesVersionSpec(t *testing.T) {
+	tests := []struct {
+		version string
+		spec    string
+		want    bool
+	}{
+		{"1.12.12", "1.12.12", true},
+		{"v1.12.12", "1.12.12", true},
+		{"1.12.11", "1.12.12", false},
+		{"1.12.3", "1.12.x", true},
+		{"1.12.3", "1.12", true},
+		{"1.13.0", "1.12.x", false},
+		{"1.13.0", "1.x", true},
+		{"1.12.0-beta.1", "1.12.x", false},
+		{"1.13.0-beta.1", "1.13.0-beta.1", false}, // not a valid spec
+		{"1.12.3", "1.x.3", false},
+	}
+	for _, tt := range tests {
+		if got := MatchesVersionSpec(tt.version, tt.spec); got != tt.want {
+			t.Errorf("MatchesVersionSpec(%q, %q) = %v, want %v", tt.version, tt.spec, got, tt.want)
+		}
+	}
+}
+
+// TestCompareVersions_Prerelease tests that pre-releases sort before the release
+func TestCompareVersions_Prerelease(t *testing.T) {
+	tests := []struct {
+		v1, v2 string
+		want   int
+	}{
+		{"1.13.0-beta.1", "1.13.0", -1},
+		{"1.13.0-beta.2", "1.13.0-beta.10", -1},
+		{"1.13.0-alpha.5", "1.13.0-beta.1", -1},
+		{"1.13.0-beta.1", "1.12.12", 1},
+		{"v1.12.12", "1.12.12", 0},
+		{"1.12.9", "1.12.10", -1},
+	}
+	for _, tt := range tests {
+		if got := CompareVersions(tt.v1, tt.v2); got != tt.want {
+			t.Errorf("CompareVersions(%q, %q) = %d, want %d", tt.v1, tt.v2, got, tt.want)
+		}
+	}
+}
+
+// TestSelectCoreRelease tests the release picked for each channel
+func TestSelectCoreRelease(t *testing.T) {
+	releases := []ReleaseInfo{
+		{TagName: "v1.13.0-beta.2", Prerelease: true},
+		{TagName: "v1.14.0-alpha.1", Draft: true},
+		{TagName: "v1.12.12"},
+		{TagName: "v1.13.0-beta.1", Prerelease: true},
+		{TagName: "v1.11.15"},
+		{TagName: "v1.12.11"},
+	}
+	tests := []struct {
+		channel, pin string
+		want         string
+	}{
+		{CoreChannelStable, "", "v1.12.12"},
+		{CoreChannelPrerelease, "", "v1.13.0-beta.2"},
+		{CoreChannelPinned, "1.11.x", "v1.11.15"},
+		{CoreChannelPinned, "1.x", "v1.12.12"},
+	}
+	for _, tt := range tests {
+		release, err := selectRelease(releases, tt.channel, tt.pin)
+		if err != nil {
+			t.Errorf("%s %s: %v", tt.channel, tt.pin, err)
+			continue
+		}
+		if release.TagName != tt.want {
+			t.Errorf("%s %s: expected %s, got %s", tt.channel, tt.pin, tt.want, release.TagName)
+		}
+	}
+	if _, err := selectRelease(releases, CoreChannelPinned, "1.10.x"); err == nil {
+		t.Error("Expected an error when no release matches the pin")
+	}
+}
+
+// TestCoreUpdateAvailable tests that a pinned version is offered even when it is older than the installed one
+func TestCoreUpdateAvailable(t *testing.T) {
+	ac := &AppController{Settings: NewSettingsStore(filepath.Join(t.TempDir(), "settings.json"))}
+	if !ac.CoreUpdateAvailable("1.12.11", "1.12.12") || ac.CoreUpdateAvailable("1.12.12", "1.12.11") {
+		t.Error("Stable channel: expected updates to newer versions only")
+	}
+	if err := ac.Settings.Update(func(s *Settings) { s.Core.Channel, s.Core.Pin = CoreChannelPinned, "1.11.x" }); err != nil {
+		t.Fatal(err)
+	}
+	if !ac.CoreUpdateAvailable("1.12.12", "1.11.15") {
+		t.Error("Pinned channel: expected a downgrade to the pinned range")
+	}
+	if ac.CoreUpdateAvailable("1.11.15", "1.11.14") {
+		t.Error("Pinned channel: installed version within the pin must not be downgraded")
+	}
+
+	// An exact pin needs no request to GitHub
+	if err := ac.Settings.Update(func(s *Settings) { s.Core.Pin = "1.11.14" }); err != nil {
+		t.Fatal(err)
+	}
+	if version, err := ac.GetLatestCoreVersion(); err != nil || version != "1.11.14" {
+		t.Errorf("Expected the pinned version, got %q (%v)", version, err)
+	}
+}
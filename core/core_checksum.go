@@ -0,0 +1,259 @@
+package core
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrChecksumMismatch is returned when a downloaded archive does not match the published checksum
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// ErrNoChecksum is returned when a release publishes no checksum for an archive; such archives are not installed
+// unless the user confirms it (see DownloadCore)
+var ErrNoChecksum = errors.New("no published checksum")
+
+// maxChecksumFileSize limits the size of a downloaded checksums file
+const maxChecksumFileSize = 1 << 20
+
+// BinaryIntegrity is the result of comparing an installed binary with its recorded SHA-256
+type BinaryIntegrity int
+
+const (
+	// IntegrityUnknown means no hash was recorded (the binary was installed manually or by an older launcher)
+	IntegrityUnknown BinaryIntegrity = iota
+	// IntegrityOK means the binary matches the hash recorded at install time
+	IntegrityOK
+	// IntegrityModified means the binary was changed after it was installed
+	IntegrityModified
+)
+
+// FileSHA256 returns the hex-encoded SHA-256 of the file
+func FileSHA256(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// verifyFileChecksum compares the SHA-256 of the file with expected (hex, case-insensitive)
+func verifyFileChecksum(path, expected string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+	return verifyReaderChecksum(file, filepath.Base(path), expected)
+}
+
+// verifyReaderChecksum compares the SHA-256 of everything read from r with expected;
+// name is only used in the error
+func verifyReaderChecksum(r io.Reader, name, expected string) error {
+	hash := sha256.New()
+	if _, err := io.Copy(hash, r); err != nil {
+		return fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	actual := hex.EncodeToString(hash.Sum(nil))
+	if !strings.EqualFold(actual, expected) {
+		return fmt.Errorf("%w for %s: expected %s, got %s", ErrChecksumMismatch, name, strings.ToLower(expected), actual)
+	}
+	return nil
+}
+
+// parseChecksumFile finds the hash of fileName in sha256sum output ("<hash>  <name>" per line).
+// A file with a single bare hash (asset.sha256) is accepted as well.
+func parseChecksumFile(content, fileName string) string {
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || !isSHA256Hex(fields[0]) {
+			continue
+		}
+		if len(fields) == 1 {
+			return strings.ToLower(fields[0])
+		}
+		// "*name" marks binary mode in sha256sum output
+		if strings.TrimPrefix(fields[len(fields)-1], "*") == fileName {
+			return strings.ToLower(fields[0])
+		}
+	}
+	return ""
+}
+
+// isSHA256Hex checks that s looks like a hex-encoded SHA-256
+func isSHA256Hex(s string) bool {
+	if len(s) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+// findChecksumAsset returns the release asset that publishes the checksum of asset, if any:
+// "<asset>.sha256", "<asset>.sha256sum" or a common checksums file
+func findChecksumAsset(assets []Asset, asset *Asset) *Asset {
+	for _, suffix := range []string{".sha256", ".sha256sum"} {
+		for i := range assets {
+			if assets[i].Name == asset.Name+suffix {
+				return &assets[i]
+			}
+		}
+	}
+	for i := range assets {
+		name := strings.ToLower(assets[i].Name)
+		if strings.Contains(name, "checksums") || strings.HasPrefix(name, "sha256sum") {
+			return &assets[i]
+		}
+	}
+	return nil
+}
+
+// expectedAssetChecksum returns the published SHA-256 of asset and where it came from.
+// GitHub reports a digest for every asset; release checksum files are used otherwise.
+// Returns an empty hash when the release publishes no checksums (e.g. the SourceForge fallback);
+// use requireAssetChecksum before installing.
+func (ac *AppController) expectedAssetChecksum(ctx context.Context, release *ReleaseInfo, asset *Asset) (string, string, error) {
+	if digest, ok := strings.CutPrefix(asset.Digest, "sha256:"); ok && isSHA256Hex(digest) {
+		return strings.ToLower(digest), "GitHub asset digest", nil
+	}
+	checksumAsset := findChecksumAsset(release.Assets, asset)
+	if checksumAsset == nil {
+		return "", "", nil
+	}
+	content, err := ac.fetchChecksumFile(ctx, checksumAsset.BrowserDownloadURL)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to download %s: %w", checksumAsset.Name, err)
+	}
+	hash := parseChecksumFile(content, asset.Name)
+	if hash == "" {
+		return "", "", fmt.Errorf("%s does not list %s", checksumAsset.Name, asset.Name)
+	}
+	return hash, checksumAsset.Name, nil
+}
+
+// requireAssetChecksum returns the published SHA-256 of asset like expectedAssetChecksum,
+// or ErrNoChecksum: archives from mirrors are installed only after verification
+func (ac *AppController) requireAssetChecksum(ctx context.Context, release *ReleaseInfo, asset *Asset) (string, string, error) {
+	expected, source, err := ac.expectedAssetChecksum(ctx, release, asset)
+	if err != nil {
+		return "", "", err
+	}
+	if expected == "" {
+		// Так бывает с релизом от SourceForge, когда GitHub API недоступен
+		return "", "", fmt.Errorf("%w for %s in release %s (is api.github.com reachable?), refusing to install it", ErrNoChecksum, asset.Name, release.TagName)
+	}
+	return expected, source, nil
+}
+
+// coreArchiveChecksum returns the published SHA-256 of a sing-box archive like requireAssetChecksum.
+// Without one (the SourceForge fallback when api.github.com is unreachable) it returns an empty hash
+// if allowUnverified is set: the user has confirmed installing the archive without verification.
+func (ac *AppController) coreArchiveChecksum(ctx context.Context, release *ReleaseInfo, asset *Asset, allowUnverified bool) (string, string, error) {
+	expected, source, err := ac.requireAssetChecksum(ctx, release, asset)
+	if errors.Is(err, ErrNoChecksum) && allowUnverified {
+		log.Printf("coreArchiveChecksum: WARNING: no published checksum for %s in release %s, installing it unverified as confirmed by the user", asset.Name, release.TagName)
+		return "", "", nil
+	}
+	return expected, source, err
+}
+
+// fetchChecksumFile downloads a small checksums file
+func (ac *AppController) fetchChecksumFile(ctx context.Context, url string) (string, error) {
+	client, err := ac.downloadHTTPClient(NetworkRequestTimeout)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", "singbox-launcher/1.0")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		if IsNetworkError(err) {
+			return "", fmt.Errorf("network error: %s", GetNetworkErrorMessage(err))
+		}
+		return "", fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxChecksumFileSize))
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+	return string(body), nil
+}
+
+// BinaryHashPath returns the file with the SHA-256 recorded for an installed binary
+func BinaryHashPath(binaryPath string) string {
+	return binaryPath + ".sha256"
+}
+
+// recordBinaryHash stores the SHA-256 of an installed binary next to it in sha256sum format
+func recordBinaryHash(binaryPath string) (string, error) {
+	hash, err := FileSHA256(binaryPath)
+	if err != nil {
+		return "", err
+	}
+	content := fmt.Sprintf("%s  %s\n", hash, filepath.Base(binaryPath))
+	if err := os.WriteFile(BinaryHashPath(binaryPath), []byte(content), 0644); err != nil {
+		return "", fmt.Errorf("failed to record binary hash: %w", err)
+	}
+	return hash, nil
+}
+
+// CheckBinaryIntegrity compares a binary with the SHA-256 recorded when it was installed
+func CheckBinaryIntegrity(binaryPath string) (BinaryIntegrity, error) {
+	data, err := os.ReadFile(BinaryHashPath(binaryPath))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return IntegrityUnknown, nil
+		}
+		return IntegrityUnknown, fmt.Errorf("failed to read recorded hash: %w", err)
+	}
+	expected := parseChecksumFile(string(data), filepath.Base(binaryPath))
+	if expected == "" {
+		return IntegrityUnknown, fmt.Errorf("invalid recorded hash in %s", BinaryHashPath(binaryPath))
+	}
+	actual, err := FileSHA256(binaryPath)
+	if err != nil {
+		return IntegrityUnknown, err
+	}
+	if actual != expected {
+		return IntegrityModified, nil
+	}
+	return IntegrityOK, nil
+}
+
+// CheckCoreIntegrity checks whether bin/sing-box was modified since the launcher installed it
+func (ac *AppController) CheckCoreIntegrity() (BinaryIntegrity, error) {
+	integrity, err := CheckBinaryIntegrity(ac.SingboxPath)
+	if err != nil {
+		log.Printf("CheckCoreIntegrity: %v", err)
+		return integrity, err
+	}
+	if integrity == IntegrityModified {
+		log.Printf("CheckCoreIntegrity: WARNING: %s does not match the SHA-256 recorded at install time", ac.SingboxPath)
+	}
+	return integrity, nil
+}
@@ -0,0 +1,147 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+const testArchiveName = "sing-box-1.12.12-linux-amd64.tar.gz"
+
+// TestParseChecksumFile tests sha256sum output, binary-mode names and bare hash files
+func TestParseChecksumFile(t *testing.T) {
+	hashA := "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	hashB := "60303ae22b998861bce3b28f33eec1be758a213c86c93c076dbe9f558c11c752"
+	content := fmt.Sprintf("%s  sing-box-1.12.12-windows-amd64.zip\n%s *%s\n", hashA, hashB, testArchiveName)
+
+	if got := parseChecksumFile(content, testArchiveName); got != hashB {
+		t.Errorf("Expected %s, got %q", hashB, got)
+	}
+	if got := parseChecksumFile(content, "missing.zip"); got != "" {
+		t.Errorf("Expected no hash for a missing file, got %q", got)
+	}
+	if got := parseChecksumFile("not a hash\n", testArchiveName); got != "" {
+		t.Errorf("Expected no hash for garbage, got %q", got)
+	}
+	if got := parseChecksumFile(hashA+"\n", testArchiveName); got != hashA {
+		t.Errorf("Expected bare hash %s, got %q", hashA, got)
+	}
+}
+
+// TestExpectedAssetChecksum tests the GitHub digest, a published checksums file and releases without checksums
+func TestExpectedAssetChecksum(t *testing.T) {
+	archive := filepath.Join(t.TempDir(), testArchiveName)
+	if err := os.WriteFile(archive, []byte("archive"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	hash, err := FileSHA256(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s  %s\n", hash, testArchiveName)
+	}))
+	defer server.Close()
+
+	ac := &AppController{}
+	asset := Asset{Name: testArchiveName}
+	tests := []struct {
+		name    string
+		release ReleaseInfo
+		asset   Asset
+		want    string
+	}{
+		{"digest", ReleaseInfo{}, Asset{Name: testArchiveName, Digest: "sha256:" + hash}, hash},
+		{"checksums file", ReleaseInfo{Assets: []Asset{asset, {Name: "sing-box-1.12.12-checksums.txt", BrowserDownloadURL: server.URL}}}, asset, hash},
+		{"none", ReleaseInfo{Assets: []Asset{asset}}, asset, ""},
+	}
+	for _, tt := range tests {
+		got, _, err := ac.expectedAssetChecksum(context.Background(), &tt.release, &tt.asset)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.want, got)
+		}
+	}
+
+	// Без опубликованной суммы архив не устанавливается
+	if _, _, err := ac.requireAssetChecksum(context.Background(), &tests[2].release, &asset); !errors.Is(err, ErrNoChecksum) {
+		t.Errorf("Expected ErrNoChecksum, got %v", err)
+	}
+
+	if err := verifyFileChecksum(archive, hash); err != nil {
+		t.Errorf("Expected matching checksum, got %v", err)
+	}
+	wrong := "0000000000000000000000000000000000000000000000000000000000000000"
+	if err := verifyFileChecksum(archive, wrong); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("Expected ErrChecksumMismatch, got %v", err)
+	}
+}
+
+// TestCoreArchiveChecksum_SourceForge tests the SourceForge fallback release, which has no digest:
+// the archive is refused until the user confirms installing it unverified
+func TestCoreArchiveChecksum_SourceForge(t *testing.T) {
+	ac := &AppController{}
+	release, err := ac.getReleaseInfoFromSourceForge(context.Background(), "1.12.12")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(release.Assets) == 0 {
+		t.Skipf("no SourceForge asset for %s/%s", runtime.GOOS, runtime.GOARCH)
+	}
+	asset, err := ac.findPlatformAsset(release.Assets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if asset.Digest != "" {
+		t.Fatalf("Expected no digest from SourceForge, got %q", asset.Digest)
+	}
+
+	if _, _, err := ac.coreArchiveChecksum(context.Background(), release, asset, false); !errors.Is(err, ErrNoChecksum) {
+		t.Errorf("Expected ErrNoChecksum without confirmation, got %v", err)
+	}
+	expected, _, err := ac.coreArchiveChecksum(context.Background(), release, asset, true)
+	if err != nil || expected != "" {
+		t.Errorf("Expected an unverified install after confirmation, got %q (%v)", expected, err)
+	}
+
+	// Подтверждение не отменяет проверку, когда сумма опубликована
+	asset.Digest = "sha256:" + strings.Repeat("a", 64)
+	if expected, _, err := ac.coreArchiveChecksum(context.Background(), release, asset, true); err != nil || expected != strings.Repeat("a", 64) {
+		t.Errorf("Expected the published digest, got %q (%v)", expected, err)
+	}
+}
+
+// TestCheckBinaryIntegrity tests detection of a binary modified after its hash was recorded
+func TestCheckBinaryIntegrity(t *testing.T) {
+	binary := filepath.Join(t.TempDir(), "sing-box")
+	if err := os.WriteFile(binary, []byte("original"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if integrity, err := CheckBinaryIntegrity(binary); err != nil || integrity != IntegrityUnknown {
+		t.Errorf("Expected IntegrityUnknown without a record, got %v (%v)", integrity, err)
+	}
+	if _, err := recordBinaryHash(binary); err != nil {
+		t.Fatal(err)
+	}
+	if integrity, err := CheckBinaryIntegrity(binary); err != nil || integrity != IntegrityOK {
+		t.Errorf("Expected IntegrityOK after recording, got %v (%v)", integrity, err)
+	}
+	if err := os.WriteFile(binary, []byte("replaced"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if integrity, err := CheckBinaryIntegrity(binary); err != nil || integrity != IntegrityModified {
+		t.Errorf("Expected IntegrityModified after replacing, got %v (%v)", integrity, err)
+	}
+}
@@ -21,8 +21,13 @@ import (
 
 // ReleaseInfo содержит информацию о релизе GitHub
 type ReleaseInfo struct {
-	TagName string  `json:"tag_name"`
-	Assets  []Asset `json:"assets"`
+	TagName    string  `json:"tag_name"`
+	Name       string  `json:"name"`
+	Body       string  `json:"body"`       // Release notes (Markdown)
+	HTMLURL    string  `json:"html_url"`   // Release page
+	Prerelease bool    `json:"prerelease"` // alpha/beta/rc
+	Draft      bool    `json:"draft"`
+	Assets     []Asset `json:"assets"`
 }
 
 // Asset содержит информацию об asset релиза
@@ -30,18 +35,20 @@ type Asset struct {
 	Name               string `json:"name"`
 	BrowserDownloadURL string `json:"browser_download_url"`
 	Size               int64  `json:"size"`
+	Digest             string `json:"digest,omitempty"` // "sha256:<hex>", reported by the GitHub API
 }
 
 // DownloadProgress содержит информацию о прогрессе скачивания
 type DownloadProgress struct {
 	Progress int // 0-100
 	Message  string
-	Status   string // "downloading", "extracting", "done", "error"
+	Status   string // "downloading", "retrying", "verifying", "extracting", "done", "error"
 	Error    error
 }
 
-// DownloadCore downloads and installs sing-box
-func (ac *AppController) DownloadCore(ctx context.Context, version string, progressChan chan DownloadProgress) {
+// DownloadCore downloads and installs sing-box. An archive without a published checksum fails with ErrNoChecksum;
+// allowUnverified installs it anyway and is only set after the user has confirmed that.
+func (ac *AppController) DownloadCore(ctx context.Context, version string, allowUnverified bool, progressChan chan DownloadProgress) {
 	defer close(progressChan)
 
 	// 1. Get release information
@@ -60,39 +67,89 @@ func (ac *AppController) DownloadCore(ctx context.Context, version string, progr
 		return
 	}
 
-	// 3. Создаем временную директорию
+	// 3. Контрольная сумма нужна до скачивания: без неё архив с зеркала не устанавливаем (если пользователь не подтвердил)
+	expected, source, err := ac.coreArchiveChecksum(ctx, release, asset, allowUnverified)
+	if err != nil {
+		progressChan <- DownloadProgress{Progress: 0, Message: fmt.Sprintf("Failed to get checksum: %v", err), Status: "error", Error: err}
+		return
+	}
+
+	// 4. Создаем временную директорию
 	tempDir := filepath.Join(ac.ExecDir, "temp")
 	if err := os.MkdirAll(tempDir, 0755); err != nil {
 		progressChan <- DownloadProgress{Progress: 0, Message: fmt.Sprintf("Failed to create temp dir: %v", err), Status: "error", Error: err}
 		return
 	}
-	defer os.RemoveAll(tempDir) // Удаляем временную директорию после завершения
+	// Удаляем временную директорию после завершения; недокачанный архив оставляем для продолжения
+	downloadFailed := false
+	defer func() { cleanupTempDir(tempDir, downloadFailed) }()
 
-	// 4. Download archive
+	// 5. Download archive
 	archivePath := filepath.Join(tempDir, asset.Name)
 	progressChan <- DownloadProgress{Progress: 15, Message: fmt.Sprintf("Downloading %s...", asset.Name), Status: "downloading"}
 	if err := ac.downloadFile(ctx, asset.BrowserDownloadURL, archivePath, progressChan); err != nil {
+		downloadFailed = true
 		progressChan <- DownloadProgress{Progress: 0, Message: fmt.Sprintf("Download failed: %v", err), Status: "error", Error: err}
 		return
 	}
 
-	// 5. Распаковываем архив
-	progressChan <- DownloadProgress{Progress: 80, Message: "Extracting archive...", Status: "extracting"}
+	// 6. Проверяем контрольную сумму архива
+	if expected == "" {
+		log.Printf("DownloadCore: %s is installed without checksum verification", asset.Name)
+	} else {
+		progressChan <- DownloadProgress{Progress: 80, Message: "Verifying checksum...", Status: "verifying"}
+		if err := verifyFileChecksum(archivePath, expected); err != nil {
+			progressChan <- DownloadProgress{Progress: 0, Message: fmt.Sprintf("Checksum verification failed, the archive was not installed: %v", err), Status: "error", Error: err}
+			return
+		}
+		log.Printf("DownloadCore: %s verified against %s", asset.Name, source)
+	}
+
+	// 7. Распаковываем архив
+	progressChan <- DownloadProgress{Progress: 85, Message: "Extracting archive...", Status: "extracting"}
 	binaryPath, err := ac.extractArchive(archivePath, tempDir)
 	if err != nil {
 		progressChan <- DownloadProgress{Progress: 0, Message: fmt.Sprintf("Extraction failed: %v", err), Status: "error", Error: err}
 		return
 	}
 
-	// 6. Копируем бинарник в целевую директорию
+	// 8. Сохраняем версию в bin/cores рядом с уже установленными и делаем её активной
+	installVersion := strings.TrimPrefix(release.TagName, "v")
 	progressChan <- DownloadProgress{Progress: 90, Message: "Installing binary...", Status: "extracting"}
-	if err := ac.installBinary(binaryPath, ac.SingboxPath); err != nil {
+	if err := ac.ImportActiveCore(); err != nil {
+		log.Printf("DownloadCore: failed to keep a copy of the current sing-box: %v", err)
+	}
+	if err := ac.StoreCore(binaryPath, installVersion); err != nil {
 		progressChan <- DownloadProgress{Progress: 0, Message: fmt.Sprintf("Installation failed: %v", err), Status: "error", Error: err}
 		return
 	}
+	previous, err := ac.switchCore(installVersion, true, true)
+	if err != nil {
+		progressChan <- DownloadProgress{Progress: 0, Message: fmt.Sprintf("Installation failed: %v", err), Status: "error", Error: err}
+		return
+	}
+
+	// 9. Новая версия должна принять текущий конфиг, иначе откатываемся на предыдущую
+	if previous != "" && previous != installVersion {
+		if _, statErr := os.Stat(ac.ConfigPath); statErr == nil {
+			progressChan <- DownloadProgress{Progress: 95, Message: "Checking config with the new version...", Status: "verifying"}
+			if checkErr := ac.CheckConfigWithCore(); checkErr != nil {
+				err := fmt.Errorf("sing-box v%s rejected the current config: %w", installVersion, checkErr)
+				if rollbackErr := ac.RollbackCore(checkErr.Error()); rollbackErr != nil {
+					err = fmt.Errorf("%w\n\n%v", err, rollbackErr)
+				} else {
+					err = fmt.Errorf("%w\n\nRolled back to v%s; v%s stays installed and can be activated in Versions", err, previous, installVersion)
+				}
+				progressChan <- DownloadProgress{Progress: 0, Message: err.Error(), Status: "error", Error: err}
+				return
+			}
+		}
+		ac.Events.Publish(CoreVersionChanged{Version: installVersion, Previous: previous})
+	}
+	ac.logCoreCompatibility(installVersion)
 
-	// 7. Готово!
-	progressChan <- DownloadProgress{Progress: 100, Message: fmt.Sprintf("sing-box v%s installed successfully!", version), Status: "done"}
+	// 10. Готово!
+	progressChan <- DownloadProgress{Progress: 100, Message: fmt.Sprintf("sing-box v%s installed successfully!", installVersion), Status: "done"}
 }
 
 // getReleaseInfo gets release information from GitHub (with SourceForge fallback)
@@ -116,34 +173,16 @@ func (ac *AppController) getReleaseInfoFromGitHub(ctx context.Context, version s
 		url = "https://api.github.com/repos/SagerNet/sing-box/releases/latest"
 	}
 
-	// Используем универсальный HTTP клиент
-	client := createHTTPClient(NetworkRequestTimeout)
-
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	// HTTP клиент с прокси из настроек загрузок
+	client, err := ac.downloadHTTPClient(NetworkRequestTimeout)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("Accept", "application/vnd.github.v3+json")
-	req.Header.Set("User-Agent", "singbox-launcher/1.0")
-
-	resp, err := client.Do(req)
-	if err != nil {
-		// Проверяем тип ошибки
-		if IsNetworkError(err) {
-			return nil, fmt.Errorf("network error: %s", GetNetworkErrorMessage(err))
-		}
-		return nil, fmt.Errorf("request failed: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+		return nil, err
 	}
 
-	body, err := io.ReadAll(resp.Body)
+	// Условный запрос с ETag (см. githubGet)
+	body, err := ac.githubGet(ctx, client, url)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+		return nil, err
 	}
 
 	var release ReleaseInfo
@@ -266,50 +305,77 @@ func (ac *AppController) findPlatformAsset(assets []Asset) (*Asset, error) {
 	return nil, fmt.Errorf("asset not found for platform %s/%s", runtime.GOOS, runtime.GOARCH)
 }
 
-// downloadFile downloads a file with progress tracking (with SourceForge fallback)
+// downloadFile downloads a file with progress tracking, trying the configured mirrors in order.
+// Each source gets several attempts with exponential backoff; an interrupted attempt is resumed from the partial file.
+// Every retry is reported through progressChan with Status "retrying".
 func (ac *AppController) downloadFile(ctx context.Context, url, destPath string, progressChan chan DownloadProgress) error {
-	// Try to download from original URL
-	err := ac.downloadFileFromURL(ctx, url, destPath, progressChan)
-	if err == nil {
-		return nil
+	downloads := ac.Settings.Get().Downloads
+	client, err := ac.downloadHTTPClient(5 * time.Minute)
+	if err != nil {
+		return err
 	}
 
-	log.Printf("Failed to download from original URL, trying mirrors...")
-
-	// Если не получилось, пробуем зеркала GitHub
-	mirrors := []string{
-		strings.Replace(url, "https://github.com/", "https://ghproxy.com/https://github.com/", 1),
+	var version, fileName string
+	if strings.Contains(url, "github.com/") {
+		// Формат GitHub URL нужен для SourceForge и шаблонов с {version}/{file}
+		version, fileName = ac.extractVersionAndFileName(url)
 	}
+	sources := downloadSources(url, downloads.SourceList(), version, fileName)
+	attempts := downloads.Attempts()
+	partPath := destPath + partialSuffix
 
-	for _, mirrorURL := range mirrors {
-		log.Printf("Trying mirror: %s", mirrorURL)
-		err := ac.downloadFileFromURL(ctx, mirrorURL, destPath, progressChan)
-		if err == nil {
-			return nil
+	var lastErr error
+	for i, source := range sources {
+		if i > 0 {
+			// Частичный файл другого источника может отличаться, начинаем заново
+			os.Remove(partPath)
+			log.Printf("downloadFile: trying %s", source.URL)
 		}
-		log.Printf("Mirror failed: %v", err)
-	}
-
-	// Если все зеркала GitHub не работают, пробуем SourceForge
-	if strings.Contains(url, "github.com") {
-		log.Printf("Trying SourceForge...")
-		// Извлекаем версию и имя файла из URL
-		version, fileName := ac.extractVersionAndFileName(url)
-		if version != "" && fileName != "" {
-			sourceForgeURL := fmt.Sprintf("https://sourceforge.net/projects/sing-box.mirror/files/v%s/%s/download", version, fileName)
-			err := ac.downloadFileFromURL(ctx, sourceForgeURL, destPath, progressChan)
+		for attempt := 1; attempt <= attempts; attempt++ {
+			err := ac.downloadFileFromURL(ctx, client, source.URL, destPath, progressChan)
 			if err == nil {
 				return nil
 			}
-			log.Printf("SourceForge failed: %v", err)
+			lastErr = err
+			log.Printf("downloadFile: %s attempt %d/%d failed: %v", source.Name, attempt, attempts, err)
+			if ctx.Err() != nil {
+				os.Remove(partPath)
+				return fmt.Errorf("download cancelled: %w", ctx.Err())
+			}
+			if !isRetryableDownloadError(err) || attempt == attempts {
+				break
+			}
+
+			delay := downloadRetryDelay(attempt)
+			progressChan <- DownloadProgress{
+				Progress: 15,
+				Message:  fmt.Sprintf("%s: attempt %d/%d failed, retrying in %s...", source.Name, attempt, attempts, delay),
+				Status:   "retrying",
+				Error:    err,
+			}
+			select {
+			case <-ctx.Done():
+				os.Remove(partPath)
+				return fmt.Errorf("download cancelled: %w", ctx.Err())
+			case <-time.After(delay):
+			}
+		}
+		if i+1 < len(sources) {
+			progressChan <- DownloadProgress{
+				Progress: 15,
+				Message:  fmt.Sprintf("%s failed, trying %s...", source.Name, sources[i+1].Name),
+				Status:   "retrying",
+				Error:    lastErr,
+			}
 		}
 	}
 
-	return fmt.Errorf("all download sources failed, last error: %w", err)
+	return fmt.Errorf("all download sources failed, last error: %w", lastErr)
 }
 
-// downloadFileFromURL downloads a file from a specific URL
-func (ac *AppController) downloadFileFromURL(ctx context.Context, url, destPath string, progressChan chan DownloadProgress) error {
+// downloadFileFromURL downloads a file from a specific URL. Data is written to destPath.part, which is renamed
+// to destPath when complete; if the partial file exists, the download continues from its end (HTTP Range).
+func (ac *AppController) downloadFileFromURL(ctx context.Context, client *http.Client, url, destPath string, progressChan chan DownloadProgress) error {
 	// Use parent context timeout or create one with default timeout
 	downloadTimeout := 5 * time.Minute
 	if _, ok := ctx.Deadline(); !ok {
@@ -318,8 +384,11 @@ func (ac *AppController) downloadFileFromURL(ctx context.Context, url, destPath
 		defer cancel()
 	}
 
-	// Use client with large timeout for download
-	client := createHTTPClient(downloadTimeout)
+	partPath := destPath + partialSuffix
+	var offset int64
+	if info, err := os.Stat(partPath); err == nil {
+		offset = info.Size()
+	}
 
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
@@ -327,6 +396,9 @@ func (ac *AppController) downloadFileFromURL(ctx context.Context, url, destPath
 	}
 
 	req.Header.Set("User-Agent", "singbox-launcher/1.0")
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
 
 	resp, err := client.Do(req)
 	if err != nil {
@@ -338,18 +410,38 @@ func (ac *AppController) downloadFileFromURL(ctx context.Context, url, destPath
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	flags := os.O_CREATE | os.O_WRONLY
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && offset > 0:
+		// Сервер должен продолжить ровно с конца частичного файла
+		if !strings.HasPrefix(resp.Header.Get("Content-Range"), fmt.Sprintf("bytes %d-", offset)) {
+			os.Remove(partPath)
+			return fmt.Errorf("unexpected Content-Range %q, restarting download", resp.Header.Get("Content-Range"))
+		}
+		flags |= os.O_APPEND
+		log.Printf("downloadFileFromURL: resuming %s from %d bytes", filepath.Base(destPath), offset)
+	case resp.StatusCode == http.StatusOK:
+		// Сервер не поддерживает Range (или частичного файла нет) - качаем с начала
+		flags |= os.O_TRUNC
+		offset = 0
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0:
+		os.Remove(partPath)
+		return fmt.Errorf("partial download does not match the file, restarting download")
+	default:
+		return &httpStatusError{Code: resp.StatusCode}
 	}
 
-	file, err := os.Create(destPath)
+	file, err := os.OpenFile(partPath, flags, 0644)
 	if err != nil {
 		return fmt.Errorf("failed to create file: %w", err)
 	}
 	defer file.Close()
 
-	totalSize := resp.ContentLength
-	var downloaded int64
+	var totalSize int64
+	if resp.ContentLength > 0 {
+		totalSize = offset + resp.ContentLength
+	}
+	downloaded := offset
 
 	// Download with progress tracking
 	buf := make([]byte, 32*1024) // 32KB buffer
@@ -386,10 +478,37 @@ func (ac *AppController) downloadFileFromURL(ctx context.Context, url, destPath
 			return fmt.Errorf("read failed: %w", err)
 		}
 	}
+	if totalSize > 0 && downloaded != totalSize {
+		return fmt.Errorf("incomplete download: got %d of %d bytes", downloaded, totalSize)
+	}
 
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := os.Rename(partPath, destPath); err != nil {
+		return fmt.Errorf("failed to move downloaded file: %w", err)
+	}
 	return nil
 }
 
+// cleanupTempDir removes the temporary download directory. After a failed download the partial
+// files are kept, so the next attempt resumes them instead of starting from zero.
+func cleanupTempDir(tempDir string, keepPartial bool) {
+	if !keepPartial {
+		os.RemoveAll(tempDir)
+		return
+	}
+	entries, err := os.ReadDir(tempDir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), partialSuffix) {
+			os.RemoveAll(filepath.Join(tempDir, entry.Name()))
+		}
+	}
+}
+
 // extractVersionAndFileName извлекает версию и имя файла из GitHub URL
 func (ac *AppController) extractVersionAndFileName(url string) (string, string) {
 	// Формат GitHub URL: https://github.com/SagerNet/sing-box/releases/download/v1.12.12/sing-box-1.12.12-windows-amd64.zip
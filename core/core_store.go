@@ -0,0 +1,285 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+
+	"singbox-launcher/internal/constants"
+	"singbox-launcher/internal/platform"
+)
+
+// coreTrialPeriod is how long a newly activated core must run before it is trusted (no rollback on crash)
+const coreTrialPeriod = 30 * time.Second
+
+// ErrCoreRunning is returned when the active core cannot be replaced because sing-box is running
+var ErrCoreRunning = errors.New("stop sing-box before switching the core version")
+
+// coreVersionRegex limits version directory names (they come from release tags)
+var coreVersionRegex = regexp.MustCompile(`^[0-9][0-9A-Za-z.+-]*$`)
+
+// InstalledCore describes a sing-box version kept in bin/cores
+type InstalledCore struct {
+	Version     string
+	Path        string
+	Active      bool
+	Size        int64
+	InstalledAt time.Time
+}
+
+// CoresDir returns the directory with the installed sing-box versions (bin/cores)
+func (ac *AppController) CoresDir() string {
+	return filepath.Join(platform.GetBinDir(ac.ExecDir), constants.CoresDirName)
+}
+
+// coreStorePath returns the binary of version in bin/cores/<version>/
+func (ac *AppController) coreStorePath(version string) string {
+	return filepath.Join(ac.CoresDir(), version, platform.GetExecutableNames())
+}
+
+// validateCoreVersion rejects versions that cannot be used as a directory name
+func validateCoreVersion(version string) error {
+	if !coreVersionRegex.MatchString(version) {
+		return fmt.Errorf("invalid sing-box version %q", version)
+	}
+	return nil
+}
+
+// InstalledCores lists the versions in bin/cores, newest first
+func (ac *AppController) InstalledCores() ([]InstalledCore, error) {
+	entries, err := os.ReadDir(ac.CoresDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read cores directory: %w", err)
+	}
+	active := ac.Settings.Get().Core.Active
+	var cores []InstalledCore
+	for _, entry := range entries {
+		if !entry.IsDir() || validateCoreVersion(entry.Name()) != nil {
+			continue
+		}
+		path := ac.coreStorePath(entry.Name())
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		cores = append(cores, InstalledCore{
+			Version:     entry.Name(),
+			Path:        path,
+			Active:      entry.Name() == active,
+			Size:        info.Size(),
+			InstalledAt: info.ModTime(),
+		})
+	}
+	sort.Slice(cores, func(i, j int) bool {
+		if cmp := CompareVersions(cores[i].Version, cores[j].Version); cmp != 0 {
+			return cmp > 0
+		}
+		return cores[i].Version > cores[j].Version
+	})
+	return cores, nil
+}
+
+// StoreCore copies a sing-box binary into bin/cores/<version>/ and records its hash
+func (ac *AppController) StoreCore(sourcePath, version string) error {
+	if err := validateCoreVersion(version); err != nil {
+		return err
+	}
+	destPath := ac.coreStorePath(version)
+	if err := ac.installBinary(sourcePath, destPath); err != nil {
+		return err
+	}
+	if _, err := recordBinaryHash(destPath); err != nil {
+		log.Printf("StoreCore: %v", err)
+	}
+	return nil
+}
+
+// ImportActiveCore keeps a copy of bin/sing-box in bin/cores, so a binary installed by an older launcher
+// (or replaced by hand) can be restored after switching. The settings are corrected to the real active version.
+func (ac *AppController) ImportActiveCore() error {
+	if _, err := os.Stat(ac.SingboxPath); err != nil {
+		return nil
+	}
+	version, err := ac.GetInstalledCoreVersion()
+	if err != nil {
+		return err
+	}
+	if err := validateCoreVersion(version); err != nil {
+		return err
+	}
+	if _, err := os.Stat(ac.coreStorePath(version)); os.IsNotExist(err) {
+		log.Printf("ImportActiveCore: Keeping a copy of sing-box v%s in %s", version, ac.CoresDir())
+		if err := ac.StoreCore(ac.SingboxPath, version); err != nil {
+			return err
+		}
+	}
+	if current := ac.Settings.Get().Core; current.Active != version {
+		return ac.Settings.Update(func(settings *Settings) {
+			if settings.Core.Active != "" && settings.Core.Active != version {
+				settings.Core.Previous = settings.Core.Active
+			}
+			settings.Core.Active = version
+			settings.Core.Trial = false
+		})
+	}
+	return nil
+}
+
+// EnsureCoreCapabilities sets the Linux capabilities on bin/sing-box if they are missing (a new binary has none).
+// Only when enabled in the settings; polkit asks for the administrator password.
+func (ac *AppController) EnsureCoreCapabilities() error {
+	if !platform.CapabilitiesRequired() || !ac.Settings.Get().Core.SetCapabilities {
+		return nil
+	}
+	if _, err := os.Stat(ac.SingboxPath); err != nil {
+		return nil
+	}
+	if platform.CheckAndSuggestCapabilities(ac.SingboxPath) == "" {
+		return nil
+	}
+	log.Printf("EnsureCoreCapabilities: setting capabilities on %s through polkit", ac.SingboxPath)
+	if err := platform.SetCapabilitiesWithPolkit(ac.SingboxPath); err != nil {
+		return fmt.Errorf("failed to set capabilities on sing-box: %w", err)
+	}
+	return nil
+}
+
+// switchCore copies version from bin/cores to bin/sing-box and makes it active.
+// trial marks it as not yet proven, so a crash on the first start rolls it back.
+// setCapabilities re-applies the Linux capabilities right away; without it the next Start applies them.
+// Returns the previously active version.
+func (ac *AppController) switchCore(version string, trial, setCapabilities bool) (string, error) {
+	if err := validateCoreVersion(version); err != nil {
+		return "", err
+	}
+	sourcePath := ac.coreStorePath(version)
+	if _, err := os.Stat(sourcePath); err != nil {
+		return "", fmt.Errorf("sing-box v%s is not installed", version)
+	}
+	if integrity, err := CheckBinaryIntegrity(sourcePath); err == nil && integrity == IntegrityModified {
+		return "", fmt.Errorf("%w: %s was modified after it was installed", ErrChecksumMismatch, sourcePath)
+	}
+	if err := ac.installBinary(sourcePath, ac.SingboxPath); err != nil {
+		return "", err
+	}
+	if _, err := recordBinaryHash(ac.SingboxPath); err != nil {
+		log.Printf("switchCore: %v", err)
+	}
+	// Новый бинарник не наследует capabilities прежнего
+	if setCapabilities {
+		if err := ac.EnsureCoreCapabilities(); err != nil {
+			log.Printf("switchCore: %v", err)
+			ac.Notifier.ShowError(fmt.Errorf("sing-box v%s is active, but %w", version, err))
+		}
+	}
+
+	previous := ac.Settings.Get().Core.Active
+	err := ac.Settings.Update(func(settings *Settings) {
+		if settings.Core.Active != version {
+			settings.Core.Previous = settings.Core.Active
+		}
+		settings.Core.Active = version
+		settings.Core.Trial = trial && settings.Core.Previous != ""
+	})
+	if err != nil {
+		return previous, fmt.Errorf("failed to save active core version: %w", err)
+	}
+	log.Printf("switchCore: sing-box v%s is active (previous: %q)", version, previous)
+	return previous, nil
+}
+
+// ActivateCore switches bin/sing-box to an installed version. sing-box must be stopped.
+func (ac *AppController) ActivateCore(version string) error {
+	if ac.RunningState.IsRunning() {
+		return ErrCoreRunning
+	}
+	previous, err := ac.switchCore(version, false, true)
+	if err != nil {
+		return err
+	}
+	if previous != version {
+		ac.Events.Publish(CoreVersionChanged{Version: version, Previous: previous})
+		ac.logCoreCompatibility(version)
+	}
+	return nil
+}
+
+// RollbackCore restores the previously active version after the active one failed with reason
+func (ac *AppController) RollbackCore(reason string) error {
+	return ac.rollbackCore(reason, true)
+}
+
+// rollbackCore is RollbackCore; setCapabilities is passed to switchCore
+func (ac *AppController) rollbackCore(reason string, setCapabilities bool) error {
+	core := ac.Settings.Get().Core
+	if core.Previous == "" {
+		return fmt.Errorf("no previous sing-box version to roll back to")
+	}
+	failed := core.Active
+	if _, err := ac.switchCore(core.Previous, false, setCapabilities); err != nil {
+		return fmt.Errorf("failed to roll back to sing-box v%s: %w", core.Previous, err)
+	}
+	log.Printf("RollbackCore: Rolled back from sing-box v%s to v%s: %s", failed, core.Previous, reason)
+	ac.Events.Publish(CoreVersionChanged{Version: core.Previous, Previous: failed, RolledBack: true, Reason: reason})
+	return nil
+}
+
+// rollbackTrialCore rolls back a core that crashed before completing its trial period.
+// Called under CmdMutex, so it neither asks polkit for capabilities (the restart applies them) nor shows dialogs:
+// returns the message for the user if the previous version was restored, nil otherwise.
+func (ac *AppController) rollbackTrialCore(reason string) error {
+	core := ac.Settings.Get().Core
+	if !core.Trial || core.Previous == "" {
+		return nil
+	}
+	if err := ac.rollbackCore(fmt.Sprintf("crashed on first start: %s", reason), false); err != nil {
+		log.Printf("rollbackTrialCore: %v", err)
+		return nil
+	}
+	return fmt.Errorf("sing-box v%s crashed on first start (%s).\n\nRolled back to v%s; you can switch again in Core Dashboard → Versions.", core.Active, reason, core.Previous)
+}
+
+// confirmCoreTrial ends the trial of a new core once the process pid has run for coreTrialPeriod
+func (ac *AppController) confirmCoreTrial(pid int) {
+	select {
+	case <-ac.ctx.Done():
+		return
+	case <-time.After(coreTrialPeriod):
+	}
+	if !ac.RunningState.IsRunning() || getOurPID(ac) != pid {
+		return
+	}
+	if err := ac.Settings.Update(func(settings *Settings) { settings.Core.Trial = false }); err != nil {
+		log.Printf("confirmCoreTrial: %v", err)
+		return
+	}
+	log.Printf("confirmCoreTrial: sing-box v%s ran for %v, rollback is no longer automatic", ac.Settings.Get().Core.Active, coreTrialPeriod)
+}
+
+// DeleteCore removes an installed version that is not active
+func (ac *AppController) DeleteCore(version string) error {
+	if err := validateCoreVersion(version); err != nil {
+		return err
+	}
+	if ac.Settings.Get().Core.Active == version {
+		return fmt.Errorf("sing-box v%s is active and cannot be deleted", version)
+	}
+	if err := os.RemoveAll(filepath.Join(ac.CoresDir(), version)); err != nil {
+		return fmt.Errorf("failed to delete sing-box v%s: %w", version, err)
+	}
+	if ac.Settings.Get().Core.Previous == version {
+		if err := ac.Settings.Update(func(settings *Settings) { settings.Core.Previous = "" }); err != nil {
+			return err
+		}
+	}
+	log.Printf("DeleteCore: Deleted sing-box v%s", version)
+	return nil
+}
@@ -0,0 +1,179 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"singbox-launcher/internal/platform"
+)
+
+// newCoreStoreController creates a controller with an empty bin directory and a settings file
+func newCoreStoreController(t *testing.T) *AppController {
+	t.Helper()
+	execDir := t.TempDir()
+	binDir := platform.GetBinDir(execDir)
+	ac := &AppController{
+		ExecDir:     execDir,
+		SingboxPath: filepath.Join(binDir, platform.GetExecutableNames()),
+		Events:      NewEventBus(),
+		Settings:    NewSettingsStore(GetSettingsPath(binDir)),
+		Notifier:    NewRecordingNotifier(),
+	}
+	ac.RunningState = &RunningState{controller: ac}
+	return ac
+}
+
+// storeFakeCore stores a file with content as sing-box version
+func storeFakeCore(t *testing.T, ac *AppController, version, content string) {
+	t.Helper()
+	source := filepath.Join(t.TempDir(), "sing-box")
+	if err := os.WriteFile(source, []byte(content), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ac.StoreCore(source, version); err != nil {
+		t.Fatalf("StoreCore(%s): %v", version, err)
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// TestCoreStore_SwitchAndRollback tests listing, switching, rollback and deleting installed versions
+func TestCoreStore_SwitchAndRollback(t *testing.T) {
+	ac := newCoreStoreController(t)
+	storeFakeCore(t, ac, "1.11.15", "old")
+	storeFakeCore(t, ac, "1.12.12", "new")
+
+	var changes []CoreVersionChanged
+	Subscribe(ac.Events, func(e CoreVersionChanged) { changes = append(changes, e) })
+
+	if err := ac.ActivateCore("1.11.15"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ac.switchCore("1.12.12", true, true); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, ac.SingboxPath); got != "new" {
+		t.Errorf("Expected the new core in bin, got %q", got)
+	}
+	if core := ac.Settings.Get().Core; core.Active != "1.12.12" || core.Previous != "1.11.15" || !core.Trial {
+		t.Errorf("Unexpected core settings after switch: %+v", core)
+	}
+
+	cores, err := ac.InstalledCores()
+	if err != nil || len(cores) != 2 || cores[0].Version != "1.12.12" || !cores[0].Active || cores[1].Active {
+		t.Fatalf("Unexpected installed cores: %+v (%v)", cores, err)
+	}
+
+	if notice := ac.rollbackTrialCore("test crash"); notice == nil {
+		t.Fatal("Expected the trial core to be rolled back")
+	}
+	if got := readFile(t, ac.SingboxPath); got != "old" {
+		t.Errorf("Expected the old core in bin after rollback, got %q", got)
+	}
+	if core := ac.Settings.Get().Core; core.Active != "1.11.15" || core.Previous != "1.12.12" || core.Trial {
+		t.Errorf("Unexpected core settings after rollback: %+v", core)
+	}
+	if len(changes) != 2 || !changes[1].RolledBack || changes[1].Version != "1.11.15" {
+		t.Errorf("Unexpected CoreVersionChanged events: %+v", changes)
+	}
+	if errs := ac.Notifier.(*RecordingNotifier).Of(NotifyError); len(errs) != 0 {
+		t.Errorf("Expected no notification under CmdMutex, the caller shows the rollback, got %v", errs)
+	}
+	if ac.rollbackTrialCore("again") != nil {
+		t.Error("Expected no rollback outside of a trial")
+	}
+
+	if err := ac.DeleteCore("1.11.15"); err == nil {
+		t.Error("Expected an error when deleting the active version")
+	}
+	if err := ac.DeleteCore("1.12.12"); err != nil {
+		t.Fatal(err)
+	}
+	if core := ac.Settings.Get().Core; core.Previous != "" {
+		t.Errorf("Expected no previous version after deleting it, got %q", core.Previous)
+	}
+	if err := ac.ActivateCore("1.12.12"); err == nil {
+		t.Error("Expected an error when activating a deleted version")
+	}
+}
+
+// TestCoreStore_Guards tests invalid versions, modified copies and switching while running
+func TestCoreStore_Guards(t *testing.T) {
+	ac := newCoreStoreController(t)
+	if err := ac.StoreCore(ac.SingboxPath, "../evil"); err == nil {
+		t.Error("Expected an error for a version with a path")
+	}
+
+	storeFakeCore(t, ac, "1.12.12", "binary")
+	if err := os.WriteFile(ac.coreStorePath("1.12.12"), []byte("tampered"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ac.ActivateCore("1.12.12"); err == nil {
+		t.Error("Expected an error when activating a modified copy")
+	}
+
+	ac.RunningState.Set(true)
+	if err := ac.ActivateCore("1.12.12"); err != ErrCoreRunning {
+		t.Errorf("Expected ErrCoreRunning, got %v", err)
+	}
+}
+
+// TestEnsureCoreCapabilities tests re-applying capabilities after a switch with stand-ins for pkexec, setcap and getcap
+func TestEnsureCoreCapabilities(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("capabilities are Linux-specific")
+	}
+	tools := t.TempDir()
+	marker := filepath.Join(tools, "capabilities-set")
+	scripts := map[string]string{
+		"pkexec": "#!/bin/sh\nexec \"$@\"\n",
+		"setcap": "#!/bin/sh\n: > " + marker + "\n",
+		"getcap": "#!/bin/sh\n[ -f " + marker + " ] && echo \"$1 cap_net_admin,cap_net_bind_service=ep\"\n",
+	}
+	for name, script := range scripts {
+		if err := os.WriteFile(filepath.Join(tools, name), []byte(script), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("PATH", tools)
+
+	ac := newCoreStoreController(t)
+	storeFakeCore(t, ac, "1.12.12", "v1")
+	if err := ac.ActivateCore("1.12.12"); err != nil {
+		t.Fatalf("ActivateCore: %v", err)
+	}
+	if _, err := os.Stat(marker); err == nil {
+		t.Fatalf("capabilities set although the setting is off")
+	}
+
+	ac.Settings.Update(func(settings *Settings) { settings.Core.SetCapabilities = true })
+	if err := ac.EnsureCoreCapabilities(); err != nil {
+		t.Fatalf("EnsureCoreCapabilities: %v", err)
+	}
+	if _, err := os.Stat(marker); err != nil {
+		t.Fatalf("setcap was not run through pkexec")
+	}
+
+	// Отмена в диалоге polkit
+	os.Remove(marker)
+	os.WriteFile(filepath.Join(tools, "pkexec"), []byte("#!/bin/sh\nexit 126\n"), 0755)
+	if err := ac.EnsureCoreCapabilities(); err == nil || !strings.Contains(err.Error(), "cancelled") {
+		t.Errorf("expected a cancellation error, got %v", err)
+	}
+
+	// Без polkit
+	os.Remove(filepath.Join(tools, "pkexec"))
+	if err := ac.EnsureCoreCapabilities(); err == nil || !strings.Contains(err.Error(), "polkit is not available") {
+		t.Errorf("expected a polkit error, got %v", err)
+	}
+}
@@ -3,20 +3,17 @@ package core
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
-	"fyne.io/fyne/v2"
-
-	"singbox-launcher/internal/dialogs"
 	"singbox-launcher/internal/platform"
 )
 
@@ -77,8 +74,30 @@ type CoreVersionInfo struct {
 // FallbackVersion - фиксированная версия для использования, если не удается получить последнюю
 const FallbackVersion = "1.12.12"
 
-// GetLatestCoreVersion получает последнюю версию sing-box (с fallback на фиксированную версию)
+// GetLatestCoreVersion получает последнюю версию sing-box для выбранного канала (с fallback на фиксированную версию).
+// Закреплённая версия X.Y.Z возвращается без запроса к GitHub; pre-release канал и диапазоны (1.12.x)
+// выбираются из списка релизов.
 func (ac *AppController) GetLatestCoreVersion() (string, error) {
+	channel, pin := ac.CoreChannel()
+	if channel == CoreChannelPinned && isExactVersionSpec(pin) {
+		log.Printf("GetLatestCoreVersion: using pinned version %s", pin)
+		return pin, nil
+	}
+	if channel != CoreChannelStable {
+		version, err := ac.latestCoreVersionForChannel(channel, pin)
+		if err == nil {
+			return version, nil
+		}
+		if IsRateLimitError(err) {
+			return "", err
+		}
+		if channel == CoreChannelPinned && !MatchesVersionSpec(FallbackVersion, pin) {
+			return "", fmt.Errorf("failed to get a sing-box version matching %s: %w", pin, err)
+		}
+		log.Printf("GetLatestCoreVersion: %v, using fallback version %s", err, FallbackVersion)
+		return FallbackVersion, nil
+	}
+
 	sources := []struct {
 		name string
 		url  string
@@ -87,6 +106,7 @@ func (ac *AppController) GetLatestCoreVersion() (string, error) {
 		{"GitHub Mirror (ghproxy)", "https://ghproxy.com/https://api.github.com/repos/SagerNet/sing-box/releases/latest"},
 	}
 
+	var rateLimitErr error
 	for _, source := range sources {
 		log.Printf("Trying to get latest version from %s...", source.name)
 		version, err := ac.getLatestVersionFromURL(source.url)
@@ -95,6 +115,14 @@ func (ac *AppController) GetLatestCoreVersion() (string, error) {
 			return version, nil
 		}
 		log.Printf("Failed to get latest version from %s: %v", source.name, err)
+		if IsRateLimitError(err) && rateLimitErr == nil {
+			rateLimitErr = err
+		}
+	}
+
+	// GitHub доступен, но ограничил запросы: fallback-версия здесь только запутает, ждём сброса лимита
+	if rateLimitErr != nil {
+		return "", rateLimitErr
 	}
 
 	// Если GitHub недоступен, используем фиксированную версию для скачивания с SourceForge
@@ -103,7 +131,20 @@ func (ac *AppController) GetLatestCoreVersion() (string, error) {
 }
 
 // GetLatestLauncherVersion получает последнюю версию приложения из GitHub
+// Для канала pre-release версия выбирается из списка релизов.
 func (ac *AppController) GetLatestLauncherVersion() (string, error) {
+	if ac.LauncherChannel() == CoreChannelPrerelease {
+		releases, err := ac.fetchReleases(launcherReleasesURL)
+		if err != nil {
+			return "", fmt.Errorf("failed to get launcher releases: %w", err)
+		}
+		release, err := selectRelease(releases, CoreChannelPrerelease, "")
+		if err != nil {
+			return "", err
+		}
+		return release.TagName, nil
+	}
+
 	sources := []struct {
 		name string
 		url  string
@@ -136,9 +177,10 @@ func (ac *AppController) GetCachedLauncherVersion() string {
 // SetCachedLauncherVersion сохраняет версию launcher в кеш
 func (ac *AppController) SetCachedLauncherVersion(version string) {
 	ac.LauncherVersionCheckMutex.Lock()
-	defer ac.LauncherVersionCheckMutex.Unlock()
 	ac.LauncherVersionCheckCache = version
 	ac.LauncherVersionCheckCacheTime = time.Now()
+	ac.LauncherVersionCheckMutex.Unlock()
+	ac.Events.Publish(VersionAvailable{Component: VersionComponentLauncher, Version: version})
 }
 
 // CheckLauncherVersionOnStartup выполняет разовую проверку версии launcher при старте
@@ -210,9 +252,10 @@ func (ac *AppController) GetCachedVersion() string {
 // SetCachedVersion сохраняет версию в кеш
 func (ac *AppController) SetCachedVersion(version string) {
 	ac.VersionCheckMutex.Lock()
-	defer ac.VersionCheckMutex.Unlock()
 	ac.VersionCheckCache = version
 	ac.VersionCheckCacheTime = time.Now()
+	ac.VersionCheckMutex.Unlock()
+	ac.Events.Publish(VersionAvailable{Component: VersionComponentCore, Version: version})
 }
 
 // CheckVersionInBackground запускает фоновую проверку версии с логикой повторных попыток
@@ -248,6 +291,7 @@ func (ac *AppController) CheckVersionInBackground() {
 		verySlowInterval := 30 * time.Minute // Очень медленный интервал после многих попыток
 
 		attemptCount := 0
+		var rateLimitedUntil time.Time // После превышения лимита GitHub ждём его сброса, а не обычный интервал
 		for attemptCount < maxTotalAttempts {
 			// Проверяем кеш перед каждой попыткой - возможно версия уже получена другой горутиной
 			if !ac.ShouldCheckVersion() {
@@ -264,6 +308,9 @@ func (ac *AppController) CheckVersionInBackground() {
 			} else {
 				interval = verySlowInterval
 			}
+			if wait := time.Until(rateLimitedUntil); wait > interval {
+				interval = wait
+			}
 
 			// Ждем перед попыткой (кроме первой)
 			if attemptCount > 0 {
@@ -301,6 +348,10 @@ func (ac *AppController) CheckVersionInBackground() {
 
 			if err != nil {
 				log.Printf("CheckVersionInBackground: Attempt %d failed: %v", attemptCount, err)
+				var limited *RateLimitError
+				if errors.As(err, &limited) {
+					rateLimitedUntil = limited.Until
+				}
 			}
 		}
 
@@ -322,35 +373,13 @@ func (ac *AppController) getLatestVersionFromURLWithPrefix(url string, keepPrefi
 	ctx, cancel := context.WithTimeout(context.Background(), NetworkRequestTimeout)
 	defer cancel()
 
-	// Используем универсальный HTTP клиент
+	// Используем универсальный HTTP клиент; условный запрос с ETag (см. githubGet)
 	client := createHTTPClient(NetworkRequestTimeout)
 
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("Accept", "application/vnd.github.v3+json")
-	req.Header.Set("User-Agent", "singbox-launcher/1.0")
-
-	resp, err := client.Do(req)
+	body, err := ac.githubGet(ctx, client, url)
 	if err != nil {
-		// Проверяем тип ошибки
-		if IsNetworkError(err) {
-			return "", fmt.Errorf("network error: %s", GetNetworkErrorMessage(err))
-		}
 		return "", fmt.Errorf("check failed: %w", err)
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("check failed: HTTP %d", resp.StatusCode)
-	}
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
-	}
 
 	var release struct {
 		TagName string `json:"tag_name"`
@@ -372,23 +401,18 @@ func (ac *AppController) getLatestVersionFromURLWithPrefix(url string, keepPrefi
 // Запускает синхронную проверку версии и отображает диалог с информацией.
 func (ac *AppController) CheckForUpdates() {
 	// Показываем информационное сообщение о начале проверки
-	dialogs.ShowInfo(ac.MainWindow, "Checking for Updates", "Checking for updates...")
+	ac.Notifier.ShowInfo("Checking for Updates", "Checking for updates...")
 
 	// Запускаем проверку версии в фоне
 	go func() {
 		// Сбрасываем кеш, чтобы принудительно проверить версию
-		ac.VersionCheckMutex.Lock()
-		ac.VersionCheckCache = ""
-		ac.VersionCheckCacheTime = time.Time{}
-		ac.VersionCheckMutex.Unlock()
+		ac.ResetCoreVersionCache()
 
 		// Пытаемся получить последнюю версию
 		latest, err := ac.GetLatestCoreVersion()
 		if err != nil {
 			log.Printf("CheckForUpdates: Failed to get latest version: %v", err)
-			fyne.Do(func() {
-				dialogs.ShowError(ac.MainWindow, fmt.Errorf("Failed to check for updates: %v", err))
-			})
+			ac.Notifier.ShowError(fmt.Errorf("Failed to check for updates: %v", err))
 			return
 		}
 
@@ -398,9 +422,7 @@ func (ac *AppController) CheckForUpdates() {
 		// Получаем информацию о версиях
 		info := ac.GetCoreVersionInfo()
 		if info.Error != "" {
-			fyne.Do(func() {
-				dialogs.ShowError(ac.MainWindow, fmt.Errorf("Error checking version: %s", info.Error))
-			})
+			ac.Notifier.ShowError(fmt.Errorf("Error checking version: %s", info.Error))
 			return
 		}
 
@@ -409,15 +431,11 @@ func (ac *AppController) CheckForUpdates() {
 		if info.UpdateAvailable {
 			message = fmt.Sprintf("Update available!\n\nInstalled: %s\nLatest: %s\n\nYou can download the update from the Core tab.",
 				info.InstalledVersion, info.LatestVersion)
-			fyne.Do(func() {
-				dialogs.ShowInfo(ac.MainWindow, "Update Available", message)
-			})
+			ac.Notifier.ShowInfo("Update Available", message)
 		} else {
 			message = fmt.Sprintf("You are using the latest version.\n\nInstalled: %s\nLatest: %s",
 				info.InstalledVersion, info.LatestVersion)
-			fyne.Do(func() {
-				dialogs.ShowInfo(ac.MainWindow, "No Updates", message)
-			})
+			ac.Notifier.ShowInfo("No Updates", message)
 		}
 	}()
 }
@@ -445,15 +463,35 @@ func (ac *AppController) GetCoreVersionInfo() CoreVersionInfo {
 	}
 	info.LatestVersion = latest
 
-	// Сравниваем версии
-	info.UpdateAvailable = CompareVersions(installed, latest) < 0
+	// Сравниваем версии с учётом канала (закреплённая версия может означать откат)
+	info.UpdateAvailable = ac.CoreUpdateAvailable(installed, latest)
 
 	return info
 }
 
-// CompareVersions сравнивает две версии (формат X.Y.Z)
+// CompareVersions сравнивает две версии (формат X.Y.Z, допускается префикс "v" и суффикс pre-release "-beta.1")
 // Возвращает: -1 если v1 < v2, 0 если v1 == v2, 1 если v1 > v2
 func CompareVersions(v1, v2 string) int {
+	core1, pre1, _ := strings.Cut(strings.TrimPrefix(v1, "v"), "-")
+	core2, pre2, _ := strings.Cut(strings.TrimPrefix(v2, "v"), "-")
+	if c := compareVersionParts(core1, core2); c != 0 {
+		return c
+	}
+
+	// Pre-release предшествует релизу: 1.13.0-beta.1 < 1.13.0
+	switch {
+	case pre1 == pre2:
+		return 0
+	case pre1 == "":
+		return 1
+	case pre2 == "":
+		return -1
+	}
+	return compareVersionParts(pre1, pre2)
+}
+
+// compareVersionParts сравнивает части, разделённые точками: числа численно, остальное лексически
+func compareVersionParts(v1, v2 string) int {
 	parts1 := strings.Split(v1, ".")
 	parts2 := strings.Split(v2, ".")
 
@@ -463,19 +501,33 @@ func CompareVersions(v1, v2 string) int {
 	}
 
 	for i := 0; i < maxLen; i++ {
-		var num1, num2 int
+		var s1, s2 string
 		if i < len(parts1) {
-			fmt.Sscanf(parts1[i], "%d", &num1)
+			s1 = parts1[i]
 		}
 		if i < len(parts2) {
-			fmt.Sscanf(parts2[i], "%d", &num2)
+			s2 = parts2[i]
+		}
+		num1, err1 := strconv.Atoi(s1)
+		num2, err2 := strconv.Atoi(s2)
+		if s1 == "" {
+			num1, err1 = 0, nil
+		}
+		if s2 == "" {
+			num2, err2 = 0, nil
 		}
 
-		if num1 < num2 {
-			return -1
+		if err1 == nil && err2 == nil {
+			if num1 < num2 {
+				return -1
+			}
+			if num1 > num2 {
+				return 1
+			}
+			continue
 		}
-		if num1 > num2 {
-			return 1
+		if c := strings.Compare(s1, s2); c != 0 {
+			return c
 		}
 	}
 
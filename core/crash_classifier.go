@@ -0,0 +1,202 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// CrashReason is the classified cause of an unexpected sing-box exit.
+type CrashReason string
+
+const (
+	CrashReasonNone          CrashReason = ""
+	CrashReasonUnknown       CrashReason = "unknown"
+	CrashReasonConfigError   CrashReason = "config_error"
+	CrashReasonPortInUse     CrashReason = "port_in_use"
+	CrashReasonTunPermission CrashReason = "tun_permission"
+	CrashReasonPanic         CrashReason = "panic"
+)
+
+// crashLogTailSize is how many bytes from the end of sing-box.log are scanned to classify a crash
+const crashLogTailSize = 64 * 1024
+
+// Only the line that terminated sing-box is classified: a Go runtime panic or the FATAL
+// line sing-box prints when start-up fails. Errors logged earlier by a running instance
+// (a rejected connection, a failed rule-set download) say nothing about why it exited.
+var (
+	crashPanicMarkers = []string{"panic:", "fatal error:"}
+
+	// crashStartupStages are the start-up stages sing-box reports in its FATAL line
+	crashStartupStages = []string{"decode config", "initialize inbound", "start service"}
+
+	crashPortInUse = []string{
+		"address already in use",
+		"only one usage of each socket address",
+	}
+
+	// A TUN failure is recognised only when the failing stage is the TUN inbound itself
+	crashTunStage      = []string{"inbound/tun", "configure tun interface", "open /dev/net/tun", "wintun"}
+	crashTunPermission = []string{"operation not permitted", "permission denied", "access is denied"}
+)
+
+// CrashInfo describes a classified crash.
+type CrashInfo struct {
+	Reason CrashReason
+	// Line is the log line that led to the classification (empty for unknown crashes)
+	Line string
+}
+
+// Description returns a human-readable explanation of the reason
+func (r CrashReason) Description() string {
+	switch r {
+	case CrashReasonConfigError:
+		return "config error"
+	case CrashReasonPortInUse:
+		return "port already in use"
+	case CrashReasonTunPermission:
+		return "TUN permission or capability failure"
+	case CrashReasonPanic:
+		return "fatal panic"
+	case CrashReasonUnknown:
+		return "unknown error"
+	default:
+		return ""
+	}
+}
+
+// CrashPolicy defines how the monitor reacts to a crash of a given class.
+type CrashPolicy struct {
+	MaxRetries int           // 0 means do not restart automatically
+	BaseDelay  time.Duration // delay before the first restart
+	MaxDelay   time.Duration // upper bound for the exponential backoff
+}
+
+// CrashPolicyFor returns the restart policy for the crash reason.
+// Config errors are never retried: sing-box will fail the same way until the config is fixed.
+func CrashPolicyFor(reason CrashReason) CrashPolicy {
+	switch reason {
+	case CrashReasonConfigError:
+		return CrashPolicy{MaxRetries: 0}
+	case CrashReasonTunPermission:
+		// Usually requires user action, but a stale interface may disappear after a short wait
+		return CrashPolicy{MaxRetries: 2, BaseDelay: 3 * time.Second, MaxDelay: 10 * time.Second}
+	case CrashReasonPortInUse:
+		return CrashPolicy{MaxRetries: 3, BaseDelay: 3 * time.Second, MaxDelay: 30 * time.Second}
+	case CrashReasonPanic:
+		return CrashPolicy{MaxRetries: 3, BaseDelay: 2 * time.Second, MaxDelay: 30 * time.Second}
+	default:
+		// Unknown causes are often transient (network hiccups) and deserve more attempts
+		return CrashPolicy{MaxRetries: 5, BaseDelay: 2 * time.Second, MaxDelay: 60 * time.Second}
+	}
+}
+
+// Backoff returns the delay before restart attempt number attempt (1-based).
+// The delay doubles with every attempt up to MaxDelay; jitter in [0,1) spreads it by ±20%.
+func (p CrashPolicy) Backoff(attempt int, jitter float64) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	delay := p.BaseDelay
+	for i := 1; i < attempt && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return time.Duration(float64(delay) * (0.8 + 0.4*jitter))
+}
+
+// ClassifyCrashLog classifies a crash from sing-box output lines.
+// The most recent FATAL or panic line is located first and only that line is classified;
+// a log without one (sing-box was killed or exited quietly) is an unknown crash.
+func ClassifyCrashLog(lines []string) CrashInfo {
+	line := findFatalLine(lines)
+	if line == "" {
+		return CrashInfo{Reason: CrashReasonUnknown}
+	}
+	lower := strings.ToLower(line)
+	if containsAny(lower, crashPanicMarkers) {
+		return CrashInfo{Reason: CrashReasonPanic, Line: line}
+	}
+	switch {
+	case strings.Contains(lower, "decode config"):
+		return CrashInfo{Reason: CrashReasonConfigError, Line: line}
+	case !containsAny(lower, crashStartupStages):
+		// FATAL, but not a start-up failure we know how to handle
+		return CrashInfo{Reason: CrashReasonUnknown, Line: line}
+	case containsAny(lower, crashPortInUse):
+		return CrashInfo{Reason: CrashReasonPortInUse, Line: line}
+	case containsAny(lower, crashTunStage) && containsAny(lower, crashTunPermission):
+		return CrashInfo{Reason: CrashReasonTunPermission, Line: line}
+	case strings.Contains(lower, "initialize inbound"):
+		// Inbounds are created from the config, so a failure here is a config problem
+		return CrashInfo{Reason: CrashReasonConfigError, Line: line}
+	default:
+		return CrashInfo{Reason: CrashReasonUnknown, Line: line}
+	}
+}
+
+// findFatalLine returns the last line that terminated sing-box: its FATAL log entry
+// or the first line of a Go panic. Returns "" if there is none.
+func findFatalLine(lines []string) string {
+	for i := len(lines) - 1; i >= 0; i-- {
+		line := strings.TrimSpace(lines[i])
+		if strings.HasPrefix(line, "FATAL") || strings.Contains(line, " FATAL") || strings.Contains(line, "FATAL[") ||
+			strings.HasPrefix(line, "panic:") || strings.HasPrefix(line, "fatal error:") {
+			return line
+		}
+	}
+	return ""
+}
+
+// containsAny reports whether s contains any of the substrings
+func containsAny(s string, substrings []string) bool {
+	for _, sub := range substrings {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClassifyCrashLogFile classifies a crash from the sing-box log written since offset.
+// If the file is smaller than offset (it was rotated), it is read from the beginning.
+// At most crashLogTailSize bytes from the end are scanned.
+func ClassifyCrashLogFile(path string, offset int64) CrashInfo {
+	file, err := os.Open(path)
+	if err != nil {
+		return CrashInfo{Reason: CrashReasonUnknown}
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return CrashInfo{Reason: CrashReasonUnknown}
+	}
+	size := info.Size()
+	if offset < 0 || offset > size {
+		offset = 0
+	}
+	if size-offset > crashLogTailSize {
+		offset = size - crashLogTailSize
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return CrashInfo{Reason: CrashReasonUnknown}
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return CrashInfo{Reason: CrashReasonUnknown}
+	}
+
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), crashLogTailSize)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return ClassifyCrashLog(lines)
+}
@@ -0,0 +1,140 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestClassifyCrashLog tests crash classification from sing-box output
+func TestClassifyCrashLog(t *testing.T) {
+	tests := []struct {
+		name     string
+		lines    []string
+		expected CrashReason
+	}{
+		{
+			name:     "Config decode error",
+			lines:    []string{"INFO[0000] sing-box started", "FATAL[0000] decode config at ./config.json: outbounds[2].type: unknown type"},
+			expected: CrashReasonConfigError,
+		},
+		{
+			name:     "Port in use",
+			lines:    []string{"FATAL[0000] start service: start inbound/mixed[mixed-in]: listen tcp 127.0.0.1:7890: bind: address already in use"},
+			expected: CrashReasonPortInUse,
+		},
+		{
+			name:     "Port in use on Windows",
+			lines:    []string{"FATAL[0000] start service: listen tcp 127.0.0.1:9090: bind: Only one usage of each socket address (protocol/network address/port) is normally permitted."},
+			expected: CrashReasonPortInUse,
+		},
+		{
+			name:     "TUN permission",
+			lines:    []string{"FATAL[0000] start service: start inbound/tun[tun-in]: configure tun interface: operation not permitted"},
+			expected: CrashReasonTunPermission,
+		},
+		{
+			name:     "Panic with stack trace",
+			lines:    []string{"panic: runtime error: invalid memory address or nil pointer dereference", "", "goroutine 1 [running]:", "main.main()", "\t/src/main.go:10 +0x1d"},
+			expected: CrashReasonPanic,
+		},
+		{
+			name:     "Invalid inbound",
+			lines:    []string{"FATAL[0000] initialize inbound[1]: missing listen port"},
+			expected: CrashReasonConfigError,
+		},
+		{
+			name:     "Only the FATAL line is classified",
+			lines:    []string{"ERROR[0005] inbound/mixed[mixed-in]: address already in use", "FATAL[0000] decode config at ./config.json: unknown field"},
+			expected: CrashReasonConfigError,
+		},
+		{
+			name: "Errors before exit are ignored",
+			lines: []string{
+				"ERROR[0010] outbound/vless[proxy]: dial tcp: operation not permitted",
+				"ERROR[0011] rule-set[geoip]: invalid character '<' looking for beginning of value",
+				"ERROR[0012] open /etc/sing-box/cache.db: permission denied",
+			},
+			expected: CrashReasonUnknown,
+		},
+		{
+			name:     "Permission error outside TUN",
+			lines:    []string{"FATAL[0000] start service: initialize cache-file: open cache.db: permission denied"},
+			expected: CrashReasonUnknown,
+		},
+		{
+			name:     "Timestamped FATAL line",
+			lines:    []string{"+0300 2026-01-02 10:00:00 FATAL[0000] start service: start inbound/tun[tun-in]: open /dev/net/tun: permission denied"},
+			expected: CrashReasonTunPermission,
+		},
+		{
+			name:     "Unknown",
+			lines:    []string{"INFO[0000] sing-box started", "WARN[0010] connection reset"},
+			expected: CrashReasonUnknown,
+		},
+		{
+			name:     "Empty log",
+			lines:    nil,
+			expected: CrashReasonUnknown,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := ClassifyCrashLog(tt.lines)
+			if info.Reason != tt.expected {
+				t.Errorf("Expected reason %q, got %q (line: %q)", tt.expected, info.Reason, info.Line)
+			}
+			if tt.expected != CrashReasonUnknown && info.Line == "" {
+				t.Error("Expected matched line to be set")
+			}
+		})
+	}
+}
+
+// TestClassifyCrashLogFile tests that only output written after the offset is considered
+func TestClassifyCrashLogFile(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "sing-box.log")
+	previousRun := "FATAL[0000] decode config at ./config.json: unknown field\n"
+	currentRun := "INFO[0000] started\nFATAL[0001] start service: listen tcp :7890: bind: address already in use\n"
+	if err := os.WriteFile(logPath, []byte(previousRun+currentRun), 0644); err != nil {
+		t.Fatalf("Failed to write log: %v", err)
+	}
+
+	if info := ClassifyCrashLogFile(logPath, int64(len(previousRun))); info.Reason != CrashReasonPortInUse {
+		t.Errorf("Expected port_in_use for current run, got %q", info.Reason)
+	}
+	// Offset beyond file size (log was rotated) - read from the beginning
+	if info := ClassifyCrashLogFile(logPath, 1<<20); info.Reason != CrashReasonPortInUse {
+		t.Errorf("Expected port_in_use after rotation, got %q", info.Reason)
+	}
+	if info := ClassifyCrashLogFile(filepath.Join(t.TempDir(), "missing.log"), 0); info.Reason != CrashReasonUnknown {
+		t.Errorf("Expected unknown for missing log, got %q", info.Reason)
+	}
+}
+
+// TestCrashPolicy tests per-class restart policies and backoff
+func TestCrashPolicy(t *testing.T) {
+	if p := CrashPolicyFor(CrashReasonConfigError); p.MaxRetries != 0 {
+		t.Errorf("Config errors must not be retried, got %d retries", p.MaxRetries)
+	}
+	if CrashPolicyFor(CrashReasonUnknown).MaxRetries <= CrashPolicyFor(CrashReasonPanic).MaxRetries {
+		t.Error("Unknown (possibly transient) crashes should get more attempts than panics")
+	}
+
+	p := CrashPolicy{MaxRetries: 5, BaseDelay: 2 * time.Second, MaxDelay: 10 * time.Second}
+	expected := []time.Duration{2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second}
+	for i, want := range expected {
+		// jitter 0.5 means no spread
+		if got := p.Backoff(i+1, 0.5); got != want {
+			t.Errorf("Backoff(%d) = %v, expected %v", i+1, got, want)
+		}
+	}
+	if got := p.Backoff(1, 0); got != 1600*time.Millisecond {
+		t.Errorf("Expected -20%% jitter, got %v", got)
+	}
+	if got := p.Backoff(1, 0.999); got > 2400*time.Millisecond {
+		t.Errorf("Jitter must not exceed +20%%, got %v", got)
+	}
+}
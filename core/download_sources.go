@@ -0,0 +1,157 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// downloadRetryMaxDelay caps the delay between attempts
+	downloadRetryMaxDelay = 30 * time.Second
+	// partialSuffix marks a download that can be resumed with a Range request
+	partialSuffix = ".part"
+)
+
+// downloadRetryBaseDelay is the delay before the second attempt; it doubles with every attempt (shortened in tests)
+var downloadRetryBaseDelay = 2 * time.Second
+
+// httpStatusError is an unexpected HTTP status of a download
+type httpStatusError struct {
+	Code int
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("HTTP %d", e.Code)
+}
+
+// isRetryableDownloadError reports whether another attempt at the same source may succeed.
+// Client errors like 404 will not change, so the next source is tried instead.
+func isRetryableDownloadError(err error) bool {
+	var statusErr *httpStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.Code >= 500 || statusErr.Code == http.StatusTooManyRequests || statusErr.Code == http.StatusRequestTimeout
+	}
+	return true
+}
+
+// downloadRetryDelay returns the backoff before attempt+1
+func downloadRetryDelay(attempt int) time.Duration {
+	delay := downloadRetryBaseDelay << (attempt - 1)
+	if delay <= 0 || delay > downloadRetryMaxDelay {
+		return downloadRetryMaxDelay
+	}
+	return delay
+}
+
+// downloadSource is one place a file can be downloaded from
+type downloadSource struct {
+	Name string // Shown in progress messages (host of the URL)
+	URL  string
+}
+
+// downloadSources expands the configured mirrors for rawURL, skipping duplicates:
+//   - "github" is rawURL itself (the GitHub release URL for sing-box, the vendor URL for wintun.dll);
+//   - "sourceforge" is the SourceForge mirror of a sing-box GitHub release file (other files skip it);
+//   - anything else is a URL template: {url} is rawURL, {version} and {file} are taken from a GitHub release URL.
+//
+// rawURL is used when no mirror applies to it.
+func downloadSources(rawURL string, mirrors []string, version, fileName string) []downloadSource {
+	var urls []string
+	for _, mirror := range mirrors {
+		mirror = strings.TrimSpace(mirror)
+		switch {
+		case mirror == "":
+			continue
+		case strings.EqualFold(mirror, MirrorGitHub):
+			urls = append(urls, rawURL)
+		case strings.EqualFold(mirror, MirrorSourceForge):
+			// SourceForge mirrors sing-box releases only
+			if version != "" && fileName != "" && strings.Contains(rawURL, "/SagerNet/sing-box/") {
+				urls = append(urls, fmt.Sprintf("https://sourceforge.net/projects/sing-box.mirror/files/v%s/%s/download", version, fileName))
+			}
+		default:
+			needsRelease := strings.Contains(mirror, "{version}") || strings.Contains(mirror, "{file}")
+			if needsRelease && (version == "" || fileName == "") {
+				continue
+			}
+			urls = append(urls, strings.NewReplacer("{url}", rawURL, "{version}", version, "{file}", fileName).Replace(mirror))
+		}
+	}
+	if len(urls) == 0 {
+		urls = []string{rawURL}
+	}
+
+	var sources []downloadSource
+	seen := make(map[string]bool)
+	for _, u := range urls {
+		if seen[u] {
+			continue
+		}
+		seen[u] = true
+		name := u
+		if parsed, err := url.Parse(u); err == nil && parsed.Host != "" {
+			name = parsed.Host
+		}
+		sources = append(sources, downloadSource{Name: name, URL: u})
+	}
+	return sources
+}
+
+// downloadProxy returns the proxy for downloads according to the settings (nil for direct) and a description for logs.
+// The sing-box proxy is only used while sing-box is running; otherwise downloads go direct.
+func (ac *AppController) downloadProxy() (*url.URL, string, error) {
+	setting := strings.TrimSpace(ac.Settings.Get().Downloads.Proxy)
+	switch setting {
+	case "":
+		return nil, "direct", nil
+	case DownloadProxySingBox:
+		if ac.RunningState == nil || !ac.RunningState.IsRunning() {
+			log.Println("downloadProxy: sing-box is not running, downloading directly")
+			return nil, "direct (sing-box is not running)", nil
+		}
+		inbound, err := FindLocalProxyInbound(ac.ConfigPath)
+		if err != nil {
+			return nil, "", fmt.Errorf("cannot download through sing-box: %w", err)
+		}
+		return inbound.ProxyURL(), fmt.Sprintf("sing-box %s inbound %s", inbound.Type, inbound.Address()), nil
+	}
+	proxyURL, err := ParseDownloadProxy(setting)
+	if err != nil {
+		return nil, "", err
+	}
+	return proxyURL, proxyURL.Redacted(), nil
+}
+
+// ParseDownloadProxy validates a user-specified proxy URL (http, https, socks5, socks5h)
+func ParseDownloadProxy(value string) (*url.URL, error) {
+	proxyURL, err := url.Parse(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid download proxy %q: %w", value, err)
+	}
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("invalid download proxy %q: expected http://, https:// or socks5:// URL", value)
+	}
+	if proxyURL.Host == "" {
+		return nil, fmt.Errorf("invalid download proxy %q: missing host", value)
+	}
+	return proxyURL, nil
+}
+
+// downloadHTTPClient creates an HTTP client for downloads that uses the configured proxy
+func (ac *AppController) downloadHTTPClient(timeout time.Duration) (*http.Client, error) {
+	proxyURL, via, err := ac.downloadProxy()
+	if err != nil {
+		return nil, err
+	}
+	if proxyURL != nil {
+		log.Printf("downloadHTTPClient: using proxy %s", via)
+	}
+	return createHTTPClientWithProxy(timeout, proxyURL), nil
+}
@@ -0,0 +1,128 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestDownloadSources tests expansion of the built-in mirrors and URL templates
+func TestDownloadSources(t *testing.T) {
+	coreURL := "https://github.com/SagerNet/sing-box/releases/download/v1.12.12/sing-box-1.12.12-linux-amd64.tar.gz"
+	mirrors := []string{"github", "https://mirror.example/{url}", "sourceforge", "https://dl.example/{version}/{file}", "github"}
+
+	sources := downloadSources(coreURL, mirrors, "1.12.12", "sing-box-1.12.12-linux-amd64.tar.gz")
+	want := []string{
+		coreURL,
+		"https://mirror.example/" + coreURL,
+		"https://sourceforge.net/projects/sing-box.mirror/files/v1.12.12/sing-box-1.12.12-linux-amd64.tar.gz/download",
+		"https://dl.example/1.12.12/sing-box-1.12.12-linux-amd64.tar.gz",
+	}
+	if len(sources) != len(want) {
+		t.Fatalf("Expected %d sources, got %+v", len(want), sources)
+	}
+	for i, source := range sources {
+		if source.URL != want[i] {
+			t.Errorf("Source %d: expected %s, got %s", i, want[i], source.URL)
+		}
+	}
+	if sources[1].Name != "mirror.example" {
+		t.Errorf("Expected the host as source name, got %q", sources[1].Name)
+	}
+
+	// wintun.dll is not a GitHub release: release mirrors do not apply
+	wintunURL := "https://www.wintun.net/builds/wintun-0.14.1.zip"
+	sources = downloadSources(wintunURL, []string{"sourceforge", "https://dl.example/{version}/{file}"}, "", "")
+	if len(sources) != 1 || sources[0].URL != wintunURL {
+		t.Errorf("Expected fallback to the original URL, got %+v", sources)
+	}
+}
+
+// TestDownloadFile_ResumeAndRetry tests that a failed attempt is retried and resumed from the partial file
+func TestDownloadFile_ResumeAndRetry(t *testing.T) {
+	downloadRetryBaseDelay = 10 * time.Millisecond
+	defer func() { downloadRetryBaseDelay = 2 * time.Second }()
+
+	content := bytes.Repeat([]byte("sing-box"), 4096)
+	var requests int32
+	var lastRange atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lastRange.Store(r.Header.Get("Range"))
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		http.ServeContent(w, r, "archive", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	destPath := filepath.Join(t.TempDir(), "archive.tar.gz")
+	half := len(content) / 2
+	if err := os.WriteFile(destPath+partialSuffix, content[:half], 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ac := &AppController{}
+	progress := make(chan DownloadProgress, 1024)
+	if err := ac.downloadFile(context.Background(), server.URL+"/archive.tar.gz", destPath, progress); err != nil {
+		t.Fatalf("downloadFile: %v", err)
+	}
+	close(progress)
+
+	got, err := os.ReadFile(destPath)
+	if err != nil || !bytes.Equal(got, content) {
+		t.Fatalf("Downloaded content differs (%d of %d bytes, %v)", len(got), len(content), err)
+	}
+	if r, _ := lastRange.Load().(string); !strings.HasPrefix(r, "bytes=") {
+		t.Errorf("Expected a Range request to resume, got %q", r)
+	}
+	if _, err := os.Stat(destPath + partialSuffix); !os.IsNotExist(err) {
+		t.Errorf("Expected the partial file to be renamed, got %v", err)
+	}
+	retries := 0
+	for p := range progress {
+		if p.Status == "retrying" {
+			retries++
+		}
+	}
+	if retries != 1 {
+		t.Errorf("Expected one retry report, got %d", retries)
+	}
+}
+
+// TestFindLocalProxyInbound tests that mixed inbounds are preferred and wildcard listen addresses become loopback
+func TestFindLocalProxyInbound(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	config := `{
+  // comments are allowed
+  "inbounds": [
+    {"type": "tun", "tag": "tun-in"},
+    {"type": "socks", "tag": "socks-in", "listen": "127.0.0.1", "listen_port": 1080},
+    {"type": "mixed", "tag": "mixed-in", "listen": "0.0.0.0", "listen_port": 7890}
+  ]
+}`
+	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	inbound, err := FindLocalProxyInbound(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inbound.Tag != "mixed-in" || inbound.ProxyURL().String() != "http://127.0.0.1:7890" {
+		t.Errorf("Unexpected inbound: %+v (%s)", inbound, inbound.ProxyURL())
+	}
+
+	if err := os.WriteFile(configPath, []byte(`{"inbounds": [{"type": "tun"}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := FindLocalProxyInbound(configPath); err == nil {
+		t.Error("Expected an error for a config without proxy inbounds")
+	}
+}
@@ -0,0 +1,188 @@
+package core
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// Event is a message published on the EventBus. Each event type is a small struct in events.go;
+// EventName identifies the type, so it must be implemented on the value receiver.
+type Event interface {
+	EventName() string
+}
+
+// SubscribeOption configures a subscription
+type SubscribeOption func(*subscription)
+
+// WithCoalesce delivers bursty events at most once per interval: the first event starts
+// the interval and only the latest event published during it is delivered when it ends.
+func WithCoalesce(interval time.Duration) SubscribeOption {
+	return func(s *subscription) { s.coalesce = interval }
+}
+
+// WithReplay delivers the last published event (of the subscribed types) right away,
+// so a subscriber created late starts from the current state.
+func WithReplay() SubscribeOption {
+	return func(s *subscription) { s.replay = true }
+}
+
+// subscription is one handler registered for one or more event types
+type subscription struct {
+	id       int
+	handler  func(Event)
+	coalesce time.Duration
+	replay   bool
+
+	mu      sync.Mutex
+	pending Event       // latest event waiting for the coalesce timer
+	timer   *time.Timer // running coalesce timer (nil when idle)
+	closed  bool
+}
+
+// publishedEvent is the last event of a type with its publication order
+type publishedEvent struct {
+	event Event
+	seq   uint64
+}
+
+// EventBus is a typed publish/subscribe bus connecting core with the UI, the tray and the CLI.
+// Handlers run in the publishing goroutine (or the coalesce timer goroutine), never under bus locks;
+// UI subscribers must switch to the UI thread themselves.
+type EventBus struct {
+	mu          sync.Mutex
+	subscribers map[string][]*subscription
+	last        map[string]publishedEvent
+	seq         uint64
+	nextID      int
+}
+
+// NewEventBus creates an empty bus
+func NewEventBus() *EventBus {
+	return &EventBus{
+		subscribers: make(map[string][]*subscription),
+		last:        make(map[string]publishedEvent),
+	}
+}
+
+// Subscribe registers handler for events of type E and returns a function that removes it.
+func Subscribe[E Event](bus *EventBus, handler func(E), opts ...SubscribeOption) (unsubscribe func()) {
+	var zero E
+	return bus.SubscribeEvents([]Event{zero}, func(e Event) {
+		if typed, ok := e.(E); ok {
+			handler(typed)
+		}
+	}, opts...)
+}
+
+// SubscribeEvents registers one handler for several event types (given as zero values, e.g. CoreStarted{}).
+// Coalescing and replay apply across all of them: replay delivers the most recent one.
+func (b *EventBus) SubscribeEvents(events []Event, handler func(Event), opts ...SubscribeOption) (unsubscribe func()) {
+	sub := &subscription{handler: handler}
+	for _, opt := range opts {
+		opt(sub)
+	}
+
+	b.mu.Lock()
+	b.nextID++
+	sub.id = b.nextID
+	var replay publishedEvent
+	for _, e := range events {
+		name := e.EventName()
+		b.subscribers[name] = append(b.subscribers[name], sub)
+		if last, ok := b.last[name]; ok && last.seq > replay.seq {
+			replay = last
+		}
+	}
+	b.mu.Unlock()
+
+	if sub.replay && replay.event != nil {
+		b.deliver(sub, replay.event)
+	}
+
+	return func() {
+		b.mu.Lock()
+		for _, e := range events {
+			name := e.EventName()
+			subs := b.subscribers[name]
+			for i, s := range subs {
+				if s.id == sub.id {
+					b.subscribers[name] = append(subs[:i:i], subs[i+1:]...)
+					break
+				}
+			}
+		}
+		b.mu.Unlock()
+
+		sub.mu.Lock()
+		sub.closed = true
+		if sub.timer != nil {
+			sub.timer.Stop()
+			sub.timer = nil
+		}
+		sub.mu.Unlock()
+	}
+}
+
+// Publish sends e to every subscriber of its type and remembers it for replay.
+// Publishing on a nil bus does nothing (controllers built by hand in tests have no bus).
+func (b *EventBus) Publish(e Event) {
+	if b == nil {
+		return
+	}
+	name := e.EventName()
+	b.mu.Lock()
+	b.seq++
+	b.last[name] = publishedEvent{event: e, seq: b.seq}
+	subs := append([]*subscription(nil), b.subscribers[name]...)
+	b.mu.Unlock()
+
+	for _, sub := range subs {
+		if sub.coalesce > 0 {
+			b.schedule(sub, e)
+		} else {
+			b.deliver(sub, e)
+		}
+	}
+}
+
+// Last returns the last published event of the same type as sample (nil if none)
+func (b *EventBus) Last(sample Event) Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.last[sample.EventName()].event
+}
+
+// schedule stores e as the pending event of a coalescing subscriber and starts its timer if idle
+func (b *EventBus) schedule(sub *subscription, e Event) {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	if sub.closed {
+		return
+	}
+	sub.pending = e
+	if sub.timer != nil {
+		return
+	}
+	sub.timer = time.AfterFunc(sub.coalesce, func() {
+		sub.mu.Lock()
+		pending := sub.pending
+		sub.pending = nil
+		sub.timer = nil
+		closed := sub.closed
+		sub.mu.Unlock()
+		if !closed && pending != nil {
+			b.deliver(sub, pending)
+		}
+	})
+}
+
+// deliver calls the handler; a panicking subscriber must not break the publisher
+func (b *EventBus) deliver(sub *subscription, e Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("EventBus: subscriber of %s panicked: %v", e.EventName(), r)
+		}
+	}()
+	sub.handler(e)
+}
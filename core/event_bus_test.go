@@ -0,0 +1,100 @@
+package core
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestEventBus_Subscribers tests typed delivery to several subscribers and unsubscribing
+func TestEventBus_Subscribers(t *testing.T) {
+	bus := NewEventBus()
+	var first, second []string
+	unsubscribe := Subscribe(bus, func(e ProxySwitched) { first = append(first, e.Proxy) })
+	Subscribe(bus, func(e ProxySwitched) { second = append(second, e.Proxy) })
+	stopped := 0
+	Subscribe(bus, func(CoreStopped) { stopped++ })
+
+	bus.Publish(ProxySwitched{Group: "proxy-out", Proxy: "a"})
+	unsubscribe()
+	bus.Publish(ProxySwitched{Group: "proxy-out", Proxy: "b"})
+
+	if len(first) != 1 || first[0] != "a" {
+		t.Errorf("Expected first subscriber to get [a], got %v", first)
+	}
+	if len(second) != 2 || second[1] != "b" {
+		t.Errorf("Expected second subscriber to get [a b], got %v", second)
+	}
+	if stopped != 0 {
+		t.Errorf("Expected no CoreStopped events, got %d", stopped)
+	}
+}
+
+// TestEventBus_Replay tests that replay delivers the latest event of the subscribed types only when asked
+func TestEventBus_Replay(t *testing.T) {
+	bus := NewEventBus()
+	bus.Publish(CoreStarted{})
+	bus.Publish(CoreStopped{})
+	bus.Publish(ParserProgress{Progress: 50})
+
+	var replayed []Event
+	bus.SubscribeEvents(CoreStateEvents, func(e Event) { replayed = append(replayed, e) }, WithReplay())
+	if len(replayed) != 1 || replayed[0] != (CoreStopped{}) {
+		t.Errorf("Expected replay of CoreStopped, got %v", replayed)
+	}
+
+	calls := 0
+	Subscribe(bus, func(ParserProgress) { calls++ })
+	if calls != 0 {
+		t.Errorf("Expected no replay without WithReplay, got %d calls", calls)
+	}
+	if last, ok := bus.Last(ParserProgress{}).(ParserProgress); !ok || last.Progress != 50 {
+		t.Errorf("Expected last progress 50, got %v", bus.Last(ParserProgress{}))
+	}
+}
+
+// TestEventBus_Coalesce tests that a burst is delivered once with its latest event
+func TestEventBus_Coalesce(t *testing.T) {
+	bus := NewEventBus()
+	var mu sync.Mutex
+	var got []ParserProgress
+	done := make(chan struct{}, 1)
+	Subscribe(bus, func(e ParserProgress) {
+		mu.Lock()
+		got = append(got, e)
+		mu.Unlock()
+		done <- struct{}{}
+	}, WithCoalesce(20*time.Millisecond))
+
+	for i := 1; i <= 10; i++ {
+		bus.Publish(ParserProgress{Progress: float64(i * 10)})
+	}
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Coalesced event was not delivered")
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(got) != 1 || got[0].Progress != 100 {
+		t.Errorf("Expected one delivery of progress 100, got %v", got)
+	}
+}
+
+// TestEventBus_PanickingSubscriber tests that a panic in one handler does not stop the others
+func TestEventBus_PanickingSubscriber(t *testing.T) {
+	bus := NewEventBus()
+	Subscribe(bus, func(CoreStarted) { panic("boom") })
+	delivered := false
+	Subscribe(bus, func(CoreStarted) { delivered = true })
+
+	bus.Publish(CoreStarted{})
+	if !delivered {
+		t.Error("Expected the second subscriber to get the event")
+	}
+
+	var nilBus *EventBus
+	nilBus.Publish(CoreStarted{}) // must not panic
+}
@@ -0,0 +1,153 @@
+package core
+
+import "time"
+
+// Events published on AppController.Events. Publishers are in core; the dashboard, the Servers tab,
+// the tray and the notifier bridge subscribe to them.
+
+// CoreStarted is published when sing-box is running
+type CoreStarted struct{}
+
+// CoreStopped is published when sing-box is not running (stopped by the user, exited or crashed)
+type CoreStopped struct{}
+
+// CoreCrashed is published after an unexpected sing-box exit, after CoreStopped
+type CoreCrashed struct {
+	Reason      CrashReason `json:"reason"`
+	Detail      string      `json:"detail,omitempty"` // Log line that led to Reason
+	Attempt     int         `json:"attempt"`          // Consecutive crash number
+	MaxAttempts int         `json:"max_attempts"`     // Restart limit of the policy for Reason
+	Restarting  bool        `json:"restarting"`       // An automatic restart is scheduled
+}
+
+// CoreRecovered is published when sing-box has run long enough after a crash restart and the crash counter is reset
+type CoreRecovered struct{}
+
+// ConfigUpdated is published after config.json was rewritten (parser, wizard)
+type ConfigUpdated struct {
+	ConfigPath string `json:"config_path"`
+}
+
+// ParserProgress reports subscription update progress in percent (0-100); a negative value means the update failed
+type ParserProgress struct {
+	Progress float64 `json:"progress"`
+	Status   string  `json:"status"`
+}
+
+// ProxiesLoaded is published when the proxies of the selected group were (re)loaded from the Clash API
+type ProxiesLoaded struct {
+	Group  string `json:"group"`
+	Active string `json:"active"`
+	Count  int    `json:"count"`
+}
+
+// ProxySwitched is published when the active proxy of a selector group changed
+type ProxySwitched struct {
+	Group string `json:"group"`
+	Proxy string `json:"proxy"`
+}
+
+// VersionAvailable is published when the latest version of a component was fetched
+type VersionAvailable struct {
+	Component string `json:"component"` // VersionComponentCore or VersionComponentLauncher
+	Version   string `json:"version"`
+}
+
+// Components of VersionAvailable
+const (
+	VersionComponentCore     = "sing-box"
+	VersionComponentLauncher = "launcher"
+)
+
+// GitHubRateLimited is published when GitHub (or a mirror) refused a version check because of its rate limit
+type GitHubRateLimited struct {
+	Host  string    `json:"host"`
+	Until time.Time `json:"until"`
+}
+
+// CoreVersionChanged is published when another installed sing-box version became active
+type CoreVersionChanged struct {
+	Version    string `json:"version"`
+	Previous   string `json:"previous,omitempty"`
+	RolledBack bool   `json:"rolled_back"`      // Version was restored because Previous failed
+	Reason     string `json:"reason,omitempty"` // Why Previous was rolled back
+}
+
+// ProfileChanged is published after switching to another configuration profile
+type ProfileChanged struct {
+	Profile string `json:"profile"`
+}
+
+// ProfilesUpdated is published when a profile was created or deleted
+type ProfilesUpdated struct{}
+
+// ClashModeChanged is published when the Clash mode or the list of modes changed
+type ClashModeChanged struct {
+	Mode string `json:"mode"`
+}
+
+// LatencyUpdated is published on new delay results and when latency view options change
+type LatencyUpdated struct {
+	Done bool `json:"done"` // The test run finished (or only options changed)
+}
+
+// TrafficUpdated is published on new traffic/memory samples from the Clash API
+type TrafficUpdated struct {
+	TrayStale bool `json:"-"` // The speed shown in the tray menu changed (throttled)
+}
+
+// TrayGroupsUpdated is published when the groups shown in the tray menu were refreshed
+type TrayGroupsUpdated struct{}
+
+func (CoreStarted) EventName() string        { return "core.started" }
+func (CoreStopped) EventName() string        { return "core.stopped" }
+func (CoreCrashed) EventName() string        { return "core.crashed" }
+func (CoreRecovered) EventName() string      { return "core.recovered" }
+func (ConfigUpdated) EventName() string      { return "config.updated" }
+func (ParserProgress) EventName() string     { return "parser.progress" }
+func (ProxiesLoaded) EventName() string      { return "proxies.loaded" }
+func (ProxySwitched) EventName() string      { return "proxies.switched" }
+func (VersionAvailable) EventName() string   { return "version.available" }
+func (CoreVersionChanged) EventName() string { return "core.version_changed" }
+func (GitHubRateLimited) EventName() string  { return "github.rate_limited" }
+func (ProfileChanged) EventName() string     { return "profile.changed" }
+func (ProfilesUpdated) EventName() string    { return "profiles.updated" }
+func (ClashModeChanged) EventName() string   { return "clash.mode_changed" }
+func (LatencyUpdated) EventName() string     { return "latency.updated" }
+func (TrafficUpdated) EventName() string     { return "traffic.updated" }
+func (TrayGroupsUpdated) EventName() string  { return "tray.groups_updated" }
+
+// CoreStateEvents are the events after which the running state or crash counters may have changed
+var CoreStateEvents = []Event{CoreStarted{}, CoreStopped{}, CoreCrashed{}, CoreRecovered{}}
+
+// AllEvents lists every event type (for subscribers that forward everything, like the control API)
+var AllEvents = []Event{
+	CoreStarted{}, CoreStopped{}, CoreCrashed{}, CoreRecovered{},
+	ConfigUpdated{}, ParserProgress{}, ProxiesLoaded{}, ProxySwitched{}, VersionAvailable{}, CoreVersionChanged{},
+	GitHubRateLimited{}, ProfileChanged{}, ProfilesUpdated{}, ClashModeChanged{}, LatencyUpdated{}, TrafficUpdated{}, TrayGroupsUpdated{},
+}
+
+// TrayMenuEvents are the events after which the tray menu must be rebuilt
+var TrayMenuEvents = []Event{
+	CoreStarted{}, CoreStopped{}, CoreCrashed{}, CoreRecovered{},
+	ProxiesLoaded{}, ProxySwitched{}, ProfileChanged{}, ProfilesUpdated{},
+	ClashModeChanged{}, LatencyUpdated{}, TrafficUpdated{}, TrayGroupsUpdated{}, ConfigUpdated{},
+}
+
+// TrayMenuStale filters TrayMenuEvents: frequent traffic and latency events only matter once throttled or finished
+func TrayMenuStale(e Event) bool {
+	switch e := e.(type) {
+	case TrafficUpdated:
+		return e.TrayStale
+	case LatencyUpdated:
+		return e.Done
+	}
+	return true
+}
+
+// bridgeNotifier forwards bus events to ac.Notifier (read at delivery time, so it may be replaced later)
+func (ac *AppController) bridgeNotifier() {
+	Subscribe(ac.Events, func(e ParserProgress) { ac.Notifier.Progress(e.Progress, e.Status) })
+	ac.Events.SubscribeEvents(CoreStateEvents, func(Event) { ac.Notifier.StateChanged() })
+	ac.Events.SubscribeEvents([]Event{ProxiesLoaded{}, ProxySwitched{}}, func(Event) { ac.Notifier.ProxiesChanged() })
+}
@@ -0,0 +1,279 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"singbox-launcher/internal/constants"
+)
+
+// Запросы к GitHub API для проверки версий. Без токена GitHub разрешает 60 запросов в час на IP,
+// что быстро исчерпывается за общим NAT. Поэтому:
+//   - ответы кешируются вместе с ETag в bin/github_cache.json, повторный запрос идёт с If-None-Match,
+//     а ответ 304 не расходует лимит;
+//   - после ответа о превышении лимита запросы к этому хосту не отправляются до X-RateLimit-Reset / Retry-After;
+//   - необязательный личный токен (Settings.GitHub.Token) поднимает лимит до 5000 запросов в час.
+
+// githubAPIHost is the only host the personal token is sent to (not to mirrors like ghproxy)
+const githubAPIHost = "api.github.com"
+
+// githubDefaultLimitWait is the pause after a rate-limit response without a reset time
+const githubDefaultLimitWait = time.Minute
+
+// RateLimitError is returned while GitHub refuses requests because the rate limit was exceeded
+type RateLimitError struct {
+	Host  string
+	Until time.Time
+}
+
+func (e *RateLimitError) Error() string {
+	name := e.Host
+	if name == githubAPIHost {
+		name = "GitHub API"
+	}
+	return fmt.Sprintf("%s rate limited until %s", name, e.Until.Local().Format("15:04"))
+}
+
+// IsRateLimitError reports whether err (or an error it wraps) is a RateLimitError
+func IsRateLimitError(err error) bool {
+	var limited *RateLimitError
+	return errors.As(err, &limited)
+}
+
+// githubCachedResponse is a response body stored with its ETag
+type githubCachedResponse struct {
+	ETag string `json:"etag"`
+	Body string `json:"body"`
+}
+
+// githubCacheFile is the content of bin/github_cache.json
+type githubCacheFile struct {
+	Responses    map[string]githubCachedResponse `json:"responses,omitempty"`          // By request URL
+	LimitedUntil map[string]time.Time            `json:"rate_limited_until,omitempty"` // By host
+}
+
+// githubAPIState holds the ETag cache and rate limits of GitHub API requests (zero value is ready to use)
+type githubAPIState struct {
+	mu     sync.Mutex
+	loaded bool
+	cache  githubCacheFile
+}
+
+// githubCachePath returns the location of the cache next to settings.json ("" keeps it in memory only)
+func (ac *AppController) githubCachePath() string {
+	if ac.Settings == nil {
+		return ""
+	}
+	return filepath.Join(filepath.Dir(ac.Settings.Path()), constants.GitHubCacheFileName)
+}
+
+// githubState returns the cache, loading it from disk on first use
+func (ac *AppController) githubState() *githubAPIState {
+	state := &ac.gitHub
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	if state.loaded {
+		return state
+	}
+	state.loaded = true
+	if path := ac.githubCachePath(); path != "" {
+		if data, err := os.ReadFile(path); err == nil {
+			if err := json.Unmarshal(data, &state.cache); err != nil {
+				log.Printf("githubState: ignoring corrupted %s: %v", path, err)
+				state.cache = githubCacheFile{}
+			}
+		}
+	}
+	if state.cache.Responses == nil {
+		state.cache.Responses = make(map[string]githubCachedResponse)
+	}
+	if state.cache.LimitedUntil == nil {
+		state.cache.LimitedUntil = make(map[string]time.Time)
+	}
+	return state
+}
+
+// saveGitHubCacheLocked writes the cache to disk. Caller must hold the state mutex.
+func (ac *AppController) saveGitHubCacheLocked(state *githubAPIState) {
+	path := ac.githubCachePath()
+	if path == "" {
+		return
+	}
+	data, err := json.Marshal(state.cache)
+	if err != nil {
+		log.Printf("saveGitHubCache: %v", err)
+		return
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		log.Printf("saveGitHubCache: failed to write %s: %v", path, err)
+	}
+}
+
+// githubCachedResponseFor returns the cached response for url
+func (ac *AppController) githubCachedResponseFor(url string) (githubCachedResponse, bool) {
+	state := ac.githubState()
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	cached, ok := state.cache.Responses[url]
+	return cached, ok
+}
+
+// storeGitHubResponse caches a response with an ETag
+func (ac *AppController) storeGitHubResponse(url string, cached githubCachedResponse) {
+	state := ac.githubState()
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	state.cache.Responses[url] = cached
+	ac.saveGitHubCacheLocked(state)
+}
+
+// githubLimitedUntil returns the end of the current rate limit of host (zero if not limited)
+func (ac *AppController) githubLimitedUntil(host string) time.Time {
+	state := ac.githubState()
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	until := state.cache.LimitedUntil[host]
+	if !time.Now().Before(until) {
+		return time.Time{}
+	}
+	return until
+}
+
+// setGitHubRateLimit remembers that host refuses requests until the given time
+func (ac *AppController) setGitHubRateLimit(host string, until time.Time) {
+	state := ac.githubState()
+	state.mu.Lock()
+	state.cache.LimitedUntil[host] = until
+	ac.saveGitHubCacheLocked(state)
+	state.mu.Unlock()
+	log.Printf("setGitHubRateLimit: %s is rate limited until %s", host, until.Local().Format("15:04:05"))
+	ac.Events.Publish(GitHubRateLimited{Host: host, Until: until})
+}
+
+// GitHubRateLimitedUntil returns when the rate limit of the GitHub API ends (zero if requests are allowed)
+func (ac *AppController) GitHubRateLimitedUntil() time.Time {
+	return ac.githubLimitedUntil(githubAPIHost)
+}
+
+// ClearGitHubRateLimit forgets recorded rate limits, e.g. after a token was configured
+func (ac *AppController) ClearGitHubRateLimit() {
+	state := ac.githubState()
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	state.cache.LimitedUntil = make(map[string]time.Time)
+	ac.saveGitHubCacheLocked(state)
+}
+
+// githubRateLimitUntil returns when requests may be sent again after a rate-limit response
+func githubRateLimitUntil(resp *http.Response, now time.Time) (time.Time, bool) {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return time.Time{}, false
+	}
+	// Retry-After: вторичный лимит GitHub и прокси (секунды или HTTP-дата)
+	if retryAfter := strings.TrimSpace(resp.Header.Get("Retry-After")); retryAfter != "" {
+		if seconds, err := strconv.Atoi(retryAfter); err == nil {
+			return now.Add(time.Duration(seconds) * time.Second), true
+		}
+		if at, err := http.ParseTime(retryAfter); err == nil {
+			return at, true
+		}
+	}
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			return time.Unix(reset, 0), true
+		}
+		return now.Add(githubDefaultLimitWait), true
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return now.Add(githubDefaultLimitWait), true
+	}
+	return time.Time{}, false // 403 по другой причине
+}
+
+// githubGet fetches a GitHub API URL (or its mirror) with a conditional request. While the host is
+// rate limited no request is sent: the cached response is returned if there is one, a RateLimitError otherwise.
+func (ac *AppController) githubGet(ctx context.Context, client *http.Client, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	host := req.URL.Host
+	cached, hasCached := ac.githubCachedResponseFor(url)
+
+	if until := ac.githubLimitedUntil(host); !until.IsZero() {
+		if hasCached {
+			log.Printf("githubGet: %s is rate limited, using the cached response for %s", host, url)
+			return []byte(cached.Body), nil
+		}
+		return nil, &RateLimitError{Host: host, Until: until}
+	}
+
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+	req.Header.Set("User-Agent", "singbox-launcher/1.0")
+	token := strings.TrimSpace(ac.Settings.Get().GitHub.Token)
+	if token != "" && host == githubAPIHost {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if hasCached && cached.ETag != "" {
+		req.Header.Set("If-None-Match", cached.ETag)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		if IsNetworkError(err) {
+			return nil, fmt.Errorf("network error: %s", GetNetworkErrorMessage(err))
+		}
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if until, limited := githubRateLimitUntil(resp, time.Now()); limited {
+		ac.setGitHubRateLimit(host, until)
+		if hasCached {
+			return []byte(cached.Body), nil
+		}
+		return nil, &RateLimitError{Host: host, Until: until}
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		if !hasCached {
+			return nil, fmt.Errorf("HTTP 304 without a cached response")
+		}
+		return []byte(cached.Body), nil
+	case http.StatusUnauthorized:
+		if token != "" && host == githubAPIHost {
+			return nil, fmt.Errorf("GitHub rejected the personal token from the settings (HTTP 401)")
+		}
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	default:
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if etag := resp.Header.Get("ETag"); etag != "" {
+		ac.storeGitHubResponse(url, githubCachedResponse{ETag: etag, Body: string(body)})
+	}
+	// Последний разрешённый запрос: следующие подождут сброса лимита
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			ac.setGitHubRateLimit(host, time.Unix(reset, 0))
+		}
+	}
+	return body, nil
+}
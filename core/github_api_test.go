@@ -0,0 +1,119 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestGitHubGet_ETag tests conditional requests and the persisted cache
+func TestGitHubGet_ETag(t *testing.T) {
+	var requests, notModified int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if r.Header.Get("Authorization") != "" {
+			t.Errorf("token sent to %s", r.Host)
+		}
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			atomic.AddInt32(&notModified, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte(`{"tag_name":"v1.12.12"}`))
+	}))
+	defer server.Close()
+
+	settingsPath := filepath.Join(t.TempDir(), "settings.json")
+	store := NewSettingsStore(settingsPath)
+	store.Update(func(settings *Settings) { settings.GitHub.Token = "secret" })
+	ac := &AppController{Settings: store}
+	for i := 0; i < 2; i++ {
+		body, err := ac.githubGet(context.Background(), http.DefaultClient, server.URL+"/releases/latest")
+		if err != nil {
+			t.Fatalf("githubGet: %v", err)
+		}
+		if string(body) != `{"tag_name":"v1.12.12"}` {
+			t.Fatalf("unexpected body %q", body)
+		}
+	}
+	if notModified != 1 {
+		t.Errorf("expected the second request to be conditional, got %d of %d", notModified, requests)
+	}
+
+	// Новый контроллер читает ETag из файла
+	ac = &AppController{Settings: NewSettingsStore(settingsPath)}
+	if _, err := ac.githubGet(context.Background(), http.DefaultClient, server.URL+"/releases/latest"); err != nil {
+		t.Fatalf("githubGet after restart: %v", err)
+	}
+	if notModified != 2 {
+		t.Errorf("expected the persisted ETag to be used after restart")
+	}
+}
+
+// TestGitHubGet_RateLimit tests that no requests are sent until X-RateLimit-Reset
+func TestGitHubGet_RateLimit(t *testing.T) {
+	reset := time.Now().Add(10 * time.Minute).Truncate(time.Second)
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	ac := &AppController{Settings: NewSettingsStore(filepath.Join(t.TempDir(), "settings.json"))}
+	for i := 0; i < 2; i++ {
+		_, err := ac.githubGet(context.Background(), http.DefaultClient, server.URL+"/releases/latest")
+		limited, ok := err.(*RateLimitError)
+		if !ok {
+			t.Fatalf("expected RateLimitError, got %v", err)
+		}
+		if !limited.Until.Equal(reset) {
+			t.Errorf("expected the limit to end at %v, got %v", reset, limited.Until)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("expected 1 request while rate limited, got %d", requests)
+	}
+
+	ac.ClearGitHubRateLimit()
+	ac.githubGet(context.Background(), http.DefaultClient, server.URL+"/releases/latest")
+	if requests != 2 {
+		t.Errorf("expected a request after the limit was cleared")
+	}
+}
+
+// TestGitHubRateLimitUntil tests Retry-After and rate-limit headers
+func TestGitHubRateLimitUntil(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	tests := []struct {
+		name    string
+		status  int
+		headers map[string]string
+		want    time.Time
+		limited bool
+	}{
+		{"retry after seconds", http.StatusForbidden, map[string]string{"Retry-After": "120"}, now.Add(2 * time.Minute), true},
+		{"reset", http.StatusForbidden, map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": "1700000600"}, time.Unix(1700000600, 0), true},
+		{"too many requests", http.StatusTooManyRequests, nil, now.Add(githubDefaultLimitWait), true},
+		{"forbidden", http.StatusForbidden, map[string]string{"X-RateLimit-Remaining": "42"}, time.Time{}, false},
+		{"ok", http.StatusOK, map[string]string{"X-RateLimit-Remaining": "0"}, time.Time{}, false},
+	}
+	for _, tt := range tests {
+		resp := &http.Response{StatusCode: tt.status, Header: http.Header{}}
+		for key, value := range tt.headers {
+			resp.Header.Set(key, value)
+		}
+		got, limited := githubRateLimitUntil(resp, now)
+		if limited != tt.limited || !got.Equal(tt.want) {
+			t.Errorf("%s: got %v %v, want %v %v", tt.name, got, limited, tt.want, tt.limited)
+		}
+	}
+}
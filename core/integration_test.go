@@ -2,6 +2,8 @@ package core
 
 import (
 	"encoding/base64"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -270,3 +272,84 @@ func TestIntegration_ParserConfigFlow(t *testing.T) {
 	})
 }
 
+
+// TestIntegration_UpdateConfigNotifications runs a full config update without a GUI and checks
+// what core reports through the notifier
+func TestIntegration_UpdateConfigNotifications(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	configContent := `{
+  /** @ParserConfig
+{
+  "ParserConfig": {
+    "version": 4,
+    "proxies": [
+      {
+        "connections": [
+          "vless://2ee2a715-d541-416a-8713-d66567448c2e@91.98.155.240:443?encryption=none&security=none&type=grpc#🇩🇪 Germany"
+        ]
+      }
+    ],
+    "outbounds": [
+      {"tag": "proxy-out", "type": "selector"}
+    ]
+  }
+}
+*/
+  "outbounds": [
+    /** @ParserSTART */
+    /** @ParserEND */
+    {"type": "direct", "tag": "direct-out"}
+  ],
+  "route": {}
+}`
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+
+	notifier := NewRecordingNotifier()
+	ac := &AppController{ConfigPath: configPath, Notifier: notifier, Events: NewEventBus()}
+	ac.bridgeNotifier()
+	ac.RunningState = &RunningState{controller: ac}
+	ac.ConfigService = NewConfigService(ac)
+
+	ac.ConfigService.RunParserProcess()
+
+	if errs := notifier.Of(NotifyError); len(errs) != 0 {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+	progress := notifier.Of(NotifyProgress)
+	if len(progress) == 0 {
+		t.Fatal("Expected progress notifications")
+	}
+	if last := progress[len(progress)-1]; last.Progress != 100 {
+		t.Errorf("Expected final progress 100, got %v (%s)", last.Progress, last.Message)
+	}
+	if infos := notifier.Of(NotifyAutoHideInfo); len(infos) != 1 || infos[0].Title != "Parser" {
+		t.Errorf("Expected one 'Parser' info, got %v", infos)
+	}
+
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"proxy-out"`) {
+		t.Error("Expected generated selector in config")
+	}
+	if updated, ok := ac.Events.Last(ConfigUpdated{}).(ConfigUpdated); !ok || updated.ConfigPath != configPath {
+		t.Errorf("Expected ConfigUpdated for %s, got %v", configPath, ac.Events.Last(ConfigUpdated{}))
+	}
+
+	// A broken config is reported as failed progress and an error, not a panic
+	notifier.Reset()
+	if err := os.WriteFile(configPath, []byte(`{}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ac.ConfigService.RunParserProcess()
+	progress = notifier.Of(NotifyProgress)
+	if len(progress) == 0 || progress[len(progress)-1].Progress >= 0 {
+		t.Errorf("Expected failed progress, got %v", progress)
+	}
+	if len(notifier.Of(NotifyError)) != 1 {
+		t.Errorf("Expected one error, got %v", notifier.Notifications())
+	}
+}
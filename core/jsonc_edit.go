@@ -0,0 +1,273 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Правка JSONC (config.json с комментариями и маркерами @ParserConfig / @ParserSTART / @ParserEND)
+// без полной перезаписи: заменяются только изменённые элементы массивов верхнего уровня и изменённые
+// объекты верхнего уровня. Комментарии в остальной части файла сохраняются.
+
+// skipJSONCSpace returns the index of the next token after whitespace and comments
+func skipJSONCSpace(s string, i int) int {
+	for i < len(s) {
+		switch {
+		case s[i] == ' ' || s[i] == '\t' || s[i] == '\n' || s[i] == '\r':
+			i++
+		case strings.HasPrefix(s[i:], "//"):
+			end := strings.IndexByte(s[i:], '\n')
+			if end < 0 {
+				return len(s)
+			}
+			i += end + 1
+		case strings.HasPrefix(s[i:], "/*"):
+			end := strings.Index(s[i+2:], "*/")
+			if end < 0 {
+				return len(s)
+			}
+			i += end + 4
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+// jsoncStringEnd returns the index after the closing quote of the string starting at i
+func jsoncStringEnd(s string, i int) (int, error) {
+	for j := i + 1; j < len(s); j++ {
+		switch s[j] {
+		case '\\':
+			j++
+		case '"':
+			return j + 1, nil
+		}
+	}
+	return 0, fmt.Errorf("unterminated string at offset %d", i)
+}
+
+// jsoncValueEnd returns the index after the value starting at i
+func jsoncValueEnd(s string, i int) (int, error) {
+	if i >= len(s) {
+		return 0, fmt.Errorf("unexpected end of JSON")
+	}
+	switch s[i] {
+	case '"':
+		return jsoncStringEnd(s, i)
+	case '{', '[':
+		depth := 0
+		for j := i; j < len(s); {
+			switch {
+			case s[j] == '"':
+				end, err := jsoncStringEnd(s, j)
+				if err != nil {
+					return 0, err
+				}
+				j = end
+				continue
+			case strings.HasPrefix(s[j:], "//"), strings.HasPrefix(s[j:], "/*"):
+				j = skipJSONCSpace(s, j)
+				continue
+			case s[j] == '{' || s[j] == '[':
+				depth++
+			case s[j] == '}' || s[j] == ']':
+				depth--
+				if depth == 0 {
+					return j + 1, nil
+				}
+			}
+			j++
+		}
+		return 0, fmt.Errorf("unterminated value at offset %d", i)
+	}
+	j := i
+	for j < len(s) && !strings.ContainsRune(",}] \t\r\n/", rune(s[j])) {
+		j++
+	}
+	return j, nil
+}
+
+// jsoncSpan is a value in JSONC text: s[Start:End], Comma is the index of the following comma or -1
+type jsoncSpan struct {
+	Start, End, Comma int
+}
+
+// jsoncTopLevelValues returns the spans of the values of the top-level object keys
+func jsoncTopLevelValues(s string) (map[string]jsoncSpan, error) {
+	i := skipJSONCSpace(s, 0)
+	if i >= len(s) || s[i] != '{' {
+		return nil, fmt.Errorf("config is not a JSON object")
+	}
+	values := make(map[string]jsoncSpan)
+	i = skipJSONCSpace(s, i+1)
+	for i < len(s) && s[i] != '}' {
+		if s[i] != '"' {
+			return nil, fmt.Errorf("expected a key at offset %d", i)
+		}
+		keyEnd, err := jsoncStringEnd(s, i)
+		if err != nil {
+			return nil, err
+		}
+		var key string
+		if err := json.Unmarshal([]byte(s[i:keyEnd]), &key); err != nil {
+			return nil, fmt.Errorf("invalid key at offset %d: %w", i, err)
+		}
+		i = skipJSONCSpace(s, keyEnd)
+		if i >= len(s) || s[i] != ':' {
+			return nil, fmt.Errorf("expected ':' after %q", key)
+		}
+		start := skipJSONCSpace(s, i+1)
+		end, err := jsoncValueEnd(s, start)
+		if err != nil {
+			return nil, err
+		}
+		span := jsoncSpan{Start: start, End: end, Comma: -1}
+		i = skipJSONCSpace(s, end)
+		if i < len(s) && s[i] == ',' {
+			span.Comma = i
+			i = skipJSONCSpace(s, i+1)
+		}
+		values[key] = span
+	}
+	return values, nil
+}
+
+// jsoncArrayElements returns the spans of the elements of the array at s[start:end]
+func jsoncArrayElements(s string, start, end int) ([]jsoncSpan, error) {
+	var elements []jsoncSpan
+	i := skipJSONCSpace(s, start+1)
+	for i < end-1 {
+		elemEnd, err := jsoncValueEnd(s, i)
+		if err != nil {
+			return nil, err
+		}
+		span := jsoncSpan{Start: i, End: elemEnd, Comma: -1}
+		i = skipJSONCSpace(s, elemEnd)
+		if i < end && s[i] == ',' {
+			span.Comma = i
+			i = skipJSONCSpace(s, i+1)
+		}
+		elements = append(elements, span)
+	}
+	return elements, nil
+}
+
+// jsoncIndentedJSON marshals v indented to continue at the column of offset in s
+func jsoncIndentedJSON(s string, offset int, v interface{}) (string, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		return "", err
+	}
+	lineStart := strings.LastIndexByte(s[:offset], '\n') + 1
+	indent := s[lineStart:offset]
+	if strings.TrimSpace(indent) != "" {
+		indent = strings.Repeat(" ", len(indent)-len(strings.TrimLeft(indent, " \t")))
+	}
+	return strings.ReplaceAll(strings.TrimRight(buf.String(), "\n"), "\n", "\n"+indent), nil
+}
+
+// jsoncLineStart extends offset back over the indentation to the end of the previous line,
+// so that a removed element does not leave an empty line
+func jsoncLineStart(s string, offset int) int {
+	i := offset
+	for i > 0 && (s[i-1] == ' ' || s[i-1] == '\t') {
+		i--
+	}
+	if i > 0 && s[i-1] == '\n' {
+		i--
+		if i > 0 && s[i-1] == '\r' {
+			i--
+		}
+		return i
+	}
+	return offset
+}
+
+// jsoncEdit replaces s[Start:End]
+type jsoncEdit struct {
+	Start, End int
+	Text       string
+}
+
+// applyJSONCChanges writes the differences between original and updated (parsed from s) back into s.
+// Top-level arrays are edited element by element (nil elements of updated are removed, which needs
+// the same length); other changed values are replaced as a whole.
+func applyJSONCChanges(s string, original, updated map[string]interface{}) (string, error) {
+	spans, err := jsoncTopLevelValues(s)
+	if err != nil {
+		return "", err
+	}
+	var edits []jsoncEdit
+	for key, value := range updated {
+		if reflect.DeepEqual(original[key], value) {
+			continue
+		}
+		span, ok := spans[key]
+		if !ok {
+			return "", fmt.Errorf("cannot add new section %q", key)
+		}
+		before, wasArray := original[key].([]interface{})
+		after, isArray := value.([]interface{})
+		if !wasArray || !isArray || len(before) != len(after) {
+			text, err := jsoncIndentedJSON(s, span.Start, value)
+			if err != nil {
+				return "", err
+			}
+			edits = append(edits, jsoncEdit{span.Start, span.End, text})
+			continue
+		}
+		elements, err := jsoncArrayElements(s, span.Start, span.End)
+		if err != nil {
+			return "", err
+		}
+		if len(elements) != len(before) {
+			return "", fmt.Errorf("failed to locate the elements of %q", key)
+		}
+		kept := 0
+		for i := range after {
+			if after[i] != nil {
+				kept++
+			}
+		}
+		if kept == 0 {
+			edits = append(edits, jsoncEdit{span.Start, span.End, "[]"})
+			continue
+		}
+		for i, element := range elements {
+			switch {
+			case after[i] == nil && element.Comma >= 0:
+				edits = append(edits, jsoncEdit{jsoncLineStart(s, element.Start), element.Comma + 1, ""})
+			case after[i] == nil:
+				// Последний элемент: убираем и запятую после предыдущего оставшегося элемента
+				edits = append(edits, jsoncEdit{jsoncLineStart(s, element.Start), element.End, ""})
+				for j := i - 1; j >= 0; j-- {
+					if after[j] != nil {
+						edits = append(edits, jsoncEdit{elements[j].Comma, elements[j].Comma + 1, ""})
+						break
+					}
+				}
+			case !reflect.DeepEqual(before[i], after[i]):
+				text, err := jsoncIndentedJSON(s, element.Start, after[i])
+				if err != nil {
+					return "", err
+				}
+				edits = append(edits, jsoncEdit{element.Start, element.End, text})
+			}
+		}
+	}
+
+	// Применяем с конца, чтобы смещения оставались верными
+	sort.Slice(edits, func(i, j int) bool { return edits[i].Start > edits[j].Start })
+	for _, edit := range edits {
+		s = s[:edit.Start] + edit.Text + s[edit.End:]
+	}
+	return s, nil
+}
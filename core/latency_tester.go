@@ -0,0 +1,355 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"singbox-launcher/api"
+)
+
+const (
+	// DefaultLatencyTestConcurrency is the number of proxies tested in parallel by the per-proxy fallback
+	DefaultLatencyTestConcurrency = 8
+	// maxLatencyTestConcurrency protects sing-box from too many simultaneous test connections
+	maxLatencyTestConcurrency = 64
+)
+
+// ErrLatencyTestInProgress is returned when a group latency test is already running
+var ErrLatencyTestInProgress = errors.New("latency test already in progress")
+
+// LatencyTestSettings configures "Test all" latency tests. Zero values mean defaults.
+type LatencyTestSettings struct {
+	URL            string `json:"url,omitempty"`             // Overrides the urltest URL from config.json
+	TimeoutMs      int    `json:"timeout_ms,omitempty"`      // Per-proxy timeout
+	Concurrency    int    `json:"concurrency,omitempty"`     // Worker pool size of the per-proxy fallback
+	ExpectedStatus string `json:"expected_status,omitempty"` // Expected HTTP status ("204", "200-299")
+	SortByLatency  bool   `json:"sort_by_latency,omitempty"` // Sort proxy lists by the last delay
+	HideTimedOut   bool   `json:"hide_timed_out,omitempty"`  // Hide proxies that failed the last test
+}
+
+// WorkerCount returns the effective worker pool size
+func (s LatencyTestSettings) WorkerCount() int {
+	if s.Concurrency <= 0 {
+		return DefaultLatencyTestConcurrency
+	}
+	return min(s.Concurrency, maxLatencyTestConcurrency)
+}
+
+// GetLatencyTestURLFromConfig returns the test URL of the urltest outbound for group,
+// or of the first urltest outbound if group is not a urltest. Empty if none is configured.
+func GetLatencyTestURLFromConfig(configPath, group string) (string, error) {
+	jsonData, err := readConfigJSON(configPath)
+	if err != nil {
+		return "", err
+	}
+	outbounds, _ := jsonData["outbounds"].([]interface{})
+	firstURL := ""
+	for _, outbound := range outbounds {
+		outboundMap, ok := outbound.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if outboundType, _ := outboundMap["type"].(string); outboundType != "urltest" {
+			continue
+		}
+		testURL, _ := outboundMap["url"].(string)
+		if testURL == "" {
+			continue
+		}
+		if tag, _ := outboundMap["tag"].(string); tag == group {
+			return testURL, nil
+		}
+		if firstURL == "" {
+			firstURL = testURL
+		}
+	}
+	return firstURL, nil
+}
+
+// LatencyTestSettings returns the saved latency test settings (defaults if the state store is not available)
+func (ac *AppController) LatencyTestSettings() LatencyTestSettings {
+	store := ac.CurrentStateStore()
+	if store == nil {
+		return LatencyTestSettings{}
+	}
+	return store.LatencyTestSettings()
+}
+
+// LatencyTestOptions resolves the test options for group: profile state first, then config.json,
+// then the launcher-wide URL from the settings, then defaults
+func (ac *AppController) LatencyTestOptions(group string) api.DelayTestOptions {
+	settings := ac.LatencyTestSettings()
+	opts := api.DelayTestOptions{
+		URL:            settings.URL,
+		Timeout:        time.Duration(settings.TimeoutMs) * time.Millisecond,
+		ExpectedStatus: settings.ExpectedStatus,
+	}
+	if opts.URL == "" && ac.ConfigPath != "" {
+		if configURL, err := GetLatencyTestURLFromConfig(ac.ConfigPath, group); err == nil {
+			opts.URL = configURL
+		}
+	}
+	if opts.URL == "" {
+		opts.URL = ac.Settings.Get().LatencyTestURL
+	}
+	return opts
+}
+
+// TestGroupLatency tests all members of group and stores the results in ProxyInfo.Delay
+// (api.DelayTimedOut for failed proxies). The Clash /group/{name}/delay endpoint is used first;
+// if it is not available, proxies are tested one by one with a worker pool.
+// onResult is called from background goroutines for every result as it arrives.
+func (ac *AppController) TestGroupLatency(ctx context.Context, group string, onResult func(name string, delay int64)) error {
+	ac.LatencyTestMutex.Lock()
+	if ac.LatencyTestInProgress {
+		ac.LatencyTestMutex.Unlock()
+		return ErrLatencyTestInProgress
+	}
+	ac.LatencyTestInProgress = true
+	ac.LatencyTestMutex.Unlock()
+	defer func() {
+		ac.LatencyTestMutex.Lock()
+		ac.LatencyTestInProgress = false
+		ac.LatencyTestMutex.Unlock()
+	}()
+
+	ac.APIStateMutex.RLock()
+	enabled := ac.ClashAPIEnabled
+	ac.APIStateMutex.RUnlock()
+	if !enabled {
+		return fmt.Errorf("clash API is disabled")
+	}
+
+	client := ac.ClashClient()
+	names, err := ac.groupMemberNames(ctx, client, group)
+	if err != nil {
+		return err
+	}
+	opts := ac.LatencyTestOptions(group)
+	report := func(name string, delay int64) {
+		ac.SetProxyDelay(name, delay)
+		if onResult != nil {
+			onResult(name, delay)
+		}
+	}
+
+	log.Printf("TestGroupLatency: Testing %d proxies of '%s' (url: %s)", len(names), group, opts.URL)
+	delays, err := client.GroupDelay(ctx, group, opts)
+	if err == nil {
+		for _, name := range names {
+			if delay, ok := delays[name]; ok {
+				report(name, delay)
+			} else {
+				report(name, api.DelayTimedOut)
+			}
+		}
+		return nil
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	log.Printf("TestGroupLatency: group delay endpoint failed (%v), testing proxies one by one", err)
+	workers := ac.LatencyTestSettings().WorkerCount()
+	RunLatencyTests(ctx, names, workers, func(name string) (int64, error) {
+		return client.Delay(ctx, name, opts)
+	}, func(name string, delay int64, err error) {
+		if err != nil {
+			if !errors.Is(err, api.ErrDelayTimeout) {
+				log.Printf("TestGroupLatency: %s: %v", name, err)
+			}
+			delay = api.DelayTimedOut
+		}
+		report(name, delay)
+	})
+	return ctx.Err()
+}
+
+// StartGroupLatencyTest runs TestGroupLatency in the background until the launcher exits.
+// Every result is published as LatencyUpdated and the completion as LatencyUpdated{Done: true}.
+// onDone (optional) is called with the final error from the background goroutine.
+func (ac *AppController) StartGroupLatencyTest(group string, onDone func(err error)) {
+	ctx := ac.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	go func() {
+		err := ac.TestGroupLatency(ctx, group, func(string, int64) {
+			ac.Events.Publish(LatencyUpdated{})
+		})
+		if err != nil && !errors.Is(err, ErrLatencyTestInProgress) {
+			log.Printf("StartGroupLatencyTest: %v", err)
+		}
+		ac.Events.Publish(LatencyUpdated{Done: true})
+		if onDone != nil {
+			onDone(err)
+		}
+	}()
+}
+
+// UpdateLatencyView changes the "sort by latency" / "hide timed-out" options and refreshes proxy lists
+func (ac *AppController) UpdateLatencyView(sortByLatency, hideTimedOut bool) {
+	store := ac.CurrentStateStore()
+	if store == nil {
+		return
+	}
+	settings := store.LatencyTestSettings()
+	settings.SortByLatency = sortByLatency
+	settings.HideTimedOut = hideTimedOut
+	if err := store.SetLatencyTestSettings(settings); err != nil {
+		log.Printf("UpdateLatencyView: failed to save settings: %v", err)
+	}
+	ac.Events.Publish(LatencyUpdated{Done: true})
+}
+
+// groupMemberNames returns the members of group, using the loaded list when it belongs to the group
+func (ac *AppController) groupMemberNames(ctx context.Context, client *api.Client, group string) ([]string, error) {
+	ac.APIStateMutex.RLock()
+	var names []string
+	if ac.SelectedClashGroup == group {
+		for _, proxy := range ac.ProxiesList {
+			names = append(names, proxy.Name)
+		}
+	}
+	ac.APIStateMutex.RUnlock()
+	if len(names) > 0 {
+		return names, nil
+	}
+
+	proxies, _, err := client.ProxiesInGroup(ctx, group)
+	if err != nil {
+		return nil, err
+	}
+	for _, proxy := range proxies {
+		names = append(names, proxy.Name)
+	}
+	return names, nil
+}
+
+// RunLatencyTests runs test for every name with at most workers concurrent calls and reports
+// each result through onResult as soon as it is available. Returns when all tests finished
+// or ctx was cancelled (remaining names are not tested).
+func RunLatencyTests(ctx context.Context, names []string, workers int, test func(name string) (int64, error), onResult func(name string, delay int64, err error)) {
+	if workers <= 0 {
+		workers = 1
+	}
+	jobs := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < min(workers, len(names)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for name := range jobs {
+				delay, err := test(name)
+				onResult(name, delay, err)
+			}
+		}()
+	}
+
+feed:
+	for _, name := range names {
+		select {
+		case <-ctx.Done():
+			break feed
+		case jobs <- name:
+		}
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// SetProxyDelay updates the delay of a proxy in the loaded list and in the tray groups
+func (ac *AppController) SetProxyDelay(name string, delay int64) {
+	ac.APIStateMutex.Lock()
+	defer ac.APIStateMutex.Unlock()
+	for i := range ac.ProxiesList {
+		if ac.ProxiesList[i].Name == name {
+			ac.ProxiesList[i].Delay = delay
+		}
+	}
+	for _, group := range ac.trayGroups {
+		for i := range group.Members {
+			if group.Members[i].Name == name {
+				group.Members[i].Delay = delay
+			}
+		}
+	}
+}
+
+// GetVisibleProxiesList returns the loaded proxies with "sort by latency" and "hide timed-out" applied
+func (ac *AppController) GetVisibleProxiesList() []api.ProxyInfo {
+	settings := ac.LatencyTestSettings()
+	return VisibleProxies(ac.GetProxiesList(), settings.SortByLatency, settings.HideTimedOut, ac.GetActiveProxyName())
+}
+
+// VisibleProxies filters and sorts proxies for display. The active proxy is never hidden.
+// Sorting by latency keeps tested proxies first (fastest first), then untested, then timed-out ones.
+func VisibleProxies(proxies []api.ProxyInfo, sortByLatency, hideTimedOut bool, activeProxy string) []api.ProxyInfo {
+	result := make([]api.ProxyInfo, 0, len(proxies))
+	for _, proxy := range proxies {
+		if hideTimedOut && proxy.Delay == api.DelayTimedOut && proxy.Name != activeProxy {
+			continue
+		}
+		result = append(result, proxy)
+	}
+	if sortByLatency {
+		rank := func(delay int64) int {
+			switch {
+			case delay > 0:
+				return 0
+			case delay == 0:
+				return 1
+			default:
+				return 2
+			}
+		}
+		sort.SliceStable(result, func(i, j int) bool {
+			ri, rj := rank(result[i].Delay), rank(result[j].Delay)
+			if ri != rj {
+				return ri < rj
+			}
+			return ri == 0 && result[i].Delay < result[j].Delay
+		})
+	}
+	return result
+}
+
+// FormatDelay returns the delay as shown next to a proxy name ("123 ms", "timeout" or empty if untested)
+func FormatDelay(delay int64) string {
+	switch {
+	case delay > 0:
+		return fmt.Sprintf("%d ms", delay)
+	case delay == api.DelayTimedOut:
+		return "timeout"
+	default:
+		return ""
+	}
+}
+
+// LatencySummary returns a short summary of the last test, e.g. "12 ok, 3 timed out, best 85 ms"
+func LatencySummary(proxies []api.ProxyInfo) string {
+	ok, timedOut := 0, 0
+	var best int64
+	for _, proxy := range proxies {
+		switch {
+		case proxy.Delay > 0:
+			ok++
+			if best == 0 || proxy.Delay < best {
+				best = proxy.Delay
+			}
+		case proxy.Delay == api.DelayTimedOut:
+			timedOut++
+		}
+	}
+	parts := []string{fmt.Sprintf("%d ok", ok), fmt.Sprintf("%d timed out", timedOut)}
+	if best > 0 {
+		parts = append(parts, fmt.Sprintf("best %d ms", best))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,133 @@
+package core
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"singbox-launcher/api"
+)
+
+// TestRunLatencyTests tests that every name is tested once and concurrency is bounded
+func TestRunLatencyTests(t *testing.T) {
+	names := []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"}
+	var running, maxRunning int32
+	var mu sync.Mutex
+	results := make(map[string]int64)
+
+	RunLatencyTests(context.Background(), names, 3, func(name string) (int64, error) {
+		current := atomic.AddInt32(&running, 1)
+		for {
+			prev := atomic.LoadInt32(&maxRunning)
+			if current <= prev || atomic.CompareAndSwapInt32(&maxRunning, prev, current) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		if name == "c" {
+			return 0, api.ErrDelayTimeout
+		}
+		return int64(len(name) * 10), nil
+	}, func(name string, delay int64, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			delay = api.DelayTimedOut
+		}
+		results[name] = delay
+	})
+
+	if len(results) != len(names) {
+		t.Errorf("Expected %d results, got %d", len(names), len(results))
+	}
+	if results["c"] != api.DelayTimedOut || results["a"] != 10 {
+		t.Errorf("Unexpected results: %v", results)
+	}
+	if maxRunning > 3 {
+		t.Errorf("Expected at most 3 concurrent tests, got %d", maxRunning)
+	}
+}
+
+// TestRunLatencyTestsCancelled tests that cancellation stops feeding new names
+func TestRunLatencyTestsCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var tested int32
+	RunLatencyTests(ctx, []string{"a", "b", "c", "d"}, 1, func(name string) (int64, error) {
+		atomic.AddInt32(&tested, 1)
+		cancel()
+		return 1, nil
+	}, func(string, int64, error) {})
+	if tested > 2 {
+		t.Errorf("Expected testing to stop after cancellation, tested %d", tested)
+	}
+}
+
+// TestVisibleProxies tests sorting by latency and hiding timed-out proxies
+func TestVisibleProxies(t *testing.T) {
+	proxies := []api.ProxyInfo{
+		{Name: "slow", Delay: 400},
+		{Name: "dead", Delay: api.DelayTimedOut},
+		{Name: "untested"},
+		{Name: "fast", Delay: 80},
+		{Name: "active-dead", Delay: api.DelayTimedOut},
+	}
+	names := func(list []api.ProxyInfo) []string {
+		var result []string
+		for _, p := range list {
+			result = append(result, p.Name)
+		}
+		return result
+	}
+
+	got := names(VisibleProxies(proxies, true, false, ""))
+	expected := []string{"fast", "slow", "untested", "dead", "active-dead"}
+	if len(got) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, got)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Fatalf("Expected %v, got %v", expected, got)
+		}
+	}
+
+	got = names(VisibleProxies(proxies, false, true, "active-dead"))
+	if len(got) != 4 || got[3] != "active-dead" {
+		t.Errorf("Expected dead proxy hidden and active one kept, got %v", got)
+	}
+	if proxies[0].Name != "slow" {
+		t.Error("VisibleProxies must not reorder the input slice")
+	}
+}
+
+// TestGetLatencyTestURLFromConfig tests picking the urltest URL for a group
+func TestGetLatencyTestURLFromConfig(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	config := `{
+  "outbounds": [
+    {"type": "selector", "tag": "proxy-out", "outbounds": ["auto"]},
+    {"type": "urltest", "tag": "auto", "outbounds": ["a"], "url": "https://cp.cloudflare.com"},
+    {"type": "urltest", "tag": "streaming", "outbounds": ["a"], "url": "https://www.netflix.com"}
+  ]
+}`
+	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]string{
+		"streaming": "https://www.netflix.com",
+		"proxy-out": "https://cp.cloudflare.com",
+	}
+	for group, expected := range tests {
+		got, err := GetLatencyTestURLFromConfig(configPath, group)
+		if err != nil || got != expected {
+			t.Errorf("GetLatencyTestURLFromConfig(%s) = %q, %v, expected %q", group, got, err, expected)
+		}
+	}
+	if _, err := GetLatencyTestURLFromConfig(filepath.Join(t.TempDir(), "missing.json"), "x"); err == nil {
+		t.Error("Expected error for missing config")
+	}
+}
@@ -0,0 +1,184 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"singbox-launcher/internal/constants"
+)
+
+// LauncherState holds launcher runtime state that must survive restarts of the
+// launcher and of sing-box. It is stored next to config.json as launcher_state.json.
+type LauncherState struct {
+	// SelectorChoices maps selector group tag to the last member chosen by the user.
+	SelectorChoices map[string]string `json:"selector_choices,omitempty"`
+	// AutoCloseConnections closes connections of a selector after a manual switch
+	// when the selector does not set interrupt_exist_connections.
+	AutoCloseConnections bool `json:"auto_close_connections,omitempty"`
+	// LatencyTest configures "Test all" latency tests and how results are shown.
+	LatencyTest LatencyTestSettings `json:"latency_test,omitempty"`
+	// ClashMode is the last Clash mode (rule / global / direct) chosen by the user.
+	ClashMode string `json:"clash_mode,omitempty"`
+}
+
+// StateStore provides thread-safe access to the persisted LauncherState.
+type StateStore struct {
+	path  string
+	mu    sync.Mutex
+	state LauncherState
+}
+
+// GetStatePath returns the path of the launcher state file for the given config.json
+func GetStatePath(configPath string) string {
+	return filepath.Join(filepath.Dir(configPath), constants.StateFileName)
+}
+
+// NewStateStore creates a store bound to path and loads the existing state, if any.
+// A missing or corrupted file results in an empty state (the error is only logged).
+func NewStateStore(path string) *StateStore {
+	store := &StateStore{path: path}
+	if err := store.load(); err != nil {
+		log.Printf("StateStore: failed to load %s: %v (starting with empty state)", path, err)
+	}
+	return store
+}
+
+// Path returns the location of the state file
+func (s *StateStore) Path() string {
+	return s.path
+}
+
+// load reads the state file into memory
+func (s *StateStore) load() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.state = LauncherState{}
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read state file: %w", err)
+	}
+	if err := json.Unmarshal(data, &s.state); err != nil {
+		s.state = LauncherState{}
+		return fmt.Errorf("failed to parse state file: %w", err)
+	}
+	return nil
+}
+
+// saveLocked writes the state to disk. Caller must hold s.mu.
+// The file is written to a temporary file first and then renamed to avoid partial writes.
+func (s *StateStore) saveLocked() error {
+	data, err := json.MarshalIndent(s.state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal state: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to replace state file: %w", err)
+	}
+	return nil
+}
+
+// SelectorChoice returns the remembered member for the selector group
+func (s *StateStore) SelectorChoice(group string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	proxy, ok := s.state.SelectorChoices[group]
+	return proxy, ok
+}
+
+// SelectorChoices returns a copy of all remembered selector choices
+func (s *StateStore) SelectorChoices() map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result := make(map[string]string, len(s.state.SelectorChoices))
+	for group, proxy := range s.state.SelectorChoices {
+		result[group] = proxy
+	}
+	return result
+}
+
+// SetSelectorChoice remembers the member chosen in a selector group and saves the state
+func (s *StateStore) SetSelectorChoice(group, proxy string) error {
+	if group == "" || proxy == "" {
+		return fmt.Errorf("group and proxy must not be empty")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.state.SelectorChoices == nil {
+		s.state.SelectorChoices = make(map[string]string)
+	}
+	if s.state.SelectorChoices[group] == proxy {
+		return nil
+	}
+	s.state.SelectorChoices[group] = proxy
+	return s.saveLocked()
+}
+
+// AutoCloseConnections returns whether connections are closed after a manual switch
+func (s *StateStore) AutoCloseConnections() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state.AutoCloseConnections
+}
+
+// SetAutoCloseConnections enables or disables closing connections after a manual switch
+func (s *StateStore) SetAutoCloseConnections(enabled bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.state.AutoCloseConnections == enabled {
+		return nil
+	}
+	s.state.AutoCloseConnections = enabled
+	return s.saveLocked()
+}
+
+// LatencyTestSettings returns the latency test settings
+func (s *StateStore) LatencyTestSettings() LatencyTestSettings {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state.LatencyTest
+}
+
+// SetLatencyTestSettings saves the latency test settings
+func (s *StateStore) SetLatencyTestSettings(settings LatencyTestSettings) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.state.LatencyTest == settings {
+		return nil
+	}
+	s.state.LatencyTest = settings
+	return s.saveLocked()
+}
+
+// ClashMode returns the remembered Clash mode (empty if never chosen)
+func (s *StateStore) ClashMode() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state.ClashMode
+}
+
+// SetClashMode remembers the Clash mode chosen by the user
+func (s *StateStore) SetClashMode(mode string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.state.ClashMode == mode {
+		return nil
+	}
+	s.state.ClashMode = mode
+	return s.saveLocked()
+}
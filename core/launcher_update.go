@@ -0,0 +1,575 @@
+package core
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	ps "github.com/mitchellh/go-ps"
+
+	"singbox-launcher/internal/constants"
+	"singbox-launcher/internal/platform"
+)
+
+const (
+	// launcherReleasesURL lists launcher releases, newest first
+	launcherReleasesURL = "https://api.github.com/repos/Leadaxe/singbox-launcher/releases?per_page=30"
+	// launcherReadyTimeout is how long a relaunched launcher may take to start (including the wait for the old one)
+	launcherReadyTimeout = 90 * time.Second
+	// launcherParentWaitTimeout limits how long a relaunched launcher waits for the old one to exit
+	launcherParentWaitTimeout = 30 * time.Second
+	// LauncherUpdateParentEnv passes the PID of the launcher that installed an update to the relaunched one
+	LauncherUpdateParentEnv = "SINGBOX_LAUNCHER_UPDATE_PARENT"
+	// LauncherUpdateReadyEnv passes the file the relaunched launcher creates once it has started
+	LauncherUpdateReadyEnv = "SINGBOX_LAUNCHER_UPDATE_READY"
+	// LauncherUpdateWatchdogEnv starts the previous executable as the watchdog of the new one (JSON launcherWatch)
+	LauncherUpdateWatchdogEnv = "SINGBOX_LAUNCHER_UPDATE_WATCHDOG"
+	// LauncherUpdateFailedEnv tells the restored launcher why the update was rolled back
+	LauncherUpdateFailedEnv = "SINGBOX_LAUNCHER_UPDATE_FAILED"
+)
+
+// gitDescribeSuffixRegex matches the "-<commits>-g<hash>" suffix of development builds (git describe)
+var gitDescribeSuffixRegex = regexp.MustCompile(`-\d+-g[0-9a-f]+$`)
+
+// launcherCompareVersion strips the development suffixes from a launcher version,
+// so that a build made after a tag is not offered that tag as an update
+func launcherCompareVersion(version string) string {
+	version = strings.TrimPrefix(version, "v")
+	version = strings.TrimSuffix(version, "-dirty")
+	return gitDescribeSuffixRegex.ReplaceAllString(version, "")
+}
+
+// LauncherChannel returns the release channel for launcher updates (stable or prerelease)
+func (ac *AppController) LauncherChannel() string {
+	if ac.Settings.Get().Launcher.Channel == CoreChannelPrerelease {
+		return CoreChannelPrerelease
+	}
+	return CoreChannelStable
+}
+
+// LauncherUpdate is a launcher release that can be installed on this platform
+type LauncherUpdate struct {
+	Version string // Tag of the release, e.g. "v0.5.0"
+	Release *ReleaseInfo
+	Asset   *Asset
+}
+
+// LauncherUpdater replaces the launcher executable with a newer release.
+// The fields default to the running launcher; tests point ReleasesURL to a local server.
+type LauncherUpdater struct {
+	ReleasesURL    string   // GitHub API release list
+	CurrentVersion string   // Version of the running launcher
+	Channel        string   // stable or prerelease
+	ExecPath       string   // Executable to replace
+	Args           []string // Command line arguments for the relaunch
+	GOOS, GOARCH   string   // Platform of the release asset
+	ReadyTimeout   time.Duration
+	WatchdogPath   string // Executable watching the relaunch; empty for the rollback copy (the running version)
+
+	ac *AppController // Downloads use the proxy, mirrors and retries from the settings
+}
+
+// NewLauncherUpdater creates an updater for the running launcher
+func (ac *AppController) NewLauncherUpdater() (*LauncherUpdater, error) {
+	execPath, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("cannot detect executable path: %w", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(execPath); err == nil {
+		execPath = resolved
+	}
+	return &LauncherUpdater{
+		ReleasesURL:    launcherReleasesURL,
+		CurrentVersion: constants.AppVersion,
+		Channel:        ac.LauncherChannel(),
+		ExecPath:       execPath,
+		Args:           os.Args[1:],
+		GOOS:           runtime.GOOS,
+		GOARCH:         runtime.GOARCH,
+		ReadyTimeout:   launcherReadyTimeout,
+		ac:             ac,
+	}, nil
+}
+
+// LatestRelease returns the newest launcher release of the channel
+func (u *LauncherUpdater) LatestRelease() (*ReleaseInfo, error) {
+	releases, err := u.ac.fetchReleases(u.ReleasesURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get launcher releases: %w", err)
+	}
+	return selectRelease(releases, u.Channel, "")
+}
+
+// Check returns the update to install or nil if the launcher is up to date
+func (u *LauncherUpdater) Check() (*LauncherUpdate, error) {
+	release, err := u.LatestRelease()
+	if err != nil {
+		return nil, err
+	}
+	if CompareVersions(launcherCompareVersion(u.CurrentVersion), launcherCompareVersion(release.TagName)) >= 0 {
+		return nil, nil
+	}
+	asset, err := findLauncherAsset(release.Assets, u.GOOS, u.GOARCH)
+	if err != nil {
+		return nil, fmt.Errorf("launcher %s: %w", release.TagName, err)
+	}
+	return &LauncherUpdate{Version: release.TagName, Release: release, Asset: asset}, nil
+}
+
+// launcherPlatformNames are the words release assets use for an OS or architecture
+var launcherPlatformNames = map[string][]string{
+	"windows": {"windows", "win64", "win32"},
+	"darwin":  {"macos", "darwin", "mac", "osx"},
+	"linux":   {"linux"},
+	"amd64":   {"amd64", "x86_64", "x64"},
+	"arm64":   {"arm64", "aarch64"},
+	"386":     {"386", "i686", "win32"},
+}
+
+// containsPlatformName reports whether name mentions one of the words of key
+func containsPlatformName(name, key string) bool {
+	for _, word := range launcherPlatformNames[key] {
+		if strings.Contains(name, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// findLauncherAsset picks the release asset for the platform. An asset without an architecture
+// (or a "universal" macOS build) matches any architecture of its OS.
+func findLauncherAsset(assets []Asset, goos, goarch string) (*Asset, error) {
+	var fallback *Asset
+	for i := range assets {
+		name := strings.ToLower(assets[i].Name)
+		if strings.HasSuffix(name, ".sha256") || strings.HasSuffix(name, ".sha256sum") || strings.Contains(name, "checksums") {
+			continue
+		}
+		if !containsPlatformName(name, goos) {
+			continue
+		}
+		if containsPlatformName(name, goarch) {
+			return &assets[i], nil
+		}
+		otherArch := false
+		for _, arch := range []string{"amd64", "arm64", "386"} {
+			if arch != goarch && containsPlatformName(name, arch) {
+				otherArch = true
+			}
+		}
+		if !otherArch && fallback == nil {
+			fallback = &assets[i]
+		}
+	}
+	if fallback == nil {
+		return nil, fmt.Errorf("no release asset for %s/%s", goos, goarch)
+	}
+	return fallback, nil
+}
+
+// launcherExecutableName returns the file name of the launcher executable inside a release archive
+func launcherExecutableName(goos string) string {
+	if goos == "windows" {
+		return "singbox-launcher.exe"
+	}
+	return "singbox-launcher"
+}
+
+// Download fetches the asset of update into a private temporary directory, verifies its checksum and returns
+// the new executable, read from the same open file that was verified. Close removes the download.
+// The release must publish a checksum (GitHub asset digest or a checksums file).
+func (u *LauncherUpdater) Download(ctx context.Context, update *LauncherUpdate, progressChan chan DownloadProgress) (io.ReadCloser, error) {
+	expected, source, err := u.ac.requireAssetChecksum(ctx, update.Release, update.Asset)
+	if err != nil {
+		return nil, err
+	}
+
+	// Не общий каталог в /tmp: другой пользователь не должен подменить файл между проверкой и установкой
+	tempDir, err := os.MkdirTemp("", "singbox-launcher-update-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	assetPath := filepath.Join(tempDir, filepath.Base(update.Asset.Name))
+	if err := u.ac.downloadFile(ctx, update.Asset.BrowserDownloadURL, assetPath, progressChan); err != nil {
+		os.RemoveAll(tempDir)
+		return nil, fmt.Errorf("failed to download %s: %w", update.Asset.Name, err)
+	}
+	asset, err := os.Open(assetPath)
+	if err != nil {
+		os.RemoveAll(tempDir)
+		return nil, fmt.Errorf("failed to open %s: %w", update.Asset.Name, err)
+	}
+	download := &launcherDownload{Reader: asset, asset: asset, dir: tempDir}
+
+	progressChan <- DownloadProgress{Progress: 80, Message: "Verifying checksum...", Status: "verifying"}
+	if err := verifyReaderChecksum(asset, update.Asset.Name, expected); err != nil {
+		download.Close()
+		return nil, err
+	}
+	log.Printf("LauncherUpdater: %s matches the checksum from %s", update.Asset.Name, source)
+	if _, err := asset.Seek(0, io.SeekStart); err != nil {
+		download.Close()
+		return nil, fmt.Errorf("failed to read %s: %w", update.Asset.Name, err)
+	}
+
+	name := strings.ToLower(update.Asset.Name)
+	if !strings.HasSuffix(name, ".zip") && !strings.HasSuffix(name, ".tar.gz") {
+		return download, nil // Release publishes the bare executable
+	}
+	progressChan <- DownloadProgress{Progress: 90, Message: "Extracting...", Status: "extracting"}
+	executable, err := openArchivedFile(asset, name, launcherExecutableName(u.GOOS))
+	if err != nil {
+		download.Close()
+		return nil, err
+	}
+	download.Reader = executable
+	download.entry = executable
+	return download, nil
+}
+
+// launcherDownload is the new executable read from a verified asset (directly or from the archive inside it)
+type launcherDownload struct {
+	io.Reader
+	entry io.Closer // Archive entry, nil for a bare executable
+	asset *os.File
+	dir   string // Private directory of the download
+}
+
+// Close closes the asset and removes its directory
+func (d *launcherDownload) Close() error {
+	if d.entry != nil {
+		d.entry.Close()
+	}
+	err := d.asset.Close()
+	os.RemoveAll(d.dir)
+	return err
+}
+
+// openArchivedFile opens the first file called fileName (in any folder, e.g. inside a macOS .app bundle)
+// in a .zip or .tar.gz archive
+func openArchivedFile(archive *os.File, archiveName, fileName string) (io.ReadCloser, error) {
+	if strings.HasSuffix(archiveName, ".zip") {
+		info, err := archive.Stat()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open zip: %w", err)
+		}
+		r, err := zip.NewReader(archive, info.Size())
+		if err != nil {
+			return nil, fmt.Errorf("failed to open zip: %w", err)
+		}
+		for _, f := range r.File {
+			if f.FileInfo().IsDir() || filepath.Base(f.Name) != fileName {
+				continue
+			}
+			rc, err := f.Open()
+			if err != nil {
+				return nil, fmt.Errorf("failed to open file in zip: %w", err)
+			}
+			return rc, nil
+		}
+		return nil, fmt.Errorf("%s not found in archive", fileName)
+	}
+
+	gzr, err := gzip.NewReader(archive)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gzip reader: %w", err)
+	}
+	tr := tar.NewReader(gzr)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			gzr.Close()
+			return nil, fmt.Errorf("failed to read tar: %w", err)
+		}
+		if header.Typeflag == tar.TypeReg && filepath.Base(header.Name) == fileName {
+			return struct {
+				io.Reader
+				io.Closer
+			}{tr, gzr}, nil
+		}
+	}
+	gzr.Close()
+	return nil, fmt.Errorf("%s not found in archive", fileName)
+}
+
+// LauncherBackupPath returns the rollback copy of the previous launcher executable
+func LauncherBackupPath(execPath string) string {
+	return execPath + ".old"
+}
+
+// Install swaps the executable: the new one is written next to it, the running executable is renamed
+// to the rollback copy (allowed for a running program on Windows too) and the new file takes its name.
+func (u *LauncherUpdater) Install(newExecutable io.Reader) error {
+	stagedPath := u.ExecPath + ".new"
+	if err := writeStagedExecutable(newExecutable, stagedPath); err != nil {
+		os.Remove(stagedPath)
+		return fmt.Errorf("failed to stage the new launcher: %w", err)
+	}
+	if err := os.Chmod(stagedPath, 0755); err != nil {
+		os.Remove(stagedPath)
+		return fmt.Errorf("failed to make the new launcher executable: %w", err)
+	}
+	backupPath := LauncherBackupPath(u.ExecPath)
+	os.Remove(backupPath)
+	if err := os.Rename(u.ExecPath, backupPath); err != nil {
+		os.Remove(stagedPath)
+		return fmt.Errorf("failed to keep a rollback copy: %w", err)
+	}
+	if err := os.Rename(stagedPath, u.ExecPath); err != nil {
+		if restoreErr := os.Rename(backupPath, u.ExecPath); restoreErr != nil {
+			return fmt.Errorf("failed to install the new launcher: %w (restore failed: %v)", err, restoreErr)
+		}
+		os.Remove(stagedPath)
+		return fmt.Errorf("failed to install the new launcher: %w", err)
+	}
+	log.Printf("LauncherUpdater: installed %s, previous version kept as %s", u.ExecPath, backupPath)
+	return nil
+}
+
+// writeStagedExecutable writes the new executable to path
+func writeStagedExecutable(r io.Reader, path string) error {
+	out, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0755)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// Rollback restores the executable from the rollback copy
+func (u *LauncherUpdater) Rollback() error {
+	backupPath := LauncherBackupPath(u.ExecPath)
+	if _, err := os.Stat(backupPath); err != nil {
+		return fmt.Errorf("no rollback copy of the launcher: %w", err)
+	}
+	failedPath := u.ExecPath + ".failed"
+	os.Remove(failedPath)
+	if err := os.Rename(u.ExecPath, failedPath); err != nil {
+		return fmt.Errorf("failed to move the new launcher aside: %w", err)
+	}
+	if err := os.Rename(backupPath, u.ExecPath); err != nil {
+		return fmt.Errorf("failed to restore the previous launcher: %w", err)
+	}
+	os.Remove(failedPath) // On Windows it stays until the next update if it is still running
+	log.Printf("LauncherUpdater: rolled back to the previous %s", u.ExecPath)
+	return nil
+}
+
+// LauncherReadyPath returns the file a relaunched launcher creates once it has started
+func LauncherReadyPath(execPath string) string {
+	return execPath + ".ready"
+}
+
+// launcherWatch is what the watchdog of a relaunch needs to know (passed in LauncherUpdateWatchdogEnv)
+type launcherWatch struct {
+	PID       int           `json:"pid"` // Relaunched launcher
+	ExecPath  string        `json:"exec_path"`
+	Args      []string      `json:"args"`
+	ReadyPath string        `json:"ready_path"`
+	Version   string        `json:"version"`
+	Timeout   time.Duration `json:"timeout"`
+}
+
+// Relaunch starts the installed executable with the same arguments and the previous executable as its watchdog,
+// then returns: the caller exits right away, and the new launcher waits for that (see WaitForUpdatedParent).
+// The new launcher reports that it has started with SignalLauncherReady; if it exits or hangs before that,
+// the watchdog restores the previous executable and starts it (see RunLauncherWatchdog).
+func (u *LauncherUpdater) Relaunch(version string) error {
+	readyPath := LauncherReadyPath(u.ExecPath)
+	os.Remove(readyPath)
+
+	cmd := exec.Command(u.ExecPath, u.Args...)
+	cmd.Env = append(os.Environ(),
+		LauncherUpdateParentEnv+"="+strconv.Itoa(os.Getpid()),
+		LauncherUpdateReadyEnv+"="+readyPath)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		return u.rollbackAfter(fmt.Errorf("failed to start the new launcher: %w", err))
+	}
+
+	watch, err := json.Marshal(launcherWatch{
+		PID:       cmd.Process.Pid,
+		ExecPath:  u.ExecPath,
+		Args:      u.Args,
+		ReadyPath: readyPath,
+		Version:   version,
+		Timeout:   u.ReadyTimeout,
+	})
+	if err != nil {
+		cmd.Process.Kill()
+		return u.rollbackAfter(fmt.Errorf("failed to marshal the watchdog state: %w", err))
+	}
+	watchdogPath := u.WatchdogPath
+	if watchdogPath == "" {
+		watchdogPath = LauncherBackupPath(u.ExecPath)
+	}
+	watchdog := exec.Command(watchdogPath)
+	watchdog.Env = append(os.Environ(), LauncherUpdateWatchdogEnv+"="+string(watch))
+	platform.PrepareCommand(watchdog)
+	if err := watchdog.Start(); err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return u.rollbackAfter(fmt.Errorf("failed to start the update watchdog: %w", err))
+	}
+	log.Printf("LauncherUpdater: new launcher started (PID=%d), watchdog PID=%d", cmd.Process.Pid, watchdog.Process.Pid)
+	// Both outlive this process
+	cmd.Process.Release()
+	watchdog.Process.Release()
+	return nil
+}
+
+// rollbackAfter restores the previous executable after a failed relaunch
+func (u *LauncherUpdater) rollbackAfter(err error) error {
+	if rollbackErr := u.Rollback(); rollbackErr != nil {
+		return fmt.Errorf("%w; %v", err, rollbackErr)
+	}
+	return fmt.Errorf("%w; the previous version was restored", err)
+}
+
+// UpdateLauncher downloads and installs update and starts the new launcher.
+// On success the caller must exit right away (GracefulExit) so that the new launcher can take over.
+func (ac *AppController) UpdateLauncher(ctx context.Context, updater *LauncherUpdater, update *LauncherUpdate, progressChan chan DownloadProgress) error {
+	newExecutable, err := updater.Download(ctx, update, progressChan)
+	if err != nil {
+		return err
+	}
+	defer newExecutable.Close()
+
+	progressChan <- DownloadProgress{Progress: 95, Message: "Installing...", Status: "extracting"}
+	if err := updater.Install(newExecutable); err != nil {
+		return err
+	}
+	if err := updater.Relaunch(update.Version); err != nil {
+		return err
+	}
+	progressChan <- DownloadProgress{Progress: 100, Message: fmt.Sprintf("Launcher %s installed, restarting...", update.Version), Status: "done"}
+	return nil
+}
+
+// launcherReadyPath is the file SignalLauncherReady creates (set by WaitForUpdatedParent after a relaunch)
+var launcherReadyPath string
+
+// WaitForUpdatedParent is called at startup: a launcher started by Relaunch waits until the old one has
+// exited, so that it does not see it as a second instance and can take over sing-box and the control port.
+func WaitForUpdatedParent() {
+	launcherReadyPath = os.Getenv(LauncherUpdateReadyEnv)
+	os.Unsetenv(LauncherUpdateReadyEnv)
+	value := os.Getenv(LauncherUpdateParentEnv)
+	if value == "" {
+		return
+	}
+	os.Unsetenv(LauncherUpdateParentEnv)
+	pid, err := strconv.Atoi(value)
+	if err != nil {
+		return
+	}
+	deadline := time.Now().Add(launcherParentWaitTimeout)
+	for time.Now().Before(deadline) {
+		if process, err := ps.FindProcess(pid); err != nil || process == nil {
+			return
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+	log.Printf("WaitForUpdatedParent: previous launcher (PID=%d) is still running, continuing", pid)
+}
+
+// SignalLauncherReady tells the watchdog of a relaunch that the new launcher has started (no-op otherwise)
+func SignalLauncherReady() {
+	if launcherReadyPath == "" {
+		return
+	}
+	if err := os.WriteFile(launcherReadyPath, []byte(strconv.Itoa(os.Getpid())), 0644); err != nil {
+		log.Printf("SignalLauncherReady: %v", err)
+		return
+	}
+	log.Printf("SignalLauncherReady: updated launcher %s started", constants.AppVersion)
+	launcherReadyPath = ""
+}
+
+// LauncherUpdateFailure returns why the previous launcher update was rolled back (empty if it was not)
+func LauncherUpdateFailure() string {
+	message := os.Getenv(LauncherUpdateFailedEnv)
+	os.Unsetenv(LauncherUpdateFailedEnv)
+	return message
+}
+
+// IsLauncherWatchdog reports whether this process was started by Relaunch to watch the new launcher
+func IsLauncherWatchdog() bool {
+	return os.Getenv(LauncherUpdateWatchdogEnv) != ""
+}
+
+// RunLauncherWatchdog watches a relaunched launcher and returns the exit code of the watchdog process
+func RunLauncherWatchdog() int {
+	var watch launcherWatch
+	if err := json.Unmarshal([]byte(os.Getenv(LauncherUpdateWatchdogEnv)), &watch); err != nil {
+		log.Printf("RunLauncherWatchdog: invalid %s: %v", LauncherUpdateWatchdogEnv, err)
+		return 1
+	}
+	os.Unsetenv(LauncherUpdateWatchdogEnv)
+	logPath := filepath.Join(filepath.Dir(watch.ExecPath), logFileName)
+	if logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644); err == nil {
+		defer logFile.Close()
+		log.SetOutput(logFile)
+	}
+	if err := watch.run(); err != nil {
+		log.Printf("RunLauncherWatchdog: %v", err)
+		return 1
+	}
+	return 0
+}
+
+// run waits until the relaunched launcher signals readiness. If it exits first or does not start
+// within Timeout, it is stopped, the previous executable is restored and started again.
+func (w launcherWatch) run() error {
+	deadline := time.Now().Add(w.Timeout)
+	var failure error
+	for failure == nil {
+		if _, err := os.Stat(w.ReadyPath); err == nil {
+			os.Remove(w.ReadyPath)
+			log.Printf("launcherWatch: launcher %s started (PID=%d)", w.Version, w.PID)
+			return nil
+		}
+		if process, err := ps.FindProcess(w.PID); err == nil && process == nil {
+			failure = fmt.Errorf("launcher %s exited before it finished starting", w.Version)
+		} else if time.Now().After(deadline) {
+			if process, err := os.FindProcess(w.PID); err == nil {
+				process.Kill()
+			}
+			failure = fmt.Errorf("launcher %s did not finish starting within %v", w.Version, w.Timeout)
+		} else {
+			time.Sleep(200 * time.Millisecond)
+		}
+	}
+
+	updater := &LauncherUpdater{ExecPath: w.ExecPath}
+	if err := updater.Rollback(); err != nil {
+		return fmt.Errorf("%w; %v", failure, err)
+	}
+	cmd := exec.Command(w.ExecPath, w.Args...)
+	cmd.Env = append(os.Environ(), LauncherUpdateFailedEnv+"="+failure.Error()+"; the previous version was restored")
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("%w; the previous version was restored but failed to start: %v", failure, err)
+	}
+	cmd.Process.Release()
+	return fmt.Errorf("%w; the previous version was restored and started", failure)
+}
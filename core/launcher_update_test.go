@@ -0,0 +1,263 @@
+package core
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// launcherTestArchive packs an executable like a release archive: singbox-launcher/singbox-launcher
+func launcherTestArchive(t *testing.T, content string) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, file := range []struct{ name, content string }{
+		{"singbox-launcher/README.md", "readme"},
+		{"singbox-launcher/singbox-launcher", content},
+	} {
+		if err := tw.WriteHeader(&tar.Header{Name: file.name, Mode: 0755, Size: int64(len(file.content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte(file.content))
+	}
+	tw.Close()
+	gz.Close()
+	return buf.Bytes()
+}
+
+// newLauncherTestServer stands in for the GitHub API and release downloads
+func newLauncherTestServer(t *testing.T, archive []byte, digest string) *httptest.Server {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/releases":
+			assetName := "singbox-launcher-v0.5.0-linux-amd64.tar.gz"
+			releases := []ReleaseInfo{
+				{TagName: "v0.6.0-beta.1", Prerelease: true, Assets: []Asset{
+					{Name: "singbox-launcher-v0.6.0-beta.1-linux-amd64.tar.gz", BrowserDownloadURL: server.URL + "/download/beta.tar.gz", Digest: digest},
+				}},
+				{TagName: "v0.5.0", Body: "Fixes", Assets: []Asset{
+					{Name: "singbox-launcher-v0.5.0-windows-amd64.zip", BrowserDownloadURL: server.URL + "/download/windows.zip"},
+					{Name: "singbox-launcher-v0.5.0-linux-arm64.tar.gz", BrowserDownloadURL: server.URL + "/download/arm64.tar.gz"},
+					{Name: assetName, BrowserDownloadURL: server.URL + "/download/" + assetName, Digest: digest},
+				}},
+				{TagName: "v0.4.1"},
+			}
+			json.NewEncoder(w).Encode(releases)
+		case "/download/singbox-launcher-v0.5.0-linux-amd64.tar.gz":
+			w.Write(archive)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// newTestLauncherUpdater creates an updater for a fake executable in a temp dir
+func newTestLauncherUpdater(t *testing.T, server *httptest.Server, current string) *LauncherUpdater {
+	execPath := filepath.Join(t.TempDir(), "singbox-launcher")
+	if err := os.WriteFile(execPath, []byte("old launcher"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return &LauncherUpdater{
+		ReleasesURL:    server.URL + "/releases",
+		CurrentVersion: current,
+		Channel:        CoreChannelStable,
+		ExecPath:       execPath,
+		GOOS:           "linux",
+		GOARCH:         "amd64",
+		ReadyTimeout:   500 * time.Millisecond,
+		ac:             &AppController{},
+	}
+}
+
+// TestLauncherUpdater_Check tests the channel, development versions and asset selection
+func TestLauncherUpdater_Check(t *testing.T) {
+	server := newLauncherTestServer(t, nil, "")
+
+	update, err := newTestLauncherUpdater(t, server, "0.4.1").Check()
+	if err != nil || update == nil {
+		t.Fatalf("Expected an update, got %v (%v)", update, err)
+	}
+	if update.Version != "v0.5.0" || update.Asset.Name != "singbox-launcher-v0.5.0-linux-amd64.tar.gz" {
+		t.Errorf("Unexpected update %s %s", update.Version, update.Asset.Name)
+	}
+
+	updater := newTestLauncherUpdater(t, server, "0.4.1")
+	updater.Channel = CoreChannelPrerelease
+	if update, err := updater.Check(); err != nil || update == nil || update.Version != "v0.6.0-beta.1" {
+		t.Errorf("Expected the pre-release, got %+v (%v)", update, err)
+	}
+
+	// A build made after the v0.5.0 tag is not older than v0.5.0
+	if update, err := newTestLauncherUpdater(t, server, "v0.5.0-3-g1a2b3c4-dirty").Check(); err != nil || update != nil {
+		t.Errorf("Expected no update for a development build, got %+v (%v)", update, err)
+	}
+}
+
+// TestLauncherUpdater_DownloadAndInstall tests verification, the executable swap and the rollback copy
+func TestLauncherUpdater_DownloadAndInstall(t *testing.T) {
+	archive := launcherTestArchive(t, "new launcher")
+	sum := sha256.Sum256(archive)
+
+	// A tampered archive is not installed
+	server := newLauncherTestServer(t, archive, "sha256:"+strings.Repeat("0", 64))
+	updater := newTestLauncherUpdater(t, server, "0.4.1")
+	update, err := updater.Check()
+	if err != nil {
+		t.Fatal(err)
+	}
+	progress := make(chan DownloadProgress, 100)
+	if _, err := updater.Download(context.Background(), update, progress); !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("Expected a checksum mismatch, got %v", err)
+	}
+
+	server = newLauncherTestServer(t, archive, "sha256:"+hex.EncodeToString(sum[:]))
+	updater = newTestLauncherUpdater(t, server, "0.4.1")
+	if update, err = updater.Check(); err != nil {
+		t.Fatal(err)
+	}
+	newExecutable, err := updater.Download(context.Background(), update, progress)
+	if err != nil {
+		t.Fatalf("Download: %v", err)
+	}
+	defer newExecutable.Close()
+	if err := updater.Install(newExecutable); err != nil {
+		t.Fatalf("Install: %v", err)
+	}
+	if data, _ := os.ReadFile(updater.ExecPath); string(data) != "new launcher" {
+		t.Errorf("Expected the new executable, got %q", data)
+	}
+	if data, _ := os.ReadFile(LauncherBackupPath(updater.ExecPath)); string(data) != "old launcher" {
+		t.Errorf("Expected the rollback copy, got %q", data)
+	}
+
+	if err := updater.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(updater.ExecPath); string(data) != "old launcher" {
+		t.Errorf("Expected the previous executable after rollback, got %q", data)
+	}
+}
+
+// TestLauncherUpdater_Relaunch tests that the new launcher and its watchdog are started,
+// and that the update is rolled back when the watchdog cannot be started
+func TestLauncherUpdater_Relaunch(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses shell scripts as executables")
+	}
+	server := newLauncherTestServer(t, nil, "")
+	dir := t.TempDir()
+	newExecutable := "#!/bin/sh\nsleep 1\n"
+	watchdog := filepath.Join(dir, "watchdog")
+	watchFile := filepath.Join(dir, "watch.json")
+	if err := os.WriteFile(watchdog, []byte("#!/bin/sh\necho \"$"+LauncherUpdateWatchdogEnv+"\" > "+watchFile+"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	updater := newTestLauncherUpdater(t, server, "0.4.1")
+	updater.WatchdogPath = watchdog
+	if err := updater.Install(strings.NewReader(newExecutable)); err != nil {
+		t.Fatal(err)
+	}
+	if err := updater.Relaunch("v0.5.0"); err != nil {
+		t.Fatalf("Relaunch: %v", err)
+	}
+	var watch launcherWatch
+	for i := 0; i < 50 && watch.PID == 0; i++ {
+		time.Sleep(50 * time.Millisecond)
+		if data, err := os.ReadFile(watchFile); err == nil {
+			json.Unmarshal(data, &watch)
+		}
+	}
+	if watch.PID == 0 || watch.ReadyPath != LauncherReadyPath(updater.ExecPath) || watch.Version != "v0.5.0" {
+		t.Errorf("Watchdog not started with the new launcher: %+v", watch)
+	}
+
+	// Без watchdog обновление откатывается сразу
+	updater = newTestLauncherUpdater(t, server, "0.4.1")
+	updater.WatchdogPath = filepath.Join(dir, "missing")
+	if err := updater.Install(strings.NewReader(newExecutable)); err != nil {
+		t.Fatal(err)
+	}
+	if err := updater.Relaunch("v0.5.0"); err == nil {
+		t.Errorf("Expected an error without a watchdog")
+	}
+	if data, _ := os.ReadFile(updater.ExecPath); string(data) != "old launcher" {
+		t.Errorf("Expected the previous executable to be restored, got %q", data)
+	}
+}
+
+// TestLauncherWatch tests the watchdog: a launcher that signals readiness is kept,
+// one that exits or hangs before that is replaced by the previous version, which is started again
+func TestLauncherWatch(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses shell scripts as executables")
+	}
+	for _, tt := range []struct {
+		name     string
+		script   string
+		wantErr  bool
+		wantExec string
+	}{
+		{"ready", ": > \"$READY\"; sleep 1", false, "new launcher"},
+		{"crash", "exit 3", true, "old launcher"},
+		{"hang", "sleep 5", true, "old launcher"},
+	} {
+		dir := t.TempDir()
+		execPath := filepath.Join(dir, "singbox-launcher")
+		restartedFile := filepath.Join(dir, "restarted")
+		oldLauncher := "#!/bin/sh\n# old launcher\necho \"$" + LauncherUpdateFailedEnv + "\" > " + restartedFile + "\n"
+		if err := os.WriteFile(execPath, []byte("#!/bin/sh\n# new launcher\n"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(LauncherBackupPath(execPath), []byte(oldLauncher), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		readyPath := LauncherReadyPath(execPath)
+		cmd := exec.Command("/bin/sh", "-c", tt.script)
+		cmd.Env = append(os.Environ(), "READY="+readyPath)
+		if err := cmd.Start(); err != nil {
+			t.Fatal(err)
+		}
+		go cmd.Wait() // Reap the process, otherwise a zombie looks alive
+
+		watch := launcherWatch{PID: cmd.Process.Pid, ExecPath: execPath, ReadyPath: readyPath, Version: "v0.5.0", Timeout: time.Second}
+		err := watch.run()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		}
+		data, _ := os.ReadFile(execPath)
+		if !strings.Contains(string(data), tt.wantExec) {
+			t.Errorf("%s: expected the %s, got %q", tt.name, tt.wantExec, data)
+		}
+		if tt.wantErr {
+			var restarted []byte
+			for i := 0; i < 50 && len(restarted) == 0; i++ {
+				time.Sleep(50 * time.Millisecond)
+				restarted, _ = os.ReadFile(restartedFile)
+			}
+			if !strings.Contains(string(restarted), "v0.5.0") {
+				t.Errorf("%s: previous launcher not started with the failure, got %q", tt.name, restarted)
+			}
+		} else if _, err := os.Stat(readyPath); !os.IsNotExist(err) {
+			t.Errorf("%s: ready file not removed", tt.name)
+		}
+	}
+}
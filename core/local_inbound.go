@@ -0,0 +1,103 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+
+	"github.com/muhammadmuzzammil1998/jsonc"
+)
+
+// LocalInbound is a proxy inbound of the sing-box config that local applications can use
+type LocalInbound struct {
+	Type string // "mixed", "http" or "socks"
+	Tag  string
+	Host string // Address to connect to (wildcard listen addresses are replaced with loopback)
+	Port int
+}
+
+// Address returns host:port of the inbound
+func (in LocalInbound) Address() string {
+	return net.JoinHostPort(in.Host, strconv.Itoa(in.Port))
+}
+
+// ProxyURL returns the inbound as a proxy URL for http.Transport (mixed inbounds accept HTTP)
+func (in LocalInbound) ProxyURL() *url.URL {
+	scheme := "http"
+	if in.Type == "socks" {
+		scheme = "socks5"
+	}
+	return &url.URL{Scheme: scheme, Host: in.Address()}
+}
+
+// localInboundPriority orders inbound types: mixed serves both HTTP and SOCKS clients
+var localInboundPriority = map[string]int{"mixed": 0, "http": 1, "socks": 2}
+
+// configInbound is the part of an inbound needed to find local proxies
+type configInbound struct {
+	Type       string `json:"type"`
+	Tag        string `json:"tag"`
+	Listen     string `json:"listen"`
+	ListenPort int    `json:"listen_port"`
+}
+
+// readConfigInbounds reads the inbounds of a JSONC config
+func readConfigInbounds(configPath string) ([]configInbound, error) {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	var config struct {
+		Inbounds []configInbound `json:"inbounds"`
+	}
+	if err := json.Unmarshal(jsonc.ToJSON(data), &config); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	return config.Inbounds, nil
+}
+
+// ConfigHasTunInbound reports whether the config captures traffic with a tun inbound
+func ConfigHasTunInbound(configPath string) (bool, error) {
+	inbounds, err := readConfigInbounds(configPath)
+	if err != nil {
+		return false, err
+	}
+	for _, inbound := range inbounds {
+		if inbound.Type == "tun" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// FindLocalProxyInbound returns the mixed, http or socks inbound of the config (mixed preferred).
+// Returns an error if the config has none with a listen_port.
+func FindLocalProxyInbound(configPath string) (LocalInbound, error) {
+	inbounds, err := readConfigInbounds(configPath)
+	if err != nil {
+		return LocalInbound{}, err
+	}
+
+	var found *LocalInbound
+	for _, inbound := range inbounds {
+		priority, ok := localInboundPriority[inbound.Type]
+		if !ok || inbound.ListenPort <= 0 {
+			continue
+		}
+		if found != nil && localInboundPriority[found.Type] <= priority {
+			continue
+		}
+		host := inbound.Listen
+		if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+			host = "127.0.0.1"
+		}
+		found = &LocalInbound{Type: inbound.Type, Tag: inbound.Tag, Host: host, Port: inbound.ListenPort}
+	}
+	if found == nil {
+		return LocalInbound{}, fmt.Errorf("config has no mixed, http or socks inbound with listen_port")
+	}
+	return *found, nil
+}
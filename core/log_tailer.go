@@ -0,0 +1,477 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"singbox-launcher/api"
+	"singbox-launcher/internal/constants"
+)
+
+const (
+	// logBufferCapacity is the maximum number of entries kept in memory per log file
+	logBufferCapacity = 5000
+	// logInitialTailBytes is how much of an existing log file is loaded when tailing starts
+	logInitialTailBytes = 256 * 1024
+	// logMaxReadPerPoll limits how much is read in a single poll to keep UI updates short
+	logMaxReadPerPoll = 1024 * 1024
+	// logMaxLineLength is the length after which an unterminated line is flushed as is
+	logMaxLineLength = 64 * 1024
+)
+
+// Normalized log levels
+const (
+	LogLevelDebug = "DEBUG"
+	LogLevelInfo  = "INFO"
+	LogLevelWarn  = "WARN"
+	LogLevelError = "ERROR"
+)
+
+// LogLevels lists normalized levels in order of severity
+var LogLevels = []string{LogLevelDebug, LogLevelInfo, LogLevelWarn, LogLevelError}
+
+// LogEntry is a single parsed log line.
+type LogEntry struct {
+	Time      string
+	Level     string
+	Component string
+	Message   string
+	Raw       string
+}
+
+// LiveLogSourceName is the name of the log source backed by the Clash API /logs stream
+const LiveLogSourceName = "sing-box (live API)"
+
+// LogSource describes a log that can be shown in the Logs tab:
+// a file (Path set) or the live Clash API stream (Path empty).
+type LogSource struct {
+	Name string
+	Path string
+}
+
+// LogFeed is a source of parsed log entries: a file tailer or the Clash API log stream.
+type LogFeed interface {
+	Buffer() *LogBuffer
+	Run(ctx context.Context, interval time.Duration, onUpdate func())
+}
+
+// LogSources returns the available log sources in display order (sing-box first)
+func (ac *AppController) LogSources() []LogSource {
+	return []LogSource{
+		{Name: constants.ChildLogFileName, Path: filepath.Join(ac.ExecDir, childLogFileName)},
+		{Name: LiveLogSourceName},
+		{Name: constants.MainLogFileName, Path: filepath.Join(ac.ExecDir, logFileName)},
+		{Name: constants.ParserLogFileName, Path: filepath.Join(ac.ExecDir, parserLogFileName)},
+		{Name: constants.APILogFileName, Path: filepath.Join(ac.ExecDir, apiLogFileName)},
+	}
+}
+
+// NewLogFeed creates a feed for the log source
+func (ac *AppController) NewLogFeed(source LogSource) LogFeed {
+	if source.Path == "" {
+		return NewAPILogStream(ac, 0)
+	}
+	return NewLogTailer(source.Path, 0)
+}
+
+var (
+	// sing-box: "+0300 2024-01-02 15:04:05 INFO [1234 5ms] router: message" or "INFO[0000] router: message"
+	singboxLineRegex = regexp.MustCompile(`^(?:([+-]\d{4} \d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}(?:\.\d+)?) )?(TRACE|DEBUG|INFO|WARN|WARNING|ERROR|FATAL|PANIC)(?:\[(\d+)\])?\s*(?:\[[^\]]*\]\s*)?(.*)$`)
+	// Go standard logger used by the launcher: "2024/01/02 15:04:05 FuncName: message"
+	goLogLineRegex = regexp.MustCompile(`^(\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2}(?:\.\d+)?) (.*)$`)
+	// ANSI color sequences sing-box writes when the output is a terminal
+	ansiRegex = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+)
+
+// ParseLogLine splits a sing-box or launcher log line into time, level, component and message.
+// Lines that match neither format are returned as INFO messages with the raw text.
+func ParseLogLine(line string) LogEntry {
+	clean := ansiRegex.ReplaceAllString(strings.TrimRight(line, "\r\n"), "")
+	entry := LogEntry{Raw: clean, Level: LogLevelInfo, Message: clean}
+
+	if m := singboxLineRegex.FindStringSubmatch(clean); m != nil {
+		entry.Time = m[1]
+		if entry.Time == "" && m[3] != "" {
+			// Seconds since start, printed by sing-box when timestamps are disabled
+			entry.Time = "[" + m[3] + "]"
+		}
+		entry.Level = normalizeLogLevel(m[2])
+		entry.Component, entry.Message = splitLogComponent(m[4])
+		return entry
+	}
+
+	if m := goLogLineRegex.FindStringSubmatch(clean); m != nil {
+		entry.Time = m[1]
+		entry.Component, entry.Message = splitLogComponent(m[2])
+		entry.Level = guessLogLevel(m[2])
+		return entry
+	}
+
+	entry.Level = guessLogLevel(clean)
+	return entry
+}
+
+// normalizeLogLevel maps sing-box levels onto the four levels used for filtering
+func normalizeLogLevel(level string) string {
+	switch strings.ToUpper(level) {
+	case "TRACE", "DEBUG":
+		return LogLevelDebug
+	case "WARN", "WARNING":
+		return LogLevelWarn
+	case "ERROR", "FATAL", "PANIC":
+		return LogLevelError
+	default:
+		return LogLevelInfo
+	}
+}
+
+// guessLogLevel infers a level for lines without one (launcher and parser logs)
+func guessLogLevel(message string) string {
+	lower := strings.ToLower(message)
+	switch {
+	case strings.Contains(lower, "error") || strings.Contains(lower, "failed") || strings.Contains(lower, "panic"):
+		return LogLevelError
+	case strings.Contains(lower, "warning") || strings.Contains(lower, "warn:"):
+		return LogLevelWarn
+	default:
+		return LogLevelInfo
+	}
+}
+
+// splitLogComponent splits "component: message" when the component contains no spaces
+func splitLogComponent(text string) (string, string) {
+	idx := strings.Index(text, ": ")
+	if idx <= 0 || strings.ContainsAny(text[:idx], " \t") {
+		return "", text
+	}
+	return text[:idx], text[idx+2:]
+}
+
+// LogBuffer is a bounded ring buffer of log entries safe for concurrent use.
+// When full, the oldest entries are overwritten so memory usage stays flat.
+type LogBuffer struct {
+	mu      sync.Mutex
+	entries []LogEntry
+	start   int
+	count   int
+}
+
+// NewLogBuffer creates a ring buffer holding at most capacity entries
+func NewLogBuffer(capacity int) *LogBuffer {
+	if capacity <= 0 {
+		capacity = logBufferCapacity
+	}
+	return &LogBuffer{entries: make([]LogEntry, capacity)}
+}
+
+// Append adds entries, overwriting the oldest ones when the buffer is full
+func (b *LogBuffer) Append(entries ...LogEntry) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	capacity := len(b.entries)
+	for _, entry := range entries {
+		idx := (b.start + b.count) % capacity
+		b.entries[idx] = entry
+		if b.count < capacity {
+			b.count++
+		} else {
+			b.start = (b.start + 1) % capacity
+		}
+	}
+}
+
+// Snapshot returns a copy of the buffered entries from oldest to newest
+func (b *LogBuffer) Snapshot() []LogEntry {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	result := make([]LogEntry, b.count)
+	capacity := len(b.entries)
+	for i := 0; i < b.count; i++ {
+		result[i] = b.entries[(b.start+i)%capacity]
+	}
+	return result
+}
+
+// Len returns the number of buffered entries
+func (b *LogBuffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.count
+}
+
+// Clear removes all buffered entries
+func (b *LogBuffer) Clear() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.start = 0
+	b.count = 0
+}
+
+// LogTailer follows a log file as it grows and parses new lines into a LogBuffer.
+// It detects rotation (file replaced or truncated) and drains the rotated file
+// (path + ".old", see checkAndRotateLogFile) before continuing with the new one.
+type LogTailer struct {
+	path    string
+	buffer  *LogBuffer
+	offset  int64
+	info    os.FileInfo
+	partial string
+	started bool
+}
+
+// NewLogTailer creates a tailer for path with its own bounded buffer
+func NewLogTailer(path string, capacity int) *LogTailer {
+	return &LogTailer{path: path, buffer: NewLogBuffer(capacity)}
+}
+
+// Path returns the followed file path
+func (t *LogTailer) Path() string {
+	return t.path
+}
+
+// Buffer returns the buffer with parsed entries
+func (t *LogTailer) Buffer() *LogBuffer {
+	return t.buffer
+}
+
+// Poll reads lines appended since the previous call and returns how many entries were added.
+// A missing file is not an error: the tailer waits for it to appear.
+func (t *LogTailer) Poll() (int, error) {
+	info, err := os.Stat(t.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to stat log file: %w", err)
+	}
+
+	added := 0
+	if !t.started {
+		t.started = true
+		t.info = info
+		t.offset = info.Size() - logInitialTailBytes
+		if t.offset < 0 {
+			t.offset = 0
+		}
+		n, err := t.readFrom(t.path, t.offset > 0)
+		return n, err
+	}
+
+	if !os.SameFile(t.info, info) || info.Size() < t.offset {
+		// Rotated: finish reading the previous file if it was renamed to .old
+		if !os.SameFile(t.info, info) {
+			if oldInfo, err := os.Stat(t.path + ".old"); err == nil && os.SameFile(t.info, oldInfo) {
+				n, _ := t.readFrom(t.path+".old", false)
+				added += n
+			}
+		}
+		t.flushPartial()
+		t.info = info
+		t.offset = 0
+	}
+
+	if info.Size() == t.offset {
+		return added, nil
+	}
+	t.info = info
+	n, err := t.readFrom(t.path, false)
+	return added + n, err
+}
+
+// readFrom reads new data from path starting at t.offset.
+// skipFirst drops the first (possibly incomplete) line when starting mid-file.
+func (t *LogTailer) readFrom(path string, skipFirst bool) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open log file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Seek(t.offset, io.SeekStart); err != nil {
+		return 0, fmt.Errorf("failed to seek log file: %w", err)
+	}
+	data, err := io.ReadAll(io.LimitReader(file, logMaxReadPerPoll))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read log file: %w", err)
+	}
+	t.offset += int64(len(data))
+
+	text := t.partial + string(data)
+	t.partial = ""
+	lines := strings.Split(text, "\n")
+	// The last element is an unterminated line (or empty if data ended with a newline)
+	last := lines[len(lines)-1]
+	lines = lines[:len(lines)-1]
+	if len(last) > logMaxLineLength {
+		lines = append(lines, last)
+	} else {
+		t.partial = last
+	}
+	if skipFirst && len(lines) > 0 {
+		lines = lines[1:]
+	}
+
+	entries := make([]LogEntry, 0, len(lines))
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		entries = append(entries, ParseLogLine(line))
+	}
+	t.buffer.Append(entries...)
+	return len(entries), nil
+}
+
+// flushPartial adds a pending unterminated line (used when the file is rotated)
+func (t *LogTailer) flushPartial() {
+	if strings.TrimSpace(t.partial) != "" {
+		t.buffer.Append(ParseLogLine(t.partial))
+	}
+	t.partial = ""
+}
+
+// Run polls the file every interval until ctx is cancelled and calls onUpdate when entries were added
+func (t *LogTailer) Run(ctx context.Context, interval time.Duration, onUpdate func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if n, _ := t.Poll(); n > 0 && onUpdate != nil {
+			onUpdate()
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// LogFilter selects log entries by level and text or regular expression.
+type LogFilter struct {
+	Levels map[string]bool // enabled levels; nil enables all
+	Query  string
+	Regex  bool
+}
+
+// Matcher compiles the filter into a predicate. Returns an error for an invalid regular expression.
+func (f LogFilter) Matcher() (func(LogEntry) bool, error) {
+	var match func(string) bool
+	switch {
+	case f.Query == "":
+		match = func(string) bool { return true }
+	case f.Regex:
+		re, err := regexp.Compile("(?i)" + f.Query)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression: %w", err)
+		}
+		match = re.MatchString
+	default:
+		query := strings.ToLower(f.Query)
+		match = func(s string) bool { return strings.Contains(strings.ToLower(s), query) }
+	}
+	levels := f.Levels
+	return func(entry LogEntry) bool {
+		if levels != nil && !levels[entry.Level] {
+			return false
+		}
+		return match(entry.Raw)
+	}, nil
+}
+
+// FilterLogEntries returns entries accepted by match
+func FilterLogEntries(entries []LogEntry, match func(LogEntry) bool) []LogEntry {
+	result := make([]LogEntry, 0, len(entries))
+	for _, entry := range entries {
+		if match(entry) {
+			result = append(result, entry)
+		}
+	}
+	return result
+}
+
+// APILogStream feeds a LogBuffer from the Clash API /logs stream while sing-box is running.
+type APILogStream struct {
+	ac     *AppController
+	buffer *LogBuffer
+	dirty  bool
+	mu     sync.Mutex
+}
+
+// NewAPILogStream creates a Clash API log stream with its own bounded buffer
+func NewAPILogStream(ac *AppController, capacity int) *APILogStream {
+	return &APILogStream{ac: ac, buffer: NewLogBuffer(capacity)}
+}
+
+// Buffer returns the buffer with received entries
+func (s *APILogStream) Buffer() *LogBuffer {
+	return s.buffer
+}
+
+// Run streams logs until ctx is cancelled, reconnecting while sing-box is running.
+// onUpdate is called at most once per interval when new entries arrived.
+func (s *APILogStream) Run(ctx context.Context, interval time.Duration, onUpdate func()) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			s.mu.Lock()
+			dirty := s.dirty
+			s.dirty = false
+			s.mu.Unlock()
+			if dirty && onUpdate != nil {
+				onUpdate()
+			}
+		}
+	}()
+
+	for {
+		if s.ac.RunningState != nil && s.ac.RunningState.IsRunning() {
+			s.ac.APIStateMutex.RLock()
+			enabled := s.ac.ClashAPIEnabled
+			s.ac.APIStateMutex.RUnlock()
+			if enabled {
+				_ = s.ac.ClashClient().StreamLogs(ctx, "debug", func(msg api.LogMessage) {
+					s.buffer.Append(ParseAPILogMessage(msg))
+					s.mu.Lock()
+					s.dirty = true
+					s.mu.Unlock()
+				})
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(trafficReconnectMinDelay * 2):
+		}
+	}
+}
+
+// ParseAPILogMessage converts a Clash API log message into a LogEntry
+func ParseAPILogMessage(msg api.LogMessage) LogEntry {
+	entry := LogEntry{
+		Time:  time.Now().Format("15:04:05"),
+		Level: normalizeLogLevel(msg.Type),
+	}
+	payload := ansiRegex.ReplaceAllString(msg.Payload, "")
+	// Payload may start with "[connection id duration] "
+	if strings.HasPrefix(payload, "[") {
+		if idx := strings.Index(payload, "] "); idx > 0 {
+			payload = payload[idx+2:]
+		}
+	}
+	entry.Component, entry.Message = splitLogComponent(payload)
+	entry.Raw = fmt.Sprintf("%s %s %s", entry.Time, entry.Level, payload)
+	return entry
+}
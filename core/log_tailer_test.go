@@ -0,0 +1,181 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestParseLogLine tests parsing of sing-box and launcher log lines
+func TestParseLogLine(t *testing.T) {
+	tests := []struct {
+		name      string
+		line      string
+		time      string
+		level     string
+		component string
+		message   string
+	}{
+		{
+			name:      "sing-box with timestamp",
+			line:      "+0300 2024-05-01 12:30:45 INFO [3412345 0ms] inbound/tun[tun-in]: inbound connection to 1.1.1.1:443",
+			time:      "+0300 2024-05-01 12:30:45",
+			level:     LogLevelInfo,
+			component: "inbound/tun[tun-in]",
+			message:   "inbound connection to 1.1.1.1:443",
+		},
+		{
+			name:      "sing-box without timestamp",
+			line:      "FATAL[0000] decode config at ./config.json: unknown field",
+			time:      "[0000]",
+			level:     LogLevelError,
+			component: "",
+			message:   "decode config at ./config.json: unknown field",
+		},
+		{
+			name:      "sing-box warning with colors",
+			line:      "\x1b[33mWARN\x1b[0m[0012] dns: exchange failed",
+			time:      "[0012]",
+			level:     LogLevelWarn,
+			component: "dns",
+			message:   "exchange failed",
+		},
+		{
+			name:      "Launcher log line",
+			line:      "2024/05/01 12:30:45 startSingBox: Failed to start Sing-Box: exit status 1",
+			time:      "2024/05/01 12:30:45",
+			level:     LogLevelError,
+			component: "startSingBox",
+			message:   "Failed to start Sing-Box: exit status 1",
+		},
+		{
+			name:    "Unstructured line",
+			line:    "goroutine 1 [running]:",
+			level:   LogLevelInfo,
+			message: "goroutine 1 [running]:",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := ParseLogLine(tt.line)
+			if entry.Time != tt.time || entry.Level != tt.level || entry.Component != tt.component || entry.Message != tt.message {
+				t.Errorf("ParseLogLine(%q) = %+v", tt.line, entry)
+			}
+		})
+	}
+}
+
+// TestLogBuffer_Bounded tests that the ring buffer keeps only the newest entries
+func TestLogBuffer_Bounded(t *testing.T) {
+	buffer := NewLogBuffer(3)
+	for i := 0; i < 10; i++ {
+		buffer.Append(LogEntry{Raw: fmt.Sprintf("line %d", i)})
+	}
+	entries := buffer.Snapshot()
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 entries, got %d", len(entries))
+	}
+	for i, want := range []string{"line 7", "line 8", "line 9"} {
+		if entries[i].Raw != want {
+			t.Errorf("Entry %d = %q, expected %q", i, entries[i].Raw, want)
+		}
+	}
+	buffer.Clear()
+	if buffer.Len() != 0 {
+		t.Error("Expected empty buffer after Clear")
+	}
+}
+
+// TestLogTailer_FollowAndRotation tests tailing appended lines and rotation to .old
+func TestLogTailer_FollowAndRotation(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "sing-box.log")
+	tailer := NewLogTailer(logPath, 100)
+
+	// Missing file is not an error
+	if n, err := tailer.Poll(); n != 0 || err != nil {
+		t.Fatalf("Expected no entries for missing file, got %d, %v", n, err)
+	}
+
+	appendToFile(t, logPath, "INFO[0000] first\nINFO[0001] sec")
+	if n, _ := tailer.Poll(); n != 1 {
+		t.Fatalf("Expected 1 complete line, got %d", n)
+	}
+	appendToFile(t, logPath, "ond\n")
+	if n, _ := tailer.Poll(); n != 1 {
+		t.Fatalf("Expected partial line to be completed, got %d", n)
+	}
+
+	// Rotate like checkAndRotateLogFile: lines written before rename must not be lost
+	appendToFile(t, logPath, "INFO[0002] before rotation\n")
+	if err := os.Rename(logPath, logPath+".old"); err != nil {
+		t.Fatal(err)
+	}
+	appendToFile(t, logPath, "INFO[0003] after rotation\n")
+	if n, _ := tailer.Poll(); n != 2 {
+		t.Fatalf("Expected 2 entries across rotation, got %d", n)
+	}
+
+	// Truncation is treated as rotation too
+	if err := os.WriteFile(logPath, []byte("ERROR[0004] truncated\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := tailer.Poll(); n != 1 {
+		t.Fatalf("Expected 1 entry after truncation, got %d", n)
+	}
+
+	var messages []string
+	for _, entry := range tailer.Buffer().Snapshot() {
+		messages = append(messages, entry.Message)
+	}
+	expected := []string{"first", "second", "before rotation", "after rotation", "truncated"}
+	if fmt.Sprint(messages) != fmt.Sprint(expected) {
+		t.Errorf("Messages = %v, expected %v", messages, expected)
+	}
+}
+
+// TestLogFilter tests level, text and regex filtering
+func TestLogFilter(t *testing.T) {
+	entries := []LogEntry{
+		ParseLogLine("INFO[0000] router: started"),
+		ParseLogLine("ERROR[0001] dns: exchange failed for example.com"),
+		ParseLogLine("DEBUG[0002] outbound/vless[proxy]: dial tcp 10.0.0.1:443"),
+	}
+
+	count := func(f LogFilter) int {
+		match, err := f.Matcher()
+		if err != nil {
+			t.Fatalf("Matcher failed: %v", err)
+		}
+		return len(FilterLogEntries(entries, match))
+	}
+
+	if got := count(LogFilter{}); got != 3 {
+		t.Errorf("Empty filter: expected 3, got %d", got)
+	}
+	if got := count(LogFilter{Levels: map[string]bool{LogLevelError: true}}); got != 1 {
+		t.Errorf("Level filter: expected 1, got %d", got)
+	}
+	if got := count(LogFilter{Query: "EXAMPLE"}); got != 1 {
+		t.Errorf("Text filter should be case-insensitive: expected 1, got %d", got)
+	}
+	if got := count(LogFilter{Query: `\d+\.\d+\.\d+\.\d+`, Regex: true}); got != 1 {
+		t.Errorf("Regex filter: expected 1, got %d", got)
+	}
+	if _, err := (LogFilter{Query: "(", Regex: true}).Matcher(); err == nil {
+		t.Error("Expected error for invalid regex")
+	}
+}
+
+func appendToFile(t *testing.T, path, content string) {
+	t.Helper()
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if _, err := file.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -5,6 +5,7 @@ package core
 import (
 	"log"
 	"os"
+	"path/filepath"
 )
 
 const (
@@ -14,10 +15,11 @@ const (
 
 // checkAndRotateLogFile checks log file size and rotates if it exceeds maxLogFileSize.
 // Rotates by renaming current file to .old and removing old backup if exists.
-func checkAndRotateLogFile(logPath string) {
+// Returns true if the file was rotated.
+func checkAndRotateLogFile(logPath string) bool {
 	info, err := os.Stat(logPath)
 	if err != nil {
-		return // File doesn't exist yet, nothing to rotate
+		return false // File doesn't exist yet, nothing to rotate
 	}
 
 	if info.Size() > maxLogFileSize {
@@ -28,8 +30,10 @@ func checkAndRotateLogFile(logPath string) {
 			log.Printf("checkAndRotateLogFile: Failed to rotate log file %s: %v", logPath, err)
 		} else {
 			log.Printf("checkAndRotateLogFile: Rotated log file %s (size: %d bytes)", logPath, info.Size())
+			return true
 		}
 	}
+	return false
 }
 
 // openLogFileWithRotation opens a log file and rotates it if it exceeds maxLogFileSize.
@@ -40,3 +44,21 @@ func openLogFileWithRotation(logPath string) (*os.File, error) {
 	// But if file was rotated, it will be a new file
 	return os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
 }
+
+// rotateChildLogFile rotates sing-box.log if needed and reopens ac.ChildLogFile,
+// otherwise the open handle would keep writing into the renamed .old file.
+func (ac *AppController) rotateChildLogFile() {
+	logPath := filepath.Join(ac.ExecDir, childLogFileName)
+	if !checkAndRotateLogFile(logPath) {
+		return
+	}
+	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		log.Printf("rotateChildLogFile: Failed to reopen %s: %v", logPath, err)
+		return
+	}
+	if ac.ChildLogFile != nil {
+		ac.ChildLogFile.Close()
+	}
+	ac.ChildLogFile = logFile
+}
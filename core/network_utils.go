@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"net/url"
 	"time"
 )
 
@@ -19,9 +20,19 @@ const (
 
 // createHTTPClient создает HTTP клиент с правильными таймаутами
 func createHTTPClient(timeout time.Duration) *http.Client {
+	return createHTTPClientWithProxy(timeout, nil)
+}
+
+// createHTTPClientWithProxy создает HTTP клиент, который ходит через proxyURL (nil - напрямую)
+func createHTTPClientWithProxy(timeout time.Duration, proxyURL *url.URL) *http.Client {
+	var proxy func(*http.Request) (*url.URL, error)
+	if proxyURL != nil {
+		proxy = http.ProxyURL(proxyURL)
+	}
 	return &http.Client{
 		Timeout: timeout,
 		Transport: &http.Transport{
+			Proxy: proxy,
 			DialContext: (&net.Dialer{
 				Timeout:   NetworkDialTimeout,
 				KeepAlive: 30 * time.Second,
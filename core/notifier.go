@@ -0,0 +1,197 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"sync"
+)
+
+// Notifier delivers what happens in core to the user interface. The GUI shows dialogs and
+// updates widgets (see ui.Notifier), headless commands print to the console, tests record.
+// Methods may be called from any goroutine.
+type Notifier interface {
+	// ShowError reports a failure
+	ShowError(err error)
+	// ShowInfo shows an informational message
+	ShowInfo(title, message string)
+	// ShowAutoHideInfo shows a short message that disappears by itself
+	ShowAutoHideInfo(title, message string)
+	// ShowCoreAlreadyRunning reports a sing-box process not started by the launcher; kill stops it
+	ShowCoreAlreadyRunning(kill func())
+	// Progress reports parser progress in percent (0-100); a negative value means the update failed
+	Progress(progress float64, status string)
+	// StateChanged reports that the sing-box running state or the crash/restart counters changed
+	StateChanged()
+	// ProxiesChanged reports that the proxies of the selected group or its active proxy changed
+	ProxiesChanged()
+	// Do runs fn on the UI thread (immediately when there is no UI)
+	Do(fn func())
+}
+
+// NopNotifier logs errors and ignores everything else; Do runs fn immediately.
+// It is the default until the GUI or a command installs its own notifier.
+type NopNotifier struct{}
+
+func (NopNotifier) ShowError(err error)                { log.Printf("NopNotifier: error: %v", err) }
+func (NopNotifier) ShowInfo(title, message string)     {}
+func (NopNotifier) ShowAutoHideInfo(title, msg string) {}
+func (NopNotifier) ShowCoreAlreadyRunning(func())      {}
+func (NopNotifier) Progress(float64, string)           {}
+func (NopNotifier) StateChanged()                      {}
+func (NopNotifier) ProxiesChanged()                    {}
+func (NopNotifier) Do(fn func())                       { fn() }
+
+// NotificationKind identifies a Notifier method in RecordingNotifier
+type NotificationKind string
+
+const (
+	NotifyError              NotificationKind = "error"
+	NotifyInfo               NotificationKind = "info"
+	NotifyAutoHideInfo       NotificationKind = "auto_hide_info"
+	NotifyCoreAlreadyRunning NotificationKind = "core_already_running"
+	NotifyProgress           NotificationKind = "progress"
+	NotifyStateChanged       NotificationKind = "state_changed"
+	NotifyProxiesChanged     NotificationKind = "proxies_changed"
+)
+
+// Notification is a single call recorded by RecordingNotifier
+type Notification struct {
+	Kind     NotificationKind
+	Title    string
+	Message  string // Error text, info message or progress status
+	Progress float64
+}
+
+// RecordingNotifier remembers every notification; used in tests to check what core reported.
+// Do runs fn immediately. ShowCoreAlreadyRunning calls kill when KillRunningCore is set.
+type RecordingNotifier struct {
+	KillRunningCore bool
+
+	mu            sync.Mutex
+	notifications []Notification
+}
+
+// NewRecordingNotifier creates an empty recording notifier
+func NewRecordingNotifier() *RecordingNotifier {
+	return &RecordingNotifier{}
+}
+
+func (n *RecordingNotifier) record(notification Notification) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.notifications = append(n.notifications, notification)
+}
+
+func (n *RecordingNotifier) ShowError(err error) {
+	n.record(Notification{Kind: NotifyError, Message: err.Error()})
+}
+
+func (n *RecordingNotifier) ShowInfo(title, message string) {
+	n.record(Notification{Kind: NotifyInfo, Title: title, Message: message})
+}
+
+func (n *RecordingNotifier) ShowAutoHideInfo(title, message string) {
+	n.record(Notification{Kind: NotifyAutoHideInfo, Title: title, Message: message})
+}
+
+func (n *RecordingNotifier) ShowCoreAlreadyRunning(kill func()) {
+	n.record(Notification{Kind: NotifyCoreAlreadyRunning})
+	if n.KillRunningCore {
+		kill()
+	}
+}
+
+func (n *RecordingNotifier) Progress(progress float64, status string) {
+	n.record(Notification{Kind: NotifyProgress, Message: status, Progress: progress})
+}
+
+func (n *RecordingNotifier) StateChanged() {
+	n.record(Notification{Kind: NotifyStateChanged})
+}
+
+func (n *RecordingNotifier) ProxiesChanged() {
+	n.record(Notification{Kind: NotifyProxiesChanged})
+}
+
+func (n *RecordingNotifier) Do(fn func()) {
+	fn()
+}
+
+// Notifications returns a copy of everything recorded so far
+func (n *RecordingNotifier) Notifications() []Notification {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	result := make([]Notification, len(n.notifications))
+	copy(result, n.notifications)
+	return result
+}
+
+// Of returns the recorded notifications of the given kind
+func (n *RecordingNotifier) Of(kind NotificationKind) []Notification {
+	var result []Notification
+	for _, notification := range n.Notifications() {
+		if notification.Kind == kind {
+			result = append(result, notification)
+		}
+	}
+	return result
+}
+
+// Reset forgets everything recorded so far
+func (n *RecordingNotifier) Reset() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.notifications = nil
+}
+
+// ConsoleNotifier prints messages for headless (CLI) use. Nothing is killed without the user's action.
+type ConsoleNotifier struct {
+	Out io.Writer
+	// ShowProgress enables printing parser progress ("[ 40%] status"), repeated statuses are skipped
+	ShowProgress bool
+
+	mu         sync.Mutex
+	lastStatus string
+}
+
+// NewConsoleNotifier creates a notifier writing to out
+func NewConsoleNotifier(out io.Writer) *ConsoleNotifier {
+	return &ConsoleNotifier{Out: out}
+}
+
+func (n *ConsoleNotifier) ShowError(err error) {
+	log.Printf("Notifier: error: %v", err)
+	fmt.Fprintf(n.Out, "Error: %v\n", err)
+}
+
+func (n *ConsoleNotifier) ShowInfo(title, message string) {
+	log.Printf("Notifier: %s: %s", title, message)
+	fmt.Fprintf(n.Out, "%s: %s\n", title, message)
+}
+
+func (n *ConsoleNotifier) ShowAutoHideInfo(title, message string) {
+	n.ShowInfo(title, message)
+}
+
+func (n *ConsoleNotifier) ShowCoreAlreadyRunning(kill func()) {
+	n.ShowInfo("Warning", "sing-box is already running outside the launcher. Stop it before starting a new instance.")
+}
+
+func (n *ConsoleNotifier) Progress(progress float64, status string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if !n.ShowProgress || progress < 0 || status == n.lastStatus {
+		return
+	}
+	n.lastStatus = status
+	fmt.Fprintf(n.Out, "[%3.0f%%] %s\n", progress, status)
+}
+
+func (n *ConsoleNotifier) StateChanged() {}
+
+func (n *ConsoleNotifier) ProxiesChanged() {}
+
+func (n *ConsoleNotifier) Do(fn func()) {
+	fn()
+}
@@ -98,7 +98,7 @@ func TestParseNode_VLESS(t *testing.T) {
 		},
 		{
 			name:        "Invalid VLESS URI",
-			uri:         "vless://invalid",
+			uri:         "vless://uuid@exa mple.com",
 			expectError: true,
 		},
 	}
@@ -270,7 +270,7 @@ func TestParseNode_SkipFilters(t *testing.T) {
 
 	t.Run("Skip by tag", func(t *testing.T) {
 		skipFilters := []map[string]string{
-			{"tag": "🇩🇪 Germany"},
+			{"tag": "🇩🇪 Germany [black lists]"}, // the tag is the full label
 		}
 		node, err := ParseNode(uri, skipFilters)
 		if err != nil {
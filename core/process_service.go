@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"math/rand"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -14,17 +15,12 @@ import (
 
 	"github.com/muhammadmuzzammil1998/jsonc"
 
-	"singbox-launcher/api"
-	"singbox-launcher/internal/dialogs"
 	"singbox-launcher/internal/platform"
 
 	ps "github.com/mitchellh/go-ps"
 )
 
 const (
-	// restartAttempts is the maximum number of consecutive crash restart attempts
-	restartAttempts = 3
-
 	// stabilityThreshold is the duration a process must run without crashing
 	// before the crash counter is reset
 	stabilityThreshold = 180 * time.Second
@@ -39,6 +35,9 @@ const (
 // The service ensures proper cleanup of TUN interfaces, log rotation, and process state management.
 type ProcessService struct {
 	ac *AppController
+	// logOffset is the size of sing-box.log when the process was started,
+	// so crash classification only looks at output of the current run
+	logOffset int64
 }
 
 // NewProcessService constructs a ProcessService bound to the controller.
@@ -51,7 +50,7 @@ func NewProcessService(ac *AppController) *ProcessService {
 func (svc *ProcessService) Start(skipRunningCheck ...bool) {
 	ac := svc.ac
 	if ac.RunningState.IsRunning() {
-		dialogs.ShowAutoHideInfo(ac.Application, ac.MainWindow, "Info", "Sing-Box already running (according to internal state).")
+		ac.Notifier.ShowAutoHideInfo("Info", "Sing-Box already running (according to internal state).")
 		return
 	}
 
@@ -63,41 +62,33 @@ func (svc *ProcessService) Start(skipRunningCheck ...bool) {
 		}
 	}
 
+	// Re-apply Linux capabilities through polkit if enabled (before CmdMutex: pkexec waits for the password)
+	if err := ac.EnsureCoreCapabilities(); err != nil {
+		log.Printf("startSingBox: %v", err)
+		ac.Notifier.ShowError(err)
+		return
+	}
+
 	ac.CmdMutex.Lock()
 	defer ac.CmdMutex.Unlock()
 
+	// Manual start clears the previous crash reason
+	if !skipCheck {
+		ac.LastCrashReason = CrashReasonNone
+		ac.LastCrashDetail = ""
+	}
+
 	// Check capabilities on Linux before starting
 	if suggestion := platform.CheckAndSuggestCapabilities(ac.SingboxPath); suggestion != "" {
 		log.Printf("startSingBox: Capabilities check failed: %s", suggestion)
-		dialogs.ShowError(ac.MainWindow, fmt.Errorf("Linux capabilities required\n\n%s", suggestion))
+		ac.Notifier.ShowError(fmt.Errorf("Linux capabilities required\n\n%s", suggestion))
 		return
 	}
 
 	// Reload API config from config.json before starting (in case it was corrupted)
 	log.Println("startSingBox: Reloading API config from config.json...")
-	if base, tok, err := api.LoadClashAPIConfig(ac.ConfigPath); err != nil {
-		log.Printf("startSingBox: Clash API config error: %v", err)
-		ac.ClashAPIBaseURL = ""
-		ac.ClashAPIToken = ""
-		ac.ClashAPIEnabled = false
-	} else {
-		ac.ClashAPIBaseURL = base
-		ac.ClashAPIToken = tok
-		ac.ClashAPIEnabled = true
-		log.Printf("startSingBox: API config reloaded successfully")
-	}
-
-	// Reload SelectedClashGroup from config
-	if ac.ClashAPIEnabled {
-		_, defaultSelector, err := GetSelectorGroupsFromConfig(ac.ConfigPath)
-		if err != nil {
-			log.Printf("startSingBox: Failed to get selector groups: %v", err)
-			ac.SelectedClashGroup = "proxy-out" // Default fallback
-		} else {
-			ac.SelectedClashGroup = defaultSelector
-			log.Printf("startSingBox: SelectedClashGroup reloaded: %s", defaultSelector)
-		}
-	}
+	// Also reloads SelectedClashGroup
+	ac.loadClashAPIConfig("startSingBox")
 
 	// Check and remove existing TUN interface before starting (prevents "file already exists" error)
 	if runtime.GOOS == "windows" {
@@ -115,18 +106,26 @@ func (svc *ProcessService) Start(skipRunningCheck ...bool) {
 	}
 
 	// Reset API cache before starting
-	if ac.ResetAPIStateFunc != nil {
-		log.Println("startSingBox: Resetting API state cache...")
-		ac.ResetAPIStateFunc()
-	}
+	log.Println("startSingBox: Resetting API state cache...")
+	ac.resetAPIState()
 
 	log.Println("startSingBox: Starting Sing-Box...")
-	ac.SingboxCmd = exec.Command(ac.SingboxPath, "run", "-c", filepath.Base(ac.ConfigPath))
+	// sing-box runs in bin so relative paths in the config keep working; profiles pass their config relative to bin
+	binDir := platform.GetBinDir(ac.ExecDir)
+	configArg, err := filepath.Rel(binDir, ac.ConfigPath)
+	if err != nil {
+		configArg = ac.ConfigPath
+	}
+	ac.SingboxCmd = exec.Command(ac.SingboxPath, "run", "-c", configArg)
 	platform.PrepareCommand(ac.SingboxCmd)
-	ac.SingboxCmd.Dir = platform.GetBinDir(ac.ExecDir)
+	ac.SingboxCmd.Dir = binDir
 	if ac.ChildLogFile != nil {
 		// Check and rotate log file before starting new process to prevent unbounded growth
-		checkAndRotateLogFile(filepath.Join(ac.ExecDir, childLogFileName))
+		ac.rotateChildLogFile()
+		svc.logOffset = 0
+		if info, err := os.Stat(filepath.Join(ac.ExecDir, childLogFileName)); err == nil {
+			svc.logOffset = info.Size()
+		}
 
 		// Write directly to file - no buffering in memory
 		// This prevents memory leaks from accumulating log output
@@ -145,9 +144,15 @@ func (svc *ProcessService) Start(skipRunningCheck ...bool) {
 	ac.StoppedByUser = false
 	// Add log with PID
 	log.Printf("startSingBox: Sing-Box started. PID=%d", ac.SingboxCmd.Process.Pid)
+	if ac.Settings.Get().Core.Trial {
+		go ac.confirmCoreTrial(ac.SingboxCmd.Process.Pid)
+	}
 
 	// Start auto-loading proxies after sing-box is running
 	go func() {
+		// Re-apply remembered selector choices (waits for the API itself)
+		ac.RestoreSelectorChoices()
+		ac.RestoreClashMode()
 		// Small delay to ensure API is ready
 		time.Sleep(2 * time.Second)
 		ac.AutoLoadProxies()
@@ -193,25 +198,68 @@ func (svc *ProcessService) Monitor(cmdToMonitor *exec.Cmd) {
 		return
 	}
 
-	// 4. Only then — crash → restart
+	// 4. Only then — crash → classify and restart according to policy
+	crash := ClassifyCrashLogFile(filepath.Join(ac.ExecDir, childLogFileName), svc.logOffset)
+	policy := CrashPolicyFor(crash.Reason)
+	ac.LastCrashReason = crash.Reason
+	ac.LastCrashDetail = crash.Line
+	ac.CrashRestartLimit = policy.MaxRetries
+	log.Printf("monitorSingBox: Sing-Box crashed: %v, reason: %s (%s)", err, crash.Reason.Description(), crash.Line)
+
+	// Новая версия ядра упала до окончания пробного периода — откатываемся и сразу запускаем предыдущую
+	if notice := ac.rollbackTrialCore(crash.Reason.Description()); notice != nil {
+		ac.ConsecutiveCrashAttempts = 0
+		ac.RunningState.Set(false)
+		ac.Events.Publish(CoreCrashed{Reason: crash.Reason, Detail: crash.Line, Attempt: 1, MaxAttempts: policy.MaxRetries, Restarting: true})
+		ac.CmdMutex.Unlock()
+		svc.Start(true)
+		ac.Notifier.ShowError(notice)
+		ac.CmdMutex.Lock()
+		return
+	}
+
 	// Процесс завершился с ошибкой - проверяем лимит попыток
-	ac.RunningState.Set(false)
 	ac.ConsecutiveCrashAttempts++
+	ac.RunningState.Set(false)
 
-	if ac.ConsecutiveCrashAttempts > restartAttempts {
-		log.Printf("monitorSingBox: Maximum restart attempts (%d) reached. Stopping auto-restart.", restartAttempts)
-		dialogs.ShowError(ac.MainWindow, fmt.Errorf("Sing-Box failed to restart after %d attempts. Check sing-box.log for details.", restartAttempts))
+	if ac.ConsecutiveCrashAttempts > policy.MaxRetries {
 		ac.ConsecutiveCrashAttempts = 0
+		if policy.MaxRetries == 0 {
+			log.Printf("monitorSingBox: Crash reason '%s' is not retried. Stopping auto-restart.", crash.Reason)
+			ac.Notifier.ShowError(fmt.Errorf("Sing-Box stopped: %s\n\n%s\n\nFix the problem and start again. Check sing-box.log for details.", crash.Reason.Description(), crash.Line))
+		} else {
+			log.Printf("monitorSingBox: Maximum restart attempts (%d) reached. Stopping auto-restart.", policy.MaxRetries)
+			ac.Notifier.ShowError(fmt.Errorf("Sing-Box failed to restart after %d attempts: %s\n\n%s\n\nCheck sing-box.log for details.", policy.MaxRetries, crash.Reason.Description(), crash.Line))
+		}
+		ac.Events.Publish(CoreCrashed{Reason: crash.Reason, Detail: crash.Line, Attempt: policy.MaxRetries + 1, MaxAttempts: policy.MaxRetries})
 		return
 	}
 
 	// Try to restart
-	log.Printf("monitorSingBox: Sing-Box crashed: %v, attempting auto-restart (attempt %d/%d)", err, ac.ConsecutiveCrashAttempts, restartAttempts)
-	dialogs.ShowAutoHideInfo(ac.Application, ac.MainWindow, "Crash", fmt.Sprintf("Sing-Box crashed, restarting... (attempt %d/%d)", ac.ConsecutiveCrashAttempts, restartAttempts))
+	delay := policy.Backoff(ac.ConsecutiveCrashAttempts, rand.Float64())
+	log.Printf("monitorSingBox: Attempting auto-restart in %v (attempt %d/%d)", delay.Round(time.Millisecond), ac.ConsecutiveCrashAttempts, policy.MaxRetries)
+	if ac.Settings.Get().Notifications.CrashRestart {
+		ac.Notifier.ShowAutoHideInfo("Crash", fmt.Sprintf("Sing-Box crashed (%s), restarting in %.0fs... (attempt %d/%d)", crash.Reason.Description(), delay.Seconds(), ac.ConsecutiveCrashAttempts, policy.MaxRetries))
+	}
+	ac.Events.Publish(CoreCrashed{Reason: crash.Reason, Detail: crash.Line, Attempt: ac.ConsecutiveCrashAttempts, MaxAttempts: policy.MaxRetries, Restarting: true})
 
-	// Wait 2 seconds before restart
+	// Wait with exponential backoff before restart
+	attempt := ac.ConsecutiveCrashAttempts
+	ac.CmdMutex.Unlock()
+	select {
+	case <-ac.ctx.Done():
+		ac.CmdMutex.Lock()
+		log.Println("monitorSingBox: Auto-restart cancelled (context cancelled)")
+		return
+	case <-time.After(delay):
+	}
+	// User may have stopped auto-restart (Stop resets the counter) or started sing-box manually
+	ac.CmdMutex.Lock()
+	if ac.RunningState.IsRunning() || ac.ConsecutiveCrashAttempts != attempt {
+		log.Println("monitorSingBox: Auto-restart skipped (state changed during backoff)")
+		return
+	}
 	ac.CmdMutex.Unlock()
-	time.Sleep(2 * time.Second)
 	svc.Start(true) // skipRunningCheck = true для автоперезапуска
 	ac.CmdMutex.Lock()
 
@@ -230,10 +278,10 @@ func (svc *ProcessService) Monitor(cmdToMonitor *exec.Cmd) {
 				if ac.RunningState.IsRunning() && ac.ConsecutiveCrashAttempts == currentAttemptCount {
 					log.Printf("monitorSingBox: Process has been stable for %v. Resetting crash counter from %d to 0.", stabilityThreshold, ac.ConsecutiveCrashAttempts)
 					ac.ConsecutiveCrashAttempts = 0
+					ac.LastCrashReason = CrashReasonNone
+					ac.LastCrashDetail = ""
 					// Обновляем UI, чтобы счетчик исчез из статуса на вкладке Core
-					if ac.UpdateCoreStatusFunc != nil {
-						ac.UpdateCoreStatusFunc()
-					}
+					ac.Events.Publish(CoreRecovered{})
 				} else {
 					log.Printf("monitorSingBox: Stability timer expired, but conditions for reset not met (running: %v, current attempts: %d, attempts at timer start: %d).", ac.RunningState.IsRunning(), ac.ConsecutiveCrashAttempts, currentAttemptCount)
 				}
@@ -0,0 +1,391 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"singbox-launcher/api"
+	"singbox-launcher/internal/constants"
+)
+
+// DefaultProfileName is the profile backed by bin/config.json (the layout used before profiles existed)
+const DefaultProfileName = "default"
+
+// profileStopTimeout is how long SwitchProfile waits for sing-box to exit before giving up
+const profileStopTimeout = 10 * time.Second
+
+// maxProfileNameLength limits profile names (they are used as directory names)
+const maxProfileNameLength = 64
+
+var (
+	// ErrProfileNotFound is returned for a profile that has no directory
+	ErrProfileNotFound = errors.New("profile not found")
+	// ErrProfileExists is returned when creating a profile whose directory already exists
+	ErrProfileExists = errors.New("profile already exists")
+)
+
+// Profile is a named configuration: its own config.json (with ParserConfig), wizard backups
+// and launcher_state.json, all kept in the profile directory.
+type Profile struct {
+	Name       string
+	ConfigPath string
+}
+
+// Dir returns the directory holding the profile's files
+func (p Profile) Dir() string {
+	return filepath.Dir(p.ConfigPath)
+}
+
+// HasConfig reports whether the profile's config.json exists
+func (p Profile) HasConfig() bool {
+	_, err := os.Stat(p.ConfigPath)
+	return err == nil
+}
+
+// ProfileManager manages configuration profiles. The default profile uses bin/config.json,
+// other profiles live in bin/profiles/<name>/config.json.
+type ProfileManager struct {
+	binDir string
+	// settings remembers the active profile (LastProfile); nil means it is not remembered
+	settings *SettingsStore
+}
+
+// NewProfileManager creates a manager for profiles under binDir that remembers the active profile in settings
+func NewProfileManager(binDir string, settings *SettingsStore) *ProfileManager {
+	return &ProfileManager{binDir: binDir, settings: settings}
+}
+
+// profilesDir returns the directory with the named profiles
+func (m *ProfileManager) profilesDir() string {
+	return filepath.Join(m.binDir, constants.ProfilesDirName)
+}
+
+// ValidateProfileName checks that name can be used as a profile directory name
+func ValidateProfileName(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return fmt.Errorf("profile name must not be empty")
+	}
+	if name != strings.TrimSpace(name) {
+		return fmt.Errorf("profile name must not start or end with spaces")
+	}
+	if len(name) > maxProfileNameLength {
+		return fmt.Errorf("profile name is too long (max %d characters)", maxProfileNameLength)
+	}
+	if name == "." || name == ".." || strings.HasPrefix(name, ".") {
+		return fmt.Errorf("profile name must not start with '.'")
+	}
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-' || r == '_' || r == '.' || r == ' ':
+		default:
+			return fmt.Errorf("profile name may only contain letters, digits, spaces, '-', '_' and '.'")
+		}
+	}
+	return nil
+}
+
+// Profile returns the profile with the given name (it does not check that the profile exists)
+func (m *ProfileManager) Profile(name string) (Profile, error) {
+	if name == DefaultProfileName {
+		return Profile{Name: name, ConfigPath: filepath.Join(m.binDir, constants.ConfigFileName)}, nil
+	}
+	if err := ValidateProfileName(name); err != nil {
+		return Profile{}, err
+	}
+	return Profile{Name: name, ConfigPath: filepath.Join(m.profilesDir(), name, constants.ConfigFileName)}, nil
+}
+
+// Exists reports whether the profile exists (the default profile always does)
+func (m *ProfileManager) Exists(name string) bool {
+	profile, err := m.Profile(name)
+	if err != nil {
+		return false
+	}
+	if name == DefaultProfileName {
+		return true
+	}
+	info, err := os.Stat(profile.Dir())
+	return err == nil && info.IsDir()
+}
+
+// List returns the default profile followed by the named profiles sorted by name
+func (m *ProfileManager) List() ([]Profile, error) {
+	defaultProfile, _ := m.Profile(DefaultProfileName)
+	profiles := []Profile{defaultProfile}
+
+	entries, err := os.ReadDir(m.profilesDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return profiles, nil
+		}
+		return profiles, fmt.Errorf("failed to read profiles directory: %w", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() || entry.Name() == DefaultProfileName || ValidateProfileName(entry.Name()) != nil {
+			continue
+		}
+		names = append(names, entry.Name())
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return strings.ToLower(names[i]) < strings.ToLower(names[j])
+	})
+	for _, name := range names {
+		profile, _ := m.Profile(name)
+		profiles = append(profiles, profile)
+	}
+	return profiles, nil
+}
+
+// Names returns the names of all profiles, default first
+func (m *ProfileManager) Names() []string {
+	profiles, err := m.List()
+	if err != nil {
+		log.Printf("ProfileManager: %v", err)
+	}
+	names := make([]string, len(profiles))
+	for i, profile := range profiles {
+		names[i] = profile.Name
+	}
+	return names
+}
+
+// Create creates an empty profile, or a copy of copyFrom's config.json when copyFrom is not empty
+func (m *ProfileManager) Create(name, copyFrom string) (Profile, error) {
+	if name == DefaultProfileName {
+		return Profile{}, fmt.Errorf("'%s': %w", name, ErrProfileExists)
+	}
+	profile, err := m.Profile(name)
+	if err != nil {
+		return Profile{}, err
+	}
+	if _, err := os.Stat(profile.Dir()); err == nil {
+		return Profile{}, fmt.Errorf("'%s': %w", name, ErrProfileExists)
+	}
+
+	var source Profile
+	if copyFrom != "" {
+		if !m.Exists(copyFrom) {
+			return Profile{}, fmt.Errorf("'%s': %w", copyFrom, ErrProfileNotFound)
+		}
+		source, _ = m.Profile(copyFrom)
+	}
+
+	if err := os.MkdirAll(profile.Dir(), 0755); err != nil {
+		return Profile{}, fmt.Errorf("failed to create profile directory: %w", err)
+	}
+	if copyFrom != "" && source.HasConfig() {
+		if err := copyFile(source.ConfigPath, profile.ConfigPath); err != nil {
+			os.RemoveAll(profile.Dir())
+			return Profile{}, fmt.Errorf("failed to copy config from profile '%s': %w", copyFrom, err)
+		}
+	}
+	log.Printf("ProfileManager: Created profile '%s' (copy of '%s')", name, copyFrom)
+	return profile, nil
+}
+
+// Delete removes a named profile with all its files. The default profile cannot be deleted.
+func (m *ProfileManager) Delete(name string) error {
+	if name == DefaultProfileName {
+		return fmt.Errorf("the default profile cannot be deleted")
+	}
+	if !m.Exists(name) {
+		return fmt.Errorf("'%s': %w", name, ErrProfileNotFound)
+	}
+	profile, _ := m.Profile(name)
+	if err := os.RemoveAll(profile.Dir()); err != nil {
+		return fmt.Errorf("failed to delete profile '%s': %w", name, err)
+	}
+	log.Printf("ProfileManager: Deleted profile '%s'", name)
+	return nil
+}
+
+// Active returns the remembered active profile, or the default profile if it is not set or no longer exists
+func (m *ProfileManager) Active() string {
+	name := m.settings.Get().LastProfile
+	if name == "" || !m.Exists(name) {
+		return DefaultProfileName
+	}
+	return name
+}
+
+// SetActive remembers the active profile for the next launch
+func (m *ProfileManager) SetActive(name string) error {
+	if !m.Exists(name) {
+		return fmt.Errorf("'%s': %w", name, ErrProfileNotFound)
+	}
+	if err := m.settings.Update(func(settings *Settings) { settings.LastProfile = name }); err != nil {
+		return fmt.Errorf("failed to save active profile: %w", err)
+	}
+	return nil
+}
+
+// copyFile copies src to dst
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// CurrentProfile returns the name of the active profile
+func (ac *AppController) CurrentProfile() string {
+	ac.ProfileMutex.Lock()
+	defer ac.ProfileMutex.Unlock()
+	return ac.ActiveProfile
+}
+
+// CurrentConfigPath returns the config path of the active profile
+func (ac *AppController) CurrentConfigPath() string {
+	ac.ProfileMutex.Lock()
+	defer ac.ProfileMutex.Unlock()
+	return ac.ConfigPath
+}
+
+// applyProfile points the controller at the profile's config and reloads everything derived from it.
+// sing-box must not be running.
+func (ac *AppController) applyProfile(profile Profile) {
+	ac.ProfileMutex.Lock()
+	ac.ActiveProfile = profile.Name
+	ac.ConfigPath = profile.ConfigPath
+	ac.ProfileMutex.Unlock()
+	log.Printf("applyProfile: Using profile '%s' (%s)", profile.Name, profile.ConfigPath)
+
+	stateStore := NewStateStore(GetStatePath(profile.ConfigPath))
+	ac.APIStateMutex.Lock()
+	ac.StateStore = stateStore
+	ac.APIStateMutex.Unlock()
+	ac.loadClashAPIConfig("applyProfile")
+
+	// Forget everything loaded from the previous profile's sing-box
+	ac.SetProxiesList([]api.ProxyInfo{})
+	ac.SetActiveProxyName("")
+	ac.SetSelectedIndex(-1)
+	ac.ResetClashModeState()
+	ac.ResetTrayGroups()
+
+	// Auto-update works on the profile's ParserConfig
+	ac.AutoUpdateMutex.Lock()
+	ac.AutoUpdateEnabled = profile.HasConfig()
+	ac.AutoUpdateFailedAttempts = 0
+	ac.AutoUpdateMutex.Unlock()
+}
+
+// SwitchProfile makes name the active profile. If sing-box is running it is stopped,
+// the profile is switched and sing-box is started again with the new config.
+// Blocks until sing-box has stopped, so call it from a background goroutine.
+func (ac *AppController) SwitchProfile(name string) error {
+	if !ac.Profiles.Exists(name) {
+		return fmt.Errorf("SwitchProfile: '%s': %w", name, ErrProfileNotFound)
+	}
+	profile, err := ac.Profiles.Profile(name)
+	if err != nil {
+		return fmt.Errorf("SwitchProfile: %w", err)
+	}
+	if name == ac.CurrentProfile() {
+		return nil
+	}
+
+	ac.ParserMutex.Lock()
+	parserRunning := ac.ParserRunning
+	ac.ParserMutex.Unlock()
+	if parserRunning {
+		return fmt.Errorf("SwitchProfile: config update is in progress, try again when it finishes")
+	}
+
+	wasRunning := ac.RunningState.IsRunning()
+	if wasRunning {
+		log.Printf("SwitchProfile: Stopping sing-box before switching to '%s'", name)
+		StopSingBoxProcess(ac)
+		if !ac.waitForStop(profileStopTimeout) {
+			return fmt.Errorf("SwitchProfile: sing-box did not stop within %v", profileStopTimeout)
+		}
+	}
+
+	ac.applyProfile(profile)
+	if err := ac.Profiles.SetActive(name); err != nil {
+		log.Printf("SwitchProfile: %v", err)
+	}
+
+	ac.Events.Publish(ProfileChanged{Profile: name})
+
+	if wasRunning {
+		if !profile.HasConfig() {
+			log.Printf("SwitchProfile: Profile '%s' has no config.json, sing-box is not restarted", name)
+			return nil
+		}
+		log.Printf("SwitchProfile: Restarting sing-box with profile '%s'", name)
+		StartSingBoxProcess(ac)
+	}
+	return nil
+}
+
+// UseProfile points the controller at a profile for this run without remembering it as the active one.
+// Intended for headless commands; sing-box must not be running.
+func (ac *AppController) UseProfile(name string) error {
+	if !ac.Profiles.Exists(name) {
+		return fmt.Errorf("'%s': %w", name, ErrProfileNotFound)
+	}
+	profile, err := ac.Profiles.Profile(name)
+	if err != nil {
+		return err
+	}
+	ac.applyProfile(profile)
+	return nil
+}
+
+// CreateProfile creates a profile, copying the active profile's config.json when copyCurrent is set
+func (ac *AppController) CreateProfile(name string, copyCurrent bool) (Profile, error) {
+	copyFrom := ""
+	if copyCurrent {
+		copyFrom = ac.CurrentProfile()
+	}
+	profile, err := ac.Profiles.Create(name, copyFrom)
+	if err != nil {
+		return Profile{}, err
+	}
+	ac.Events.Publish(ProfilesUpdated{})
+	return profile, nil
+}
+
+// DeleteProfile deletes a profile that is not active
+func (ac *AppController) DeleteProfile(name string) error {
+	if name == ac.CurrentProfile() {
+		return fmt.Errorf("the active profile cannot be deleted, switch to another profile first")
+	}
+	if err := ac.Profiles.Delete(name); err != nil {
+		return err
+	}
+	ac.Events.Publish(ProfilesUpdated{})
+	return nil
+}
+
+// waitForStop waits until sing-box is no longer running
+func (ac *AppController) waitForStop(timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for ac.RunningState.IsRunning() {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return true
+}
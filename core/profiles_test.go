@@ -0,0 +1,144 @@
+package core
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestValidateProfileName tests which names can be used as profile directories
+func TestValidateProfileName(t *testing.T) {
+	valid := []string{"work", "Home 2", "travel-eu_1.0"}
+	for _, name := range valid {
+		if err := ValidateProfileName(name); err != nil {
+			t.Errorf("ValidateProfileName(%q) unexpected error: %v", name, err)
+		}
+	}
+	invalid := []string{"", "  ", " work", "..", ".hidden", "a/b", `a\b`, "работа", string(make([]byte, 65))}
+	for _, name := range invalid {
+		if err := ValidateProfileName(name); err == nil {
+			t.Errorf("ValidateProfileName(%q) expected error", name)
+		}
+	}
+}
+
+// TestProfileManagerLayout tests that the default profile keeps bin/config.json and named profiles get own directories
+func TestProfileManagerLayout(t *testing.T) {
+	binDir := t.TempDir()
+	m := NewProfileManager(binDir, nil)
+
+	def, err := m.Profile(DefaultProfileName)
+	if err != nil {
+		t.Fatalf("Profile(default) error: %v", err)
+	}
+	if def.ConfigPath != filepath.Join(binDir, "config.json") {
+		t.Errorf("Unexpected default config path: %s", def.ConfigPath)
+	}
+	work, err := m.Profile("work")
+	if err != nil {
+		t.Fatalf("Profile(work) error: %v", err)
+	}
+	if work.ConfigPath != filepath.Join(binDir, "profiles", "work", "config.json") {
+		t.Errorf("Unexpected profile config path: %s", work.ConfigPath)
+	}
+	if GetStatePath(work.ConfigPath) != filepath.Join(binDir, "profiles", "work", "launcher_state.json") {
+		t.Errorf("State file must be stored in the profile directory, got %s", GetStatePath(work.ConfigPath))
+	}
+	if _, err := m.Profile("../escape"); err == nil {
+		t.Error("Expected error for a name with a path separator")
+	}
+}
+
+// TestProfileManagerCreateListDelete tests the profile lifecycle
+func TestProfileManagerCreateListDelete(t *testing.T) {
+	binDir := t.TempDir()
+	m := NewProfileManager(binDir, nil)
+	if err := os.WriteFile(filepath.Join(binDir, "config.json"), []byte(`{"log":{}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if names := m.Names(); !reflect.DeepEqual(names, []string{DefaultProfileName}) {
+		t.Errorf("Expected only the default profile, got %v", names)
+	}
+
+	work, err := m.Create("work", DefaultProfileName)
+	if err != nil {
+		t.Fatalf("Create(work) error: %v", err)
+	}
+	data, err := os.ReadFile(work.ConfigPath)
+	if err != nil || string(data) != `{"log":{}}` {
+		t.Errorf("Expected copied config, got %q (err %v)", data, err)
+	}
+	empty, err := m.Create("Beta", "")
+	if err != nil {
+		t.Fatalf("Create(Beta) error: %v", err)
+	}
+	if empty.HasConfig() {
+		t.Error("Empty profile must not have a config")
+	}
+
+	if _, err := m.Create("work", ""); !errors.Is(err, ErrProfileExists) {
+		t.Errorf("Expected ErrProfileExists, got %v", err)
+	}
+	if _, err := m.Create(DefaultProfileName, ""); !errors.Is(err, ErrProfileExists) {
+		t.Errorf("Expected ErrProfileExists for default, got %v", err)
+	}
+	if _, err := m.Create("copy", "missing"); !errors.Is(err, ErrProfileNotFound) {
+		t.Errorf("Expected ErrProfileNotFound for missing source, got %v", err)
+	}
+
+	// Default first, then case-insensitive order
+	if names := m.Names(); !reflect.DeepEqual(names, []string{DefaultProfileName, "Beta", "work"}) {
+		t.Errorf("Unexpected profile list: %v", names)
+	}
+
+	if err := m.Delete(DefaultProfileName); err == nil {
+		t.Error("Expected error deleting the default profile")
+	}
+	if err := m.Delete("work"); err != nil {
+		t.Fatalf("Delete(work) error: %v", err)
+	}
+	if m.Exists("work") {
+		t.Error("Profile still exists after Delete")
+	}
+	if err := m.Delete("work"); !errors.Is(err, ErrProfileNotFound) {
+		t.Errorf("Expected ErrProfileNotFound, got %v", err)
+	}
+}
+
+// TestProfileManagerActive tests remembering the active profile
+func TestProfileManagerActive(t *testing.T) {
+	binDir := t.TempDir()
+	m := NewProfileManager(binDir, NewSettingsStore(GetSettingsPath(binDir)))
+
+	if active := m.Active(); active != DefaultProfileName {
+		t.Errorf("Expected default profile initially, got %q", active)
+	}
+	if err := m.SetActive("missing"); !errors.Is(err, ErrProfileNotFound) {
+		t.Errorf("Expected ErrProfileNotFound, got %v", err)
+	}
+	if _, err := m.Create("work", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SetActive("work"); err != nil {
+		t.Fatalf("SetActive error: %v", err)
+	}
+	data, err := os.ReadFile(GetSettingsPath(binDir))
+	if err != nil || !strings.Contains(string(data), `"last_profile": "work"`) || !strings.Contains(string(data), `"version": 1`) {
+		t.Errorf("Expected the active profile in settings.json, got %s (%v)", data, err)
+	}
+	if active := NewProfileManager(binDir, NewSettingsStore(GetSettingsPath(binDir))).Active(); active != "work" {
+		t.Errorf("Expected 'work' after reload, got %q", active)
+	}
+
+	// A deleted active profile falls back to the default one
+	if err := m.Delete("work"); err != nil {
+		t.Fatal(err)
+	}
+	if active := m.Active(); active != DefaultProfileName {
+		t.Errorf("Expected fallback to default, got %q", active)
+	}
+}
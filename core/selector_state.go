@@ -0,0 +1,212 @@
+package core
+
+import (
+	"log"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"singbox-launcher/api"
+)
+
+// selectorRestoreIntervals are the waits between attempts to reach the Clash API
+// before re-applying remembered selector choices (sing-box needs a moment to start it).
+var selectorRestoreIntervals = []time.Duration{0, 1 * time.Second, 2 * time.Second, 3 * time.Second, 5 * time.Second, 5 * time.Second}
+
+// RememberSelectorChoice stores the member chosen by the user in a selector group,
+// so it can be re-applied after sing-box restarts or the config is regenerated.
+func (ac *AppController) RememberSelectorChoice(group, proxy string) {
+	store := ac.CurrentStateStore()
+	if store == nil {
+		return
+	}
+	if err := store.SetSelectorChoice(group, proxy); err != nil {
+		log.Printf("RememberSelectorChoice: failed to save choice %s -> %s: %v", group, proxy, err)
+		return
+	}
+	log.Printf("RememberSelectorChoice: remembered %s -> %s", group, proxy)
+}
+
+// RestoreSelectorChoices re-applies remembered selector choices via the Clash API.
+// If a remembered node no longer exists in its group, the closest-matching tag
+// (same country first) is selected instead and the substitution is logged.
+// Blocks until the API is reachable or the retry budget is exhausted.
+func (ac *AppController) RestoreSelectorChoices() {
+	store := ac.CurrentStateStore()
+	if store == nil {
+		return
+	}
+	choices := store.SelectorChoices()
+	if len(choices) == 0 {
+		return
+	}
+
+	ac.APIStateMutex.RLock()
+	enabled := ac.ClashAPIEnabled
+	selectedGroup := ac.SelectedClashGroup
+	ac.APIStateMutex.RUnlock()
+
+	if !enabled {
+		log.Println("RestoreSelectorChoices: Clash API is disabled, skipping")
+		return
+	}
+	client := ac.ClashClient()
+	if !ac.waitForClashAPI(client) {
+		log.Println("RestoreSelectorChoices: Clash API is not reachable, skipping")
+		return
+	}
+
+	groups := make([]string, 0, len(choices))
+	for group := range choices {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+
+	for _, group := range groups {
+		wanted := choices[group]
+		proxies, now, err := client.ProxiesInGroup(ac.ctx, group)
+		if err != nil {
+			log.Printf("RestoreSelectorChoices: skipping group '%s': %v", group, err)
+			continue
+		}
+
+		names := make([]string, 0, len(proxies))
+		for _, p := range proxies {
+			names = append(names, p.Name)
+		}
+
+		target := wanted
+		if !containsString(names, wanted) {
+			target = FindClosestProxyTag(wanted, names)
+			if target == "" {
+				log.Printf("RestoreSelectorChoices: '%s' no longer exists in group '%s' and no similar node found, keeping '%s'", wanted, group, now)
+				continue
+			}
+			log.Printf("RestoreSelectorChoices: '%s' no longer exists in group '%s', substituting closest match '%s'", wanted, group, target)
+		}
+
+		if target == now {
+			continue
+		}
+		if err := client.SwitchProxy(ac.ctx, group, target); err != nil {
+			log.Printf("RestoreSelectorChoices: failed to switch group '%s' to '%s': %v", group, target, err)
+			continue
+		}
+		log.Printf("RestoreSelectorChoices: restored group '%s' to '%s'", group, target)
+
+		if group == selectedGroup {
+			ac.SetActiveProxyName(target)
+		}
+		ac.Events.Publish(ProxySwitched{Group: group, Proxy: target})
+	}
+}
+
+// waitForClashAPI polls the Clash API until it answers, sing-box stops or attempts run out
+func (ac *AppController) waitForClashAPI(client *api.Client) bool {
+	for _, interval := range selectorRestoreIntervals {
+		select {
+		case <-ac.ctx.Done():
+			return false
+		case <-time.After(interval):
+		}
+		if !ac.RunningState.IsRunning() {
+			return false
+		}
+		if _, err := client.Version(ac.ctx); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// FindClosestProxyTag returns the candidate most similar to tag, which no longer exists.
+// When tag carries a country flag, only candidates with the same flag are considered;
+// among them (or among all candidates otherwise) the one sharing the most words wins.
+// Returns an empty string when nothing is similar enough.
+func FindClosestProxyTag(tag string, candidates []string) string {
+	flag := countryFlag(tag)
+	words := tagWords(tag)
+
+	best := ""
+	bestScore := 0
+	bestPrefix := 0
+	for _, candidate := range candidates {
+		score := 0
+		if flag != "" {
+			if countryFlag(candidate) != flag {
+				continue
+			}
+			score += 100
+		}
+		candidateWords := tagWords(candidate)
+		for word := range words {
+			if candidateWords[word] {
+				score += 10
+			}
+		}
+		if score == 0 {
+			continue
+		}
+		prefix := commonPrefixLen(tag, candidate)
+		if score > bestScore || (score == bestScore && prefix > bestPrefix) {
+			best = candidate
+			bestScore = score
+			bestPrefix = prefix
+		}
+	}
+	return best
+}
+
+// countryFlag returns the first flag emoji (pair of regional indicator symbols) in tag
+func countryFlag(tag string) string {
+	runes := []rune(tag)
+	for i := 0; i+1 < len(runes); i++ {
+		if isRegionalIndicator(runes[i]) && isRegionalIndicator(runes[i+1]) {
+			return string(runes[i : i+2])
+		}
+	}
+	return ""
+}
+
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1F1E6 && r <= 0x1F1FF
+}
+
+// tagWords splits tag into lowercase words, ignoring pure numbers and flag symbols
+func tagWords(tag string) map[string]bool {
+	fields := strings.FieldsFunc(strings.ToLower(tag), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	words := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		isNumber := true
+		for _, r := range field {
+			if !unicode.IsDigit(r) {
+				isNumber = false
+				break
+			}
+		}
+		if !isNumber {
+			words[field] = true
+		}
+	}
+	return words
+}
+
+func commonPrefixLen(a, b string) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return n
+}
+
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
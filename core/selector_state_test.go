@@ -0,0 +1,105 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestFindClosestProxyTag tests the fallback used when a remembered node disappears
+func TestFindClosestProxyTag(t *testing.T) {
+	tests := []struct {
+		name       string
+		tag        string
+		candidates []string
+		expected   string
+	}{
+		{
+			name:       "Same country preferred over shared words",
+			tag:        "🇩🇪 Germany Fast 1",
+			candidates: []string{"🇳🇱 Netherlands Fast 1", "🇩🇪 Frankfurt 2", "direct-out"},
+			expected:   "🇩🇪 Frankfurt 2",
+		},
+		{
+			name:       "Same country with most shared words",
+			tag:        "🇺🇸 USA Premium 3",
+			candidates: []string{"🇺🇸 USA 1", "🇺🇸 USA Premium 5", "🇬🇧 UK Premium 3"},
+			expected:   "🇺🇸 USA Premium 5",
+		},
+		{
+			name:       "No candidate from the same country",
+			tag:        "🇫🇷 France 1",
+			candidates: []string{"🇩🇪 Germany 1", "🇳🇱 Netherlands 1"},
+			expected:   "",
+		},
+		{
+			name:       "Word overlap without flags",
+			tag:        "node-de-frankfurt-2",
+			candidates: []string{"node-nl-amsterdam-1", "node-de-frankfurt-7", "auto-proxy-out"},
+			expected:   "node-de-frankfurt-7",
+		},
+		{
+			name:       "Nothing similar",
+			tag:        "alpha",
+			candidates: []string{"beta", "gamma"},
+			expected:   "",
+		},
+		{
+			name:       "Empty candidates",
+			tag:        "🇩🇪 Germany",
+			candidates: nil,
+			expected:   "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FindClosestProxyTag(tt.tag, tt.candidates); got != tt.expected {
+				t.Errorf("FindClosestProxyTag(%q) = %q, expected %q", tt.tag, got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestStateStore_SelectorChoices tests that selector choices survive a reload from disk
+func TestStateStore_SelectorChoices(t *testing.T) {
+	tempDir := t.TempDir()
+	statePath := GetStatePath(filepath.Join(tempDir, "config.json"))
+
+	store := NewStateStore(statePath)
+	if len(store.SelectorChoices()) != 0 {
+		t.Fatalf("Expected empty state for missing file")
+	}
+	if err := store.SetSelectorChoice("proxy-out", "🇩🇪 Germany 1"); err != nil {
+		t.Fatalf("SetSelectorChoice failed: %v", err)
+	}
+	if err := store.SetSelectorChoice("streaming", "🇺🇸 USA 2"); err != nil {
+		t.Fatalf("SetSelectorChoice failed: %v", err)
+	}
+	if err := store.SetSelectorChoice("", "x"); err == nil {
+		t.Error("Expected error for empty group")
+	}
+
+	reloaded := NewStateStore(statePath)
+	if proxy, ok := reloaded.SelectorChoice("proxy-out"); !ok || proxy != "🇩🇪 Germany 1" {
+		t.Errorf("Expected remembered choice for proxy-out, got %q (found=%v)", proxy, ok)
+	}
+	if got := len(reloaded.SelectorChoices()); got != 2 {
+		t.Errorf("Expected 2 remembered choices, got %d", got)
+	}
+}
+
+// TestStateStore_CorruptedFile tests that a corrupted state file falls back to empty state
+func TestStateStore_CorruptedFile(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "launcher_state.json")
+	if err := os.WriteFile(statePath, []byte("{not json"), 0644); err != nil {
+		t.Fatalf("Failed to write state file: %v", err)
+	}
+	store := NewStateStore(statePath)
+	if len(store.SelectorChoices()) != 0 {
+		t.Error("Expected empty state for corrupted file")
+	}
+	if err := store.SetSelectorChoice("proxy-out", "node"); err != nil {
+		t.Fatalf("SetSelectorChoice failed: %v", err)
+	}
+}
@@ -0,0 +1,386 @@
+package core
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"singbox-launcher/internal/constants"
+)
+
+// SettingsVersion is the current version of settings.json. Older files are migrated on load.
+const SettingsVersion = 1
+
+// DefaultControlPort is the port of the local control API when the settings do not set one
+const DefaultControlPort = 9095
+
+// controlTokenBytes is the length of a generated control API token (hex-encoded, so twice as many characters)
+const controlTokenBytes = 24
+
+// Settings are launcher-wide preferences shared by all profiles. They are stored in bin/settings.json;
+// runtime state of a profile lives in its launcher_state.json (see LauncherState).
+type Settings struct {
+	Version int `json:"version"`
+	// AutoStart starts sing-box when the launcher starts (like the -start flag).
+	AutoStart bool `json:"auto_start"`
+	// StartInTray hides the main window on launch (like the -tray flag).
+	StartInTray bool `json:"start_in_tray"`
+	// LastProfile is the profile used on the next launch.
+	LastProfile string `json:"last_profile,omitempty"`
+	// SelectedGroups maps a profile name to the selector group chosen on the Servers tab.
+	SelectedGroups map[string]string `json:"selected_groups,omitempty"`
+	// LatencyTestURL is used by latency tests of profiles that do not set their own URL.
+	LatencyTestURL string `json:"latency_test_url,omitempty"`
+	// SystemProxy points the system proxy (GNOME, KDE, env file) at the local inbound while sing-box runs (Linux).
+	// Off by default: it rewrites desktop settings, so the user turns it on in the Settings tab.
+	SystemProxy bool `json:"system_proxy"`
+	// AutoApplyOnUpdate restarts a running sing-box after a subscription update rewrote config.json.
+	AutoApplyOnUpdate bool `json:"auto_apply_on_update"`
+	// Notifications selects the messages shown in the GUI.
+	Notifications NotificationSettings `json:"notifications"`
+	// ControlServer configures the local control API for scripts and browser extensions.
+	ControlServer ControlServerSettings `json:"control_server"`
+	// Core records which of the cores in bin/cores is active (see ActivateCore).
+	Core CoreSettings `json:"core"`
+	// Downloads configures how sing-box and wintun.dll are downloaded.
+	Downloads DownloadSettings `json:"downloads"`
+	// Launcher configures updates of the launcher itself.
+	Launcher LauncherSettings `json:"launcher"`
+	// GitHub configures requests to the GitHub API made by version checks.
+	GitHub GitHubSettings `json:"github"`
+}
+
+// GitHubSettings configures GitHub API requests
+type GitHubSettings struct {
+	Token string `json:"token,omitempty"` // Optional personal access token (raises the rate limit from 60 to 5000 requests per hour)
+}
+
+// LauncherSettings configures launcher self-updates
+type LauncherSettings struct {
+	Channel string `json:"channel,omitempty"` // Release channel: stable (empty) or prerelease
+}
+
+// CoreSettings tracks the active sing-box version and the one to roll back to
+type CoreSettings struct {
+	Active   string `json:"active,omitempty"`   // Version copied to bin/sing-box
+	Previous string `json:"previous,omitempty"` // Version that was active before, used for rollback
+	Trial    bool   `json:"trial,omitempty"`    // Active has not completed a first run yet; a crash rolls it back
+	Channel  string `json:"channel,omitempty"`  // Release channel: stable (empty), prerelease or pinned
+	Pin      string `json:"pin,omitempty"`      // Pinned version "1.12.12" or range "1.12.x" for the pinned channel
+	// SetCapabilities re-applies the Linux capabilities to bin/sing-box through polkit whenever they are missing
+	SetCapabilities bool `json:"set_capabilities,omitempty"`
+}
+
+// NotificationSettings turns optional messages on and off. Errors are always shown.
+type NotificationSettings struct {
+	ConfigUpdated bool `json:"config_updated"` // "Config updated successfully!" after a subscription update
+	CrashRestart  bool `json:"crash_restart"`  // sing-box crashed and is being restarted
+}
+
+// Download sources of DownloadSettings.Mirrors; other entries are URL templates (see downloadSources)
+const (
+	MirrorGitHub      = "github"
+	MirrorSourceForge = "sourceforge"
+)
+
+// DownloadProxySingBox in DownloadSettings.Proxy downloads through the mixed/http/socks inbound of the running sing-box
+const DownloadProxySingBox = "sing-box"
+
+// DefaultDownloadRetries is how many times each download source is tried
+const DefaultDownloadRetries = 3
+
+// DefaultDownloadMirrors returns the download sources used when the settings do not list any.
+// Third-party mirrors (URL templates) are only used when added in the settings.
+func DefaultDownloadMirrors() []string {
+	return []string{MirrorGitHub, MirrorSourceForge}
+}
+
+// DownloadSettings configures downloads of sing-box and wintun.dll
+type DownloadSettings struct {
+	// Mirrors are tried in order: "github", "sourceforge" or a URL template with {url}, {version} and {file}.
+	Mirrors []string `json:"mirrors,omitempty"`
+	// Proxy is empty (direct), DownloadProxySingBox or a proxy URL (http://, socks5://).
+	Proxy string `json:"proxy,omitempty"`
+	// Retries is the number of attempts per source (0 means DefaultDownloadRetries).
+	Retries int `json:"retries,omitempty"`
+}
+
+// SourceList returns the configured mirrors or DefaultDownloadMirrors
+func (s DownloadSettings) SourceList() []string {
+	if len(s.Mirrors) == 0 {
+		return DefaultDownloadMirrors()
+	}
+	return s.Mirrors
+}
+
+// Attempts returns the number of attempts per source
+func (s DownloadSettings) Attempts() int {
+	if s.Retries <= 0 {
+		return DefaultDownloadRetries
+	}
+	return s.Retries
+}
+
+// ControlServerSettings configures the local control API (see package control). It is off by default.
+type ControlServerSettings struct {
+	Enabled bool   `json:"enabled"`
+	Port    int    `json:"port,omitempty"`  // 0 means DefaultControlPort
+	Token   string `json:"token,omitempty"` // Generated when the server is started without one
+	// AllowedOrigins are the browser extensions allowed to call the API from a page,
+	// e.g. "chrome-extension://<id>" or "moz-extension://<uuid>". Requests from other origins are refused.
+	AllowedOrigins []string `json:"allowed_origins,omitempty"`
+}
+
+// extensionOriginSchemes are the URL schemes of browser extension origins
+var extensionOriginSchemes = []string{"chrome-extension://", "moz-extension://", "safari-web-extension://"}
+
+// IsExtensionOrigin reports whether origin is the origin of a browser extension ("<scheme>://<id>" without a path)
+func IsExtensionOrigin(origin string) bool {
+	for _, scheme := range extensionOriginSchemes {
+		if id, ok := strings.CutPrefix(origin, scheme); ok {
+			return id != "" && !strings.ContainsAny(id, "/?#* ")
+		}
+	}
+	return false
+}
+
+// Equal reports whether both settings are the same
+func (s ControlServerSettings) Equal(other ControlServerSettings) bool {
+	return s.Enabled == other.Enabled && s.Port == other.Port && s.Token == other.Token &&
+		slices.Equal(s.AllowedOrigins, other.AllowedOrigins)
+}
+
+// ListenPort returns the configured port or DefaultControlPort
+func (s ControlServerSettings) ListenPort() int {
+	if s.Port <= 0 || s.Port > 65535 {
+		return DefaultControlPort
+	}
+	return s.Port
+}
+
+// DefaultSettings returns the settings used when settings.json does not exist
+func DefaultSettings() Settings {
+	return Settings{
+		Version:       SettingsVersion,
+		Notifications: NotificationSettings{ConfigUpdated: true, CrashRestart: true},
+	}
+}
+
+// clone returns a copy that does not share the SelectedGroups map, the Mirrors and the AllowedOrigins slices
+func (s Settings) clone() Settings {
+	if s.SelectedGroups != nil {
+		groups := make(map[string]string, len(s.SelectedGroups))
+		for profile, group := range s.SelectedGroups {
+			groups[profile] = group
+		}
+		s.SelectedGroups = groups
+	}
+	s.Downloads.Mirrors = append([]string(nil), s.Downloads.Mirrors...)
+	s.ControlServer.AllowedOrigins = slices.Clone(s.ControlServer.AllowedOrigins)
+	return s
+}
+
+// settingsMigrations migrate settings.json from version N (the key) to N+1, see ConfigMigrator.
+// Add an entry and bump SettingsVersion when a setting is renamed or changes its meaning.
+var settingsMigrations = map[int]MigrationFunc{}
+
+// migrateSettings applies the migrations from version to SettingsVersion
+func migrateSettings(jsonContent string, version int) (string, error) {
+	for ; version < SettingsVersion; version++ {
+		migration, exists := settingsMigrations[version]
+		if !exists {
+			return "", fmt.Errorf("settings migration from version %d to %d not found", version, version+1)
+		}
+		var err error
+		if jsonContent, err = migration(jsonContent); err != nil {
+			return "", fmt.Errorf("failed to migrate settings from version %d to %d: %w", version, version+1, err)
+		}
+		log.Printf("SettingsStore: Migrated settings to version %d", version+1)
+	}
+	return jsonContent, nil
+}
+
+// SettingsStore provides thread-safe typed access to the persisted Settings.
+// A nil store returns DefaultSettings and ignores changes (controllers built by hand in tests have none).
+type SettingsStore struct {
+	path     string
+	mu       sync.Mutex
+	settings Settings
+}
+
+// GetSettingsPath returns the path of the launcher settings file in binDir
+func GetSettingsPath(binDir string) string {
+	return filepath.Join(binDir, constants.SettingsFileName)
+}
+
+// NewSettingsStore creates a store bound to path and loads the existing settings, if any.
+// A missing or corrupted file results in default settings (the error is only logged).
+func NewSettingsStore(path string) *SettingsStore {
+	store := &SettingsStore{path: path}
+	if err := store.load(); err != nil {
+		log.Printf("SettingsStore: failed to load %s: %v (using defaults)", path, err)
+	}
+	return store
+}
+
+// Path returns the location of the settings file
+func (s *SettingsStore) Path() string {
+	return s.path
+}
+
+// load reads the settings file into memory, migrating and saving it if it has an older version
+func (s *SettingsStore) load() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.settings = DefaultSettings()
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read settings file: %w", err)
+	}
+
+	var header struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return fmt.Errorf("failed to parse settings file: %w", err)
+	}
+	if header.Version == 0 {
+		// Written by hand without a version (see README): read as the current version
+		header.Version = SettingsVersion
+	}
+	content := string(data)
+	if header.Version > SettingsVersion {
+		log.Printf("SettingsStore: settings version %d is newer than supported version %d, unknown settings are ignored", header.Version, SettingsVersion)
+	} else if header.Version < SettingsVersion {
+		if content, err = migrateSettings(content, header.Version); err != nil {
+			return err
+		}
+	}
+
+	// Fields missing from the file keep their defaults
+	settings := DefaultSettings()
+	if err := json.Unmarshal([]byte(content), &settings); err != nil {
+		return fmt.Errorf("failed to parse settings file: %w", err)
+	}
+	s.settings = settings
+	if header.Version < SettingsVersion {
+		s.settings.Version = SettingsVersion
+		if err := s.saveLocked(); err != nil {
+			log.Printf("SettingsStore: failed to save migrated settings: %v", err)
+		}
+	}
+	return nil
+}
+
+// saveLocked writes the settings to disk through a temporary file. Caller must hold s.mu.
+// The file may contain the control token, so it is readable by the owner only.
+func (s *SettingsStore) saveLocked() error {
+	data, err := json.MarshalIndent(s.settings, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal settings: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create settings directory: %w", err)
+	}
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write settings file: %w", err)
+	}
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to replace settings file: %w", err)
+	}
+	return nil
+}
+
+// Get returns a copy of the current settings
+func (s *SettingsStore) Get() Settings {
+	if s == nil {
+		return DefaultSettings()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.settings.clone()
+}
+
+// Update changes the settings with fn and saves them
+func (s *SettingsStore) Update(fn func(settings *Settings)) error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	updated := s.settings.clone()
+	fn(&updated)
+	updated.Version = SettingsVersion
+	s.settings = updated
+	return s.saveLocked()
+}
+
+// ControlServer returns the control API settings
+func (s *SettingsStore) ControlServer() ControlServerSettings {
+	return s.Get().ControlServer
+}
+
+// SetControlServer saves the control API settings
+func (s *SettingsStore) SetControlServer(settings ControlServerSettings) error {
+	return s.Update(func(current *Settings) { current.ControlServer = settings })
+}
+
+// EnsureControlToken returns the control API token, generating and saving one if it is not set yet
+func (s *SettingsStore) EnsureControlToken() (string, error) {
+	if token := s.ControlServer().Token; token != "" {
+		return token, nil
+	}
+	token, err := GenerateControlToken()
+	if err != nil {
+		return "", err
+	}
+	if err := s.Update(func(current *Settings) {
+		if current.ControlServer.Token == "" {
+			current.ControlServer.Token = token
+		}
+		token = current.ControlServer.Token
+	}); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// SelectedGroup returns the selector group remembered for profile (empty if none)
+func (s *SettingsStore) SelectedGroup(profile string) string {
+	return s.Get().SelectedGroups[profile]
+}
+
+// SetSelectedGroup remembers the selector group chosen for profile
+func (s *SettingsStore) SetSelectedGroup(profile, group string) error {
+	if s.SelectedGroup(profile) == group {
+		return nil
+	}
+	return s.Update(func(current *Settings) {
+		if current.SelectedGroups == nil {
+			current.SelectedGroups = make(map[string]string)
+		}
+		current.SelectedGroups[profile] = group
+	})
+}
+
+// GenerateControlToken returns a random token for the control API
+func GenerateControlToken() (string, error) {
+	buf := make([]byte, controlTokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate control token: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
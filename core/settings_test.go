@@ -0,0 +1,124 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestSettingsStore_ControlToken tests that a generated token is saved and survives reloading
+func TestSettingsStore_ControlToken(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "settings.json")
+	store := NewSettingsStore(path)
+	if settings := store.ControlServer(); settings.Enabled || settings.ListenPort() != DefaultControlPort {
+		t.Errorf("Expected disabled server on the default port, got %+v", settings)
+	}
+
+	origin := "chrome-extension://abcdefghijklmnopabcdefghijklmnop"
+	if err := store.SetControlServer(ControlServerSettings{Enabled: true, Port: 9100, AllowedOrigins: []string{origin}}); err != nil {
+		t.Fatal(err)
+	}
+	store.Get().ControlServer.AllowedOrigins[0] = "changed" // Get returns a copy
+	if origins := store.ControlServer().AllowedOrigins; len(origins) != 1 || origins[0] != origin {
+		t.Errorf("Expected the stored origin, got %v", origins)
+	}
+	token, err := store.EnsureControlToken()
+	if err != nil || len(token) != 2*controlTokenBytes {
+		t.Fatalf("Expected a generated token, got %q (%v)", token, err)
+	}
+	if again, _ := store.EnsureControlToken(); again != token {
+		t.Errorf("Expected the same token on the second call, got %q", again)
+	}
+
+	reloaded := NewSettingsStore(path).ControlServer()
+	if !reloaded.Enabled || reloaded.ListenPort() != 9100 || reloaded.Token != token {
+		t.Errorf("Unexpected reloaded settings: %+v", reloaded)
+	}
+}
+
+// TestSettingsStore_Corrupted tests that a broken file falls back to defaults
+func TestSettingsStore_Corrupted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "settings.json")
+	if err := os.WriteFile(path, []byte("{broken"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if settings := NewSettingsStore(path).ControlServer(); settings.Enabled {
+		t.Errorf("Expected defaults for a corrupted file, got %+v", settings)
+	}
+}
+
+// TestSettingsStore_WithoutVersion tests that a hand-written file without a version keeps the defaults of missing fields
+func TestSettingsStore_WithoutVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "settings.json")
+	handWritten := `{"control_server": {"enabled": true, "port": 9200}}`
+	if err := os.WriteFile(path, []byte(handWritten), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewSettingsStore(path)
+	settings := store.Get()
+	if settings.Version != SettingsVersion {
+		t.Errorf("Expected version %d, got %d", SettingsVersion, settings.Version)
+	}
+	if !settings.ControlServer.Enabled || settings.ControlServer.Port != 9200 {
+		t.Errorf("Control server settings were lost: %+v", settings.ControlServer)
+	}
+	if !settings.Notifications.ConfigUpdated || !settings.Notifications.CrashRestart {
+		t.Errorf("Expected default notifications, got %+v", settings.Notifications)
+	}
+
+	if err := store.Update(func(settings *Settings) { settings.AutoStart = true }); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"version": 1`) {
+		t.Errorf("Expected the saved file to have a version, got %s", data)
+	}
+}
+
+// TestSettingsStore_SelectedGroups tests remembering selector groups per profile and the nil store
+func TestSettingsStore_SelectedGroups(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "settings.json")
+	store := NewSettingsStore(path)
+	if err := store.SetSelectedGroup("work", "auto-out"); err != nil {
+		t.Fatal(err)
+	}
+	settings := store.Get()
+	settings.SelectedGroups["work"] = "changed" // Get returns a copy
+	if group := NewSettingsStore(path).SelectedGroup("work"); group != "auto-out" {
+		t.Errorf("Expected 'auto-out', got %q", group)
+	}
+	if group := store.SelectedGroup(DefaultProfileName); group != "" {
+		t.Errorf("Expected no group for the default profile, got %q", group)
+	}
+
+	var none *SettingsStore
+	if !none.Get().Notifications.ConfigUpdated || none.SelectedGroup("work") != "" {
+		t.Error("Expected defaults from a nil store")
+	}
+	if err := none.SetSelectedGroup("work", "auto-out"); err != nil {
+		t.Errorf("Expected nil store to ignore changes, got %v", err)
+	}
+}
+
+// TestIsExtensionOrigin tests which origins may be allowed to call the control API
+func TestIsExtensionOrigin(t *testing.T) {
+	for origin, want := range map[string]bool{
+		"chrome-extension://abcdefgh":                          true,
+		"moz-extension://2b3c1a8e-0000-4e6f-9f00-1234567890ab": true,
+		"chrome-extension://":                                  false,
+		"chrome-extension://abc/popup.html":                    false,
+		"chrome-extension://*":                                 false,
+		"https://example.com":                                  false,
+		"*":                                                    false,
+		"":                                                     false,
+	} {
+		if got := IsExtensionOrigin(origin); got != want {
+			t.Errorf("IsExtensionOrigin(%q) = %v, expected %v", origin, got, want)
+		}
+	}
+}
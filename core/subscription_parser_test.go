@@ -53,14 +53,10 @@ func TestDecodeSubscriptionContent(t *testing.T) {
 			expectError: true,
 		},
 		{
+			// Trimmed to nothing, it decodes as empty base64: there are no nodes to parse
 			name:        "Whitespace only",
 			content:     []byte("   \n\t  "),
-			expectError: false,
-			checkResult: func(t *testing.T, decoded []byte) {
-				if len(decoded) == 0 {
-					t.Error("Expected decoded content to be returned even if whitespace")
-				}
-			},
+			expectError: true,
 		},
 	}
 
@@ -133,7 +129,8 @@ func TestNormalizeParserConfig(t *testing.T) {
 				} `json:"parser,omitempty"`
 			}{},
 		}
-		before := time.Now()
+		// last_updated is RFC3339, i.e. whole seconds
+		before := time.Now().Truncate(time.Second)
 		NormalizeParserConfig(config, true)
 		after := time.Now()
 		if config.ParserConfig.Parser.LastUpdated == "" {
@@ -203,8 +200,9 @@ func TestExtractParserConfig(t *testing.T) {
 		if config == nil {
 			t.Fatal("Expected config, got nil")
 		}
-		if config.ParserConfig.Version != 3 {
-			t.Errorf("Expected version 3, got %d", config.ParserConfig.Version)
+		// Version 3 is migrated to the current format on load
+		if config.ParserConfig.Version != ParserConfigVersion {
+			t.Errorf("Expected version %d, got %d", ParserConfigVersion, config.ParserConfig.Version)
 		}
 		if len(config.ParserConfig.Proxies) != 1 {
 			t.Errorf("Expected 1 proxy source, got %d", len(config.ParserConfig.Proxies))
@@ -0,0 +1,164 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	ps "github.com/mitchellh/go-ps"
+
+	"singbox-launcher/internal/constants"
+	"singbox-launcher/internal/platform"
+)
+
+// Системный прокси (Linux): пока sing-box запущен с конфигом без TUN, системный прокси GNOME/KDE и
+// файл proxy.env указывают на mixed/http/socks inbound конфига. Прежние настройки сохраняются в
+// bin/system_proxy_backup.json до изменения и восстанавливаются при остановке или падении sing-box;
+// если лаунчер завершился аварийно, их восстанавливает следующий запуск.
+
+// systemProxyBackup is the content of bin/system_proxy_backup.json
+type systemProxyBackup struct {
+	PID   int                       `json:"pid"` // Launcher that set the system proxy
+	State platform.SystemProxyState `json:"state"`
+}
+
+// systemProxyBackupPath returns the location of the saved system proxy settings
+func (ac *AppController) systemProxyBackupPath() string {
+	return filepath.Join(platform.GetBinDir(ac.ExecDir), constants.SystemProxyBackupFileName)
+}
+
+// readSystemProxyBackup returns the saved settings (nil if the system proxy was not set)
+func (ac *AppController) readSystemProxyBackup() (*systemProxyBackup, error) {
+	data, err := os.ReadFile(ac.systemProxyBackupPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read system proxy backup: %w", err)
+	}
+	var backup systemProxyBackup
+	if err := json.Unmarshal(data, &backup); err != nil {
+		return nil, fmt.Errorf("failed to parse system proxy backup: %w", err)
+	}
+	return &backup, nil
+}
+
+// EnableSystemProxy points the system proxy at the local inbound of the active config.
+// Does nothing when the setting is off, on other platforms and for TUN configs (they capture all traffic).
+func (ac *AppController) EnableSystemProxy() error {
+	if !ac.Settings.Get().SystemProxy || !platform.SystemProxySupported() {
+		return nil
+	}
+	if tun, err := ConfigHasTunInbound(ac.ConfigPath); err != nil || tun {
+		return err
+	}
+	inbound, err := FindLocalProxyInbound(ac.ConfigPath)
+	if err != nil {
+		log.Printf("EnableSystemProxy: system proxy not set: %v", err)
+		return nil
+	}
+
+	ac.systemProxyMutex.Lock()
+	defer ac.systemProxyMutex.Unlock()
+
+	// После перезапуска sing-box резервная копия уже есть: в ней исходные настройки, а не наши
+	backup, err := ac.readSystemProxyBackup()
+	if err != nil {
+		return err
+	}
+	if backup == nil {
+		state, err := platform.GetSystemProxyState()
+		if err != nil {
+			return fmt.Errorf("failed to read the system proxy settings: %w", err)
+		}
+		data, err := json.MarshalIndent(systemProxyBackup{PID: os.Getpid(), State: state}, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal system proxy backup: %w", err)
+		}
+		if err := os.WriteFile(ac.systemProxyBackupPath(), data, 0644); err != nil {
+			return fmt.Errorf("failed to save system proxy backup: %w", err)
+		}
+	}
+
+	proxy := platform.SystemProxy{
+		Host:  inbound.Host,
+		Port:  inbound.Port,
+		HTTP:  inbound.Type != "socks",
+		SOCKS: inbound.Type != "http",
+	}
+	if err := platform.SetSystemProxy(proxy); err != nil {
+		return fmt.Errorf("failed to set the system proxy: %w", err)
+	}
+	log.Printf("EnableSystemProxy: system proxy set to %s inbound %s", inbound.Type, inbound.Address())
+	return nil
+}
+
+// RestoreSystemProxy writes back the settings replaced by EnableSystemProxy (no-op if it did not set them)
+func (ac *AppController) RestoreSystemProxy() error {
+	ac.systemProxyMutex.Lock()
+	defer ac.systemProxyMutex.Unlock()
+
+	backup, err := ac.readSystemProxyBackup()
+	if err != nil || backup == nil {
+		return err
+	}
+	if err := platform.RestoreSystemProxy(backup.State); err != nil {
+		return fmt.Errorf("failed to restore the system proxy: %w", err)
+	}
+	if err := os.Remove(ac.systemProxyBackupPath()); err != nil {
+		return fmt.Errorf("failed to remove system proxy backup: %w", err)
+	}
+	log.Printf("RestoreSystemProxy: previous system proxy settings restored")
+	return nil
+}
+
+// restoreStaleSystemProxy restores settings left by a launcher that exited without restoring them
+func (ac *AppController) restoreStaleSystemProxy() {
+	backup, err := ac.readSystemProxyBackup()
+	if err != nil {
+		log.Printf("restoreStaleSystemProxy: %v", err)
+		return
+	}
+	if backup == nil {
+		return
+	}
+	if backup.PID != os.Getpid() {
+		if process, err := ps.FindProcess(backup.PID); err == nil && process != nil {
+			return // Прокси задан другим запущенным экземпляром
+		}
+	}
+	log.Printf("restoreStaleSystemProxy: restoring the system proxy left by launcher PID %d", backup.PID)
+	if err := ac.RestoreSystemProxy(); err != nil {
+		log.Printf("restoreStaleSystemProxy: %v", err)
+	}
+}
+
+// BindSystemProxy sets the system proxy while sing-box runs and restores it when sing-box stops or crashes.
+// Commands of the desktop environments are slow, so they run outside the publishing goroutine;
+// the running state is checked again in case it changed meanwhile.
+func (ac *AppController) BindSystemProxy() {
+	ac.restoreStaleSystemProxy()
+	Subscribe(ac.Events, func(CoreStarted) {
+		go func() {
+			if !ac.RunningState.IsRunning() {
+				return
+			}
+			if err := ac.EnableSystemProxy(); err != nil {
+				log.Printf("BindSystemProxy: %v", err)
+				ac.Notifier.ShowError(err)
+			}
+		}()
+	})
+	Subscribe(ac.Events, func(CoreStopped) {
+		go func() {
+			if ac.RunningState.IsRunning() {
+				return
+			}
+			if err := ac.RestoreSystemProxy(); err != nil {
+				log.Printf("BindSystemProxy: %v", err)
+			}
+		}()
+	})
+}
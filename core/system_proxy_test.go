@@ -0,0 +1,85 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"singbox-launcher/internal/platform"
+)
+
+// TestSystemProxy_EnableRestore tests the env file and the backup without GNOME/KDE tools in PATH
+func TestSystemProxy_EnableRestore(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("system proxy is implemented on Linux only")
+	}
+	t.Setenv("PATH", "")
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	execDir := t.TempDir()
+	binDir := platform.GetBinDir(execDir)
+	if err := os.MkdirAll(binDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	configPath := filepath.Join(binDir, "config.json")
+	config := `{
+  // mixed inbound for browsers
+  "inbounds": [{"type": "mixed", "tag": "proxy-in", "listen": "127.0.0.1", "listen_port": 7890}]
+}`
+	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ac := &AppController{
+		ExecDir:    execDir,
+		ConfigPath: configPath,
+		Settings:   NewSettingsStore(filepath.Join(binDir, "settings.json")),
+	}
+
+	// Выключено по умолчанию: настройки рабочего стола не трогаются
+	if err := ac.EnableSystemProxy(); err != nil {
+		t.Fatalf("EnableSystemProxy: %v", err)
+	}
+	if _, err := os.Stat(ac.systemProxyBackupPath()); !os.IsNotExist(err) {
+		t.Fatalf("system proxy set although the setting is off")
+	}
+	ac.Settings.Update(func(settings *Settings) { settings.SystemProxy = true })
+
+	if err := ac.EnableSystemProxy(); err != nil {
+		t.Fatalf("EnableSystemProxy: %v", err)
+	}
+	envFile, err := os.ReadFile(platform.SystemProxyEnvPath())
+	if err != nil {
+		t.Fatalf("env file not written: %v", err)
+	}
+	for _, want := range []string{`export http_proxy="http://127.0.0.1:7890"`, `export ALL_PROXY="socks5://127.0.0.1:7890"`} {
+		if !strings.Contains(string(envFile), want) {
+			t.Errorf("env file lacks %s:\n%s", want, envFile)
+		}
+	}
+	if _, err := os.Stat(ac.systemProxyBackupPath()); err != nil {
+		t.Errorf("backup not saved: %v", err)
+	}
+
+	if err := ac.RestoreSystemProxy(); err != nil {
+		t.Fatalf("RestoreSystemProxy: %v", err)
+	}
+	envFile, _ = os.ReadFile(platform.SystemProxyEnvPath())
+	if strings.Contains(string(envFile), "export") || !strings.Contains(string(envFile), "unset http_proxy") {
+		t.Errorf("env file not cleared:\n%s", envFile)
+	}
+	if _, err := os.Stat(ac.systemProxyBackupPath()); !os.IsNotExist(err) {
+		t.Errorf("backup not removed")
+	}
+
+	// Конфиг с TUN не трогает системный прокси
+	config = `{"inbounds": [{"type": "tun"}, {"type": "mixed", "listen_port": 7890}]}`
+	os.WriteFile(configPath, []byte(config), 0644)
+	if err := ac.EnableSystemProxy(); err != nil {
+		t.Fatalf("EnableSystemProxy with TUN: %v", err)
+	}
+	if _, err := os.Stat(ac.systemProxyBackupPath()); !os.IsNotExist(err) {
+		t.Errorf("system proxy set for a TUN config")
+	}
+}
@@ -0,0 +1,208 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"singbox-launcher/api"
+)
+
+const (
+	// trafficHistorySize is the number of per-second samples kept for the sparkline (2 minutes)
+	trafficHistorySize = 120
+	// trafficReconnectMinDelay and trafficReconnectMaxDelay bound the reconnect backoff
+	trafficReconnectMinDelay = 1 * time.Second
+	trafficReconnectMaxDelay = 10 * time.Second
+	// trafficTrayRefreshInterval limits how often the tray menu is rebuilt to show the current speed
+	trafficTrayRefreshInterval = 5 * time.Second
+)
+
+// TrafficPoint is a single speed sample in bytes per second.
+type TrafficPoint struct {
+	Time time.Time
+	Up   int64
+	Down int64
+}
+
+// TrafficMonitor streams /traffic and /memory from the Clash API while sing-box is running.
+// Streams reconnect automatically until Stop is called (sing-box stopped).
+type TrafficMonitor struct {
+	ac *AppController
+
+	mu      sync.RWMutex
+	history []TrafficPoint // oldest first, at most trafficHistorySize
+	memory  uint64
+	cancel  context.CancelFunc
+
+	lastTrayRefresh time.Time
+	lastTrayText    string
+}
+
+// NewTrafficMonitor creates a monitor bound to the controller.
+func NewTrafficMonitor(ac *AppController) *TrafficMonitor {
+	return &TrafficMonitor{ac: ac}
+}
+
+// Start begins streaming if the Clash API is enabled. Calling Start while running is a no-op.
+func (m *TrafficMonitor) Start() {
+	m.ac.APIStateMutex.RLock()
+	enabled := m.ac.ClashAPIEnabled
+	m.ac.APIStateMutex.RUnlock()
+	if !enabled {
+		return
+	}
+
+	m.mu.Lock()
+	if m.cancel != nil {
+		m.mu.Unlock()
+		return
+	}
+	parent := m.ac.ctx
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, cancel := context.WithCancel(parent)
+	m.cancel = cancel
+	m.history = nil
+	m.memory = 0
+	m.mu.Unlock()
+
+	client := m.ac.ClashClient()
+	log.Println("TrafficMonitor: Starting traffic and memory streams")
+	go m.runStream(ctx, "traffic", func(ctx context.Context) error {
+		return client.StreamTraffic(ctx, m.addTraffic)
+	})
+	go m.runStream(ctx, "memory", func(ctx context.Context) error {
+		return client.StreamMemory(ctx, func(sample api.MemorySample) {
+			m.mu.Lock()
+			m.memory = sample.InUse
+			m.mu.Unlock()
+		})
+	})
+}
+
+// Stop stops streaming and clears the current values.
+func (m *TrafficMonitor) Stop() {
+	m.mu.Lock()
+	cancel := m.cancel
+	m.cancel = nil
+	m.history = nil
+	m.memory = 0
+	m.lastTrayText = ""
+	m.mu.Unlock()
+
+	if cancel != nil {
+		log.Println("TrafficMonitor: Stopping streams")
+		cancel()
+		m.notify()
+	}
+}
+
+// runStream keeps a stream open with exponential reconnect delay while sing-box is running
+func (m *TrafficMonitor) runStream(ctx context.Context, name string, stream func(context.Context) error) {
+	delay := trafficReconnectMinDelay
+	for {
+		// Give sing-box a moment to open the API before (re)connecting
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		if !m.ac.RunningState.IsRunning() {
+			return
+		}
+
+		started := time.Now()
+		err := stream(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(started) > trafficReconnectMaxDelay {
+			delay = trafficReconnectMinDelay // the stream worked for a while, reconnect quickly
+		} else if delay *= 2; delay > trafficReconnectMaxDelay {
+			delay = trafficReconnectMaxDelay
+		}
+		log.Printf("TrafficMonitor: %s stream ended: %v, reconnecting in %v", name, err, delay)
+	}
+}
+
+// addTraffic appends a sample to the bounded history and notifies the UI
+func (m *TrafficMonitor) addTraffic(sample api.TrafficSample) {
+	m.mu.Lock()
+	m.history = append(m.history, TrafficPoint{Time: time.Now(), Up: sample.Up, Down: sample.Down})
+	if len(m.history) > trafficHistorySize {
+		m.history = append(m.history[:0], m.history[len(m.history)-trafficHistorySize:]...)
+	}
+	m.mu.Unlock()
+	m.ac.Events.Publish(TrafficUpdated{TrayStale: m.trayStale()})
+}
+
+// trayStale reports whether the tray should be rebuilt to show the current speed, at most once per trafficTrayRefreshInterval
+func (m *TrafficMonitor) trayStale() bool {
+	text := m.StatusText()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if text == m.lastTrayText || time.Since(m.lastTrayRefresh) < trafficTrayRefreshInterval {
+		return false
+	}
+	m.lastTrayText = text
+	m.lastTrayRefresh = time.Now()
+	return true
+}
+
+func (m *TrafficMonitor) notify() {
+	m.ac.Events.Publish(TrafficUpdated{})
+}
+
+// Current returns the latest speed (bytes/s) and memory usage (bytes). ok is false when no data yet.
+func (m *TrafficMonitor) Current() (up, down int64, memory uint64, ok bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if len(m.history) == 0 {
+		return 0, 0, m.memory, false
+	}
+	last := m.history[len(m.history)-1]
+	return last.Up, last.Down, m.memory, true
+}
+
+// History returns a copy of the recent speed samples, oldest first
+func (m *TrafficMonitor) History() []TrafficPoint {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	result := make([]TrafficPoint, len(m.history))
+	copy(result, m.history)
+	return result
+}
+
+// StatusText returns a short "↓ speed ↑ speed" summary, or an empty string when there is no data
+func (m *TrafficMonitor) StatusText() string {
+	up, down, _, ok := m.Current()
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("↓ %s  ↑ %s", FormatSpeed(down), FormatSpeed(up))
+}
+
+// FormatSpeed formats bytes per second as a human-readable speed
+func FormatSpeed(bytesPerSecond int64) string {
+	return FormatBytes(uint64(max(bytesPerSecond, 0))) + "/s"
+}
+
+// FormatBytes formats a byte count using binary units
+func FormatBytes(bytes uint64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	value := float64(bytes)
+	suffixes := []string{"KB", "MB", "GB", "TB"}
+	i := -1
+	for value >= unit && i < len(suffixes)-1 {
+		value /= unit
+		i++
+	}
+	return fmt.Sprintf("%.1f %s", value, suffixes[i])
+}
@@ -0,0 +1,52 @@
+package core
+
+import (
+	"testing"
+
+	"singbox-launcher/api"
+)
+
+// TestTrafficMonitor_History tests that speed history is bounded and Current returns the latest sample
+func TestTrafficMonitor_History(t *testing.T) {
+	monitor := NewTrafficMonitor(&AppController{})
+	if _, _, _, ok := monitor.Current(); ok {
+		t.Error("Expected no data before first sample")
+	}
+
+	for i := 0; i < trafficHistorySize+30; i++ {
+		monitor.addTraffic(api.TrafficSample{Up: int64(i), Down: int64(i * 10)})
+	}
+	history := monitor.History()
+	if len(history) != trafficHistorySize {
+		t.Fatalf("Expected %d samples, got %d", trafficHistorySize, len(history))
+	}
+	if history[0].Up != 30 {
+		t.Errorf("Expected oldest sample to be 30, got %d", history[0].Up)
+	}
+	up, down, _, ok := monitor.Current()
+	if !ok || up != int64(trafficHistorySize+29) || down != int64((trafficHistorySize+29)*10) {
+		t.Errorf("Unexpected current values: up=%d down=%d ok=%v", up, down, ok)
+	}
+}
+
+// TestFormatBytes tests human-readable sizes
+func TestFormatBytes(t *testing.T) {
+	tests := map[uint64]string{
+		0:                "0 B",
+		1023:             "1023 B",
+		1024:             "1.0 KB",
+		1536:             "1.5 KB",
+		5 * 1024 * 1024:  "5.0 MB",
+		3 << 30:          "3.0 GB",
+		2 << 40:          "2.0 TB",
+		2048 * (1 << 40): "2048.0 TB",
+	}
+	for input, expected := range tests {
+		if got := FormatBytes(input); got != expected {
+			t.Errorf("FormatBytes(%d) = %q, expected %q", input, got, expected)
+		}
+	}
+	if got := FormatSpeed(-5); got != "0 B/s" {
+		t.Errorf("FormatSpeed(-5) = %q", got)
+	}
+}
@@ -0,0 +1,263 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"reflect"
+	"time"
+
+	"singbox-launcher/api"
+)
+
+// trayGroupsMaxAge is how old the cached groups may get before a tray rebuild refreshes them
+// (urltest groups pick a new node on their own, so "now" goes stale)
+const trayGroupsMaxAge = 30 * time.Second
+
+// TrayGroup is a selector or urltest group shown as a tray submenu.
+type TrayGroup struct {
+	Name    string
+	Type    string // "selector" or "urltest"
+	Now     string // Selected member (auto-picked one for urltest)
+	Members []api.ProxyInfo
+}
+
+// Switchable reports whether the user can pick a member (urltest groups choose by themselves)
+func (g TrayGroup) Switchable() bool {
+	return g.Type == "selector"
+}
+
+// GetTrayGroups returns a copy of the cached groups
+func (ac *AppController) GetTrayGroups() []TrayGroup {
+	ac.APIStateMutex.RLock()
+	defer ac.APIStateMutex.RUnlock()
+	result := make([]TrayGroup, len(ac.trayGroups))
+	for i, group := range ac.trayGroups {
+		group.Members = append([]api.ProxyInfo(nil), group.Members...)
+		result[i] = group
+	}
+	return result
+}
+
+// TrayMenuItemCount returns the number of proxy items in the tray menu (used to size the update debounce)
+func (ac *AppController) TrayMenuItemCount() int {
+	ac.APIStateMutex.RLock()
+	defer ac.APIStateMutex.RUnlock()
+	count := 0
+	for _, group := range ac.trayGroups {
+		count += len(group.Members)
+	}
+	if count == 0 {
+		count = len(ac.ProxiesList)
+	}
+	return count
+}
+
+// TrayMenu is what the system tray menu shows; the ui package turns it into a Fyne menu.
+type TrayMenu struct {
+	Buttons       VPNButtonState
+	Traffic       string   // Current speed (empty when unknown or stopped)
+	Profiles      []string // Profile names, shown as a submenu when there is more than one
+	ActiveProfile string
+	Modes         ClashModeState // Clash modes, shown when ShowModes is set
+	ShowModes     bool
+	Groups        []TrayGroup
+	NoProxies     bool // The Clash API is on but the selected group has no proxies loaded
+	Latency       LatencyTestSettings
+}
+
+// TrayMenu collects the content of the tray menu. Proxies and groups that are missing or stale
+// are loaded in the background; TrayGroupsUpdated and ProxiesLoaded then trigger a rebuild.
+func (ac *AppController) TrayMenu() TrayMenu {
+	// Get proxies from current group
+	ac.APIStateMutex.RLock()
+	proxies := append([]api.ProxyInfo(nil), ac.ProxiesList...) // copy: delays are updated in place
+	activeProxy := ac.ActiveProxyName
+	selectedGroup := ac.SelectedClashGroup
+	clashAPIEnabled := ac.ClashAPIEnabled
+	ac.APIStateMutex.RUnlock()
+
+	// Auto-load proxies if list is empty and API is enabled
+	// Note: AutoLoadProxies has internal guard to prevent multiple simultaneous loads
+	if clashAPIEnabled && selectedGroup != "" && len(proxies) == 0 && ac.RunningState.IsRunning() {
+		// Check if auto-load is already in progress to avoid duplicate calls
+		ac.AutoLoadMutex.Lock()
+		alreadyInProgress := ac.AutoLoadInProgress
+		ac.AutoLoadMutex.Unlock()
+		if !alreadyInProgress {
+			go ac.AutoLoadProxies()
+		}
+	}
+
+	menu := TrayMenu{
+		Buttons:       ac.GetVPNButtonState(),
+		ActiveProfile: ac.CurrentProfile(),
+		Latency:       ac.LatencyTestSettings(),
+	}
+	if menu.Buttons.IsRunning && ac.TrafficMonitor != nil {
+		menu.Traffic = ac.TrafficMonitor.StatusText()
+	}
+	if names := ac.Profiles.Names(); len(names) > 1 {
+		menu.Profiles = names
+	}
+	menu.Modes = ac.ClashModeState()
+	menu.ShowModes = clashAPIEnabled && menu.Buttons.IsRunning && menu.Modes.Available()
+
+	// One submenu per selector/urltest group; the cache is refreshed in the background
+	if clashAPIEnabled && menu.Buttons.IsRunning {
+		menu.Groups = ac.GetTrayGroups()
+		if len(menu.Groups) == 0 || ac.trayGroupsStale() {
+			ac.refreshTrayGroupsAsync()
+		}
+	}
+	if len(menu.Groups) == 0 && clashAPIEnabled && selectedGroup != "" && len(proxies) > 0 {
+		// Groups are not loaded yet - show the group loaded by the Clash API tab
+		menu.Groups = []TrayGroup{{Name: selectedGroup, Type: "selector", Now: activeProxy, Members: proxies}}
+	}
+	menu.NoProxies = len(menu.Groups) == 0 && clashAPIEnabled && selectedGroup != ""
+	return menu
+}
+
+// TestTrayGroup runs a latency test of group and refreshes the tray groups when it finishes
+func (ac *AppController) TestTrayGroup(group string) {
+	ac.StartGroupLatencyTest(group, func(error) { ac.refreshTrayGroupsAsync() })
+}
+
+// ResetTrayGroups forgets the cached groups (sing-box stopped)
+func (ac *AppController) ResetTrayGroups() {
+	ac.APIStateMutex.Lock()
+	ac.trayGroups = nil
+	ac.trayGroupsUpdated = time.Time{}
+	ac.APIStateMutex.Unlock()
+}
+
+// trayGroupsStale reports whether the cache should be refreshed before it is shown
+func (ac *AppController) trayGroupsStale() bool {
+	ac.APIStateMutex.RLock()
+	defer ac.APIStateMutex.RUnlock()
+	return time.Since(ac.trayGroupsUpdated) > trayGroupsMaxAge
+}
+
+// RefreshTrayGroups loads every selector/urltest group from config.json with its members,
+// current member and cached latency in one /proxies request. The tray is rebuilt only when something changed.
+func (ac *AppController) RefreshTrayGroups(ctx context.Context) error {
+	ac.APIStateMutex.RLock()
+	enabled := ac.ClashAPIEnabled
+	ac.APIStateMutex.RUnlock()
+	if !enabled {
+		return fmt.Errorf("clash API is disabled")
+	}
+
+	configGroups, err := GetProxyGroupsFromConfig(ac.ConfigPath)
+	if err != nil {
+		return fmt.Errorf("failed to read groups from config: %w", err)
+	}
+	proxies, err := ac.ClashClient().Proxies(ctx)
+	if err != nil {
+		return err
+	}
+
+	ac.APIStateMutex.Lock()
+	// Timeouts are not kept in the core's history, so remember them from the previous results
+	knownDelays := make(map[string]int64)
+	for _, group := range ac.trayGroups {
+		for _, member := range group.Members {
+			knownDelays[member.Name] = member.Delay
+		}
+	}
+	for _, member := range ac.ProxiesList {
+		knownDelays[member.Name] = member.Delay
+	}
+	ac.APIStateMutex.Unlock()
+
+	groups := make([]TrayGroup, 0, len(configGroups))
+	for _, configGroup := range configGroups {
+		proxy, ok := proxies[configGroup.Tag]
+		if !ok || !proxy.IsGroup() {
+			continue
+		}
+		group := TrayGroup{Name: configGroup.Tag, Type: configGroup.Type, Now: proxy.Now}
+		for _, name := range proxy.All {
+			delay := proxies[name].LastDelay()
+			if delay == 0 && knownDelays[name] == api.DelayTimedOut {
+				delay = api.DelayTimedOut
+			}
+			group.Members = append(group.Members, api.ProxyInfo{Name: name, Delay: delay})
+		}
+		groups = append(groups, group)
+	}
+
+	ac.APIStateMutex.Lock()
+	changed := !reflect.DeepEqual(ac.trayGroups, groups)
+	ac.trayGroups = groups
+	ac.trayGroupsUpdated = time.Now()
+	ac.APIStateMutex.Unlock()
+
+	if changed {
+		ac.Events.Publish(TrayGroupsUpdated{})
+	}
+	return nil
+}
+
+// refreshTrayGroupsAsync refreshes the cached groups in the background, logging failures
+func (ac *AppController) refreshTrayGroupsAsync() {
+	ac.APIStateMutex.Lock()
+	if ac.trayGroupsRefreshing {
+		ac.APIStateMutex.Unlock()
+		return
+	}
+	ac.trayGroupsRefreshing = true
+	ac.APIStateMutex.Unlock()
+
+	go func() {
+		defer func() {
+			ac.APIStateMutex.Lock()
+			ac.trayGroupsRefreshing = false
+			ac.APIStateMutex.Unlock()
+		}()
+		ctx := ac.ctx
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		if err := ac.RefreshTrayGroups(ctx); err != nil {
+			log.Printf("refreshTrayGroupsAsync: %v", err)
+		}
+	}()
+}
+
+// SwitchGroupProxy selects proxy in a selector group and updates everything that shows the selection
+func (ac *AppController) SwitchGroupProxy(ctx context.Context, group, proxy string) error {
+	if err := ac.ClashClient().SwitchProxy(ctx, group, proxy); err != nil {
+		return fmt.Errorf("failed to switch proxy: %w", err)
+	}
+
+	ac.APIStateMutex.Lock()
+	isSelectedGroup := ac.SelectedClashGroup == group
+	for i := range ac.trayGroups {
+		if ac.trayGroups[i].Name == group {
+			ac.trayGroups[i].Now = proxy
+		}
+	}
+	ac.APIStateMutex.Unlock()
+	if isSelectedGroup {
+		ac.SetActiveProxyName(proxy)
+	}
+
+	ac.RememberSelectorChoice(group, proxy)
+	go ac.CloseConnectionsAfterSwitch(group)
+	ac.Events.Publish(ProxySwitched{Group: group, Proxy: proxy})
+	return nil
+}
+
+// LoadGroupProxies loads the members of group from the Clash API, makes them the proxies list
+// of the Servers tab and publishes ProxiesLoaded
+func (ac *AppController) LoadGroupProxies(ctx context.Context, group string) ([]api.ProxyInfo, string, error) {
+	proxies, now, err := ac.ClashClient().ProxiesInGroup(ctx, group)
+	if err != nil {
+		return nil, "", err
+	}
+	ac.SetProxiesList(proxies)
+	ac.SetActiveProxyName(now)
+	ac.Events.Publish(ProxiesLoaded{Group: group, Active: now, Count: len(proxies)})
+	return proxies, now, nil
+}
@@ -0,0 +1,127 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"singbox-launcher/api"
+)
+
+// newTrayGroupsTestController returns a controller with a config of two selectors and a urltest
+// and a fake Clash API serving /proxies
+func newTrayGroupsTestController(t *testing.T) *AppController {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.json")
+	config := `{
+  "outbounds": [
+    {"type": "selector", "tag": "proxy-out", "outbounds": ["auto", "🇩🇪 Germany", "🇳🇱 Netherlands"]},
+    {"type": "urltest", "tag": "auto", "outbounds": ["🇩🇪 Germany", "🇳🇱 Netherlands"]},
+    {"type": "selector", "tag": "streaming", "outbounds": ["🇳🇱 Netherlands", "direct-out"]},
+    {"type": "direct", "tag": "direct-out"}
+  ]
+}`
+	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	selected := map[string]string{"proxy-out": "auto", "streaming": "direct-out"}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/proxies":
+			fmt.Fprintf(w, `{"proxies":{
+				"proxy-out":{"type":"Selector","now":%q,"all":["auto","🇩🇪 Germany","🇳🇱 Netherlands"],"history":[]},
+				"auto":{"type":"URLTest","now":"🇳🇱 Netherlands","all":["🇩🇪 Germany","🇳🇱 Netherlands"],"history":[{"delay":90}]},
+				"streaming":{"type":"Selector","now":%q,"all":["🇳🇱 Netherlands","direct-out"],"history":[]},
+				"🇩🇪 Germany":{"type":"VLESS","history":[]},
+				"🇳🇱 Netherlands":{"type":"Trojan","history":[{"delay":90}]},
+				"direct-out":{"type":"Direct","history":[]},
+				"GLOBAL":{"type":"Selector","now":"proxy-out","all":["proxy-out","streaming"],"history":[]}
+			}}`, selected["proxy-out"], selected["streaming"])
+		case r.Method == "PUT":
+			var body map[string]string
+			json.NewDecoder(r.Body).Decode(&body)
+			selected[r.URL.Path[len("/proxies/"):]] = body["name"]
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	ac := &AppController{
+		ConfigPath:         configPath,
+		ClashAPIBaseURL:    server.URL,
+		ClashAPIEnabled:    true,
+		SelectedClashGroup: "proxy-out",
+		StateStore:         NewStateStore(filepath.Join(dir, "launcher_state.json")),
+		ctx:                context.Background(),
+	}
+	ac.RunningState = &RunningState{running: true, controller: ac}
+	return ac
+}
+
+// TestRefreshTrayGroups tests that every selector/urltest from config becomes a tray group
+func TestRefreshTrayGroups(t *testing.T) {
+	ac := newTrayGroupsTestController(t)
+	if err := ac.RefreshTrayGroups(context.Background()); err != nil {
+		t.Fatalf("RefreshTrayGroups() error: %v", err)
+	}
+
+	groups := ac.GetTrayGroups()
+	if len(groups) != 3 {
+		t.Fatalf("Expected 3 groups (GLOBAL excluded), got %+v", groups)
+	}
+	if groups[0].Name != "proxy-out" || groups[1].Name != "auto" || groups[2].Name != "streaming" {
+		t.Errorf("Expected config order, got %s, %s, %s", groups[0].Name, groups[1].Name, groups[2].Name)
+	}
+	auto := groups[1]
+	if auto.Switchable() || auto.Now != "🇳🇱 Netherlands" {
+		t.Errorf("Expected non-switchable urltest with auto-picked node, got %+v", auto)
+	}
+	if auto.Members[1].Delay != 90 {
+		t.Errorf("Expected cached latency 90, got %+v", auto.Members)
+	}
+	if ac.TrayMenuItemCount() != 7 {
+		t.Errorf("Expected 7 tray items, got %d", ac.TrayMenuItemCount())
+	}
+
+	// A timeout is not in the core's history but must survive a refresh
+	ac.SetProxyDelay("🇩🇪 Germany", api.DelayTimedOut)
+	if err := ac.RefreshTrayGroups(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if delay := ac.GetTrayGroups()[0].Members[1].Delay; delay != api.DelayTimedOut {
+		t.Errorf("Expected timeout to be kept, got %d", delay)
+	}
+}
+
+// TestSwitchGroupProxy tests switching a group that is not selected in the Clash API tab
+func TestSwitchGroupProxy(t *testing.T) {
+	ac := newTrayGroupsTestController(t)
+	if err := ac.RefreshTrayGroups(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ac.SwitchGroupProxy(context.Background(), "streaming", "🇳🇱 Netherlands"); err != nil {
+		t.Fatalf("SwitchGroupProxy() error: %v", err)
+	}
+	if now := ac.GetTrayGroups()[2].Now; now != "🇳🇱 Netherlands" {
+		t.Errorf("Expected tray group to show the new member, got %q", now)
+	}
+	if choice, _ := ac.StateStore.SelectorChoice("streaming"); choice != "🇳🇱 Netherlands" {
+		t.Errorf("Expected choice to be remembered, got %q", choice)
+	}
+	if ac.GetActiveProxyName() != "" {
+		t.Errorf("Active proxy of the selected group must not change, got %q", ac.GetActiveProxyName())
+	}
+}
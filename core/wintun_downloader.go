@@ -54,14 +54,16 @@ func (ac *AppController) DownloadWintunDLL(ctx context.Context, progressChan cha
 		}
 		return
 	}
-	defer os.RemoveAll(tempDir)
+	downloadFailed := false
+	defer func() { cleanupTempDir(tempDir, downloadFailed) }()
 
 	// 2. Скачиваем ZIP архив
 	zipURL := fmt.Sprintf(WinTunDownloadURL, WinTunVersion)
 	zipPath := filepath.Join(tempDir, fmt.Sprintf("wintun-%s.zip", WinTunVersion))
 
 	progressChan <- DownloadProgress{Progress: 10, Message: "Downloading wintun.dll...", Status: "downloading"}
-	if err := ac.downloadFileFromURL(ctx, zipURL, zipPath, progressChan); err != nil {
+	if err := ac.downloadFile(ctx, zipURL, zipPath, progressChan); err != nil {
+		downloadFailed = true
 		progressChan <- DownloadProgress{
 			Progress: 0,
 			Message:  fmt.Sprintf("Download failed: %v", err),
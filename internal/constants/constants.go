@@ -2,16 +2,22 @@ package constants
 
 // File names
 const (
-	WinTunDLLName   = "wintun.dll"
-	TunDLLName      = "tun.dll"
-	ConfigFileName  = "config.json"
-	SingBoxExecName = "sing-box"
+	WinTunDLLName             = "wintun.dll"
+	TunDLLName                = "tun.dll"
+	ConfigFileName            = "config.json"
+	SingBoxExecName           = "sing-box"
+	StateFileName             = "launcher_state.json"
+	SettingsFileName          = "settings.json"            // Launcher-wide settings shared by all profiles (in bin)
+	GitHubCacheFileName       = "github_cache.json"        // ETags and responses of GitHub API version checks (in bin)
+	SystemProxyBackupFileName = "system_proxy_backup.json" // System proxy settings replaced while sing-box runs (in bin)
 )
 
 // Directory names
 const (
-	BinDirName  = "bin"
-	LogsDirName = "logs"
+	BinDirName      = "bin"
+	LogsDirName     = "logs"
+	ProfilesDirName = "profiles" // Inside bin: one subdirectory per configuration profile
+	CoresDirName    = "cores"    // Inside bin: one subdirectory per installed sing-box version
 )
 
 // Log file names
@@ -37,4 +43,4 @@ const (
 // Can be overridden at build time using -ldflags="-X singbox-launcher/internal/constants.AppVersion=..."
 var (
 	AppVersion = "0.4.1" // Default version, overridden by build scripts from git tag
-)
\ No newline at end of file
+)
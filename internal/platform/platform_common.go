@@ -35,3 +35,17 @@ func EnsureDirectories(execDir string) error {
 	}
 	return nil
 }
+
+// SystemProxy is the local proxy set as the system proxy while sing-box runs
+type SystemProxy struct {
+	Host  string
+	Port  int
+	HTTP  bool // Accepts HTTP proxy requests (http and mixed inbounds)
+	SOCKS bool // Accepts SOCKS5 (socks and mixed inbounds)
+}
+
+// SystemProxyState holds the system proxy settings replaced by SetSystemProxy, to restore them later
+type SystemProxyState struct {
+	GNOME map[string]string `json:"gnome,omitempty"` // "schema key" -> gsettings value
+	KDE   map[string]string `json:"kde,omitempty"`   // kioslaverc [Proxy Settings] key -> value ("" = not set)
+}
@@ -172,3 +172,38 @@ func getSOCKSProxyForInterface(iface string) (host string, port int, enabled boo
 
 	return "", 0, false, nil
 }
+
+// SystemProxySupported reports whether the launcher can set the system proxy on this platform
+func SystemProxySupported() bool {
+	return false
+}
+
+// SystemProxyEnvPath returns "" (the env file is written on Linux only)
+func SystemProxyEnvPath() string {
+	return ""
+}
+
+// GetSystemProxyState is not implemented on macOS
+func GetSystemProxyState() (SystemProxyState, error) {
+	return SystemProxyState{}, fmt.Errorf("system proxy is not implemented on macOS")
+}
+
+// SetSystemProxy is not implemented on macOS
+func SetSystemProxy(proxy SystemProxy) error {
+	return fmt.Errorf("system proxy is not implemented on macOS")
+}
+
+// RestoreSystemProxy is not implemented on macOS
+func RestoreSystemProxy(state SystemProxyState) error {
+	return fmt.Errorf("system proxy is not implemented on macOS")
+}
+
+// CapabilitiesRequired reports whether sing-box needs capabilities set on its binary (Linux only)
+func CapabilitiesRequired() bool {
+	return false
+}
+
+// SetCapabilitiesWithPolkit is a no-op on macOS (capabilities not needed)
+func SetCapabilitiesWithPolkit(singboxPath string) error {
+	return nil
+}
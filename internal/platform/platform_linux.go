@@ -4,6 +4,7 @@
 package platform
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -100,9 +101,78 @@ func CheckSingBoxCapabilities(singboxPath string) bool {
 	return hasNetAdmin && hasNetBind
 }
 
+// singboxCapabilities are the capabilities sing-box needs for a TUN interface and ports below 1024
+const singboxCapabilities = "cap_net_admin,cap_net_bind_service=+ep"
+
+// ErrPolkitUnavailable is returned when capabilities cannot be set because pkexec is missing
+// or polkit cannot ask for the password
+var ErrPolkitUnavailable = errors.New("polkit is not available")
+
 // GetSetCapCommand returns the command to set capabilities on sing-box
 func GetSetCapCommand(singboxPath string) string {
-	return fmt.Sprintf("sudo setcap 'cap_net_admin,cap_net_bind_service=+ep' %s", singboxPath)
+	return fmt.Sprintf("sudo setcap '%s' %s", singboxCapabilities, singboxPath)
+}
+
+// CapabilitiesRequired reports whether sing-box needs capabilities set on its binary (Linux only)
+func CapabilitiesRequired() bool {
+	return true
+}
+
+// findSystemTool looks for name in PATH and in the sbin directories, which are not in the PATH
+// of ordinary users on some distributions
+func findSystemTool(name string) (string, error) {
+	if path, err := exec.LookPath(name); err == nil {
+		return path, nil
+	}
+	for _, dir := range []string{"/usr/sbin", "/sbin", "/usr/local/sbin"} {
+		path := filepath.Join(dir, name)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("%s not found", name)
+}
+
+// SetCapabilitiesWithPolkit sets the capabilities on sing-box through pkexec: polkit asks for the
+// administrator password in its own dialog. A new binary has no capabilities, so this is needed after each install.
+func SetCapabilitiesWithPolkit(singboxPath string) error {
+	absPath, err := filepath.Abs(singboxPath)
+	if err != nil {
+		return fmt.Errorf("invalid sing-box path: %w", err)
+	}
+	pkexec, err := exec.LookPath("pkexec")
+	if err != nil {
+		return fmt.Errorf("%w: pkexec not found, install polkit or run:\n%s", ErrPolkitUnavailable, GetSetCapCommand(absPath))
+	}
+	setcap, err := findSystemTool("setcap")
+	if err != nil {
+		return fmt.Errorf("setcap not found: install libcap (libcap2-bin on Debian/Ubuntu) or run:\n%s", GetSetCapCommand(absPath))
+	}
+
+	output, err := exec.Command(pkexec, setcap, singboxCapabilities, absPath).CombinedOutput()
+	message := strings.TrimSpace(string(output))
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			switch exitErr.ExitCode() {
+			case 126:
+				return fmt.Errorf("authorization was cancelled, capabilities were not set")
+			case 127:
+				if strings.Contains(strings.ToLower(message), "authentication agent") {
+					return fmt.Errorf("%w: no polkit authentication agent is running (start the one of your desktop) or run:\n%s",
+						ErrPolkitUnavailable, GetSetCapCommand(absPath))
+				}
+				return fmt.Errorf("%w: not authorized to run setcap (%s)", ErrPolkitUnavailable, message)
+			}
+		}
+		return fmt.Errorf("pkexec setcap failed: %w (%s)", err, message)
+	}
+
+	if !CheckSingBoxCapabilities(absPath) {
+		return fmt.Errorf("setcap finished, but %s still has no capabilities: the file system may not support them (nosuid mount, FAT, network share)", absPath)
+	}
+	log.Printf("platform: capabilities %s set on %s", singboxCapabilities, absPath)
+	return nil
 }
 
 // SuggestCapabilities shows a dialog suggesting to set capabilities
@@ -113,7 +183,9 @@ func SuggestCapabilities(singboxPath string) string {
 		"⚠️ Sing-box requires elevated privileges to create TUN interface.\n\n"+
 			"To avoid entering password every time, set capabilities once:\n\n"+
 			"%s\n\n"+
-			"This will allow sing-box to run without sudo/pkexec.",
+			"This will allow sing-box to run without sudo/pkexec.\n\n"+
+			"Or enable \"Set capabilities after each sing-box install\" on the Settings tab "+
+			"to let the launcher do it through polkit.",
 		command,
 	)
 }
@@ -133,13 +205,6 @@ func CheckAndSuggestCapabilities(singboxPath string) string {
 	return "" // Capabilities are OK
 }
 
-// GetSystemSOCKSProxy returns system SOCKS proxy settings if enabled
-// On Linux, this is not currently implemented
-func GetSystemSOCKSProxy() (host string, port int, enabled bool, err error) {
-	log.Printf("platform: GetSystemSOCKSProxy is not implemented on Linux")
-	return "", 0, false, fmt.Errorf("GetSystemSOCKSProxy is not implemented on Linux")
-}
-
 // SetupDockReopenHandler is a no-op on Linux (Dock is macOS-specific)
 func SetupDockReopenHandler(showWindowCallback func()) {
 	log.Printf("platform: SetupDockReopenHandler is not implemented on Linux (Dock is macOS-specific)")
@@ -105,3 +105,38 @@ func SetupDockReopenHandler(showWindowCallback func()) {
 func CleanupDockReopenHandler() {
 	log.Printf("platform: CleanupDockReopenHandler is not implemented on Windows (Dock is macOS-specific)")
 }
+
+// SystemProxySupported reports whether the launcher can set the system proxy on this platform
+func SystemProxySupported() bool {
+	return false
+}
+
+// SystemProxyEnvPath returns "" (the env file is written on Linux only)
+func SystemProxyEnvPath() string {
+	return ""
+}
+
+// GetSystemProxyState is not implemented on Windows
+func GetSystemProxyState() (SystemProxyState, error) {
+	return SystemProxyState{}, fmt.Errorf("system proxy is not implemented on Windows")
+}
+
+// SetSystemProxy is not implemented on Windows
+func SetSystemProxy(proxy SystemProxy) error {
+	return fmt.Errorf("system proxy is not implemented on Windows")
+}
+
+// RestoreSystemProxy is not implemented on Windows
+func RestoreSystemProxy(state SystemProxyState) error {
+	return fmt.Errorf("system proxy is not implemented on Windows")
+}
+
+// CapabilitiesRequired reports whether sing-box needs capabilities set on its binary (Linux only)
+func CapabilitiesRequired() bool {
+	return false
+}
+
+// SetCapabilitiesWithPolkit is a no-op on Windows (capabilities not needed)
+func SetCapabilitiesWithPolkit(singboxPath string) error {
+	return nil
+}
@@ -0,0 +1,366 @@
+//go:build linux
+// +build linux
+
+package platform
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Системный прокси на Linux не имеет единого API, поэтому он задаётся в трёх местах:
+//   - GNOME (и другие окружения на GSettings): схемы org.gnome.system.proxy* через gsettings;
+//   - KDE Plasma: группа [Proxy Settings] в kioslaverc через kwriteconfig5/6;
+//   - переменные окружения для терминала: файл proxy.env, который подключается из ~/.profile или ~/.bashrc.
+
+// gnomeProxyKeys are the gsettings keys changed by SetSystemProxy
+var gnomeProxyKeys = []string{
+	"org.gnome.system.proxy mode",
+	"org.gnome.system.proxy ignore-hosts",
+	"org.gnome.system.proxy.http host",
+	"org.gnome.system.proxy.http port",
+	"org.gnome.system.proxy.https host",
+	"org.gnome.system.proxy.https port",
+	"org.gnome.system.proxy.socks host",
+	"org.gnome.system.proxy.socks port",
+}
+
+// kdeProxyKeys are the kioslaverc keys changed by SetSystemProxy
+var kdeProxyKeys = []string{"ProxyType", "httpProxy", "httpsProxy", "socksProxy", "NoProxyFor"}
+
+// systemProxyBypass are the destinations that never go through the system proxy
+var systemProxyBypass = []string{"localhost", "127.0.0.0/8", "::1"}
+
+// SystemProxySupported reports whether the launcher can set the system proxy on this platform
+func SystemProxySupported() bool {
+	return true
+}
+
+// SystemProxyEnvPath returns the env file with proxy variables for shells
+func SystemProxyEnvPath() string {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		configDir = filepath.Join(os.Getenv("HOME"), ".config")
+	}
+	return filepath.Join(configDir, "singbox-launcher", "proxy.env")
+}
+
+// gnomeProxyAvailable reports whether gsettings with the GNOME proxy schema is installed
+func gnomeProxyAvailable() bool {
+	if _, err := exec.LookPath("gsettings"); err != nil {
+		return false
+	}
+	return exec.Command("gsettings", "list-keys", "org.gnome.system.proxy").Run() == nil
+}
+
+// kdeConfigTools returns kwriteconfig and kreadconfig of Plasma 6 or 5 ("" if not installed)
+func kdeConfigTools() (string, string) {
+	for _, version := range []string{"6", "5"} {
+		write, errWrite := exec.LookPath("kwriteconfig" + version)
+		read, errRead := exec.LookPath("kreadconfig" + version)
+		if errWrite == nil && errRead == nil {
+			return write, read
+		}
+	}
+	return "", ""
+}
+
+// gvariantString quotes s as a GVariant string for gsettings set
+func gvariantString(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
+
+// gvariantStringList formats values as a GVariant array of strings
+func gvariantStringList(values []string) string {
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = gvariantString(value)
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
+
+// gsettingsGet returns the value of "schema key" in GVariant text format
+func gsettingsGet(schemaKey string) (string, error) {
+	schema, key, _ := strings.Cut(schemaKey, " ")
+	output, err := exec.Command("gsettings", "get", schema, key).Output()
+	if err != nil {
+		return "", fmt.Errorf("gsettings get %s: %w", schemaKey, err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// gsettingsSet sets "schema key" to a value in GVariant text format
+func gsettingsSet(schemaKey, value string) error {
+	schema, key, _ := strings.Cut(schemaKey, " ")
+	if output, err := exec.Command("gsettings", "set", schema, key, value).CombinedOutput(); err != nil {
+		return fmt.Errorf("gsettings set %s: %w (%s)", schemaKey, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// kdeProxyGet returns a key of the [Proxy Settings] group of kioslaverc
+func kdeProxyGet(kreadconfig, key string) (string, error) {
+	output, err := exec.Command(kreadconfig, "--file", "kioslaverc", "--group", "Proxy Settings", "--key", key).Output()
+	if err != nil {
+		return "", fmt.Errorf("%s %s: %w", filepath.Base(kreadconfig), key, err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// kdeProxySet writes a key of the [Proxy Settings] group of kioslaverc; an empty value deletes the key
+func kdeProxySet(kwriteconfig, key, value string) error {
+	args := []string{"--file", "kioslaverc", "--group", "Proxy Settings", "--key", key}
+	if value == "" {
+		args = append(args, "--delete")
+	} else {
+		args = append(args, value)
+	}
+	if output, err := exec.Command(kwriteconfig, args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("%s %s: %w (%s)", filepath.Base(kwriteconfig), key, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// notifyKDEProxyChanged asks running KDE applications to reread kioslaverc
+func notifyKDEProxyChanged() {
+	cmd := exec.Command("dbus-send", "--type=signal", "/KIO/Scheduler",
+		"org.kde.KIO.Scheduler.reparseSlaveConfiguration", "string:")
+	if err := cmd.Run(); err != nil {
+		log.Printf("platform: failed to notify KDE about the proxy change: %v", err)
+	}
+}
+
+// GetSystemProxyState reads the current system proxy settings of GNOME and KDE
+func GetSystemProxyState() (SystemProxyState, error) {
+	var state SystemProxyState
+	if gnomeProxyAvailable() {
+		state.GNOME = make(map[string]string)
+		for _, schemaKey := range gnomeProxyKeys {
+			value, err := gsettingsGet(schemaKey)
+			if err != nil {
+				return state, err
+			}
+			state.GNOME[schemaKey] = value
+		}
+	}
+	if _, kreadconfig := kdeConfigTools(); kreadconfig != "" {
+		state.KDE = make(map[string]string)
+		for _, key := range kdeProxyKeys {
+			value, err := kdeProxyGet(kreadconfig, key)
+			if err != nil {
+				return state, err
+			}
+			state.KDE[key] = value
+		}
+	}
+	return state, nil
+}
+
+// SetSystemProxy points GNOME, KDE and the env file at proxy. Save the current settings with
+// GetSystemProxyState first. Settings of the desktop environments that are not installed are skipped.
+func SetSystemProxy(proxy SystemProxy) error {
+	var errs []error
+	if gnomeProxyAvailable() {
+		if err := setGNOMEProxy(proxy); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if kwriteconfig, _ := kdeConfigTools(); kwriteconfig != "" {
+		if err := setKDEProxy(kwriteconfig, proxy); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := writeProxyEnvFile(&proxy); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// RestoreSystemProxy writes back the settings saved by GetSystemProxyState and clears the env file
+func RestoreSystemProxy(state SystemProxyState) error {
+	var errs []error
+	if len(state.GNOME) > 0 && gnomeProxyAvailable() {
+		// Режим последним: прокси включается/выключается уже с прежними адресами
+		for _, schemaKey := range gnomeProxyKeys[1:] {
+			if value, ok := state.GNOME[schemaKey]; ok {
+				if err := gsettingsSet(schemaKey, value); err != nil {
+					errs = append(errs, err)
+				}
+			}
+		}
+		if value, ok := state.GNOME[gnomeProxyKeys[0]]; ok {
+			if err := gsettingsSet(gnomeProxyKeys[0], value); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if kwriteconfig, _ := kdeConfigTools(); len(state.KDE) > 0 && kwriteconfig != "" {
+		for _, key := range kdeProxyKeys {
+			if value, ok := state.KDE[key]; ok {
+				if err := kdeProxySet(kwriteconfig, key, value); err != nil {
+					errs = append(errs, err)
+				}
+			}
+		}
+		notifyKDEProxyChanged()
+	}
+	if err := writeProxyEnvFile(nil); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// setGNOMEProxy switches GNOME to a manual proxy. Protocols the inbound does not accept are cleared,
+// so that addresses of a previous proxy are not used with ours.
+func setGNOMEProxy(proxy SystemProxy) error {
+	hostPort := func(enabled bool) (string, string) {
+		if !enabled {
+			return gvariantString(""), "0"
+		}
+		return gvariantString(proxy.Host), strconv.Itoa(proxy.Port)
+	}
+	httpHost, httpPort := hostPort(proxy.HTTP)
+	socksHost, socksPort := hostPort(proxy.SOCKS)
+	values := [][2]string{
+		{"org.gnome.system.proxy ignore-hosts", gvariantStringList(systemProxyBypass)},
+		{"org.gnome.system.proxy.http host", httpHost},
+		{"org.gnome.system.proxy.http port", httpPort},
+		{"org.gnome.system.proxy.https host", httpHost},
+		{"org.gnome.system.proxy.https port", httpPort},
+		{"org.gnome.system.proxy.socks host", socksHost},
+		{"org.gnome.system.proxy.socks port", socksPort},
+		{"org.gnome.system.proxy mode", gvariantString("manual")},
+	}
+	for _, value := range values {
+		if err := gsettingsSet(value[0], value[1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setKDEProxy switches KDE to a manual proxy (ProxyType=1, addresses as "scheme://host port")
+func setKDEProxy(kwriteconfig string, proxy SystemProxy) error {
+	address := func(enabled bool, scheme string) string {
+		if !enabled {
+			return ""
+		}
+		return fmt.Sprintf("%s://%s %d", scheme, proxy.Host, proxy.Port)
+	}
+	values := [][2]string{
+		{"httpProxy", address(proxy.HTTP, "http")},
+		{"httpsProxy", address(proxy.HTTP, "http")},
+		{"socksProxy", address(proxy.SOCKS, "socks")},
+		{"NoProxyFor", strings.Join(systemProxyBypass, ",")},
+		{"ProxyType", "1"},
+	}
+	for _, value := range values {
+		if err := kdeProxySet(kwriteconfig, value[0], value[1]); err != nil {
+			return err
+		}
+	}
+	notifyKDEProxyChanged()
+	return nil
+}
+
+// writeProxyEnvFile writes exports for proxy, or unsets the variables when proxy is nil
+// (the file stays, so shells that source it unconditionally do not fail)
+func writeProxyEnvFile(proxy *SystemProxy) error {
+	path := SystemProxyEnvPath()
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Proxy variables of singbox-launcher, updated when sing-box starts and stops.\n")
+	fmt.Fprintf(&b, "# Add to ~/.profile or ~/.bashrc: [ -f %q ] && . %q\n", path, path)
+	names := []string{"http_proxy", "https_proxy", "all_proxy", "no_proxy"}
+	values := map[string]string{}
+	if proxy != nil {
+		address := net.JoinHostPort(proxy.Host, strconv.Itoa(proxy.Port))
+		if proxy.HTTP {
+			values["http_proxy"] = "http://" + address
+			values["https_proxy"] = "http://" + address
+		}
+		if proxy.SOCKS {
+			values["all_proxy"] = "socks5://" + address
+		}
+		values["no_proxy"] = "localhost,127.0.0.0/8,::1"
+	}
+	for _, name := range names {
+		for _, variable := range []string{name, strings.ToUpper(name)} {
+			if value, ok := values[name]; ok {
+				fmt.Fprintf(&b, "export %s=%q\n", variable, value)
+			} else {
+				fmt.Fprintf(&b, "unset %s\n", variable)
+			}
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// parseProxyAddress parses "socks5://host:port" or the KDE form "socks://host port"
+func parseProxyAddress(value string) (string, int, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", 0, false
+	}
+	if host, port, ok := strings.Cut(value, " "); ok {
+		value = host + ":" + strings.TrimSpace(port)
+	}
+	if !strings.Contains(value, "://") {
+		value = "socks5://" + value
+	}
+	u, err := url.Parse(value)
+	if err != nil || u.Hostname() == "" {
+		return "", 0, false
+	}
+	port, err := strconv.Atoi(u.Port())
+	if err != nil || port <= 0 {
+		return "", 0, false
+	}
+	return u.Hostname(), port, true
+}
+
+// GetSystemSOCKSProxy returns system SOCKS proxy settings if enabled.
+// GNOME is checked first, then KDE, then the all_proxy environment variable.
+func GetSystemSOCKSProxy() (host string, port int, enabled bool, err error) {
+	if gnomeProxyAvailable() {
+		mode, err := gsettingsGet("org.gnome.system.proxy mode")
+		if err == nil && mode == gvariantString("manual") {
+			hostValue, _ := gsettingsGet("org.gnome.system.proxy.socks host")
+			portValue, _ := gsettingsGet("org.gnome.system.proxy.socks port")
+			host = strings.Trim(hostValue, "'")
+			port, _ = strconv.Atoi(portValue)
+			if host != "" && port > 0 {
+				return host, port, true, nil
+			}
+		}
+	}
+	if _, kreadconfig := kdeConfigTools(); kreadconfig != "" {
+		if proxyType, err := kdeProxyGet(kreadconfig, "ProxyType"); err == nil && proxyType == "1" {
+			value, _ := kdeProxyGet(kreadconfig, "socksProxy")
+			if host, port, ok := parseProxyAddress(value); ok {
+				return host, port, true, nil
+			}
+		}
+	}
+	for _, name := range []string{"all_proxy", "ALL_PROXY"} {
+		if value := os.Getenv(name); strings.HasPrefix(value, "socks") {
+			if host, port, ok := parseProxyAddress(value); ok {
+				return host, port, true, nil
+			}
+		}
+	}
+	return "", 0, false, nil
+}
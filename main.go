@@ -3,14 +3,17 @@ package main
 import (
 	_ "embed" // For embedding resource files (icons)
 	"flag"
+	"fmt"
 	"log"
+	"os"
 	"runtime"
 	"time"
 
 	"fyne.io/fyne/v2"
-	"fyne.io/fyne/v2/driver/desktop"
 
 	// Import our new packages
+	"singbox-launcher/cli"
+	"singbox-launcher/control"
 	"singbox-launcher/core"
 	"singbox-launcher/internal/platform"
 	"singbox-launcher/ui"
@@ -34,103 +37,61 @@ const (
 
 // main is the application's entry point. It simply creates and runs the AppController.
 func main() {
+	// A launcher update starts the previous executable as the watchdog of the new one
+	if core.IsLauncherWatchdog() {
+		os.Exit(core.RunLauncherWatchdog())
+	}
+
+	// Headless subcommands (update, check, nodes, run, status) never create a window
+	if len(os.Args) > 1 && cli.IsCommand(os.Args[1]) {
+		os.Exit(cli.Run(os.Args[1:]))
+	}
+
+	// After a self-update the new launcher waits for the previous one to exit
+	core.WaitForUpdatedParent()
+
 	// Parse command line arguments
 	autoStart := flag.Bool("start", false, "Automatically start VPN on launch")
 	startInTray := flag.Bool("tray", false, "Start minimized to system tray (hide window on launch)")
+	profileName := flag.String("profile", "", "Use the given configuration profile (it becomes the active profile)")
 	flag.Parse()
 
 	// Create the application controller. If an error occurs, print it and exit the program.
-	// Use greyIconData for red icon (no separate red icon yet)
-	controller, err := core.NewAppController(appIconData, greyIconData, greenIconData, greyIconData)
+	controller, err := core.NewAppController()
 	if err != nil {
 		log.Fatalf("Failed to initialize application: %v", err)
 	}
+	// Fyne application, main window and tray icons
+	// Use greyIconData for red icon (no separate red icon yet)
+	gui := ui.NewDesktop(controller, appIconData, greyIconData, greenIconData, greyIconData)
+	// Dialogs and widget updates for messages and state changes reported by core
+	controller.Notifier = ui.NewNotifier(controller)
+	if failure := core.LauncherUpdateFailure(); failure != "" {
+		controller.Notifier.ShowError(fmt.Errorf("launcher update failed: %s", failure))
+	}
 
-	// Check launcher version on startup
-	controller.CheckLauncherVersionOnStartup()
-
-	// Configure the system tray if the application is running on a Desktop platform.
-	if desk, ok := controller.Application.(desktop.App); ok {
-		log.Println("System tray: Desktop platform detected, initializing...")
-		// Create the menu update function for the system tray with proxy selection submenu
-		// Safe wrapper with debounce to prevent "Invalid menu handle" errors
-		// when menu updates happen too quickly
-		updateTrayMenu := func() {
-			controller.TrayMenuUpdateMutex.Lock()
-			defer controller.TrayMenuUpdateMutex.Unlock()
-
-			// Cancel previous timer if it exists
-			if controller.TrayMenuUpdateTimer != nil {
-				controller.TrayMenuUpdateTimer.Stop()
-			}
-
-			// Calculate dynamic delay based on number of proxies
-			// Get proxy count to determine appropriate delay
-			controller.APIStateMutex.RLock()
-			proxyCount := len(controller.ProxiesList)
-			controller.APIStateMutex.RUnlock()
-
-			// Dynamic delay formula:
-			// - Base delay: 100ms for small menus (0-10 proxies)
-			// - For each proxy above 10, add 20ms
-			// - Maximum delay: 500ms to ensure systray has enough time for large menus
-			delay := 100 * time.Millisecond
-			if proxyCount > 10 {
-				extraDelay := time.Duration(proxyCount-10) * 20 * time.Millisecond
-				delay += extraDelay
-				// Cap at 500ms maximum
-				if delay > 500*time.Millisecond {
-					delay = 500 * time.Millisecond
-				}
-			}
+	// Launcher settings enable the same behavior as -start and -tray
+	settings := controller.Settings.Get()
+	*autoStart = *autoStart || settings.AutoStart
+	*startInTray = *startInTray || settings.StartInTray
 
-			// Create new timer with dynamic debounce delay
-			// This prevents rapid successive menu updates that cause systray errors
-			controller.TrayMenuUpdateTimer = time.AfterFunc(delay, func() {
-				// Check if update is already in progress
-				controller.TrayMenuUpdateMutex.Lock()
-				if controller.TrayMenuUpdateInProgress {
-					controller.TrayMenuUpdateMutex.Unlock()
-					return // Skip update if already in progress
-				}
-				controller.TrayMenuUpdateInProgress = true
-				controller.TrayMenuUpdateMutex.Unlock()
-
-				fyne.Do(func() {
-					defer func() {
-						// Reset flag after update completes
-						controller.TrayMenuUpdateMutex.Lock()
-						controller.TrayMenuUpdateInProgress = false
-						controller.TrayMenuUpdateTimer = nil
-						controller.TrayMenuUpdateMutex.Unlock()
-					}()
-
-					menu := controller.CreateTrayMenu()
-					// Use recover to handle any panics during menu update
-					func() {
-						defer func() {
-							if r := recover(); r != nil {
-								log.Printf("updateTrayMenu: Recovered from panic: %v", r)
-							}
-						}()
-						desk.SetSystemTrayMenu(menu)
-					}()
-				})
-			})
+	// Switch to the profile requested on the command line (sing-box is not running yet)
+	if *profileName != "" {
+		if err := controller.SwitchProfile(*profileName); err != nil {
+			log.Printf("Failed to use profile %q: %v (continuing with profile %q)", *profileName, err, controller.CurrentProfile())
 		}
-		controller.UpdateTrayMenuFunc = updateTrayMenu
+	}
 
-		// Initialize system tray immediately (required on macOS, works on Windows too)
-		// On macOS, system tray must be initialized BEFORE app.Run() to work properly
-		log.Println("System tray: Setting icon...")
-		desk.SetSystemTrayIcon(controller.GreyIconData)
-		log.Println("System tray: Creating initial menu...")
-		initialMenu := controller.CreateTrayMenu()
-		desk.SetSystemTrayMenu(initialMenu)
-		log.Println("System tray: Icon and menu initialized successfully")
+	// Check launcher version on startup
+	controller.CheckLauncherVersionOnStartup()
 
+	// Configure the system tray if the application is running on a Desktop platform.
+	if gui.SetupTray() {
 		// Set a handler that fires when the application is fully ready
-		controller.Application.Lifecycle().SetOnStarted(func() {
+		gui.App.Lifecycle().SetOnStarted(func() {
+			// After a self-update: the new launcher has started, the watchdog can exit
+			core.SignalLauncherReady()
+
 			// Read config once at application startup
 			go func() {
 				log.Println("Application startup: Reading config...")
@@ -145,12 +106,12 @@ func main() {
 					len(config.ParserConfig.Outbounds))
 			}()
 
-			// Auto-start VPN if -start flag is provided
+			// Auto-start VPN if -start flag is provided or auto-start is enabled in settings
 			if *autoStart {
 				go func() {
 					// Wait a bit for everything to initialize
 					time.Sleep(autoStartDelay)
-					log.Println("Auto-start: Starting VPN due to -start parameter")
+					log.Println("Auto-start: Starting VPN due to -start parameter or settings")
 					core.StartSingBoxProcess(controller)
 				}()
 			}
@@ -161,28 +122,28 @@ func main() {
 					// Wait a bit for window to be fully initialized
 					time.Sleep(500 * time.Millisecond)
 					fyne.Do(func() {
-						controller.MainWindow.Hide()
+						gui.Window.Hide()
 						log.Println("Tray mode: Window hidden")
 					})
 				}()
 			}
 		})
+	} else {
+		// Without a tray the updated launcher has still started
+		gui.App.Lifecycle().SetOnStarted(core.SignalLauncherReady)
 	}
 
-	controller.MainWindow = controller.Application.NewWindow("Singbox Launcher") // Create the main application window
-	controller.MainWindow.SetIcon(controller.AppIconData)
-
 	// Create App structure to manage UI
-	app := ui.NewApp(controller.MainWindow, controller)
-	controller.MainWindow.SetContent(app.GetTabs())      // Set the window's content
-	controller.MainWindow.Resize(fyne.NewSize(350, 450)) // initial window size
-	controller.MainWindow.CenterOnScreen()               // Center the window on the screen
+	app := ui.NewApp(gui.Window, controller)
+	gui.Window.SetContent(app.GetTabs())      // Set the window's content
+	gui.Window.Resize(fyne.NewSize(350, 450)) // initial window size
+	gui.Window.CenterOnScreen()               // Center the window on the screen
 
 	core.CheckIfLauncherAlreadyRunningUtil(controller)
 
 	// Intercept the window close event (clicking "X") to hide it instead of exiting completely.
-	controller.MainWindow.SetCloseIntercept(func() {
-		controller.MainWindow.Hide()
+	gui.Window.SetCloseIntercept(func() {
+		gui.Window.Hide()
 	})
 
 	// Handle Dock icon click on macOS - show window when app is activated
@@ -194,15 +155,15 @@ func main() {
 		platform.SetupDockReopenHandler(func() {
 			fyne.Do(func() {
 				// Show() is safe to call even if window is already visible
-				controller.MainWindow.Show()
-				controller.MainWindow.RequestFocus()
+				gui.Window.Show()
+				gui.Window.RequestFocus()
 				log.Println("Dock icon clicked (native handler): Window shown and focused")
 			})
 		})
 		log.Println("Dock icon click handler registered for macOS (native NSApplicationDelegate)")
 	}
 
-	controller.UpdateUI()
+	gui.UpdateTrayIcon()
 
 	// Check if config.json exists and show a warning if it doesn't
 	core.CheckConfigFileExists(controller)
@@ -213,19 +174,24 @@ func main() {
 	// Check if sing-box is running on startup and show a warning if it is.
 	core.CheckIfSingBoxRunningAtStartUtil(controller)
 
+	// Local control API for scripts and browser extensions (off unless enabled in bin/settings.json)
+	if _, err := control.Start(controller); err != nil {
+		log.Printf("Control server not started: %v", err)
+	}
+
 	// Use app.Run() instead of ShowAndRun() for windowless support
 	// This allows the app to keep running even when window is closed/hidden
 	// On macOS, this enables standard Dock behavior (applicationShouldHandleReopen)
 	// See: https://github.com/fyne-io/fyne/issues/3845
 	if !*startInTray {
 		// Show window on startup if not starting in tray
-		controller.MainWindow.Show()
+		gui.Window.Show()
 	}
 
 	// Start the application event loop (windowless mode)
 	// This keeps the app running even when window is hidden/closed
-	// The menu already has "Open" item that calls MainWindow.Show()
-	controller.Application.Run()
+	// The menu already has "Open" item that shows the main window
+	gui.App.Run()
 
 	// The code below executes only after app.Run() finishes (when app.Quit() is called).
 	// This is where final cleanup is performed.
@@ -236,15 +202,6 @@ func main() {
 		platform.CleanupDockReopenHandler()
 	}
 	
+	// Also closes the log files
 	controller.GracefulExit()
-
-	if controller.MainLogFile != nil {
-		controller.MainLogFile.Close()
-	}
-	if controller.ChildLogFile != nil {
-		controller.ChildLogFile.Close()
-	}
-	if controller.ApiLogFile != nil {
-		controller.ApiLogFile.Close()
-	}
 }
@@ -9,11 +9,14 @@ import (
 
 // App manages the UI structure and tabs
 type App struct {
-	window      fyne.Window
-	core        *core.AppController
-	tabs        *container.AppTabs
-	clashAPITab *container.TabItem
-	currentTab  *container.TabItem
+	window          fyne.Window
+	core            *core.AppController
+	tabs            *container.AppTabs
+	clashAPITab     *container.TabItem
+	clashAPIView    *ClashAPITab
+	connectionsTab  *container.TabItem
+	connectionsView *ConnectionsTab
+	currentTab      *container.TabItem
 }
 
 // NewApp creates a new App instance
@@ -26,17 +29,30 @@ func NewApp(window fyne.Window, controller *core.AppController) *App {
 	// Create tabs - Core is first (opens on startup)
 	// Создаем вкладку Core первой, чтобы её callback установился
 	coreTabItem := container.NewTabItem("⚙️ Core", CreateCoreDashboardTab(controller))
-	app.clashAPITab = container.NewTabItem("🖥️ Servers", CreateClashAPITab(controller))
+	clashAPIView, clashAPIContent := CreateClashAPITab(controller)
+	app.clashAPIView = clashAPIView
+	app.clashAPITab = container.NewTabItem("🖥️ Servers", clashAPIContent)
+	connectionsView, connectionsContent := CreateConnectionsTab(controller)
+	app.connectionsView = connectionsView
+	app.connectionsTab = container.NewTabItem("🔗 Connections", connectionsContent)
 	app.tabs = container.NewAppTabs(
 		coreTabItem,
 		app.clashAPITab,
+		app.connectionsTab,
 		container.NewTabItem("🔍 Diagnostics", CreateDiagnosticsTab(controller)),
+		container.NewTabItem("📜 Logs", CreateLogsTab(controller)),
+		container.NewTabItem("🛠️ Settings", CreateSettingsTab(controller)),
 		container.NewTabItem("❓ Help", CreateHelpTab(controller)),
 	)
 
 	// Set tab selection handler
 	app.tabs.OnSelected = func(item *container.TabItem) {
 		app.currentTab = item
+		app.connectionsView.SetActive(item == app.connectionsTab)
+		if item == app.connectionsTab && !controller.RunningState.IsRunning() {
+			app.tabs.Select(coreTabItem)
+			return
+		}
 		if item == app.clashAPITab {
 			// Проверяем, запущен ли sing-box
 			if !controller.RunningState.IsRunning() {
@@ -45,24 +61,12 @@ func NewApp(window fyne.Window, controller *core.AppController) *App {
 				// Можно показать сообщение пользователю
 				return
 			}
-			controller.RefreshAPIFunc()
+			app.clashAPIView.Refresh()
 		}
 	}
 
-	// Сохраняем оригинальный callback, который был установлен в CreateCoreDashboardTab
-	originalUpdateCoreStatusFunc := controller.UpdateCoreStatusFunc
-
-	// Регистрируем комбинированный callback для обновления состояния вкладки Servers
-	controller.UpdateCoreStatusFunc = func() {
-		// Вызываем оригинальный callback, если он есть
-		if originalUpdateCoreStatusFunc != nil {
-			originalUpdateCoreStatusFunc()
-		}
-		// Обновляем состояние вкладки Servers
-		fyne.Do(func() {
-			app.updateClashAPITabState()
-		})
-	}
+	// Обновляем состояние вкладки Servers при изменении состояния sing-box
+	onEvents(controller, core.CoreStateEvents, func(core.Event) { app.updateClashAPITabState() })
 
 	// Инициализируем состояние вкладки
 	app.updateClashAPITabState()
@@ -97,13 +101,15 @@ func (a *App) updateClashAPITabState() {
 	if !isRunning {
 		// Вкладка неактивна - отключаем её (будет показана серым цветом)
 		a.tabs.DisableItem(a.clashAPITab)
+		a.tabs.DisableItem(a.connectionsTab)
 	} else {
 		// Вкладка активна - включаем её
 		a.tabs.EnableItem(a.clashAPITab)
+		a.tabs.EnableItem(a.connectionsTab)
 	}
 
-	// Если sing-box не запущен и вкладка Servers выбрана, переключаем на Core
-	if !isRunning && a.currentTab == a.clashAPITab {
+	// Если sing-box не запущен и вкладка Servers/Connections выбрана, переключаем на Core
+	if !isRunning && (a.currentTab == a.clashAPITab || a.currentTab == a.connectionsTab) {
 		if len(a.tabs.Items) > 0 {
 			coreTab := a.tabs.Items[0]
 			a.tabs.Select(coreTab)
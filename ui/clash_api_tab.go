@@ -1,9 +1,12 @@
 package ui
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"image/color"
 	"log"
+	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/canvas"
@@ -15,11 +18,23 @@ import (
 	"singbox-launcher/core"
 )
 
-// CreateClashAPITab creates and returns the content for the "Clash API" tab.
-func CreateClashAPITab(ac *core.AppController) fyne.CanvasObject {
-	ac.ApiStatusLabel = widget.NewLabel("Status: Not checked")
+// latencyRefreshInterval limits how often the list is redrawn while a latency test reports results
+const latencyRefreshInterval = 200 * time.Millisecond
+
+// ClashAPITab is the "Servers" tab. Call Refresh when the tab is shown.
+type ClashAPITab struct {
+	refresh func()
+}
+
+// Refresh checks the Clash API connection and reloads the proxies of the selected group
+func (t *ClashAPITab) Refresh() {
+	t.refresh()
+}
+
+// CreateClashAPITab creates the "Servers" tab and returns it with its content.
+func CreateClashAPITab(ac *core.AppController) (*ClashAPITab, fyne.CanvasObject) {
+	apiStatusLabel := widget.NewLabel("Status: Not checked")
 	status := widget.NewLabel("Click 'Load Proxies' or 'Test API'")
-	ac.ListStatusLabel = status
 
 	selectorOptions, defaultSelector, err := core.GetSelectorGroupsFromConfig(ac.ConfigPath)
 	if err != nil {
@@ -33,26 +48,27 @@ func CreateClashAPITab(ac *core.AppController) fyne.CanvasObject {
 		selectedGroup = selectorOptions[0]
 	}
 	// Only set SelectedClashGroup if it's not already set (to preserve value from initialization)
-	if ac.SelectedClashGroup == "" {
-		ac.SelectedClashGroup = selectedGroup
+	if current := ac.GetSelectedClashGroup(); current == "" {
+		ac.SetSelectedClashGroup(selectedGroup)
 	} else {
 		// Use existing value, but update selectedGroup variable for UI
-		selectedGroup = ac.SelectedClashGroup
+		selectedGroup = current
 	}
 
 	var (
+		proxiesListWidget      *widget.List
 		groupSelect            *widget.Select
 		suppressSelectCallback bool
+		modeSelect             *widget.Select
+		suppressModeCallback   bool
 	)
 
 	// --- Логика обновления и сброса ---
 
 	onLoadAndRefreshProxies := func() {
 		if !ac.ClashAPIEnabled {
-			ShowErrorText(ac.MainWindow, "Clash API", "API is disabled: config error")
-			if ac.ListStatusLabel != nil {
-				ac.ListStatusLabel.SetText("Clash API disabled due to config error")
-			}
+			ShowErrorText(mainWindow(), "Clash API", "API is disabled: config error")
+			status.SetText("Clash API disabled due to config error")
 			return
 		}
 
@@ -60,36 +76,15 @@ func CreateClashAPITab(ac *core.AppController) fyne.CanvasObject {
 		if group == "" {
 			return
 		}
-		if ac.ListStatusLabel != nil {
-			ac.ListStatusLabel.SetText(fmt.Sprintf("Loading proxies for '%s'...", group))
-		}
+		status.SetText(fmt.Sprintf("Loading proxies for '%s'...", group))
 		go func(group string) {
-			proxies, now, err := api.GetProxiesInGroup(ac.ClashAPIBaseURL, ac.ClashAPIToken, group, ac.ApiLogFile)
-			fyne.Do(func() {
-				if err != nil {
-					ShowError(ac.MainWindow, err)
-					if ac.ListStatusLabel != nil {
-						ac.ListStatusLabel.SetText("Error: " + err.Error())
-					}
-					return
-				}
-
-				ac.SetProxiesList(proxies)
-				ac.SetActiveProxyName(now)
-
-				if ac.ProxiesListWidget != nil {
-					ac.ProxiesListWidget.Refresh()
-				}
-
-				if ac.ListStatusLabel != nil {
-					ac.ListStatusLabel.SetText(fmt.Sprintf("Proxies loaded for '%s'. Active: %s", group, now))
-				}
-
-				// Update tray menu with new proxy list
-				if ac.UpdateTrayMenuFunc != nil {
-					ac.UpdateTrayMenuFunc()
-				}
-			})
+			// On success the list is refreshed by the ProxiesLoaded handler below
+			if _, _, err := ac.LoadGroupProxies(context.Background(), group); err != nil {
+				fyne.Do(func() {
+					ShowError(mainWindow(), err)
+					status.SetText("Error: " + err.Error())
+				})
+			}
 		}(group)
 	}
 
@@ -117,26 +112,31 @@ func CreateClashAPITab(ac *core.AppController) fyne.CanvasObject {
 				suppressSelectCallback = true
 				groupSelect.SetSelected(selectedGroup)
 				suppressSelectCallback = false
-				ac.SelectedClashGroup = selectedGroup
+				ac.SetSelectedClashGroup(selectedGroup)
 			}
 		}
 	}
 
 	onTestAPIConnection := func() {
 		if !ac.ClashAPIEnabled {
-			ac.ApiStatusLabel.SetText("❌ ClashAPI Off (Config Error)")
-			ShowErrorText(ac.MainWindow, "Clash API", "API is disabled: config error")
+			apiStatusLabel.SetText("❌ ClashAPI Off (Config Error)")
+			ShowErrorText(mainWindow(), "Clash API", "API is disabled: config error")
 			return
 		}
 		go func() {
-			err := api.TestAPIConnection(ac.ClashAPIBaseURL, ac.ClashAPIToken, ac.ApiLogFile)
+			_, err := ac.ClashClient().Version(context.Background())
 			fyne.Do(func() {
 				if err != nil {
-					ac.ApiStatusLabel.SetText("❌ Clash API Off (Error)")
-					ShowError(ac.MainWindow, err)
+					apiStatusLabel.SetText("❌ Clash API Off (Error)")
+					ShowError(mainWindow(), err)
 					return
 				}
-				ac.ApiStatusLabel.SetText("✅ Clash API On")
+				apiStatusLabel.SetText("✅ Clash API On")
+				go func() {
+					if _, err := ac.RefreshClashMode(context.Background()); err != nil {
+						log.Printf("clash_api_tab: %v", err)
+					}
+				}()
 				// Обновить список селекторов после успешного подключения (sing-box запущен, конфиг загружен)
 				updateSelectorList()
 				onLoadAndRefreshProxies()
@@ -144,44 +144,81 @@ func CreateClashAPITab(ac *core.AppController) fyne.CanvasObject {
 		}()
 	}
 
-	onResetAPIState := func() {
-		log.Println("clash_api_tab: Resetting API state.")
-		ac.SetProxiesList([]api.ProxyInfo{})
-		ac.SetActiveProxyName("")
-		ac.SetSelectedIndex(-1)
-		fyne.Do(func() {
-			if ac.ApiStatusLabel != nil {
-				ac.ApiStatusLabel.SetText("Status: Not running")
-			}
-			if ac.ListStatusLabel != nil {
-				ac.ListStatusLabel.SetText("Sing-box is stopped.")
-			}
-			if ac.ProxiesListWidget != nil {
-				ac.ProxiesListWidget.Refresh()
-			}
-			// Update tray menu when API state is reset
-			if ac.UpdateTrayMenuFunc != nil {
-				ac.UpdateTrayMenuFunc()
-			}
-		})
-	}
+	// --- Подписки на события core ---
+
+	// Core clears the API state when sing-box stops
+	onEvent(ac, func(core.CoreStopped) {
+		apiStatusLabel.SetText("Status: Not running")
+		status.SetText("Sing-box is stopped.")
+		if proxiesListWidget != nil {
+			proxiesListWidget.Refresh()
+		}
+	})
+
+	// После смены профиля группы селекторов берутся из нового конфига
+	onEvent(ac, func(e core.ProfileChanged) {
+		if current := ac.GetSelectedClashGroup(); current != "" {
+			selectedGroup = current
+		}
+		updateSelectorList()
+		if groupSelect != nil {
+			suppressSelectCallback = true
+			groupSelect.SetSelected(selectedGroup)
+			suppressSelectCallback = false
+		}
+		if proxiesListWidget != nil {
+			proxiesListWidget.Refresh()
+		}
+		status.SetText(fmt.Sprintf("Profile '%s' loaded.", e.Profile))
+	})
+
+	// Proxies of the selected group were (re)loaded by this tab or by auto-loading
+	onEvent(ac, func(e core.ProxiesLoaded) {
+		if e.Group != ac.GetSelectedClashGroup() {
+			return
+		}
+		apiStatusLabel.SetText("✅ Clash API On")
+		if proxiesListWidget != nil {
+			proxiesListWidget.Refresh()
+		}
+		status.SetText(fmt.Sprintf("Proxies loaded for '%s'. Active: %s", e.Group, e.Active))
+	})
 
-	// --- Регистрация колбэков в контроллере ---
-	ac.RefreshAPIFunc = onTestAPIConnection
-	ac.ResetAPIStateFunc = onResetAPIState
+	// The active proxy of the selected group changed (tray, selector restore)
+	onEvent(ac, func(e core.ProxySwitched) {
+		if e.Group != ac.GetSelectedClashGroup() {
+			return
+		}
+		if proxiesListWidget != nil {
+			proxiesListWidget.Refresh()
+		}
+		status.SetText(fmt.Sprintf("Switched '%s' to %s", e.Group, e.Proxy))
+	})
 
 	// --- Вспомогательная функция для пинга ---
 	pingProxy := func(proxyName string, button *widget.Button) {
+		opts := ac.LatencyTestOptions(selectedGroup)
 		go func() {
 			fyne.Do(func() { button.SetText("...") })
-			delay, err := api.GetDelay(ac.ClashAPIBaseURL, ac.ClashAPIToken, proxyName, ac.ApiLogFile)
+			delay, err := ac.ClashClient().Delay(context.Background(), proxyName, opts)
+			switch {
+			case err == nil:
+				ac.SetProxyDelay(proxyName, delay)
+			case errors.Is(err, api.ErrDelayTimeout):
+				ac.SetProxyDelay(proxyName, api.DelayTimedOut)
+			}
 			fyne.Do(func() {
 				if err != nil {
+					if errors.Is(err, api.ErrDelayTimeout) {
+						button.SetText(core.FormatDelay(api.DelayTimedOut))
+						status.SetText(fmt.Sprintf("Delay: %s timed out", proxyName))
+						return
+					}
 					button.SetText("Error")
 					status.SetText("Delay error: " + err.Error())
-					ShowError(ac.MainWindow, err)
+					ShowError(mainWindow(), err)
 				} else {
-					button.SetText(fmt.Sprintf("%d ms", delay))
+					button.SetText(core.FormatDelay(delay))
 					status.SetText(fmt.Sprintf("Delay: %d ms for %s", delay, proxyName))
 				}
 			})
@@ -212,7 +249,7 @@ func CreateClashAPITab(ac *core.AppController) fyne.CanvasObject {
 	}
 
 	updateItem := func(id int, o fyne.CanvasObject) {
-		proxies := ac.GetProxiesList()
+		proxies := ac.GetVisibleProxiesList()
 		if id < 0 || id >= len(proxies) {
 			return
 		}
@@ -229,8 +266,8 @@ func CreateClashAPITab(ac *core.AppController) fyne.CanvasObject {
 
 		nameLabel.SetText(proxyInfo.Name)
 
-		if proxyInfo.Delay > 0 {
-			pingButton.SetText(fmt.Sprintf("%d ms", proxyInfo.Delay))
+		if delayText := core.FormatDelay(proxyInfo.Delay); delayText != "" {
+			pingButton.SetText(delayText)
 		} else {
 			pingButton.SetText("Ping")
 		}
@@ -254,50 +291,91 @@ func CreateClashAPITab(ac *core.AppController) fyne.CanvasObject {
 
 		switchButton.OnTapped = func() {
 			if !ac.ClashAPIEnabled {
-				ShowErrorText(ac.MainWindow, "Clash API", "API is disabled: config error")
+				ShowErrorText(mainWindow(), "Clash API", "API is disabled: config error")
 				return
 			}
 			go func(group string) {
-				err := api.SwitchProxy(ac.ClashAPIBaseURL, ac.ClashAPIToken, group, proxyNameForCallback, ac.ApiLogFile)
+				err := ac.SwitchGroupProxy(context.Background(), group, proxyNameForCallback)
 				fyne.Do(func() {
 					if err != nil {
-						ShowError(ac.MainWindow, err)
+						ShowError(mainWindow(), err)
 						status.SetText("Switch error: " + err.Error())
 					} else {
-						ac.SetActiveProxyName(proxyNameForCallback)
-						ac.ProxiesListWidget.Refresh()
 						pingProxy(proxyNameForCallback, pingButton)
-						if ac.ListStatusLabel != nil {
-							ac.ListStatusLabel.SetText(fmt.Sprintf("Switched '%s' to %s", group, proxyNameForCallback))
-						}
 					}
 				})
 			}(selectedGroup)
 		}
 	}
 
-	proxiesListWidget := widget.NewList(
-		func() int { return len(ac.GetProxiesList()) },
+	proxiesListWidget = widget.NewList(
+		func() int { return len(ac.GetVisibleProxiesList()) },
 		createItem,
 		updateItem,
 	)
 
 	proxiesListWidget.OnSelected = func(id int) {
 		ac.SetSelectedIndex(id)
-		proxies := ac.GetProxiesList()
+		proxies := ac.GetVisibleProxiesList()
 		if id >= 0 && id < len(proxies) {
 			status.SetText("Selected: " + proxies[id].Name)
 		}
 		proxiesListWidget.Refresh()
 	}
 
-	ac.ProxiesListWidget = proxiesListWidget
-
 	// --- Сборка всего контента ---
 	scrollContainer := container.NewScroll(proxiesListWidget)
 	scrollContainer.SetMinSize(fyne.NewSize(0, 300))
 
 	loadButton := widget.NewButton("Load Proxies", onLoadAndRefreshProxies)
+
+	// --- Тест задержки всей группы ---
+	var (
+		testAllButton        *widget.Button
+		sortByLatencyCheck   *widget.Check
+		hideTimedOutCheck    *widget.Check
+		suppressViewCallback bool
+	)
+	testAllButton = widget.NewButton("Test all", func() {
+		if !ac.ClashAPIEnabled {
+			ShowErrorText(mainWindow(), "Clash API", "API is disabled: config error")
+			return
+		}
+		group := selectedGroup
+		testAllButton.Disable()
+		status.SetText(fmt.Sprintf("Testing latency of '%s'...", group))
+		ac.StartGroupLatencyTest(group, func(err error) {
+			fyne.Do(func() {
+				testAllButton.Enable()
+				if err != nil {
+					status.SetText("Latency test error: " + err.Error())
+					return
+				}
+				status.SetText(fmt.Sprintf("Latency of '%s': %s", group, core.LatencySummary(ac.GetProxiesList())))
+			})
+		})
+	})
+	onViewOptionsChanged := func(bool) {
+		if !suppressViewCallback {
+			ac.UpdateLatencyView(sortByLatencyCheck.Checked, hideTimedOutCheck.Checked)
+		}
+	}
+	sortByLatencyCheck = widget.NewCheck("Sort by latency", onViewOptionsChanged)
+	hideTimedOutCheck = widget.NewCheck("Hide timed-out", onViewOptionsChanged)
+	syncViewOptions := func() {
+		settings := ac.LatencyTestSettings()
+		suppressViewCallback = true
+		sortByLatencyCheck.SetChecked(settings.SortByLatency)
+		hideTimedOutCheck.SetChecked(settings.HideTimedOut)
+		suppressViewCallback = false
+	}
+	syncViewOptions()
+
+	// Results of latency tests arrive from background goroutines in bursts
+	onEvent(ac, func(core.LatencyUpdated) {
+		syncViewOptions()
+		proxiesListWidget.Refresh()
+	}, core.WithCoalesce(latencyRefreshInterval))
 	testAPIButton := widget.NewButton("Test API Connection", onTestAPIConnection)
 
 	groupSelect = widget.NewSelect(selectorOptions, func(value string) {
@@ -305,15 +383,13 @@ func CreateClashAPITab(ac *core.AppController) fyne.CanvasObject {
 			return
 		}
 		selectedGroup = value
-		ac.SelectedClashGroup = value
 		if suppressSelectCallback {
+			ac.SetSelectedClashGroup(value)
 			return
 		}
+		// Chosen by the user: remembered for the profile
+		ac.SelectClashGroup(value)
 		status.SetText(fmt.Sprintf("Selected group '%s'.", value))
-		// Update tray menu when group changes
-		if ac.UpdateTrayMenuFunc != nil {
-			ac.UpdateTrayMenuFunc()
-		}
 		// Start auto-loading proxies for the new group only if sing-box is running
 		if ac.RunningState.IsRunning() {
 			ac.AutoLoadProxies()
@@ -321,6 +397,44 @@ func CreateClashAPITab(ac *core.AppController) fyne.CanvasObject {
 		onLoadAndRefreshProxies()
 	})
 	groupSelect.PlaceHolder = "Select selector group"
+
+	// --- Режим Clash (rule / global / direct) ---
+	modeSelect = widget.NewSelect(nil, func(mode string) {
+		if suppressModeCallback || mode == "" || mode == ac.ClashModeState().Mode {
+			return
+		}
+		status.SetText(fmt.Sprintf("Switching mode to '%s'...", mode))
+		go func() {
+			err := ac.SetClashMode(context.Background(), mode)
+			fyne.Do(func() {
+				if err != nil {
+					ShowError(mainWindow(), err)
+					status.SetText("Mode error: " + err.Error())
+					return
+				}
+				status.SetText(fmt.Sprintf("Mode switched to '%s'", mode))
+			})
+		}()
+	})
+	modeSelect.PlaceHolder = "Mode"
+	syncModeSelect := func() {
+		state := ac.ClashModeState()
+		suppressModeCallback = true
+		modeSelect.SetOptions(state.Modes)
+		if state.Mode == "" {
+			modeSelect.ClearSelected()
+		} else {
+			modeSelect.SetSelected(state.Mode)
+		}
+		suppressModeCallback = false
+		if state.Available() {
+			modeSelect.Enable()
+		} else {
+			modeSelect.Disable()
+		}
+	}
+	syncModeSelect()
+	onEvent(ac, func(core.ClashModeChanged) { syncModeSelect() })
 	if selectedGroup != "" {
 		suppressSelectCallback = true
 		groupSelect.SetSelected(selectedGroup)
@@ -328,11 +442,12 @@ func CreateClashAPITab(ac *core.AppController) fyne.CanvasObject {
 	}
 
 	topControls := container.NewVBox(
-		ac.ApiStatusLabel,
-		container.NewHBox(widget.NewLabel("Selector group:"), groupSelect),
+		apiStatusLabel,
+		container.NewHBox(widget.NewLabel("Selector group:"), groupSelect, widget.NewLabel("Mode:"), modeSelect),
 		testAPIButton,
 		widget.NewSeparator(),
 		loadButton,
+		container.NewHBox(testAllButton, sortByLatencyCheck, hideTimedOutCheck),
 	)
 
 	contentContainer := container.NewBorder(
@@ -343,5 +458,5 @@ func CreateClashAPITab(ac *core.AppController) fyne.CanvasObject {
 		scrollContainer,
 	)
 
-	return contentContainer
+	return &ClashAPITab{refresh: onTestAPIConnection}, contentContainer
 }
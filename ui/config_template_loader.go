@@ -7,11 +7,11 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
-	"runtime"
 	"strings"
 
 	"github.com/muhammadmuzzammil1998/jsonc"
 
+	"singbox-launcher/core"
 	"singbox-launcher/internal/debuglog"
 )
 
@@ -43,10 +43,7 @@ type TemplateSelectableRule struct {
 // GetTemplateFileName returns the template file name for the current platform.
 // On macOS returns "config_template_macos.json", on other platforms returns "config_template.json".
 func GetTemplateFileName() string {
-	if runtime.GOOS == "darwin" {
-		return "config_template_macos.json"
-	}
-	return "config_template.json"
+	return core.ConfigTemplateFileName()
 }
 
 // GetTemplateURL returns the GitHub URL for downloading the template for the current platform.
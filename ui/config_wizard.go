@@ -50,10 +50,8 @@ func ShowConfigWizard(parent fyne.Window, controller *core.AppController) {
 	if templateData, err := loadTemplateData(controller.ExecDir); err != nil {
 		templateFileName := GetTemplateFileName()
 		errorLog("ConfigWizard: failed to load %s from %s: %v", templateFileName, filepath.Join(controller.ExecDir, "bin", templateFileName), err)
-		// Update config status in Core Dashboard (similar to UpdateConfigStatusFunc)
-		if controller.UpdateConfigStatusFunc != nil {
-			controller.UpdateConfigStatusFunc()
-		}
+		// Refresh config status in Core Dashboard (it shows the missing template)
+		controller.Events.Publish(core.ConfigUpdated{ConfigPath: controller.ConfigPath})
 		// Show error to user
 		//	dialog.ShowError(fmt.Errorf("Failed to load template file:\n%v\n\nPlease ensure bin/config_template.json exists and is valid.", err), wizardWindow)
 		return
@@ -62,7 +60,7 @@ func ShowConfigWizard(parent fyne.Window, controller *core.AppController) {
 	}
 
 	// Создаем новое окно для мастера
-	wizardWindow := controller.Application.NewWindow("Config Wizard")
+	wizardWindow := application().NewWindow("Config Wizard")
 	wizardWindow.Resize(fyne.NewSize(920, 720))
 	wizardWindow.CenterOnScreen()
 	state.Window = wizardWindow
@@ -801,9 +799,9 @@ func (state *WizardState) saveConfigWithBackup(text string) (string, error) {
 	if err := os.WriteFile(configPath, []byte(finalText), 0o644); err != nil {
 		return "", err
 	}
-	// Update config status in Core Dashboard if callback is set
-	if state.Controller != nil && state.Controller.UpdateConfigStatusFunc != nil {
-		state.Controller.UpdateConfigStatusFunc()
+	// Update config status in Core Dashboard
+	if state.Controller != nil {
+		state.Controller.Events.Publish(core.ConfigUpdated{ConfigPath: configPath})
 	}
 	return configPath, nil
 }
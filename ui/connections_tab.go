@@ -0,0 +1,291 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+
+	"singbox-launcher/api"
+	"singbox-launcher/core"
+)
+
+// connectionsRefreshInterval is how often the connections list is reloaded while the tab is open
+const connectionsRefreshInterval = 2 * time.Second
+
+// connectionColumns are the table columns with their widths
+var connectionColumns = []struct {
+	title string
+	width float32
+}{
+	{"Host", 220},
+	{"Network", 70},
+	{"Rule", 160},
+	{"Chain", 180},
+	{"↑", 80},
+	{"↓", 80},
+	{"Start", 80},
+	{"Process", 140},
+}
+
+// ConnectionsTab управляет вкладкой Connections (активные соединения через Clash API)
+type ConnectionsTab struct {
+	controller *core.AppController
+
+	// UI elements
+	table          *widget.Table
+	searchEntry    *widget.Entry
+	sortSelect     *widget.Select
+	descCheck      *widget.Check
+	autoCloseCheck *widget.Check
+	closeButton    *widget.Button
+	statusLabel    *widget.Label
+
+	// Data (UI thread only)
+	all         []api.Connection
+	visible     []api.Connection
+	selectedID  string
+	selectedRow int
+	active      bool
+	stopRefresh chan struct{}
+}
+
+// CreateConnectionsTab creates the "Connections" tab. Call SetActive when the tab is shown or hidden.
+func CreateConnectionsTab(ac *core.AppController) (*ConnectionsTab, fyne.CanvasObject) {
+	tab := &ConnectionsTab{controller: ac, selectedRow: -1}
+
+	tab.searchEntry = widget.NewEntry()
+	tab.searchEntry.SetPlaceHolder("Search host, IP, process, rule, chain...")
+	tab.searchEntry.OnChanged = func(string) { tab.applyView() }
+
+	tab.sortSelect = widget.NewSelect(core.ConnectionSortKeys, func(string) { tab.applyView() })
+	tab.descCheck = widget.NewCheck("Desc", func(bool) { tab.applyView() })
+	tab.sortSelect.SetSelected(core.ConnectionSortStart)
+	tab.descCheck.SetChecked(true)
+
+	tab.autoCloseCheck = widget.NewCheck("Close connections after switching", func(enabled bool) {
+		store := ac.CurrentStateStore()
+		if store == nil {
+			return
+		}
+		if err := store.SetAutoCloseConnections(enabled); err != nil {
+			log.Printf("ConnectionsTab: failed to save auto-close setting: %v", err)
+		}
+	})
+	if store := ac.CurrentStateStore(); store != nil {
+		tab.autoCloseCheck.SetChecked(store.AutoCloseConnections())
+	}
+
+	tab.statusLabel = widget.NewLabel("")
+
+	tab.table = widget.NewTableWithHeaders(
+		func() (int, int) { return len(tab.visible), len(connectionColumns) },
+		func() fyne.CanvasObject {
+			label := widget.NewLabel("")
+			label.Truncation = fyne.TextTruncateEllipsis
+			return label
+		},
+		func(id widget.TableCellID, obj fyne.CanvasObject) {
+			if id.Row < 0 || id.Row >= len(tab.visible) {
+				return
+			}
+			obj.(*widget.Label).SetText(connectionCell(tab.visible[id.Row], id.Col))
+		},
+	)
+	tab.table.ShowHeaderColumn = false
+	tab.table.CreateHeader = func() fyne.CanvasObject {
+		return widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+	}
+	tab.table.UpdateHeader = func(id widget.TableCellID, obj fyne.CanvasObject) {
+		if id.Col >= 0 && id.Col < len(connectionColumns) {
+			obj.(*widget.Label).SetText(connectionColumns[id.Col].title)
+		}
+	}
+	for i, column := range connectionColumns {
+		tab.table.SetColumnWidth(i, column.width)
+	}
+	tab.table.OnSelected = func(id widget.TableCellID) {
+		if id.Row >= 0 && id.Row < len(tab.visible) {
+			tab.selectedID = tab.visible[id.Row].ID
+			tab.selectedRow = id.Row
+			tab.closeButton.Enable()
+		}
+	}
+
+	tab.closeButton = widget.NewButton("Close selected", tab.closeSelected)
+	tab.closeButton.Disable()
+	closeAllButton := widget.NewButton("Close all", func() {
+		ShowConfirm(mainWindow(), "Close all connections", "Close all active connections?", func(ok bool) {
+			if ok {
+				tab.closeAll()
+			}
+		})
+	})
+	refreshButton := widget.NewButton("Refresh", func() { go tab.reload() })
+
+	topRow := container.NewBorder(nil, nil,
+		nil,
+		container.NewHBox(widget.NewLabel("Sort:"), tab.sortSelect, tab.descCheck),
+		tab.searchEntry,
+	)
+	actionsRow := container.NewBorder(nil, nil,
+		container.NewHBox(tab.closeButton, closeAllButton, refreshButton),
+		tab.autoCloseCheck,
+		tab.statusLabel,
+	)
+
+	return tab, container.NewBorder(container.NewVBox(topRow, actionsRow), nil, nil, nil, tab.table)
+}
+
+// SetActive starts periodic reloading while the tab is visible and stops it otherwise (UI thread only)
+func (tab *ConnectionsTab) SetActive(active bool) {
+	if active == tab.active {
+		return
+	}
+	tab.active = active
+	if !active {
+		close(tab.stopRefresh)
+		tab.stopRefresh = nil
+		return
+	}
+	stop := make(chan struct{})
+	tab.stopRefresh = stop
+	go func() {
+		ticker := time.NewTicker(connectionsRefreshInterval)
+		defer ticker.Stop()
+		for {
+			tab.reload()
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// reload fetches connections from the Clash API (background goroutine)
+func (tab *ConnectionsTab) reload() {
+	ac := tab.controller
+	if !ac.RunningState.IsRunning() || !ac.ClashAPIEnabled {
+		fyne.Do(func() {
+			tab.all = nil
+			tab.applyView()
+			tab.statusLabel.SetText("sing-box is not running")
+		})
+		return
+	}
+	snapshot, err := ac.ClashClient().Connections(context.Background())
+	fyne.Do(func() {
+		if err != nil {
+			tab.statusLabel.SetText("Error: " + err.Error())
+			return
+		}
+		tab.all = snapshot.Connections
+		tab.applyView()
+		tab.statusLabel.SetText(fmt.Sprintf("%d of %d connections · total ↓ %s ↑ %s",
+			len(tab.visible), len(tab.all), core.FormatBytes(uint64(snapshot.DownloadTotal)), core.FormatBytes(uint64(snapshot.UploadTotal))))
+	})
+}
+
+// applyView filters and sorts the loaded connections (UI thread only)
+func (tab *ConnectionsTab) applyView() {
+	if tab.table == nil {
+		return // widgets are still being created
+	}
+	visible := core.FilterConnections(tab.all, tab.searchEntry.Text)
+	visible = append([]api.Connection(nil), visible...)
+	core.SortConnections(visible, tab.sortSelect.Selected, tab.descCheck.Checked)
+	tab.visible = visible
+
+	// Keep the selection on the same connection after reordering
+	newRow := -1
+	for i, conn := range tab.visible {
+		if conn.ID == tab.selectedID {
+			newRow = i
+			break
+		}
+	}
+	if newRow < 0 {
+		tab.selectedID = ""
+		tab.selectedRow = -1
+		tab.table.UnselectAll()
+		tab.closeButton.Disable()
+	} else if newRow != tab.selectedRow {
+		tab.table.Select(widget.TableCellID{Row: newRow, Col: 0})
+	}
+	tab.table.Refresh()
+}
+
+func (tab *ConnectionsTab) closeSelected() {
+	id := tab.selectedID
+	if id == "" {
+		return
+	}
+	ac := tab.controller
+	go func() {
+		err := ac.ClashClient().CloseConnection(context.Background(), id)
+		if err != nil {
+			fyne.Do(func() { ShowError(mainWindow(), fmt.Errorf("failed to close connection: %w", err)) })
+			return
+		}
+		fyne.Do(func() { tab.selectedID = "" })
+		tab.reload()
+	}()
+}
+
+func (tab *ConnectionsTab) closeAll() {
+	ac := tab.controller
+	go func() {
+		err := ac.ClashClient().CloseAllConnections(context.Background())
+		if err != nil {
+			fyne.Do(func() { ShowError(mainWindow(), fmt.Errorf("failed to close connections: %w", err)) })
+			return
+		}
+		tab.reload()
+	}()
+}
+
+// connectionCell returns the text of a table cell
+func connectionCell(conn api.Connection, col int) string {
+	switch col {
+	case 0:
+		return conn.Destination()
+	case 1:
+		return strings.ToUpper(conn.Metadata.Network)
+	case 2:
+		if conn.RulePayload != "" {
+			return conn.Rule + " (" + conn.RulePayload + ")"
+		}
+		return conn.Rule
+	case 3:
+		// Chains are reported from the final outbound to the first selector, show them in routing order
+		chain := make([]string, len(conn.Chains))
+		for i, tag := range conn.Chains {
+			chain[len(conn.Chains)-1-i] = tag
+		}
+		return strings.Join(chain, " → ")
+	case 4:
+		return core.FormatBytes(uint64(conn.Upload))
+	case 5:
+		return core.FormatBytes(uint64(conn.Download))
+	case 6:
+		if conn.Start.IsZero() {
+			return ""
+		}
+		return conn.Start.Local().Format("15:04:05")
+	case 7:
+		if conn.Metadata.ProcessPath == "" {
+			return ""
+		}
+		return filepath.Base(conn.Metadata.ProcessPath)
+	default:
+		return ""
+	}
+}
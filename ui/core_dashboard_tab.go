@@ -2,6 +2,7 @@ package ui
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"image/color"
 	"io"
@@ -25,6 +26,9 @@ import (
 
 const downloadPlaceholderWidth = 180
 
+// trafficRefreshInterval limits how often the speed and memory labels are redrawn
+const trafficRefreshInterval = 500 * time.Millisecond
+
 // CoreDashboardTab управляет вкладкой Core Dashboard
 type CoreDashboardTab struct {
 	controller *core.AppController
@@ -49,12 +53,19 @@ type CoreDashboardTab struct {
 	updateConfigButton        *widget.Button
 	parserProgressBar         *widget.ProgressBar // Progress bar for parser
 	parserStatusLabel         *widget.Label       // Status label for parser
+	trafficLabel              *widget.Label       // Current speed and memory usage
+	trafficGraph              *Sparkline          // Recent download/upload speed
+	trafficRow                fyne.CanvasObject   // Container shown only while running
+	profileSelect             *widget.Select      // Active configuration profile
+	deleteProfileButton       *widget.Button
+	suppressProfileSelect     bool // Set while the selection is changed programmatically
 
 	// Data
 	stopAutoUpdate           chan bool
 	lastUpdateSuccess        bool // Track success of last version update
 	downloadInProgress       bool // Flag for sing-box download process
 	wintunDownloadInProgress bool // Flag for wintun.dll download process
+	coreModified             bool // sing-box differs from the SHA-256 recorded at install time
 }
 
 // CreateCoreDashboardTab creates and returns the Core Dashboard tab
@@ -84,86 +95,89 @@ func CreateCoreDashboardTab(ac *core.AppController) fyne.CanvasObject {
 
 	contentItems := []fyne.CanvasObject{
 		statusRow,
+		tab.createTrafficRow(),
 		widget.NewSeparator(),
 		coreInfo,
 		widget.NewSeparator(),
 	}
 
 	// Горизонтальная линия и кнопка Exit в конце списка
-	exitButton := widget.NewButton("Exit", ac.GracefulExit)
+	exitButton := widget.NewButton("Exit", func() { quitApp(ac) })
 	// Кнопка Exit в отдельной строке с отступом вниз
 	contentItems = append(contentItems, widget.NewLabel("")) // Отступ
 	contentItems = append(contentItems, container.NewCenter(exitButton))
 
 	content := container.NewVBox(contentItems...)
 
-	// Регистрируем callback для обновления статуса при изменении RunningState
-	// Сохраняем оригинальный callback, если он есть
-	originalUpdateCoreStatusFunc := tab.controller.UpdateCoreStatusFunc
-	tab.controller.UpdateCoreStatusFunc = func() {
-		// Вызываем оригинальный callback, если он есть
-		if originalUpdateCoreStatusFunc != nil {
-			originalUpdateCoreStatusFunc()
+	// Подписываемся на события core (обработчики вызываются в UI-потоке)
+	onEvents(tab.controller, core.CoreStateEvents, func(core.Event) { tab.updateRunningStatus() })
+	onEvent(tab.controller, func(core.TrafficUpdated) { tab.updateTraffic() }, core.WithCoalesce(trafficRefreshInterval))
+	onEvents(tab.controller, []core.Event{core.ConfigUpdated{}, core.ProfileChanged{}}, func(core.Event) { tab.updateConfigInfo() })
+	onEvents(tab.controller, []core.Event{core.ProfileChanged{}, core.ProfilesUpdated{}}, func(core.Event) { tab.updateProfileList() })
+	onEvent(tab.controller, func(e core.CoreVersionChanged) {
+		tab.coreModified = false
+		tab.updateVersionInfo()
+		if e.RolledBack {
+			tab.updateBinaryStatus()
 		}
-		// Вызываем наш callback
-		fyne.Do(func() {
-			tab.updateRunningStatus()
-		})
-	}
-
-	// Регистрируем callback для обновления статуса конфига
-	tab.controller.UpdateConfigStatusFunc = func() {
-		fyne.Do(func() {
-			tab.updateConfigInfo()
-		})
-	}
+	})
+	onEvent(tab.controller, func(e core.VersionAvailable) {
+		if e.Component == core.VersionComponentCore && !tab.downloadInProgress {
+			tab.updateVersionInfo()
+		}
+	})
+	onEvent(tab.controller, func(e core.GitHubRateLimited) {
+		if !tab.downloadInProgress {
+			tab.updateVersionInfo()
+		}
+	})
 
-	// Регистрируем callback для обновления прогресса парсера
-	tab.controller.UpdateParserProgressFunc = func(progress float64, status string) {
-		fyne.Do(func() {
-			if tab.parserProgressBar != nil {
-				if progress < 0 {
-					// Error state - hide progress bar
-					tab.parserProgressBar.Hide()
-					tab.parserStatusLabel.Hide()
-					// Проверяем, не запущен ли парсер
-					tab.controller.ParserMutex.Lock()
-					parserRunning := tab.controller.ParserRunning
-					tab.controller.ParserMutex.Unlock()
-					if !parserRunning {
-						tab.updateConfigButton.Enable()
-					}
-				} else {
-					// Show progress
-					tab.parserProgressBar.Show()
-					tab.parserStatusLabel.Show()
-					tab.parserProgressBar.SetValue(progress / 100.0)
-					tab.parserStatusLabel.SetText(status)
-					if progress >= 100 {
-						// Completed - hide after a short delay
-						go func() {
-							time.Sleep(1 * time.Second)
-							fyne.Do(func() {
-								tab.parserProgressBar.Hide()
-								tab.parserStatusLabel.Hide()
-								// Проверяем, не запущен ли парсер
-								tab.controller.ParserMutex.Lock()
-								parserRunning := tab.controller.ParserRunning
-								tab.controller.ParserMutex.Unlock()
-								if !parserRunning {
-									tab.updateConfigButton.Enable()
-								}
-							})
-						}()
-					}
+	// Прогресс парсера
+	onEvent(tab.controller, func(e core.ParserProgress) {
+		progress, status := e.Progress, e.Status
+		if tab.parserProgressBar != nil {
+			if progress < 0 {
+				// Error state - hide progress bar
+				tab.parserProgressBar.Hide()
+				tab.parserStatusLabel.Hide()
+				// Проверяем, не запущен ли парсер
+				tab.controller.ParserMutex.Lock()
+				parserRunning := tab.controller.ParserRunning
+				tab.controller.ParserMutex.Unlock()
+				if !parserRunning {
+					tab.updateConfigButton.Enable()
+				}
+			} else {
+				// Show progress
+				tab.parserProgressBar.Show()
+				tab.parserStatusLabel.Show()
+				tab.parserProgressBar.SetValue(progress / 100.0)
+				tab.parserStatusLabel.SetText(status)
+				if progress >= 100 {
+					// Completed - hide after a short delay
+					go func() {
+						time.Sleep(1 * time.Second)
+						fyne.Do(func() {
+							tab.parserProgressBar.Hide()
+							tab.parserStatusLabel.Hide()
+							// Проверяем, не запущен ли парсер
+							tab.controller.ParserMutex.Lock()
+							parserRunning := tab.controller.ParserRunning
+							tab.controller.ParserMutex.Unlock()
+							if !parserRunning {
+								tab.updateConfigButton.Enable()
+							}
+						})
+					}()
 				}
 			}
-		})
-	}
+		}
+	})
 
 	// Первоначальное обновление
 	tab.updateBinaryStatus() // Проверяет наличие бинарника и вызывает updateRunningStatus
 	tab.updateVersionInfo()
+	tab.checkCoreIntegrity()
 	if runtime.GOOS == "windows" {
 		tab.updateWintunStatus() // Проверяет наличие wintun.dll
 	}
@@ -175,6 +189,47 @@ func CreateCoreDashboardTab(ac *core.AppController) fyne.CanvasObject {
 	return content
 }
 
+// createTrafficRow creates a row with current speed, memory usage and a speed graph
+func (tab *CoreDashboardTab) createTrafficRow() fyne.CanvasObject {
+	tab.trafficLabel = widget.NewLabel("")
+	tab.trafficGraph = NewSparkline(fyne.NewSize(120, 28),
+		color.NRGBA{R: 0x2e, G: 0x9e, B: 0x4f, A: 0xff}, // download
+		color.NRGBA{R: 0x2f, G: 0x6f, B: 0xd6, A: 0xff}, // upload
+	)
+	tab.trafficRow = container.NewBorder(nil, nil, tab.trafficLabel, nil, tab.trafficGraph)
+	tab.trafficRow.Hide()
+	return tab.trafficRow
+}
+
+// updateTraffic обновляет скорость, память и график (UI thread only)
+func (tab *CoreDashboardTab) updateTraffic() {
+	monitor := tab.controller.TrafficMonitor
+	if monitor == nil || tab.trafficRow == nil {
+		return
+	}
+	up, down, memory, ok := monitor.Current()
+	if !ok || !tab.controller.RunningState.IsRunning() {
+		tab.trafficRow.Hide()
+		return
+	}
+
+	text := fmt.Sprintf("↓ %s  ↑ %s", core.FormatSpeed(down), core.FormatSpeed(up))
+	if memory > 0 {
+		text += "  ·  Mem " + core.FormatBytes(memory)
+	}
+	tab.trafficLabel.SetText(text)
+
+	history := monitor.History()
+	downSeries := make([]float64, len(history))
+	upSeries := make([]float64, len(history))
+	for i, point := range history {
+		downSeries[i] = float64(point.Down)
+		upSeries[i] = float64(point.Up)
+	}
+	tab.trafficGraph.SetData(downSeries, upSeries)
+	tab.trafficRow.Show()
+}
+
 // createStatusRow creates a row with status and buttons
 func (tab *CoreDashboardTab) createStatusRow() fyne.CanvasObject {
 	// Объединяем все в один label: "Core Status" + иконка + текст статуса
@@ -185,12 +240,12 @@ func (tab *CoreDashboardTab) createStatusRow() fyne.CanvasObject {
 
 	startButton := widget.NewButton("Start", func() {
 		core.StartSingBoxProcess(tab.controller)
-		// Status will be updated automatically via UpdateCoreStatusFunc
+		// Status will be updated automatically via Notifier.StateChanged
 	})
 
 	stopButton := widget.NewButton("Stop", func() {
 		core.StopSingBoxProcess(tab.controller)
-		// Status will be updated automatically via UpdateCoreStatusFunc
+		// Status will be updated automatically via Notifier.StateChanged
 	})
 
 	// Save button references for updating locks
@@ -257,7 +312,7 @@ func (tab *CoreDashboardTab) createConfigBlock() fyne.CanvasObject {
 	tab.updateConfigButton.Importance = widget.MediumImportance
 
 	tab.wizardButton = widget.NewButton("⚙️ Wizard", func() {
-		ShowConfigWizard(tab.controller.MainWindow, tab.controller)
+		ShowConfigWizard(mainWindow(), tab.controller)
 	})
 	tab.wizardButton.Importance = widget.MediumImportance
 
@@ -293,12 +348,137 @@ func (tab *CoreDashboardTab) createConfigBlock() fyne.CanvasObject {
 	)
 
 	return container.NewVBox(
+		tab.createProfileRow(),
 		statusRow,
 		buttonsRow,
 		parserProgressRow, // Прогрессбар и статус парсера в отдельной строке
 	)
 }
 
+// createProfileRow creates the configuration profile picker with New/Delete buttons
+func (tab *CoreDashboardTab) createProfileRow() fyne.CanvasObject {
+	ac := tab.controller
+	tab.profileSelect = widget.NewSelect(nil, func(name string) {
+		if tab.suppressProfileSelect || name == "" || name == ac.CurrentProfile() {
+			return
+		}
+		tab.switchProfile(name)
+	})
+
+	newButton := widget.NewButton("New...", tab.showNewProfileDialog)
+	tab.deleteProfileButton = widget.NewButton("Delete...", tab.showDeleteProfileDialog)
+	tab.updateProfileList()
+
+	return container.NewBorder(nil, nil,
+		widget.NewLabel("Profile"),
+		container.NewHBox(newButton, tab.deleteProfileButton),
+		tab.profileSelect,
+	)
+}
+
+// updateProfileList reloads the profile names and selects the active one (UI thread only)
+func (tab *CoreDashboardTab) updateProfileList() {
+	if tab.profileSelect == nil {
+		return
+	}
+	tab.suppressProfileSelect = true
+	tab.profileSelect.SetOptions(tab.controller.Profiles.Names())
+	tab.profileSelect.SetSelected(tab.controller.CurrentProfile())
+	tab.suppressProfileSelect = false
+	tab.profileSelect.Enable()
+	if len(tab.profileSelect.Options) > 1 {
+		tab.deleteProfileButton.Enable()
+	} else {
+		tab.deleteProfileButton.Disable()
+	}
+}
+
+// switchProfile switches to the profile in the background (stopping and restarting sing-box if needed)
+func (tab *CoreDashboardTab) switchProfile(name string) {
+	ac := tab.controller
+	tab.profileSelect.Disable()
+	go func() {
+		err := ac.SwitchProfile(name)
+		fyne.Do(func() {
+			if err != nil {
+				log.Printf("CoreDashboard: %v", err)
+				ShowError(mainWindow(), err)
+			}
+			tab.updateProfileList()
+		})
+	}()
+}
+
+// showNewProfileDialog asks for a profile name and creates the profile, optionally as a copy of the active one
+func (tab *CoreDashboardTab) showNewProfileDialog() {
+	ac := tab.controller
+	nameEntry := widget.NewEntry()
+	nameEntry.SetPlaceHolder("e.g. work")
+	nameEntry.Validator = core.ValidateProfileName
+	copyCheck := widget.NewCheck(fmt.Sprintf("Copy config of '%s'", ac.CurrentProfile()), nil)
+	copyCheck.SetChecked(true)
+	switchCheck := widget.NewCheck("Switch to the new profile", nil)
+	switchCheck.SetChecked(true)
+
+	items := []*widget.FormItem{
+		widget.NewFormItem("Name", nameEntry),
+		widget.NewFormItem("", copyCheck),
+		widget.NewFormItem("", switchCheck),
+	}
+	dialog.ShowForm("New profile", "Create", "Cancel", items, func(ok bool) {
+		if !ok {
+			return
+		}
+		profile, err := ac.CreateProfile(nameEntry.Text, copyCheck.Checked)
+		if err != nil {
+			ShowError(mainWindow(), err)
+			return
+		}
+		tab.updateProfileList()
+		if switchCheck.Checked {
+			tab.suppressProfileSelect = true
+			tab.profileSelect.SetSelected(profile.Name)
+			tab.suppressProfileSelect = false
+			tab.switchProfile(profile.Name)
+		}
+	}, mainWindow())
+}
+
+// showDeleteProfileDialog asks for another profile to delete (the active one cannot be deleted)
+func (tab *CoreDashboardTab) showDeleteProfileDialog() {
+	ac := tab.controller
+	active := ac.CurrentProfile()
+	var candidates []string
+	for _, name := range ac.Profiles.Names() {
+		if name != active && name != core.DefaultProfileName {
+			candidates = append(candidates, name)
+		}
+	}
+	if len(candidates) == 0 {
+		ShowInfo(mainWindow(), "Delete profile", fmt.Sprintf("There are no profiles to delete. The active profile ('%s') and the default profile cannot be deleted.", active))
+		return
+	}
+
+	profileSelect := widget.NewSelect(candidates, nil)
+	profileSelect.SetSelected(candidates[0])
+	items := []*widget.FormItem{widget.NewFormItem("Profile", profileSelect)}
+	dialog.ShowForm("Delete profile", "Delete", "Cancel", items, func(ok bool) {
+		if !ok || profileSelect.Selected == "" {
+			return
+		}
+		name := profileSelect.Selected
+		ShowConfirm(mainWindow(), "Delete profile", fmt.Sprintf("Delete profile '%s' with its config, backups and state?", name), func(confirmed bool) {
+			if !confirmed {
+				return
+			}
+			if err := ac.DeleteProfile(name); err != nil {
+				ShowError(mainWindow(), err)
+			}
+			tab.updateProfileList()
+		})
+	}, mainWindow())
+}
+
 // createVersionBlock creates a block with version (similar to wintun)
 func (tab *CoreDashboardTab) createVersionBlock() fyne.CanvasObject {
 	title := widget.NewLabel("Sing-box")
@@ -330,11 +510,14 @@ func (tab *CoreDashboardTab) createVersionBlock() fyne.CanvasObject {
 		tab.downloadProgress,
 	)
 
+	versionsButton := widget.NewButton("Versions", tab.showCoreVersionsDialog)
+
 	return container.NewHBox(
 		title,
 		layout.NewSpacer(),
 		tab.singboxStatusLabel,
 		tab.downloadContainer,
+		versionsButton,
 	)
 }
 
@@ -440,13 +623,13 @@ func (tab *CoreDashboardTab) updateBinaryStatus() {
 		tab.statusLabel.SetText("Core Status ❌ Error: sing-box not found")
 		tab.statusLabel.Importance = widget.MediumImportance // Текст всегда черный
 		// Обновляем иконку трея (красная при ошибке)
-		tab.controller.UpdateUI()
+		updateTrayIcon()
 		return
 	}
 	// Если бинарник найден, обновляем статус запуска
 	tab.updateRunningStatus()
 	// Обновляем иконку трея (может измениться с красной на черную/зеленую)
-	tab.controller.UpdateUI()
+	updateTrayIcon()
 }
 
 // updateRunningStatus обновляет статус Running/Stopped на основе RunningState
@@ -456,8 +639,11 @@ func (tab *CoreDashboardTab) updateRunningStatus() {
 
 	// Update status label based on state
 	restartInfo := ""
+	crashReason := tab.controller.LastCrashReason.Description()
 	if tab.controller.ConsecutiveCrashAttempts > 0 {
-		restartInfo = fmt.Sprintf(" [restart %d/%d]", tab.controller.ConsecutiveCrashAttempts, 3)
+		restartInfo = fmt.Sprintf(" [restart %d/%d: %s]", tab.controller.ConsecutiveCrashAttempts, tab.controller.CrashRestartLimit, crashReason)
+	} else if crashReason != "" && !buttonState.IsRunning {
+		restartInfo = fmt.Sprintf(" [crashed: %s]", crashReason)
 	}
 
 	if !buttonState.BinaryExists {
@@ -499,9 +685,7 @@ func (tab *CoreDashboardTab) updateRunningStatus() {
 // readConfigOnDemand reads config when user clicks on config label/title
 func (tab *CoreDashboardTab) readConfigOnDemand() {
 	// Обновляем информацию о конфиге в UI
-	if tab.controller.UpdateConfigStatusFunc != nil {
-		tab.controller.UpdateConfigStatusFunc()
-	}
+	tab.updateConfigInfo()
 
 	// Читаем конфиг
 	config, err := core.ExtractParserConfig(tab.controller.ConfigPath)
@@ -613,7 +797,7 @@ func (tab *CoreDashboardTab) updateVersionInfoAsync() {
 			} else {
 				// Показываем версию
 				tab.singboxStatusLabel.Importance = widget.MediumImportance
-				tab.setSingboxState(installedVersion, "", -1)
+				tab.setSingboxState(tab.installedVersionText(installedVersion), "", -1)
 			}
 		})
 
@@ -644,13 +828,33 @@ func (tab *CoreDashboardTab) updateVersionInfoAsync() {
 			tab.controller.CheckVersionInBackground()
 		}
 
+		// Предлагаемая версия может не принять текущий конфиг
+		breaking := 0
+		if tab.controller.CoreUpdateAvailable(installedVersion, latest) {
+			if report, err := tab.controller.CheckCoreCompatibility(latest); err == nil {
+				breaking = report.Breaking()
+			}
+		}
+
 		// Обновляем UI с результатом
 		fyne.Do(func() {
 			// Сравниваем версии, если есть кеш
-			if latest != "" && core.CompareVersions(installedVersion, latest) < 0 {
-				// Есть обновление
+			if tab.controller.CoreUpdateAvailable(installedVersion, latest) {
+				// Есть обновление (или закреплённая версия старше установленной)
 				tab.downloadButton.Importance = widget.HighImportance
-				tab.setSingboxState("", fmt.Sprintf("Update v%s", latest), -1)
+				action := "Update"
+				if core.CompareVersions(installedVersion, latest) > 0 {
+					action = "Switch to"
+				}
+				label := fmt.Sprintf("%s v%s", action, latest)
+				if breaking > 0 {
+					label += " ⚠️" // Новая версия не примет текущий конфиг без правок
+				}
+				tab.setSingboxState("", label, -1)
+			} else if until := tab.controller.GitHubRateLimitedUntil(); latest == "" && !until.IsZero() {
+				// Проверка версии упёрлась в лимит GitHub API
+				tab.setSingboxState(fmt.Sprintf("%s (update check rate limited until %s)",
+					tab.installedVersionText(installedVersion), until.Local().Format("15:04")), "", -1)
 			} else {
 				// Версия актуальна или кеша нет
 				tab.setSingboxState("", "", -1)
@@ -659,6 +863,31 @@ func (tab *CoreDashboardTab) updateVersionInfoAsync() {
 	}()
 }
 
+// installedVersionText returns the installed version with a warning if the binary was modified
+func (tab *CoreDashboardTab) installedVersionText(version string) string {
+	if tab.coreModified {
+		return version + " ⚠️ modified"
+	}
+	return version
+}
+
+// checkCoreIntegrity compares sing-box with the hash recorded at install time in the background
+// and warns on the dashboard if the binary was replaced or modified outside the launcher
+func (tab *CoreDashboardTab) checkCoreIntegrity() {
+	go func() {
+		integrity, err := tab.controller.CheckCoreIntegrity()
+		if err != nil || integrity != core.IntegrityModified {
+			return
+		}
+		fyne.Do(func() {
+			tab.coreModified = true
+			tab.updateVersionInfo()
+			ShowAutoHideInfo(application(), mainWindow(), "sing-box modified",
+				fmt.Sprintf("%s does not match the SHA-256 recorded when it was installed. Re-download it if you did not replace it yourself.", tab.controller.GetCoreBinaryPath()))
+		})
+	}()
+}
+
 func (tab *CoreDashboardTab) downloadConfigTemplate() {
 	configTemplateURL := GetTemplateURL()
 	if tab.templateDownloadButton != nil {
@@ -674,7 +903,7 @@ func (tab *CoreDashboardTab) downloadConfigTemplate() {
 				if tab.templateDownloadButton != nil {
 					tab.templateDownloadButton.Enable()
 				}
-				ShowError(tab.controller.MainWindow, fmt.Errorf("failed to create request: %w", err))
+				ShowError(mainWindow(), fmt.Errorf("failed to create request: %w", err))
 			})
 			return
 		}
@@ -685,7 +914,7 @@ func (tab *CoreDashboardTab) downloadConfigTemplate() {
 				if tab.templateDownloadButton != nil {
 					tab.templateDownloadButton.Enable()
 				}
-				ShowError(tab.controller.MainWindow, fmt.Errorf("failed to download template: %w", err))
+				ShowError(mainWindow(), fmt.Errorf("failed to download template: %w", err))
 			})
 			return
 		}
@@ -695,7 +924,7 @@ func (tab *CoreDashboardTab) downloadConfigTemplate() {
 				if tab.templateDownloadButton != nil {
 					tab.templateDownloadButton.Enable()
 				}
-				ShowError(tab.controller.MainWindow, fmt.Errorf("download template failed: %s", resp.Status))
+				ShowError(mainWindow(), fmt.Errorf("download template failed: %s", resp.Status))
 			})
 			return
 		}
@@ -705,7 +934,7 @@ func (tab *CoreDashboardTab) downloadConfigTemplate() {
 				if tab.templateDownloadButton != nil {
 					tab.templateDownloadButton.Enable()
 				}
-				ShowError(tab.controller.MainWindow, fmt.Errorf("failed to read template: %w", err))
+				ShowError(mainWindow(), fmt.Errorf("failed to read template: %w", err))
 			})
 			return
 		}
@@ -716,7 +945,7 @@ func (tab *CoreDashboardTab) downloadConfigTemplate() {
 				if tab.templateDownloadButton != nil {
 					tab.templateDownloadButton.Enable()
 				}
-				ShowError(tab.controller.MainWindow, fmt.Errorf("failed to create bin directory: %w", err))
+				ShowError(mainWindow(), fmt.Errorf("failed to create bin directory: %w", err))
 			})
 			return
 		}
@@ -725,7 +954,7 @@ func (tab *CoreDashboardTab) downloadConfigTemplate() {
 				if tab.templateDownloadButton != nil {
 					tab.templateDownloadButton.Enable()
 				}
-				ShowError(tab.controller.MainWindow, fmt.Errorf("failed to save template: %w", err))
+				ShowError(mainWindow(), fmt.Errorf("failed to save template: %w", err))
 			})
 			return
 		}
@@ -733,7 +962,7 @@ func (tab *CoreDashboardTab) downloadConfigTemplate() {
 			if tab.templateDownloadButton != nil {
 				tab.templateDownloadButton.Hide()
 			}
-			dialog.ShowInformation("Config Template", fmt.Sprintf("Template saved to %s", target), tab.controller.MainWindow)
+			dialog.ShowInformation("Config Template", fmt.Sprintf("Template saved to %s", target), mainWindow())
 			tab.updateConfigInfo()
 		})
 	}()
@@ -753,7 +982,7 @@ func (tab *CoreDashboardTab) handleDownload() {
 			latest, err := tab.controller.GetLatestCoreVersion()
 			fyne.Do(func() {
 				if err != nil {
-					ShowError(tab.controller.MainWindow, fmt.Errorf("failed to get latest version: %w", err))
+					ShowError(mainWindow(), fmt.Errorf("failed to get latest version: %w", err))
 					tab.downloadInProgress = false
 					tab.setSingboxState("", "Download", -1)
 					return
@@ -762,18 +991,19 @@ func (tab *CoreDashboardTab) handleDownload() {
 				if latest != "" && latest != core.FallbackVersion {
 					tab.controller.SetCachedVersion(latest)
 				}
-				tab.startDownloadWithVersion(latest)
+				tab.confirmCoreUpgrade(latest)
 			})
 		}()
 		return
 	}
 
 	// Запускаем скачивание с известной версией
-	tab.startDownloadWithVersion(targetVersion)
+	tab.confirmCoreUpgrade(targetVersion)
 }
 
 // startDownloadWithVersion запускает процесс скачивания с указанной версией
-func (tab *CoreDashboardTab) startDownloadWithVersion(targetVersion string) {
+// (allowUnverified - пользователь согласился установить архив без контрольной суммы)
+func (tab *CoreDashboardTab) startDownloadWithVersion(targetVersion string, allowUnverified bool) {
 	// Запускаем скачивание в отдельной горутине
 	tab.downloadInProgress = true
 	tab.downloadButton.Disable()
@@ -786,7 +1016,7 @@ func (tab *CoreDashboardTab) startDownloadWithVersion(targetVersion string) {
 	go func() {
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
 		defer cancel()
-		tab.controller.DownloadCore(ctx, targetVersion, progressChan)
+		tab.controller.DownloadCore(ctx, targetVersion, allowUnverified, progressChan)
 	}()
 
 	// Обрабатываем прогресс в отдельной горутине
@@ -799,22 +1029,46 @@ func (tab *CoreDashboardTab) startDownloadWithVersion(targetVersion string) {
 
 				if progress.Status == "done" {
 					tab.downloadInProgress = false
+					tab.coreModified = false // Хеш нового бинарника записан при установке
 					// Обновляем статусы после успешного скачивания (это уберет ошибки и обновит статус)
 					tab.updateVersionInfo()
 					tab.updateBinaryStatus() // Это вызовет updateRunningStatus() и обновит статус
 					// Обновляем иконку трея (может измениться с красной на черную/зеленую)
-					tab.controller.UpdateUI()
-					ShowInfo(tab.controller.MainWindow, "Download Complete", progress.Message)
+					updateTrayIcon()
+					ShowInfo(mainWindow(), "Download Complete", progress.Message)
+				} else if progress.Status == "retrying" {
+					tab.setSingboxState(progress.Message, "", progressValue)
 				} else if progress.Status == "error" {
 					tab.downloadInProgress = false
 					tab.setSingboxState("", "Download", -1)
-					ShowError(tab.controller.MainWindow, progress.Error)
+					tab.updateVersionInfo() // Возвращаем версию вместо сообщения о повторе
+					if errors.Is(progress.Error, core.ErrNoChecksum) && !allowUnverified {
+						tab.confirmUnverifiedDownload(targetVersion, progress.Error)
+						return
+					}
+					ShowError(mainWindow(), progress.Error)
 				}
 			})
 		}
 	}()
 }
 
+// confirmUnverifiedDownload asks whether to install an archive without a published checksum
+// (the SourceForge fallback when api.github.com is unreachable); nothing is downloaded until Install
+func (tab *CoreDashboardTab) confirmUnverifiedDownload(version string, err error) {
+	message := fmt.Sprintf("%v.\n\nThe archive cannot be verified: it may be corrupted or replaced by the mirror.\n"+
+		"Install sing-box v%s anyway? Cancel and try again when GitHub is reachable to install a verified copy.", err, version)
+	label := widget.NewLabel(message)
+	label.Wrapping = fyne.TextWrapWord
+	d := dialog.NewCustomConfirm("No checksum for sing-box v"+version, "Install", "Cancel", label, func(confirmed bool) {
+		if confirmed {
+			tab.startDownloadWithVersion(version, true)
+		}
+	}, mainWindow())
+	d.Resize(fyne.NewSize(480, 240))
+	d.Show()
+}
+
 // startAutoUpdate запускает автообновление версии (статус управляется через RunningState)
 func (tab *CoreDashboardTab) startAutoUpdate() {
 	// Запускаем периодическое обновление с умной логикой
@@ -948,11 +1202,13 @@ func (tab *CoreDashboardTab) handleWintunDownload() {
 				if progress.Status == "done" {
 					tab.wintunDownloadInProgress = false
 					tab.updateWintunStatus() // Обновляет статус и управляет кнопкой
-					ShowInfo(tab.controller.MainWindow, "Download Complete", progress.Message)
+					ShowInfo(mainWindow(), "Download Complete", progress.Message)
+				} else if progress.Status == "retrying" {
+					tab.setWintunState(progress.Message, "", progressValue)
 				} else if progress.Status == "error" {
 					tab.wintunDownloadInProgress = false
-					tab.setWintunState("", "Download wintun.dll", -1)
-					ShowError(tab.controller.MainWindow, progress.Error)
+					tab.setWintunState("❌ wintun.dll not found", "Download wintun.dll", -1)
+					ShowError(mainWindow(), progress.Error)
 				}
 			})
 		}
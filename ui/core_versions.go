@@ -0,0 +1,213 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+
+	"singbox-launcher/core"
+)
+
+// showCoreVersionsDialog lists the sing-box versions in bin/cores and lets the user switch between them or delete them
+func (tab *CoreDashboardTab) showCoreVersionsDialog() {
+	ac := tab.controller
+	list := container.NewVBox(widget.NewLabel("Loading..."))
+	hint := widget.NewLabel("Switching copies the version to " + ac.GetCoreBinaryPath() + ". sing-box must be stopped.")
+	hint.Wrapping = fyne.TextWrapWord
+
+	var refresh func()
+	refresh = func() {
+		go func() {
+			// Бинарник, установленный старой версией лаунчера, сохраняем в bin/cores, чтобы к нему можно было вернуться
+			if err := ac.ImportActiveCore(); err != nil {
+				log.Printf("showCoreVersionsDialog: %v", err)
+			}
+			cores, err := ac.InstalledCores()
+			fyne.Do(func() {
+				list.RemoveAll()
+				if err != nil {
+					list.Add(widget.NewLabel(err.Error()))
+					return
+				}
+				if len(cores) == 0 {
+					list.Add(widget.NewLabel("No versions installed. Use Download on the dashboard."))
+					return
+				}
+				for _, installed := range cores {
+					list.Add(tab.coreVersionRow(installed, refresh))
+				}
+			})
+		}()
+	}
+	refresh()
+
+	content := container.NewBorder(nil, hint, nil, nil, container.NewVScroll(list))
+	d := dialog.NewCustom("sing-box versions", "Close", content, mainWindow())
+	d.Resize(fyne.NewSize(460, 320))
+	d.Show()
+}
+
+// coreVersionRow shows one installed version with its Activate and Delete buttons
+func (tab *CoreDashboardTab) coreVersionRow(installed core.InstalledCore, refresh func()) fyne.CanvasObject {
+	ac := tab.controller
+	text := fmt.Sprintf("v%s  (%.1f MB, %s)", installed.Version, float64(installed.Size)/(1024*1024), installed.InstalledAt.Format("2006-01-02"))
+	if installed.Active {
+		text += "  ✅ active"
+	}
+	label := widget.NewLabel(text)
+
+	activate := func() {
+		if err := ac.ActivateCore(installed.Version); err != nil {
+			ShowError(mainWindow(), err)
+			return
+		}
+		refresh() // Dashboard is updated by CoreVersionChanged
+	}
+	activateButton := widget.NewButton("Activate", func() {
+		report, err := ac.CheckCoreCompatibility(installed.Version)
+		if err != nil {
+			log.Printf("coreVersionRow: %v", err)
+		}
+		if len(report.Config)+len(report.Template) == 0 {
+			activate()
+			return
+		}
+		content := container.NewVScroll(tab.compatSection(report))
+		d := dialog.NewCustomConfirm(fmt.Sprintf("Activate sing-box v%s", installed.Version), "Activate", "Cancel", content, func(confirmed bool) {
+			if confirmed {
+				activate()
+			}
+		}, mainWindow())
+		d.Resize(fyne.NewSize(560, 360))
+		d.Show()
+	})
+	deleteButton := widget.NewButton("Delete", func() {
+		ShowConfirm(mainWindow(), "Delete version", fmt.Sprintf("Delete sing-box v%s from %s?", installed.Version, ac.CoresDir()), func(confirmed bool) {
+			if !confirmed {
+				return
+			}
+			if err := ac.DeleteCore(installed.Version); err != nil {
+				ShowError(mainWindow(), err)
+			}
+			refresh()
+		})
+	})
+	if installed.Active {
+		activateButton.Disable()
+		deleteButton.Disable()
+	}
+	return container.NewHBox(label, layout.NewSpacer(), activateButton, deleteButton)
+}
+
+// confirmCoreUpgrade shows the release notes of version before replacing an installed sing-box.
+// A first install starts right away.
+func (tab *CoreDashboardTab) confirmCoreUpgrade(version string) {
+	ac := tab.controller
+	installed, err := ac.GetInstalledCoreVersion()
+	if err != nil || installed == version {
+		tab.startDownloadWithVersion(version, false)
+		return
+	}
+
+	tab.downloadButton.Disable()
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), core.NetworkRequestTimeout)
+		defer cancel()
+		release, err := ac.GetCoreRelease(ctx, version)
+		report, compatErr := ac.CheckCoreCompatibility(version)
+		if compatErr != nil {
+			log.Printf("confirmCoreUpgrade: %v", compatErr)
+		}
+		fyne.Do(func() {
+			tab.downloadButton.Enable()
+			notes := "Release notes are not available."
+			if err != nil {
+				log.Printf("confirmCoreUpgrade: %v", err)
+			} else if body := strings.TrimSpace(release.Body); body != "" {
+				notes = body
+			}
+			title := fmt.Sprintf("sing-box v%s → v%s", installed, version)
+			if release != nil && release.Prerelease {
+				title += " (pre-release)"
+			}
+
+			richText := widget.NewRichTextFromMarkdown(notes)
+			richText.Wrapping = fyne.TextWrapWord
+			content := container.NewVScroll(container.NewVBox(tab.compatSection(report), richText))
+			d := dialog.NewCustomConfirm(title, "Install", "Cancel", content, func(confirmed bool) {
+				if confirmed {
+					tab.startDownloadWithVersion(version, false)
+				}
+			}, mainWindow())
+			d.Resize(fyne.NewSize(560, 420))
+			d.Show()
+		})
+	}()
+}
+
+// compatSection lists the config constructs that sing-box report.Version deprecates or no longer accepts,
+// with a button for the mechanical fixes of config.json. Empty when the config is compatible.
+func (tab *CoreDashboardTab) compatSection(report core.CompatReport) fyne.CanvasObject {
+	ac := tab.controller
+	box := container.NewVBox()
+	var fill func(report core.CompatReport)
+	fill = func(report core.CompatReport) {
+		box.RemoveAll()
+		if len(report.Config)+len(report.Template) == 0 {
+			return
+		}
+		title := fmt.Sprintf("⚠️ Config compatibility with v%s", report.Version)
+		if breaking := report.Breaking(); breaking > 0 {
+			title += fmt.Sprintf(": %d breaking", breaking)
+		}
+		heading := widget.NewLabel(title)
+		heading.TextStyle.Bold = true
+		box.Add(heading)
+
+		var lines []string
+		for _, issue := range report.Config {
+			line := "config.json: " + issue.String()
+			if issue.Fixable {
+				line += " (auto-fix)"
+			}
+			lines = append(lines, "• "+line)
+		}
+		for _, issue := range report.Template {
+			lines = append(lines, "• "+core.ConfigTemplateFileName()+": "+issue.String())
+		}
+		list := widget.NewLabel(strings.Join(lines, "\n"))
+		list.Wrapping = fyne.TextWrapWord
+		box.Add(list)
+
+		if fixable := report.Fixable(); fixable > 0 {
+			box.Add(widget.NewButton(fmt.Sprintf("Fix config.json (%d)", fixable), func() {
+				fixed, err := ac.FixCoreCompatibility(report.Version)
+				if err != nil {
+					ShowError(mainWindow(), err)
+					return
+				}
+				ShowAutoHideInfo(application(), mainWindow(), "Config", fmt.Sprintf("Fixed %d constructs, the previous config is saved as config.json.old", len(fixed)))
+				updated, err := ac.CheckCoreCompatibility(report.Version)
+				if err != nil {
+					log.Printf("compatSection: %v", err)
+				}
+				fill(updated)
+			}))
+		}
+		if len(report.Template) > 0 {
+			hint := widget.NewLabel("The template is not changed automatically: download a new one on the Core tab.")
+			hint.Wrapping = fyne.TextWrapWord
+			box.Add(hint)
+		}
+		box.Add(widget.NewSeparator())
+	}
+	fill(report)
+	return box
+}
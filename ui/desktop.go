@@ -0,0 +1,354 @@
+package ui
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/app"
+	"fyne.io/fyne/v2/driver/desktop"
+
+	"singbox-launcher/core"
+)
+
+// Desktop owns everything Fyne-specific of the launcher: the application, the main window,
+// the icons and the system tray. core only provides the data shown there (see core.TrayMenu).
+type Desktop struct {
+	App    fyne.App
+	Window fyne.Window
+
+	AppIcon   fyne.Resource
+	GreyIcon  fyne.Resource
+	GreenIcon fyne.Resource
+	RedIcon   fyne.Resource // Icon for error state
+
+	controller *core.AppController
+
+	// --- Tray menu update protection ---
+	trayMenuMutex            sync.Mutex  // Protects the two fields below
+	trayMenuUpdateInProgress bool        // Flag to prevent multiple simultaneous menu updates
+	trayMenuUpdateTimer      *time.Timer // Timer for debouncing menu updates
+}
+
+// currentDesktop is the running GUI; tabs and dialogs reach the main window through mainWindow()
+var currentDesktop *Desktop
+
+// NewDesktop creates the Fyne application and the (hidden) main window for controller
+func NewDesktop(controller *core.AppController, appIconData, greyIconData, greenIconData, redIconData []byte) *Desktop {
+	d := &Desktop{
+		controller: controller,
+		AppIcon:    fyne.NewStaticResource("appIcon", appIconData),
+		GreyIcon:   fyne.NewStaticResource("trayIcon", greyIconData),
+		GreenIcon:  fyne.NewStaticResource("runningIcon", greenIconData),
+		RedIcon:    fyne.NewStaticResource("errorIcon", redIconData),
+	}
+	d.App = app.NewWithID("com.singbox.launcher")
+	d.App.SetIcon(d.AppIcon)
+	d.Window = d.App.NewWindow("Singbox Launcher")
+	d.Window.SetIcon(d.AppIcon)
+	currentDesktop = d
+	return d
+}
+
+// mainWindow returns the main window (nil before NewDesktop)
+func mainWindow() fyne.Window {
+	if currentDesktop == nil {
+		return nil
+	}
+	return currentDesktop.Window
+}
+
+// application returns the Fyne application (nil before NewDesktop)
+func application() fyne.App {
+	if currentDesktop == nil {
+		return nil
+	}
+	return currentDesktop.App
+}
+
+// Quit stops sing-box, restores the system proxy and ends the Fyne event loop
+func (d *Desktop) Quit() {
+	d.trayMenuMutex.Lock()
+	if d.trayMenuUpdateTimer != nil {
+		d.trayMenuUpdateTimer.Stop()
+		d.trayMenuUpdateTimer = nil
+	}
+	d.trayMenuMutex.Unlock()
+
+	d.controller.GracefulExit()
+	d.App.Quit()
+}
+
+// quitApp quits the running GUI (used by dialogs that end the launcher)
+func quitApp(ac *core.AppController) {
+	if currentDesktop == nil {
+		ac.GracefulExit()
+		return
+	}
+	currentDesktop.Quit()
+}
+
+// SetupTray shows the tray icon and menu and rebuilds them on events that change what they show.
+// Returns false when the platform has no system tray.
+func (d *Desktop) SetupTray() bool {
+	desk, ok := d.App.(desktop.App)
+	if !ok {
+		return false
+	}
+	log.Println("System tray: Desktop platform detected, initializing...")
+	// Rebuild the tray menu (and icon) on events that change what it shows
+	d.controller.Events.SubscribeEvents(core.TrayMenuEvents, func(e core.Event) {
+		if !core.TrayMenuStale(e) {
+			return
+		}
+		d.UpdateTrayIcon()
+		d.updateTrayMenu(desk)
+	})
+
+	// Initialize system tray immediately (required on macOS, works on Windows too)
+	// On macOS, system tray must be initialized BEFORE app.Run() to work properly
+	log.Println("System tray: Setting icon...")
+	desk.SetSystemTrayIcon(d.GreyIcon)
+	log.Println("System tray: Creating initial menu...")
+	desk.SetSystemTrayMenu(d.createTrayMenu())
+	log.Println("System tray: Icon and menu initialized successfully")
+	return true
+}
+
+// UpdateTrayIcon sets the tray icon for the current state: green while running, red without the sing-box binary, grey otherwise.
+func (d *Desktop) UpdateTrayIcon() {
+	fyne.Do(func() {
+		desk, ok := d.App.(desktop.App)
+		if !ok {
+			return
+		}
+		switch d.controller.TrayIconState() {
+		case core.TrayIconRunning:
+			desk.SetSystemTrayIcon(d.GreenIcon)
+		case core.TrayIconError:
+			desk.SetSystemTrayIcon(d.RedIcon)
+		default:
+			desk.SetSystemTrayIcon(d.GreyIcon)
+		}
+	})
+}
+
+// updateTrayIcon updates the tray icon of the running GUI (no-op in tests)
+func updateTrayIcon() {
+	if currentDesktop != nil {
+		currentDesktop.UpdateTrayIcon()
+	}
+}
+
+// updateTrayMenu rebuilds the tray menu with a debounce to prevent "Invalid menu handle" errors
+// when menu updates happen too quickly
+func (d *Desktop) updateTrayMenu(desk desktop.App) {
+	d.trayMenuMutex.Lock()
+	defer d.trayMenuMutex.Unlock()
+
+	// Cancel previous timer if it exists
+	if d.trayMenuUpdateTimer != nil {
+		d.trayMenuUpdateTimer.Stop()
+	}
+
+	// Dynamic delay formula:
+	// - Base delay: 100ms for small menus (0-10 proxies)
+	// - For each proxy above 10, add 20ms
+	// - Maximum delay: 500ms to ensure systray has enough time for large menus
+	delay := 100 * time.Millisecond
+	if proxyCount := d.controller.TrayMenuItemCount(); proxyCount > 10 {
+		delay += time.Duration(proxyCount-10) * 20 * time.Millisecond
+		if delay > 500*time.Millisecond {
+			delay = 500 * time.Millisecond
+		}
+	}
+
+	// Create new timer with dynamic debounce delay
+	// This prevents rapid successive menu updates that cause systray errors
+	d.trayMenuUpdateTimer = time.AfterFunc(delay, func() {
+		// Check if update is already in progress
+		d.trayMenuMutex.Lock()
+		if d.trayMenuUpdateInProgress {
+			d.trayMenuMutex.Unlock()
+			return // Skip update if already in progress
+		}
+		d.trayMenuUpdateInProgress = true
+		d.trayMenuMutex.Unlock()
+
+		fyne.Do(func() {
+			defer func() {
+				// Reset flag after update completes
+				d.trayMenuMutex.Lock()
+				d.trayMenuUpdateInProgress = false
+				d.trayMenuUpdateTimer = nil
+				d.trayMenuMutex.Unlock()
+			}()
+
+			menu := d.createTrayMenu()
+			// Use recover to handle any panics during menu update
+			func() {
+				defer func() {
+					if r := recover(); r != nil {
+						log.Printf("updateTrayMenu: Recovered from panic: %v", r)
+					}
+				}()
+				desk.SetSystemTrayMenu(menu)
+			}()
+		})
+	})
+}
+
+// createTrayMenu creates the system tray menu with a proxy selection submenu per selector/urltest group
+func (d *Desktop) createTrayMenu() *fyne.Menu {
+	ac := d.controller
+	state := ac.TrayMenu()
+
+	// Create main menu items
+	menuItems := []*fyne.MenuItem{
+		fyne.NewMenuItem("Open", func() { d.Window.Show() }),
+	}
+	// Current speed (system tray has no tooltip API, so it is shown as a disabled item)
+	if state.Traffic != "" {
+		trafficItem := fyne.NewMenuItem(state.Traffic, nil)
+		trafficItem.Disabled = true
+		menuItems = append(menuItems, trafficItem)
+	}
+	menuItems = append(menuItems, fyne.NewMenuItemSeparator())
+
+	// Add Start/Stop VPN buttons based on centralized state
+	startItem := fyne.NewMenuItem("Start VPN", func() { core.StartSingBoxProcess(ac) })
+	startItem.Disabled = !state.Buttons.StartEnabled
+	stopItem := fyne.NewMenuItem("Stop VPN", func() { core.StopSingBoxProcess(ac) })
+	stopItem.Disabled = !state.Buttons.StopEnabled
+	menuItems = append(menuItems, startItem, stopItem, fyne.NewMenuItemSeparator())
+
+	// Add profile submenu (radio items) when there is more than one configuration profile
+	if len(state.Profiles) > 0 {
+		profileItems := make([]*fyne.MenuItem, 0, len(state.Profiles))
+		for _, name := range state.Profiles {
+			name := name
+			profileItem := fyne.NewMenuItem(name, func() {
+				go func() {
+					if err := ac.SwitchProfile(name); err != nil {
+						log.Printf("createTrayMenu: %v", err)
+						ac.Notifier.ShowError(err)
+					}
+				}()
+			})
+			profileItem.Checked = name == state.ActiveProfile
+			profileItems = append(profileItems, profileItem)
+		}
+		profileItem := fyne.NewMenuItem("Profile: "+state.ActiveProfile, nil)
+		profileItem.ChildMenu = fyne.NewMenu("Profile", profileItems...)
+		menuItems = append(menuItems, profileItem, fyne.NewMenuItemSeparator())
+	}
+
+	// Add Clash mode submenu (radio items) when the running config offers several modes
+	clashItems := 0
+	if state.ShowModes {
+		modeItems := make([]*fyne.MenuItem, 0, len(state.Modes.Modes))
+		for _, mode := range state.Modes.Modes {
+			mode := mode
+			modeItem := fyne.NewMenuItem(mode, func() {
+				go func() {
+					if err := ac.SetClashMode(context.Background(), mode); err != nil {
+						log.Printf("createTrayMenu: %v", err)
+						ac.Notifier.ShowError(err)
+					}
+				}()
+			})
+			modeItem.Checked = strings.EqualFold(mode, state.Modes.Mode)
+			modeItems = append(modeItems, modeItem)
+		}
+		modeItem := fyne.NewMenuItem("Mode: "+state.Modes.Mode, nil)
+		modeItem.ChildMenu = fyne.NewMenu("Mode", modeItems...)
+		menuItems = append(menuItems, modeItem)
+		clashItems++
+	}
+
+	// Add group submenus if Clash API is enabled
+	for _, group := range state.Groups {
+		menuItems = append(menuItems, d.createTrayGroupItem(group, state.Latency))
+		clashItems++
+	}
+	if state.NoProxies {
+		// Show disabled item if no proxies available
+		disabledItem := fyne.NewMenuItem("No proxies available", nil)
+		disabledItem.Disabled = true
+		selectProxyItem := fyne.NewMenuItem("Select Proxy", nil)
+		selectProxyItem.ChildMenu = fyne.NewMenu("Select Proxy", disabledItem)
+		menuItems = append(menuItems, selectProxyItem)
+		clashItems++
+	}
+	if clashItems > 0 {
+		menuItems = append(menuItems, fyne.NewMenuItemSeparator())
+	}
+
+	// Add Quit item
+	menuItems = append(menuItems, fyne.NewMenuItem("Quit", d.Quit))
+
+	return fyne.NewMenu("Singbox Launcher", menuItems...)
+}
+
+// createTrayGroupItem creates the tray item of a group with a submenu of its members.
+// Selector members switch the group; urltest members are informational (the core picks the node).
+func (d *Desktop) createTrayGroupItem(group core.TrayGroup, latencySettings core.LatencyTestSettings) *fyne.MenuItem {
+	ac := d.controller
+	members := core.VisibleProxies(group.Members, latencySettings.SortByLatency, latencySettings.HideTimedOut, group.Now)
+	groupName := group.Name
+
+	var items []*fyne.MenuItem
+	for _, member := range members {
+		proxyName := member.Name
+		item := fyne.NewMenuItem(proxyName, nil)
+		if group.Switchable() {
+			item.Action = func() {
+				go func() {
+					// Subscribers of ProxySwitched refresh the Servers tab if it shows this group
+					if err := ac.SwitchGroupProxy(context.Background(), groupName, proxyName); err != nil {
+						log.Printf("createTrayMenu: Failed to switch proxy: %v", err)
+						ac.Notifier.ShowError(err)
+					}
+				}()
+			}
+		} else {
+			item.Disabled = true
+		}
+		// Mark current member with checkmark
+		if proxyName == group.Now {
+			item.Label = "✓ " + proxyName
+		}
+		if delayText := core.FormatDelay(member.Delay); delayText != "" {
+			item.Label += " · " + delayText
+		}
+		items = append(items, item)
+	}
+	if len(items) == 0 {
+		emptyItem := fyne.NewMenuItem("No proxies available", nil)
+		emptyItem.Disabled = true
+		items = append(items, emptyItem)
+	}
+
+	// Latency test and view options
+	testItem := fyne.NewMenuItem("Test group", func() { ac.TestTrayGroup(groupName) })
+	sortItem := fyne.NewMenuItem("Sort by latency", func() {
+		ac.UpdateLatencyView(!latencySettings.SortByLatency, latencySettings.HideTimedOut)
+	})
+	sortItem.Checked = latencySettings.SortByLatency
+	hideItem := fyne.NewMenuItem("Hide timed-out", func() {
+		ac.UpdateLatencyView(latencySettings.SortByLatency, !latencySettings.HideTimedOut)
+	})
+	hideItem.Checked = latencySettings.HideTimedOut
+	items = append(items, fyne.NewMenuItemSeparator(), testItem, sortItem, hideItem)
+
+	label := group.Name
+	if group.Now != "" {
+		label += ": " + group.Now
+	}
+	groupItem := fyne.NewMenuItem(label, nil)
+	groupItem.ChildMenu = fyne.NewMenu(group.Name, items...)
+	return groupItem
+}
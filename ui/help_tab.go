@@ -21,7 +21,7 @@ func CreateHelpTab(ac *core.AppController) fyne.CanvasObject {
 		logsDir := platform.GetLogsDir(ac.ExecDir)
 		if err := platform.OpenFolder(logsDir); err != nil {
 			log.Printf("toolsTab: Failed to open logs folder: %v", err)
-			ShowError(ac.MainWindow, err)
+			ShowError(mainWindow(), err)
 		}
 	})
 
@@ -29,7 +29,7 @@ func CreateHelpTab(ac *core.AppController) fyne.CanvasObject {
 		binDir := platform.GetBinDir(ac.ExecDir)
 		if err := platform.OpenFolder(binDir); err != nil {
 			log.Printf("toolsTab: Failed to open config folder: %v", err)
-			ShowError(ac.MainWindow, err)
+			ShowError(mainWindow(), err)
 		}
 	})
 	killButton := widget.NewButton("🛑 Kill Sing-Box", func() {
@@ -37,7 +37,7 @@ func CreateHelpTab(ac *core.AppController) fyne.CanvasObject {
 			processName := platform.GetProcessNameForCheck()
 			_ = platform.KillProcess(processName)
 			fyne.Do(func() {
-				ShowAutoHideInfo(ac.Application, ac.MainWindow, "Kill", "Sing-Box killed if running.")
+				ShowAutoHideInfo(application(), mainWindow(), "Kill", "Sing-Box killed if running.")
 				ac.RunningState.Set(false)
 			})
 		}()
@@ -52,6 +52,12 @@ func CreateHelpTab(ac *core.AppController) fyne.CanvasObject {
 	launcherUpdateLabel.Alignment = fyne.TextAlignCenter
 	launcherUpdateLabel.Wrapping = fyne.TextWrapWord
 
+	// Self-update: downloads the release for this platform, verifies it and restarts the launcher
+	var launcherUpdateButton *widget.Button
+	launcherUpdateButton = widget.NewButton("⬆️ Update Launcher", func() {
+		checkLauncherUpdate(ac, launcherUpdateButton)
+	})
+
 	// Update launcher version info
 	updateLauncherVersionInfo := func() {
 		latest := ac.GetCachedLauncherVersion()
@@ -102,7 +108,7 @@ func CreateHelpTab(ac *core.AppController) fyne.CanvasObject {
 	telegramLink.OnTapped = func() {
 		if err := platform.OpenURL("https://t.me/singbox_launcher"); err != nil {
 			log.Printf("toolsTab: Failed to open Telegram link: %v", err)
-			ShowError(ac.MainWindow, err)
+			ShowError(mainWindow(), err)
 		}
 	}
 
@@ -111,7 +117,7 @@ func CreateHelpTab(ac *core.AppController) fyne.CanvasObject {
 	githubLink.OnTapped = func() {
 		if err := platform.OpenURL("https://github.com/Leadaxe/singbox-launcher"); err != nil {
 			log.Printf("toolsTab: Failed to open GitHub link: %v", err)
-			ShowError(ac.MainWindow, err)
+			ShowError(mainWindow(), err)
 		}
 	}
 
@@ -122,6 +128,7 @@ func CreateHelpTab(ac *core.AppController) fyne.CanvasObject {
 		widget.NewSeparator(),
 		versionLabel,
 		launcherUpdateLabel,
+		launcherUpdateButton,
 		widget.NewSeparator(),
 		container.NewHBox(
 			telegramLink,
@@ -0,0 +1,104 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"singbox-launcher/core"
+	"singbox-launcher/internal/constants"
+)
+
+// checkLauncherUpdate looks for a newer launcher release and offers to install it
+func checkLauncherUpdate(ac *core.AppController, button *widget.Button) {
+	button.Disable()
+	go func() {
+		updater, err := ac.NewLauncherUpdater()
+		var update *core.LauncherUpdate
+		if err == nil {
+			update, err = updater.Check()
+		}
+		fyne.Do(func() {
+			button.Enable()
+			switch {
+			case err != nil:
+				log.Printf("checkLauncherUpdate: %v", err)
+				ShowError(mainWindow(), err)
+			case update == nil:
+				ShowInfo(mainWindow(), "Launcher Update", fmt.Sprintf("You are using the latest version (%s).", constants.AppVersion))
+			default:
+				confirmLauncherUpdate(ac, updater, update)
+			}
+		})
+	}()
+}
+
+// confirmLauncherUpdate shows the release notes of update and installs it on confirmation
+func confirmLauncherUpdate(ac *core.AppController, updater *core.LauncherUpdater, update *core.LauncherUpdate) {
+	notes := strings.TrimSpace(update.Release.Body)
+	if notes == "" {
+		notes = "Release notes are not available."
+	}
+	title := fmt.Sprintf("Launcher %s → %s", constants.AppVersion, update.Version)
+	if update.Release.Prerelease {
+		title += " (pre-release)"
+	}
+
+	richText := widget.NewRichTextFromMarkdown(notes)
+	richText.Wrapping = fyne.TextWrapWord
+	content := container.NewVScroll(container.NewVBox(
+		widget.NewLabel(fmt.Sprintf("The launcher will restart after installing %s.", update.Asset.Name)),
+		richText,
+	))
+	d := dialog.NewCustomConfirm(title, "Install", "Cancel", content, func(confirmed bool) {
+		if confirmed {
+			installLauncherUpdate(ac, updater, update)
+		}
+	}, mainWindow())
+	d.Resize(fyne.NewSize(560, 420))
+	d.Show()
+}
+
+// installLauncherUpdate downloads and installs update with a progress dialog, then the launcher exits
+// so that the new version can take over (its watchdog rolls back a failed start)
+func installLauncherUpdate(ac *core.AppController, updater *core.LauncherUpdater, update *core.LauncherUpdate) {
+	progressBar := widget.NewProgressBar()
+	statusLabel := widget.NewLabel("Starting download...")
+	progressDialog := dialog.NewCustomWithoutButtons("Updating launcher to "+update.Version,
+		container.NewVBox(statusLabel, progressBar), mainWindow())
+	progressDialog.Resize(fyne.NewSize(420, 120))
+	progressDialog.Show()
+
+	progressChan := make(chan core.DownloadProgress, 10)
+	go func() {
+		for progress := range progressChan {
+			fyne.Do(func() {
+				progressBar.SetValue(float64(progress.Progress) / 100.0)
+				statusLabel.SetText(progress.Message)
+			})
+		}
+	}()
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+		defer cancel()
+		err := ac.UpdateLauncher(ctx, updater, update, progressChan)
+		close(progressChan)
+		fyne.Do(func() {
+			progressDialog.Hide()
+			if err != nil {
+				log.Printf("installLauncherUpdate: %v", err)
+				ShowError(mainWindow(), fmt.Errorf("launcher update failed: %w", err))
+				return
+			}
+			quitApp(ac)
+		})
+	}()
+}
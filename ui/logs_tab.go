@@ -0,0 +1,271 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"singbox-launcher/core"
+)
+
+// logsPollInterval is how often the selected log file is checked for new lines
+const logsPollInterval = time.Second
+
+// LogsTab управляет вкладкой Logs (просмотр логов в реальном времени)
+type LogsTab struct {
+	controller *core.AppController
+
+	// UI elements
+	sourceSelect *widget.Select
+	levelGroup   *widget.CheckGroup
+	searchEntry  *widget.Entry
+	regexCheck   *widget.Check
+	followCheck  *widget.Check
+	pauseCheck   *widget.Check
+	list         *widget.List
+	statusLabel  *widget.Label
+
+	// Data
+	sources     []core.LogSource
+	feed        core.LogFeed
+	stopFeed    context.CancelFunc
+	visible     []core.LogEntry // filtered entries shown in the list (UI thread only)
+	selectedRow int
+}
+
+// CreateLogsTab creates and returns the content for the "Logs" tab.
+func CreateLogsTab(ac *core.AppController) fyne.CanvasObject {
+	tab := &LogsTab{
+		controller:  ac,
+		sources:     ac.LogSources(),
+		selectedRow: -1,
+	}
+
+	sourceNames := make([]string, 0, len(tab.sources))
+	for _, source := range tab.sources {
+		sourceNames = append(sourceNames, source.Name)
+	}
+	tab.sourceSelect = widget.NewSelect(sourceNames, func(name string) {
+		tab.switchSource(name)
+	})
+
+	tab.levelGroup = widget.NewCheckGroup(core.LogLevels, func([]string) { tab.refresh() })
+	tab.levelGroup.Horizontal = true
+	tab.levelGroup.SetSelected(core.LogLevels)
+
+	tab.searchEntry = widget.NewEntry()
+	tab.searchEntry.SetPlaceHolder("Search...")
+	tab.searchEntry.OnChanged = func(string) { tab.refresh() }
+	tab.regexCheck = widget.NewCheck("Regex", func(bool) { tab.refresh() })
+
+	tab.followCheck = widget.NewCheck("Follow", func(follow bool) {
+		if follow && len(tab.visible) > 0 {
+			tab.list.ScrollToBottom()
+		}
+	})
+	tab.followCheck.SetChecked(true)
+	tab.pauseCheck = widget.NewCheck("Pause", func(paused bool) {
+		if !paused {
+			tab.refresh()
+		}
+	})
+
+	tab.statusLabel = widget.NewLabel("")
+
+	tab.list = widget.NewList(
+		func() int { return len(tab.visible) },
+		func() fyne.CanvasObject {
+			label := widget.NewLabel("")
+			label.TextStyle = fyne.TextStyle{Monospace: true}
+			label.Truncation = fyne.TextTruncateEllipsis
+			return label
+		},
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			if id < 0 || id >= len(tab.visible) {
+				return
+			}
+			entry := tab.visible[id]
+			label := obj.(*widget.Label)
+			label.Importance = logLevelImportance(entry.Level)
+			label.SetText(formatLogEntry(entry))
+		},
+	)
+	tab.list.OnSelected = func(id widget.ListItemID) { tab.selectedRow = id }
+	tab.list.OnUnselected = func(widget.ListItemID) { tab.selectedRow = -1 }
+
+	copyButton := widget.NewButton("Copy", tab.copySelection)
+	exportButton := widget.NewButton("Export...", tab.exportVisible)
+	clearButton := widget.NewButton("Clear", func() {
+		if tab.feed != nil {
+			tab.feed.Buffer().Clear()
+		}
+		tab.refresh()
+	})
+
+	filterRow := container.NewBorder(nil, nil,
+		container.NewHBox(tab.sourceSelect, tab.levelGroup),
+		tab.regexCheck,
+		tab.searchEntry,
+	)
+	actionsRow := container.NewBorder(nil, nil,
+		container.NewHBox(tab.followCheck, tab.pauseCheck),
+		container.NewHBox(copyButton, exportButton, clearButton),
+		tab.statusLabel,
+	)
+
+	if len(sourceNames) > 0 {
+		tab.sourceSelect.SetSelected(sourceNames[0])
+	}
+
+	return container.NewBorder(container.NewVBox(filterRow, actionsRow), nil, nil, nil, tab.list)
+}
+
+// switchSource stops following the previous log and starts following the selected one
+func (tab *LogsTab) switchSource(name string) {
+	if tab.stopFeed != nil {
+		tab.stopFeed()
+		tab.stopFeed = nil
+	}
+	for _, source := range tab.sources {
+		if source.Name != name {
+			continue
+		}
+		feed := tab.controller.NewLogFeed(source)
+		ctx, cancel := context.WithCancel(context.Background())
+		tab.feed = feed
+		tab.stopFeed = cancel
+		go feed.Run(ctx, logsPollInterval, func() {
+			fyne.Do(func() {
+				// Ignore updates from a feed that was replaced meanwhile
+				if tab.feed == feed {
+					tab.refresh()
+				}
+			})
+		})
+		break
+	}
+	tab.selectedRow = -1
+	tab.list.UnselectAll()
+	tab.refresh()
+}
+
+// refresh re-applies filters to the buffer and updates the list (UI thread only)
+func (tab *LogsTab) refresh() {
+	if tab.list == nil || tab.statusLabel == nil {
+		return // widgets are still being created
+	}
+	if tab.pauseCheck.Checked {
+		return
+	}
+	var entries []core.LogEntry
+	if tab.feed != nil {
+		entries = tab.feed.Buffer().Snapshot()
+	}
+
+	levels := make(map[string]bool, len(core.LogLevels))
+	for _, level := range tab.levelGroup.Selected {
+		levels[level] = true
+	}
+	filter := core.LogFilter{Levels: levels, Query: tab.searchEntry.Text, Regex: tab.regexCheck.Checked}
+	match, err := filter.Matcher()
+	if err != nil {
+		tab.statusLabel.SetText(err.Error())
+		return
+	}
+
+	tab.visible = core.FilterLogEntries(entries, match)
+	tab.statusLabel.SetText(fmt.Sprintf("Showing %d of %d lines", len(tab.visible), len(entries)))
+	tab.list.Refresh()
+	if tab.followCheck.Checked && len(tab.visible) > 0 {
+		tab.list.ScrollToBottom()
+	}
+}
+
+// copySelection copies the selected line, or all visible lines if nothing is selected
+func (tab *LogsTab) copySelection() {
+	text := ""
+	if tab.selectedRow >= 0 && tab.selectedRow < len(tab.visible) {
+		text = tab.visible[tab.selectedRow].Raw
+	} else {
+		text = joinLogEntries(tab.visible)
+	}
+	if text == "" {
+		return
+	}
+	mainWindow().Clipboard().SetContent(text)
+	tab.statusLabel.SetText("Copied to clipboard")
+}
+
+// exportVisible saves the visible (filtered) lines to a file chosen by the user
+func (tab *LogsTab) exportVisible() {
+	content := joinLogEntries(tab.visible)
+	if content == "" {
+		ShowInfo(mainWindow(), "Export", "Nothing to export")
+		return
+	}
+	saveDialog := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+		if err != nil {
+			ShowError(mainWindow(), err)
+			return
+		}
+		if writer == nil {
+			return // cancelled
+		}
+		defer writer.Close()
+		if _, err := writer.Write([]byte(content)); err != nil {
+			ShowError(mainWindow(), fmt.Errorf("failed to export logs: %w", err))
+			return
+		}
+		tab.statusLabel.SetText("Exported to " + writer.URI().Path())
+	}, mainWindow())
+	saveDialog.SetFileName(fmt.Sprintf("%s-%s.log", strings.TrimSuffix(tab.sourceSelect.Selected, ".log"), time.Now().Format("20060102-150405")))
+	saveDialog.Show()
+}
+
+// formatLogEntry renders an entry as a single list row
+func formatLogEntry(entry core.LogEntry) string {
+	var b strings.Builder
+	if entry.Time != "" {
+		b.WriteString(entry.Time)
+		b.WriteString(" ")
+	}
+	b.WriteString(fmt.Sprintf("%-5s ", entry.Level))
+	if entry.Component != "" {
+		b.WriteString(entry.Component)
+		b.WriteString(": ")
+	}
+	b.WriteString(entry.Message)
+	return b.String()
+}
+
+// joinLogEntries joins raw lines of entries for copy/export
+func joinLogEntries(entries []core.LogEntry) string {
+	if len(entries) == 0 {
+		return ""
+	}
+	lines := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		lines = append(lines, entry.Raw)
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// logLevelImportance maps a log level to label importance (color)
+func logLevelImportance(level string) widget.Importance {
+	switch level {
+	case core.LogLevelError:
+		return widget.DangerImportance
+	case core.LogLevelWarn:
+		return widget.WarningImportance
+	case core.LogLevelDebug:
+		return widget.LowImportance
+	default:
+		return widget.MediumImportance
+	}
+}
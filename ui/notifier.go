@@ -0,0 +1,74 @@
+package ui
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"singbox-launcher/core"
+)
+
+// Notifier is the GUI implementation of core.Notifier: messages become dialogs in the main window.
+// Progress and state changes are not handled here: tabs subscribe to AppController.Events (see onEvent).
+type Notifier struct {
+	controller *core.AppController
+}
+
+// NewNotifier creates a notifier showing dialogs over the main window
+func NewNotifier(controller *core.AppController) *Notifier {
+	return &Notifier{controller: controller}
+}
+
+// onEvent subscribes handler to events of type E; the handler runs on the Fyne UI thread
+func onEvent[E core.Event](ac *core.AppController, handler func(E), opts ...core.SubscribeOption) {
+	core.Subscribe(ac.Events, func(e E) {
+		fyne.Do(func() { handler(e) })
+	}, opts...)
+}
+
+// onEvents subscribes one handler to several event types; the handler runs on the Fyne UI thread
+func onEvents(ac *core.AppController, events []core.Event, handler func(core.Event), opts ...core.SubscribeOption) {
+	ac.Events.SubscribeEvents(events, func(e core.Event) {
+		fyne.Do(func() { handler(e) })
+	}, opts...)
+}
+
+func (n *Notifier) ShowError(err error) {
+	ShowError(mainWindow(), err)
+}
+
+func (n *Notifier) ShowInfo(title, message string) {
+	ShowInfo(mainWindow(), title, message)
+}
+
+func (n *Notifier) ShowAutoHideInfo(title, message string) {
+	ShowAutoHideInfo(application(), mainWindow(), title, message)
+}
+
+func (n *Notifier) ShowCoreAlreadyRunning(kill func()) {
+	label := widget.NewLabel("Sing-Box appears to be already running.\nWould you like to kill the existing process?")
+	killButton := widget.NewButton("Kill Process", nil)
+	closeButton := widget.NewButton("Close This Warning", nil)
+	content := container.NewVBox(label, killButton, closeButton)
+	var d dialog.Dialog
+	d = dialog.NewCustomWithoutButtons("Warning", content, mainWindow())
+	killButton.OnTapped = func() {
+		go kill()
+		fyne.Do(func() { d.Hide() })
+	}
+	closeButton.OnTapped = func() { fyne.Do(func() { d.Hide() }) }
+	fyne.Do(func() { d.Show() })
+}
+
+// Progress, StateChanged and ProxiesChanged are delivered to the tabs by the event bus
+
+func (n *Notifier) Progress(float64, string) {}
+
+func (n *Notifier) StateChanged() {}
+
+func (n *Notifier) ProxiesChanged() {}
+
+func (n *Notifier) Do(fn func()) {
+	fyne.Do(fn)
+}
@@ -0,0 +1,477 @@
+package ui
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+
+	"singbox-launcher/core"
+	"singbox-launcher/internal/platform"
+)
+
+// Download proxy choices of the Settings tab
+const (
+	proxyDirect  = "Direct"
+	proxySingBox = "Through running sing-box"
+	proxyCustom  = "Custom proxy"
+)
+
+// sing-box release channel choices of the Settings tab
+const (
+	channelStable     = "Stable"
+	channelPrerelease = "Pre-release (alpha, beta, rc)"
+	channelPinned     = "Pinned version"
+)
+
+// Login autostart choices of the Settings tab
+const (
+	autostartDesktopMode = "Desktop autostart (~/.config/autostart)"
+	autostartSystemdMode = "systemd user service"
+	autostartLastProfile = "Last used"
+)
+
+// CreateSettingsTab creates the "Settings" tab for the launcher-wide preferences stored in bin/settings.json.
+func CreateSettingsTab(ac *core.AppController) fyne.CanvasObject {
+	heading := func(text string) *widget.Label {
+		label := widget.NewLabel(text)
+		label.TextStyle.Bold = true
+		return label
+	}
+
+	autoStartCheck := widget.NewCheck("Start sing-box when the launcher starts", nil)
+	startInTrayCheck := widget.NewCheck("Start minimized to the tray", nil)
+	systemProxyCheck := widget.NewCheck("Set the system proxy while sing-box runs (GNOME, KDE, proxy.env; not for TUN configs)", nil)
+	if !platform.SystemProxySupported() {
+		systemProxyCheck.Hide()
+	}
+	loginCheck := widget.NewCheck("Start the launcher on login", nil)
+	loginModeSelect := widget.NewSelect([]string{autostartDesktopMode, autostartSystemdMode}, nil)
+	loginStartCheck := widget.NewCheck("Start sing-box (-start)", nil)
+	loginTrayCheck := widget.NewCheck("Start minimized to the tray (-tray)", nil)
+	loginProfileSelect := widget.NewSelect(nil, nil)
+	loginStatusLabel := widget.NewLabel("")
+	loginStatusLabel.Wrapping = fyne.TextWrapWord
+	var loginBefore core.AutostartStatus
+	autoApplyCheck := widget.NewCheck("Restart sing-box after a subscription update to apply it", nil)
+	notifyUpdateCheck := widget.NewCheck("Config updated successfully", nil)
+	notifyCrashCheck := widget.NewCheck("sing-box crashed and is restarting", nil)
+
+	latencyURLEntry := widget.NewEntry()
+	latencyURLEntry.SetPlaceHolder("URL from config.json or the sing-box default")
+
+	mirrorsEntry := widget.NewMultiLineEntry()
+	mirrorsEntry.SetPlaceHolder(strings.Join(core.DefaultDownloadMirrors(), "\n"))
+	mirrorsEntry.SetMinRowsVisible(3)
+	proxyURLEntry := widget.NewEntry()
+	proxyURLEntry.SetPlaceHolder("socks5://127.0.0.1:1080")
+	proxySelect := widget.NewSelect([]string{proxyDirect, proxySingBox, proxyCustom}, func(value string) {
+		if value == proxyCustom {
+			proxyURLEntry.Enable()
+		} else {
+			proxyURLEntry.Disable()
+		}
+	})
+	retriesEntry := widget.NewEntry()
+	retriesEntry.SetPlaceHolder(strconv.Itoa(core.DefaultDownloadRetries))
+
+	pinEntry := widget.NewEntry()
+	pinEntry.SetPlaceHolder(core.FallbackVersion + " or 1.12.x")
+	channelSelect := widget.NewSelect([]string{channelStable, channelPrerelease, channelPinned}, func(value string) {
+		if value == channelPinned {
+			pinEntry.Enable()
+		} else {
+			pinEntry.Disable()
+		}
+	})
+
+	capabilitiesCheck := widget.NewCheck("Set capabilities after each sing-box install (asks for the password through polkit)", nil)
+	if !platform.CapabilitiesRequired() {
+		capabilitiesCheck.Hide()
+	}
+	launcherChannelSelect := widget.NewSelect([]string{channelStable, channelPrerelease}, nil)
+	githubTokenEntry := widget.NewPasswordEntry()
+	githubTokenEntry.SetPlaceHolder("Optional personal access token")
+
+	controlEnabledCheck := widget.NewCheck("Enable the local control API (127.0.0.1)", nil)
+	controlPortEntry := widget.NewEntry()
+	controlPortEntry.SetPlaceHolder(strconv.Itoa(core.DefaultControlPort))
+	controlTokenEntry := widget.NewEntry()
+	controlTokenEntry.SetPlaceHolder("Generated when the server starts")
+	controlOriginsEntry := widget.NewMultiLineEntry()
+	controlOriginsEntry.SetPlaceHolder("chrome-extension://<id>")
+	controlOriginsEntry.SetMinRowsVisible(2)
+
+	// Автозапуск читается из файла на диске: его могли изменить или удалить вручную
+	loadAutostart := func() {
+		if !core.AutostartSupported() {
+			return
+		}
+		loginProfileSelect.SetOptions(append([]string{autostartLastProfile}, ac.Profiles.Names()...))
+		status, err := core.ReadAutostart()
+		if err != nil {
+			log.Printf("settingsTab: %v", err)
+			loginStatusLabel.SetText(err.Error())
+			loginStatusLabel.Show()
+		}
+		loginBefore = status
+		options := status.Options
+		if status.Path == "" {
+			options = core.AutostartOptions{Mode: core.AutostartDesktop, Tray: true}
+		}
+		loginCheck.SetChecked(status.Enabled)
+		if options.Mode == core.AutostartSystemd {
+			loginModeSelect.SetSelected(autostartSystemdMode)
+		} else {
+			loginModeSelect.SetSelected(autostartDesktopMode)
+		}
+		loginStartCheck.SetChecked(options.StartVPN)
+		loginTrayCheck.SetChecked(options.Tray)
+		if options.Profile == "" {
+			loginProfileSelect.SetSelected(autostartLastProfile)
+		} else {
+			loginProfileSelect.SetSelected(options.Profile)
+		}
+		if err == nil {
+			switch {
+			case status.Modified:
+				loginStatusLabel.SetText(fmt.Sprintf("%s was changed outside the launcher (%s). Saving with this section changed overwrites it.", status.Path, status.Detail))
+				loginStatusLabel.Show()
+			case status.Path != "" && !status.Enabled:
+				loginStatusLabel.SetText(fmt.Sprintf("%s exists but is disabled (%s).", status.Path, status.Detail))
+				loginStatusLabel.Show()
+			default:
+				loginStatusLabel.Hide()
+			}
+		}
+	}
+
+	// Заполняем поля из сохраненных настроек
+	load := func() {
+		settings := ac.Settings.Get()
+		autoStartCheck.SetChecked(settings.AutoStart)
+		startInTrayCheck.SetChecked(settings.StartInTray)
+		systemProxyCheck.SetChecked(settings.SystemProxy)
+		autoApplyCheck.SetChecked(settings.AutoApplyOnUpdate)
+		notifyUpdateCheck.SetChecked(settings.Notifications.ConfigUpdated)
+		notifyCrashCheck.SetChecked(settings.Notifications.CrashRestart)
+		latencyURLEntry.SetText(settings.LatencyTestURL)
+		mirrorsEntry.SetText(strings.Join(settings.Downloads.Mirrors, "\n"))
+		proxyURLEntry.SetText("")
+		switch settings.Downloads.Proxy {
+		case "":
+			proxySelect.SetSelected(proxyDirect)
+		case core.DownloadProxySingBox:
+			proxySelect.SetSelected(proxySingBox)
+		default:
+			proxySelect.SetSelected(proxyCustom)
+			proxyURLEntry.SetText(settings.Downloads.Proxy)
+		}
+		retriesEntry.SetText("")
+		if settings.Downloads.Retries > 0 {
+			retriesEntry.SetText(strconv.Itoa(settings.Downloads.Retries))
+		}
+		pinEntry.SetText(settings.Core.Pin)
+		capabilitiesCheck.SetChecked(settings.Core.SetCapabilities)
+		switch settings.Core.Channel {
+		case core.CoreChannelPrerelease:
+			channelSelect.SetSelected(channelPrerelease)
+		case core.CoreChannelPinned:
+			channelSelect.SetSelected(channelPinned)
+		default:
+			channelSelect.SetSelected(channelStable)
+		}
+		if settings.Launcher.Channel == core.CoreChannelPrerelease {
+			launcherChannelSelect.SetSelected(channelPrerelease)
+		} else {
+			launcherChannelSelect.SetSelected(channelStable)
+		}
+		githubTokenEntry.SetText(settings.GitHub.Token)
+		controlEnabledCheck.SetChecked(settings.ControlServer.Enabled)
+		controlPortEntry.SetText("")
+		if settings.ControlServer.Port > 0 {
+			controlPortEntry.SetText(strconv.Itoa(settings.ControlServer.Port))
+		}
+		controlTokenEntry.SetText(settings.ControlServer.Token)
+		controlOriginsEntry.SetText(strings.Join(settings.ControlServer.AllowedOrigins, "\n"))
+		loadAutostart()
+	}
+	load()
+
+	regenerateButton := widget.NewButton("New token", func() {
+		token, err := core.GenerateControlToken()
+		if err != nil {
+			ShowError(mainWindow(), err)
+			return
+		}
+		controlTokenEntry.SetText(token)
+	})
+	copyButton := widget.NewButton("Copy", func() {
+		mainWindow().Clipboard().SetContent(controlTokenEntry.Text)
+	})
+
+	githubTokenHint := widget.NewLabel("Version checks are limited to 60 requests per hour per IP address. A token without scopes raises the limit to 5000; it is sent to api.github.com only.")
+	githubTokenHint.Wrapping = fyne.TextWrapWord
+
+	mirrorsHint := widget.NewLabel("One source per line, tried in order: github, sourceforge or a URL template with {url}, {version}, {file}.")
+	mirrorsHint.Wrapping = fyne.TextWrapWord
+
+	controlOriginsHint := widget.NewLabel("Browser extensions allowed to call the API, one origin per line (chrome-extension://<id>, moz-extension://<uuid>). Scripts and curl need only the token.")
+	controlOriginsHint.Wrapping = fyne.TextWrapWord
+
+	saveButton := widget.NewButton("Save", func() {
+		port := 0
+		if text := strings.TrimSpace(controlPortEntry.Text); text != "" {
+			value, err := strconv.Atoi(text)
+			if err != nil || value < 1 || value > 65535 {
+				ShowErrorText(mainWindow(), "Settings", fmt.Sprintf("Invalid control API port %q: expected 1-65535", text))
+				return
+			}
+			port = value
+		}
+		latencyURL := strings.TrimSpace(latencyURLEntry.Text)
+		if latencyURL != "" && !strings.HasPrefix(latencyURL, "http://") && !strings.HasPrefix(latencyURL, "https://") {
+			ShowErrorText(mainWindow(), "Settings", "Latency test URL must start with http:// or https://")
+			return
+		}
+
+		var origins []string
+		for _, line := range strings.Split(controlOriginsEntry.Text, "\n") {
+			line = strings.TrimSuffix(strings.TrimSpace(line), "/")
+			if line == "" {
+				continue
+			}
+			if !core.IsExtensionOrigin(line) {
+				ShowErrorText(mainWindow(), "Settings", fmt.Sprintf("Invalid extension origin %q: expected chrome-extension://<id> or moz-extension://<uuid>", line))
+				return
+			}
+			origins = append(origins, line)
+		}
+
+		var mirrors []string
+		for _, line := range strings.Split(mirrorsEntry.Text, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				mirrors = append(mirrors, line)
+			}
+		}
+		proxy := ""
+		switch proxySelect.Selected {
+		case proxySingBox:
+			proxy = core.DownloadProxySingBox
+		case proxyCustom:
+			proxy = strings.TrimSpace(proxyURLEntry.Text)
+			if _, err := core.ParseDownloadProxy(proxy); err != nil {
+				ShowErrorText(mainWindow(), "Settings", err.Error())
+				return
+			}
+		}
+		retries := 0
+		if text := strings.TrimSpace(retriesEntry.Text); text != "" {
+			value, err := strconv.Atoi(text)
+			if err != nil || value < 1 || value > 10 {
+				ShowErrorText(mainWindow(), "Settings", fmt.Sprintf("Invalid number of download attempts %q: expected 1-10", text))
+				return
+			}
+			retries = value
+		}
+
+		channel, pin := core.CoreChannelStable, ""
+		switch channelSelect.Selected {
+		case channelPrerelease:
+			channel = core.CoreChannelPrerelease
+		case channelPinned:
+			channel, pin = core.CoreChannelPinned, strings.TrimPrefix(strings.TrimSpace(pinEntry.Text), "v")
+			if err := core.ValidateVersionSpec(pin); err != nil {
+				ShowErrorText(mainWindow(), "Settings", err.Error())
+				return
+			}
+		}
+
+		launcherChannel := core.CoreChannelStable
+		if launcherChannelSelect.Selected == channelPrerelease {
+			launcherChannel = core.CoreChannelPrerelease
+		}
+
+		controlBefore := ac.Settings.ControlServer()
+		channelBefore, pinBefore := ac.CoreChannel()
+		launcherChannelBefore := ac.LauncherChannel()
+		systemProxyBefore := ac.Settings.Get().SystemProxy
+		capabilitiesBefore := ac.Settings.Get().Core.SetCapabilities
+		githubToken := strings.TrimSpace(githubTokenEntry.Text)
+		githubTokenBefore := ac.Settings.Get().GitHub.Token
+		err := ac.Settings.Update(func(settings *core.Settings) {
+			settings.AutoStart = autoStartCheck.Checked
+			settings.StartInTray = startInTrayCheck.Checked
+			settings.SystemProxy = systemProxyCheck.Checked
+			settings.AutoApplyOnUpdate = autoApplyCheck.Checked
+			settings.Notifications.ConfigUpdated = notifyUpdateCheck.Checked
+			settings.Notifications.CrashRestart = notifyCrashCheck.Checked
+			settings.LatencyTestURL = latencyURL
+			settings.Downloads = core.DownloadSettings{Mirrors: mirrors, Proxy: proxy, Retries: retries}
+			settings.Core.Channel = channel
+			settings.Core.Pin = pin
+			settings.Core.SetCapabilities = capabilitiesCheck.Checked
+			settings.Launcher.Channel = launcherChannel
+			settings.GitHub.Token = githubToken
+			settings.ControlServer = core.ControlServerSettings{
+				Enabled:        controlEnabledCheck.Checked,
+				Port:           port,
+				Token:          strings.TrimSpace(controlTokenEntry.Text),
+				AllowedOrigins: origins,
+			}
+		})
+		if err != nil {
+			log.Printf("settingsTab: Failed to save settings: %v", err)
+			ShowError(mainWindow(), err)
+			return
+		}
+		if channel != channelBefore || pin != pinBefore {
+			// Последняя версия в кеше относится к прежнему каналу
+			ac.ResetCoreVersionCache()
+			ac.CheckVersionInBackground()
+		}
+		if systemProxyCheck.Checked != systemProxyBefore && ac.RunningState.IsRunning() {
+			apply := ac.RestoreSystemProxy
+			if systemProxyCheck.Checked {
+				apply = ac.EnableSystemProxy
+			}
+			go func() {
+				if err := apply(); err != nil {
+					log.Printf("settingsTab: %v", err)
+					fyne.Do(func() { ShowError(mainWindow(), err) })
+				}
+			}()
+		}
+		if capabilitiesCheck.Checked && !capabilitiesBefore {
+			// Сразу применяем к установленному бинарнику
+			go func() {
+				if err := ac.EnsureCoreCapabilities(); err != nil {
+					log.Printf("settingsTab: %v", err)
+					fyne.Do(func() { ShowError(mainWindow(), err) })
+				}
+			}()
+		}
+		if githubToken != githubTokenBefore {
+			// Лимит без токена к новому токену не относится
+			ac.ClearGitHubRateLimit()
+			ac.CheckVersionInBackground()
+		}
+		if launcherChannel != launcherChannelBefore {
+			go func() {
+				if version, err := ac.GetLatestLauncherVersion(); err == nil {
+					ac.SetCachedLauncherVersion(version)
+				}
+			}()
+		}
+		if core.AutostartSupported() {
+			options := core.AutostartOptions{
+				Mode:     core.AutostartDesktop,
+				StartVPN: loginStartCheck.Checked,
+				Tray:     loginTrayCheck.Checked,
+			}
+			if loginModeSelect.Selected == autostartSystemdMode {
+				options.Mode = core.AutostartSystemd
+			}
+			if loginProfileSelect.Selected != autostartLastProfile {
+				options.Profile = loginProfileSelect.Selected
+			}
+			// Файл перезаписывается только при изменении раздела, чтобы не затереть ручные правки
+			before := loginBefore.Options
+			before.Executable = ""
+			var err error
+			switch {
+			case loginCheck.Checked && (!loginBefore.Enabled || before != options):
+				err = core.EnableAutostart(options)
+			case !loginCheck.Checked && loginBefore.Path != "" && loginBefore.Enabled:
+				err = core.DisableAutostart()
+			}
+			loadAutostart()
+			if err != nil {
+				log.Printf("settingsTab: %v", err)
+				ShowError(mainWindow(), err)
+				return
+			}
+		}
+		message := "Settings saved."
+		if !ac.Settings.ControlServer().Equal(controlBefore) {
+			message += " Control API changes apply after restarting the launcher."
+		}
+		ShowAutoHideInfo(application(), mainWindow(), "Settings", message)
+	})
+	saveButton.Importance = widget.HighImportance
+	revertButton := widget.NewButton("Revert", load)
+
+	loginSection := container.NewVBox(
+		widget.NewSeparator(),
+		heading("Start on login"),
+		loginCheck,
+		widget.NewForm(
+			widget.NewFormItem("Method", loginModeSelect),
+			widget.NewFormItem("Profile", loginProfileSelect),
+		),
+		loginStartCheck,
+		loginTrayCheck,
+		loginStatusLabel,
+	)
+	if !core.AutostartSupported() {
+		loginSection.Hide()
+	}
+
+	content := container.NewVBox(
+		heading("Startup"),
+		autoStartCheck,
+		startInTrayCheck,
+		systemProxyCheck,
+		loginSection,
+		widget.NewSeparator(),
+		heading("Subscriptions"),
+		autoApplyCheck,
+		widget.NewSeparator(),
+		heading("Notifications"),
+		notifyUpdateCheck,
+		notifyCrashCheck,
+		widget.NewSeparator(),
+		heading("Latency test"),
+		widget.NewForm(widget.NewFormItem("Default URL", latencyURLEntry)),
+		widget.NewSeparator(),
+		heading("Downloads (sing-box, wintun.dll)"),
+		widget.NewForm(
+			widget.NewFormItem("Mirrors", mirrorsEntry),
+			widget.NewFormItem("Proxy", proxySelect),
+			widget.NewFormItem("Proxy URL", proxyURLEntry),
+			widget.NewFormItem("Attempts", retriesEntry),
+		),
+		mirrorsHint,
+		widget.NewSeparator(),
+		heading("sing-box updates"),
+		widget.NewForm(
+			widget.NewFormItem("Channel", channelSelect),
+			widget.NewFormItem("Version", pinEntry),
+		),
+		capabilitiesCheck,
+		widget.NewSeparator(),
+		heading("Launcher updates"),
+		widget.NewForm(widget.NewFormItem("Channel", launcherChannelSelect)),
+		widget.NewSeparator(),
+		heading("GitHub API"),
+		widget.NewForm(widget.NewFormItem("Token", githubTokenEntry)),
+		githubTokenHint,
+		widget.NewSeparator(),
+		heading("Control API"),
+		controlEnabledCheck,
+		widget.NewForm(
+			widget.NewFormItem("Port", controlPortEntry),
+			widget.NewFormItem("Token", container.NewBorder(nil, nil, nil, container.NewHBox(copyButton, regenerateButton), controlTokenEntry)),
+			widget.NewFormItem("Extensions", controlOriginsEntry),
+		),
+		controlOriginsHint,
+		widget.NewSeparator(),
+		container.NewHBox(saveButton, revertButton),
+		widget.NewLabel("Stored in "+ac.Settings.Path()),
+	)
+	return container.NewVScroll(content)
+}
@@ -0,0 +1,106 @@
+package ui
+
+import (
+	"image/color"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Sparkline is a small line graph of recent values (e.g. download/upload speed).
+// Each series is drawn with its own color and scaled to the common maximum.
+type Sparkline struct {
+	widget.BaseWidget
+
+	series  [][]float64
+	colors  []color.Color
+	minSize fyne.Size
+}
+
+// NewSparkline creates an empty sparkline with one color per series
+func NewSparkline(minSize fyne.Size, colors ...color.Color) *Sparkline {
+	s := &Sparkline{colors: colors, minSize: minSize, series: make([][]float64, len(colors))}
+	s.ExtendBaseWidget(s)
+	return s
+}
+
+// SetData replaces the series values and redraws (UI thread only)
+func (s *Sparkline) SetData(series ...[]float64) {
+	s.series = series
+	s.Refresh()
+}
+
+// CreateRenderer implements fyne.Widget
+func (s *Sparkline) CreateRenderer() fyne.WidgetRenderer {
+	r := &sparklineRenderer{sparkline: s, background: canvas.NewRectangle(theme.Color(theme.ColorNameInputBackground))}
+	r.Refresh()
+	return r
+}
+
+type sparklineRenderer struct {
+	sparkline  *Sparkline
+	background *canvas.Rectangle
+	lines      []fyne.CanvasObject
+}
+
+func (r *sparklineRenderer) Layout(size fyne.Size) {
+	r.background.Resize(size)
+	r.rebuild(size)
+}
+
+func (r *sparklineRenderer) MinSize() fyne.Size {
+	return r.sparkline.minSize
+}
+
+func (r *sparklineRenderer) Refresh() {
+	r.background.FillColor = theme.Color(theme.ColorNameInputBackground)
+	r.background.Refresh()
+	r.rebuild(r.sparkline.Size())
+	canvas.Refresh(r.sparkline)
+}
+
+func (r *sparklineRenderer) Objects() []fyne.CanvasObject {
+	return append([]fyne.CanvasObject{r.background}, r.lines...)
+}
+
+func (r *sparklineRenderer) Destroy() {}
+
+// rebuild recreates line segments for the current data and size
+func (r *sparklineRenderer) rebuild(size fyne.Size) {
+	r.lines = r.lines[:0]
+	if size.Width <= 0 || size.Height <= 0 {
+		return
+	}
+
+	maxValue := 0.0
+	for _, values := range r.sparkline.series {
+		for _, v := range values {
+			if v > maxValue {
+				maxValue = v
+			}
+		}
+	}
+	if maxValue <= 0 {
+		maxValue = 1
+	}
+
+	for i, values := range r.sparkline.series {
+		if len(values) < 2 || i >= len(r.sparkline.colors) {
+			continue
+		}
+		step := size.Width / float32(len(values)-1)
+		point := func(j int) fyne.Position {
+			y := size.Height - float32(values[j]/maxValue)*(size.Height-2) - 1
+			return fyne.NewPos(float32(j)*step, y)
+		}
+		for j := 1; j < len(values); j++ {
+			line := canvas.NewLine(r.sparkline.colors[i])
+			line.StrokeWidth = 1.5
+			line.Position1 = point(j - 1)
+			line.Position2 = point(j)
+			r.lines = append(r.lines, line)
+		}
+	}
+}